@@ -0,0 +1,145 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Achievements earned in a course.
+package achievements
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/history"
+)
+
+// Course stats used to check achievements.
+type stats struct {
+	vocabSize int
+	streak    int
+	reviews   int
+}
+
+type Achievement struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Nil if not yet earned.
+	Earned *time.Time `json:"earned"`
+
+	check func(s stats) bool
+}
+
+// Returns list of all achievements.
+func all() []Achievement {
+	return []Achievement{
+		{
+			Name:        "words-100",
+			Description: "Learn 100 words",
+			check:       func(s stats) bool { return s.vocabSize >= 100 },
+		},
+		{
+			Name:        "words-1000",
+			Description: "Learn 1,000 words",
+			check:       func(s stats) bool { return s.vocabSize >= 1000 },
+		},
+		{
+			Name:        "streak-7",
+			Description: "Study 7 days in a row",
+			check:       func(s stats) bool { return s.streak >= 7 },
+		},
+		{
+			Name:        "streak-30",
+			Description: "Study 30 days in a row",
+			check:       func(s stats) bool { return s.streak >= 30 },
+		},
+		{
+			Name:        "reviews-10000",
+			Description: "Do 10,000 reviews",
+			check:       func(s stats) bool { return s.reviews >= 10000 },
+		},
+	}
+}
+
+func getStats(db *sql.DB, now time.Time) (stats, error) {
+	var s stats
+	var err error
+
+	s.vocabSize, err = history.CurrentVocabSize(db)
+	if err != nil {
+		return s, err
+	}
+	s.streak, err = history.Streak(db, now)
+	if err != nil {
+		return s, err
+	}
+	s.reviews, err = history.CountReviews(db)
+	return s, err
+}
+
+// Returns earned achievements: name -> time earned.
+func earned(db *sql.DB) (map[string]time.Time, error) {
+	rows, err := db.Query(`SELECT name, earned FROM achievement`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var timestamp int64
+		if err := rows.Scan(&name, &timestamp); err != nil {
+			return nil, err
+		}
+		result[name] = time.Unix(timestamp, 0)
+	}
+	return result, nil
+}
+
+// Returns all achievements, earned or not.
+// db: review DB
+func List(db *sql.DB) ([]Achievement, error) {
+	done, err := earned(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list achievements: %w", err)
+	}
+
+	achievements := all()
+	for i, a := range achievements {
+		if t, ok := done[a.Name]; ok {
+			achievements[i].Earned = &t
+		}
+	}
+	return achievements, nil
+}
+
+// Awards achievements that have been earned since the last check.
+// Should be called after saving reviews.
+// Returns newly earned achievements.
+func Check(db *sql.DB, now time.Time) ([]Achievement, error) {
+	done, err := earned(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check achievements: %w", err)
+	}
+
+	s, err := getStats(db, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check achievements: %w", err)
+	}
+
+	var awarded []Achievement
+	for _, a := range all() {
+		if _, ok := done[a.Name]; ok || !a.check(s) {
+			continue
+		}
+
+		query := `INSERT OR IGNORE INTO achievement (name, earned) VALUES (?, ?)`
+		if _, err := db.Exec(query, a.Name, now.Unix()); err != nil {
+			return nil, fmt.Errorf("failed to award achievement (%v): %w", a.Name, err)
+		}
+		t := now
+		a.Earned = &t
+		awarded = append(awarded, a)
+	}
+	return awarded, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package achievements
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	// Study for 7 days in a row.
+	now := time.Now()
+	for i := 6; i >= 0; i-- {
+		word := fmt.Sprintf("word%v", i)
+		at := now.AddDate(0, 0, -i)
+		if err := review_scheduler.UpdateReviewAt(db, word, true, at); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	awarded, err := Check(db, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(awarded) != 1 || awarded[0].Name != "streak-7" {
+		t.Fatal("expected 7-day streak achievement:", awarded)
+	}
+
+	// Achievements are only awarded once.
+	awarded, err = Check(db, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(awarded) != 0 {
+		t.Fatal("expected no new achievements:", awarded)
+	}
+
+	achievements, err := List(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	for _, a := range achievements {
+		if (a.Earned != nil) != (a.Name == "streak-7") {
+			t.Fatal("unexpected achievement state:", a.Name, a.Earned)
+		}
+	}
+}
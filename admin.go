@@ -0,0 +1,646 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Administration subcommands.
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/bundle"
+	"github.com/polycloze/polycloze/course_patch"
+	"github.com/polycloze/polycloze/coverage"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/maintenance"
+	"github.com/polycloze/polycloze/merge"
+	"github.com/polycloze/polycloze/phrases"
+	"github.com/polycloze/polycloze/replay"
+)
+
+// Returns paths of all databases in the state directory, starting with the
+// auth DB.
+func stateDatabases() ([]string, error) {
+	paths := []string{basedir.Auth()}
+
+	userDBs, err := filepath.Glob(filepath.Join(basedir.StateDir, "users", "*", "user.db"))
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, userDBs...)
+
+	reviewDBs, err := filepath.Glob(filepath.Join(basedir.StateDir, "users", "*", "reviews", "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, reviewDBs...)
+
+	productionDBs, err := filepath.Glob(filepath.Join(basedir.StateDir, "users", "*", "production", "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	return append(paths, productionDBs...), nil
+}
+
+// Opens database in the state directory using the right migrations.
+func openStateDB(path string) (*sql.DB, error) {
+	switch {
+	case path == basedir.Auth():
+		return database.OpenAuthDB(path)
+	case filepath.Base(path) == "user.db":
+		return database.OpenUserDB(path)
+	default:
+		return database.OpenReviewDB(path)
+	}
+}
+
+// Upgrades all databases in the state directory to the latest version.
+func migrate(args []string) error {
+	paths, err := stateDatabases()
+	if err != nil {
+		return fmt.Errorf("migrate failed: %w", err)
+	}
+	for _, path := range paths {
+		db, err := openStateDB(path)
+		if err != nil {
+			return fmt.Errorf("migrate failed (%v): %w", path, err)
+		}
+		db.Close()
+		fmt.Println("migrated", path)
+	}
+	return nil
+}
+
+// Copies all databases in the state directory into another directory.
+func backup(args []string) error {
+	if len(args) != 1 {
+		return errors.New("backup failed: missing arg: directory")
+	}
+	dir := args[0]
+
+	paths, err := stateDatabases()
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	for _, path := range paths {
+		rel, err := filepath.Rel(basedir.StateDir, path)
+		if err != nil {
+			return fmt.Errorf("backup failed: %w", err)
+		}
+		dest := filepath.Join(dir, rel)
+		if err := database.Backup(path, dest); err != nil {
+			return fmt.Errorf("backup failed (%v): %w", path, err)
+		}
+		fmt.Println("backed up", path)
+	}
+	return nil
+}
+
+// Reads password from stdin.
+// Doesn't echo the password if stdin is a terminal.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	var password string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		password = string(b)
+	} else {
+		reader := bufio.NewReader(os.Stdin)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
+
+// Manages user accounts.
+func user(args []string) error {
+	if len(args) != 2 {
+		return errors.New("user: expected subcommand and username")
+	}
+	subcommand, username := args[0], args[1]
+
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch subcommand {
+	case "add":
+		password, err := readPassword()
+		if err != nil {
+			return fmt.Errorf("user add failed: %w", err)
+		}
+		return auth.Register(db, username, password)
+	case "passwd":
+		userID, err := auth.GetUserID(db, username)
+		if err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return fmt.Errorf("user passwd failed: %w", err)
+		}
+		return auth.ChangePassword(db, userID, password)
+	case "delete":
+		userID, err := auth.GetUserID(db, username)
+		if err != nil {
+			return err
+		}
+		if err := auth.DeleteUser(db, userID); err != nil {
+			return err
+		}
+		return os.RemoveAll(basedir.User(userID))
+	case "promote", "demote":
+		userID, err := auth.GetUserID(db, username)
+		if err != nil {
+			return err
+		}
+		return auth.SetAdmin(db, userID, subcommand == "promote")
+	default:
+		return fmt.Errorf("user: unknown subcommand: %v", subcommand)
+	}
+}
+
+// Merges a duplicate account into another account.
+func mergeUsers(args []string) error {
+	if len(args) != 2 {
+		return errors.New("merge-users failed: expected username and duplicate username")
+	}
+
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	userID, err := auth.GetUserID(db, args[0])
+	if err != nil {
+		return fmt.Errorf("merge-users failed: %w", err)
+	}
+	duplicateID, err := auth.GetUserID(db, args[1])
+	if err != nil {
+		return fmt.Errorf("merge-users failed: %w", err)
+	}
+
+	report, err := merge.Accounts(db, userID, duplicateID)
+	if err != nil {
+		return fmt.Errorf("merge-users failed: %w", err)
+	}
+	for _, path := range report.Merged {
+		fmt.Println("merged", path)
+	}
+	for _, path := range report.Copied {
+		fmt.Println("copied", path)
+	}
+	return nil
+}
+
+// Checks if course database has the tables needed by the server.
+func checkCourse(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := database.Open(path + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tables := []string{"language", "word", "sentence", "contains", "translation", "translates"}
+	for _, table := range tables {
+		var count int
+		query := fmt.Sprintf(`SELECT count(*) FROM %v`, table)
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 && table != "translates" {
+			return fmt.Errorf("empty table: %v", table)
+		}
+	}
+	return nil
+}
+
+// Checks if course databases are valid.
+func validateCourse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("validate-course failed: missing arg: course.db")
+	}
+	for _, path := range args {
+		if err := checkCourse(path); err != nil {
+			return fmt.Errorf("invalid course (%v): %w", path, err)
+		}
+		fmt.Println("ok", path)
+	}
+	return nil
+}
+
+// Adds phrases (one per line) to a course database.
+func addPhrases(args []string) error {
+	if len(args) != 2 {
+		return errors.New("add-phrases failed: expected course.db and phrases file")
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return fmt.Errorf("add-phrases failed: %w", err)
+	}
+	defer f.Close()
+
+	db, err := database.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("add-phrases failed: %w", err)
+	}
+	defer db.Close()
+
+	added := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		phrase := strings.TrimSpace(scanner.Text())
+		if phrase == "" || strings.HasPrefix(phrase, "#") {
+			continue
+		}
+
+		count, err := phrases.Add(db, phrase)
+		if errors.Is(err, phrases.ErrNotPhrase) {
+			log.Println(err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("add-phrases failed: %w", err)
+		}
+		if count == 0 {
+			log.Println("no sentences found:", phrase)
+			continue
+		}
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("add-phrases failed: %w", err)
+	}
+	fmt.Println("added", added, "phrases")
+	return nil
+}
+
+// Returns path to user's review DB in a course.
+func userReviewDBPath(username, l1, l2 string) (string, error) {
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	userID, err := auth.GetUserID(db, username)
+	if err != nil {
+		return "", err
+	}
+
+	path := basedir.Review(userID, l1, l2)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Opens user's review DB in a course.
+func openUserReviewDB(username, l1, l2 string) (*sql.DB, error) {
+	path, err := userReviewDBPath(username, l1, l2)
+	if err != nil {
+		return nil, err
+	}
+	return database.OpenReviewDB(path)
+}
+
+// Writes user's review history in a course as CSV into stdout.
+func export(args []string) error {
+	if len(args) != 3 {
+		return errors.New("export failed: expected username, l1 and l2")
+	}
+
+	path, err := userReviewDBPath(args[0], args[1], args[2])
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	rdb, err := database.OpenReviewDB(path)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	defer rdb.Close()
+
+	// Include reviews that have been moved into the archive.
+	return replay.ExportWithArchive(rdb, basedir.ReviewArchive(path), os.Stdout)
+}
+
+// Writes user's difficulty tuner stats in a course as JSON into stdout.
+func exportStats(args []string) error {
+	if len(args) != 3 {
+		return errors.New("export-stats failed: expected username, l1 and l2")
+	}
+
+	rdb, err := openUserReviewDB(args[0], args[1], args[2])
+	if err != nil {
+		return fmt.Errorf("export-stats failed: %w", err)
+	}
+	defer rdb.Close()
+	return replay.ExportStats(rdb, os.Stdout)
+}
+
+// Deletes expired sessions and inactive accounts.
+func prune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be deleted")
+	inactiveDays := flags.Int("inactive-days", 0, "warn users inactive for this many days, and delete their accounts two weeks later (0: never)")
+	_ = flags.Parse(args)
+
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// For warning inactive users.
+	configureMail()
+	defer mail.Flush()
+
+	policy := maintenance.Policy{
+		InactiveAccountAge: time.Duration(*inactiveDays) * 24 * time.Hour,
+	}
+	report, err := maintenance.Prune(db, policy, time.Now(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("expired sessions:", report.ExpiredSessions)
+	for _, a := range report.InactiveAccounts {
+		fmt.Printf(
+			"inactive account: %v (last active %v)\n",
+			a.Username,
+			a.LastActive.Format("2006-01-02"),
+		)
+	}
+	for _, a := range report.WarnedAccounts {
+		fmt.Printf(
+			"warned about deletion: %v (last active %v)\n",
+			a.Username,
+			a.LastActive.Format("2006-01-02"),
+		)
+	}
+	for _, a := range report.UnwarnedAccounts {
+		fmt.Printf(
+			"kept, can't be warned: %v (last active %v)\n",
+			a.Username,
+			a.LastActive.Format("2006-01-02"),
+		)
+	}
+	fmt.Println("abandoned trial accounts:", len(report.AbandonedTrials))
+	if report.DryRun {
+		fmt.Println("dry run: nothing was deleted")
+	}
+	return nil
+}
+
+// Moves old reviews into archive DBs, so that review DBs stay small.
+func archive(args []string) error {
+	flags := flag.NewFlagSet("archive", flag.ExitOnError)
+	days := flags.Int("days", 365, "archive reviews older than this many days")
+	_ = flags.Parse(args)
+
+	if *days <= 0 {
+		return errors.New("archive failed: days should be positive")
+	}
+
+	before := time.Now().AddDate(0, 0, -*days)
+	count, err := maintenance.ArchiveHistory(before)
+	if err != nil {
+		return err
+	}
+	fmt.Println("archived reviews:", count)
+	return nil
+}
+
+// Rebuilds review tables from their event logs, e.g. after restoring review
+// DBs from old backups.
+func rebuildReviews(args []string) error {
+	if len(args) != 0 {
+		return errors.New("rebuild-reviews failed: unexpected arguments")
+	}
+
+	count, err := maintenance.RebuildReviews()
+	if err != nil {
+		return err
+	}
+	fmt.Println("repaired items:", count)
+	return nil
+}
+
+// Restores user's review state in a course to what it was at the given time.
+// The time can be a date (YYYY-MM-DD, UTC) or an RFC 3339 timestamp.
+func restore(args []string) error {
+	if len(args) != 4 {
+		return errors.New("restore failed: expected username, l1, l2 and time")
+	}
+
+	t, err := time.Parse(time.RFC3339, args[3])
+	if err != nil {
+		t, err = time.Parse("2006-01-02", args[3])
+	}
+	if err != nil {
+		return fmt.Errorf("restore failed: invalid time: %w", err)
+	}
+
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	defer db.Close()
+
+	userID, err := auth.GetUserID(db, args[0])
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	count, err := maintenance.RestoreCourse(userID, args[1], args[2], t)
+	if err != nil {
+		return err
+	}
+	fmt.Println("changed items:", count)
+	return nil
+}
+
+// Deletes regenerable files in the cache directory.
+func cleanCache(args []string) error {
+	if len(args) != 0 {
+		return errors.New("clean-cache failed: unexpected arguments")
+	}
+
+	freed, err := maintenance.CleanCache()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("freed %.1f MB\n", float64(freed)/(1024*1024))
+	return nil
+}
+
+// Applies patch to course database (see package course_patch).
+func patchCourse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("patch-course failed: expected course.db and patch file")
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		return fmt.Errorf("patch-course failed: %w", err)
+	}
+	defer f.Close()
+
+	patch, err := course_patch.Parse(f)
+	if err != nil {
+		return fmt.Errorf("patch-course failed: %w", err)
+	}
+
+	if err := checkCourse(args[0]); err != nil {
+		return fmt.Errorf("invalid course (%v): %w", args[0], err)
+	}
+	db, err := database.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("patch-course failed: %w", err)
+	}
+	defer db.Close()
+
+	if err := course_patch.Apply(db, patch); err != nil {
+		return err
+	}
+	fmt.Println("ok", args[0])
+	return nil
+}
+
+// Prints checksums of course databases, for writing patch headers.
+func courseChecksum(args []string) error {
+	if len(args) == 0 {
+		return errors.New("course-checksum failed: missing arg: course.db")
+	}
+	for _, path := range args {
+		if err := checkCourse(path); err != nil {
+			return fmt.Errorf("invalid course (%v): %w", path, err)
+		}
+
+		db, err := database.Open(path + "?mode=ro")
+		if err != nil {
+			return fmt.Errorf("course-checksum failed: %w", err)
+		}
+		sum, err := course_patch.Checksum(db)
+		db.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Println(sum, path)
+	}
+	return nil
+}
+
+// Lists words in the course with few example sentences (tab-separated word,
+// frequency class and number of sentences).
+func sentenceCoverage(args []string) error {
+	flags := flag.NewFlagSet("sentence-coverage", flag.ExitOnError)
+	threshold := flags.Int("threshold", coverage.MinSentences, "list words with fewer sentences than this")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("sentence-coverage failed: expected course.db")
+	}
+	path := flags.Arg(0)
+	if err := checkCourse(path); err != nil {
+		return fmt.Errorf("invalid course (%v): %w", path, err)
+	}
+
+	db, err := database.Open(path + "?mode=ro")
+	if err != nil {
+		return fmt.Errorf("sentence-coverage failed: %w", err)
+	}
+	defer db.Close()
+
+	words, err := coverage.Report(db, *threshold)
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		fmt.Printf("%v\t%v\t%v\n", word.Word, word.FrequencyClass, word.Sentences)
+	}
+	return nil
+}
+
+// Returns user ID of user with the given username.
+func lookUpUserID(username string) (int, error) {
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return auth.GetUserID(db, username)
+}
+
+// Writes offline bundle of a course and the user's data in it into stdout
+// (see package bundle).
+func exportBundle(args []string) error {
+	if len(args) != 3 {
+		return errors.New("export-bundle failed: expected username, l1 and l2")
+	}
+
+	userID, err := lookUpUserID(args[0])
+	if err != nil {
+		return fmt.Errorf("export-bundle failed: %w", err)
+	}
+	return bundle.Export(os.Stdout, userID, args[1], args[2], time.Now())
+}
+
+// Imports reviews in offline bundle into the user's review DBs.
+func importBundle(args []string) error {
+	if len(args) != 4 {
+		return errors.New("import-bundle failed: expected username, l1, l2 and bundle file")
+	}
+
+	userID, err := lookUpUserID(args[0])
+	if err != nil {
+		return fmt.Errorf("import-bundle failed: %w", err)
+	}
+
+	f, err := os.Open(args[3])
+	if err != nil {
+		return fmt.Errorf("import-bundle failed: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("import-bundle failed: %w", err)
+	}
+	// Bundles imported by admins aren't limited to the upload size, but review
+	// DBs in them still can't be unreasonably big.
+	const maxSize = 1 << 30
+	return bundle.Import(f, info.Size(), maxSize, userID, args[1], args[2])
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Vocabulary coverage of texts.
+package analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+
+	// Whether or not the word can be studied in the course.
+	InCourse bool `json:"inCourse"`
+}
+
+type Report struct {
+	Tokens  int `json:"tokens"`  // Number of words in the text
+	Known   int `json:"known"`   // Number of tokens the user knows
+	Unknown int `json:"unknown"` // Number of tokens the user doesn't know
+
+	// Known / Tokens (0 if the text is empty).
+	Coverage float64 `json:"coverage"`
+
+	// Unknown words, most frequent first.
+	UnknownWords []WordCount `json:"unknownWords"`
+}
+
+// Analyzes how much of the text the user knows.
+// Words the user has reviewed are considered known unless the last answer
+// was incorrect.
+// Database connection should have access to course and review data.
+func Analyze[T database.Querier](q T, s string) (Report, error) {
+	counts := make(map[string]int)
+	var order []string
+	for _, word := range text.Words(s) {
+		word = text.Casefold(word)
+		if counts[word] == 0 {
+			order = append(order, word)
+		}
+		counts[word]++
+	}
+
+	report := Report{UnknownWords: make([]WordCount, 0)}
+	for _, word := range order {
+		count := counts[word]
+		report.Tokens += count
+
+		var known, inCourse bool
+		query := `
+			SELECT
+				EXISTS (SELECT 1 FROM review WHERE item = @word AND correct),
+				EXISTS (SELECT 1 FROM word WHERE word = @word)
+		`
+		if err := q.QueryRow(query, sql.Named("word", word)).Scan(&known, &inCourse); err != nil {
+			return report, fmt.Errorf("failed to analyze text: %w", err)
+		}
+
+		if known {
+			report.Known += count
+			continue
+		}
+		report.Unknown += count
+		report.UnknownWords = append(report.UnknownWords, WordCount{
+			Word:     word,
+			Count:    count,
+			InCourse: inCourse,
+		})
+	}
+
+	if report.Tokens > 0 {
+		report.Coverage = float64(report.Known) / float64(report.Tokens)
+	}
+	sort.SliceStable(report.UnknownWords, func(i, j int) bool {
+		return report.UnknownWords[i].Count > report.UnknownWords[j].Count
+	})
+	return report, nil
+}
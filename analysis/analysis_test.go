@@ -0,0 +1,58 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'das', 0), (2, 'haus', 0), (3, 'ist', 0)`,
+		`INSERT INTO review (item, interval, reviewed) VALUES ('das', 24, 0), ('ist', 0, 0)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	report, err := Analyze(db, "Das Haus ist gross. Das Haus!")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if report.Tokens != 6 || report.Known != 2 || report.Unknown != 4 {
+		t.Fatal("unexpected report:", report)
+	}
+
+	unknown := report.UnknownWords
+	if len(unknown) != 3 || unknown[0].Word != "haus" || unknown[0].Count != 2 || !unknown[0].InCourse {
+		t.Fatal("unexpected unknown words:", unknown)
+	}
+	if unknown[2].Word != "gross" || unknown[2].InCourse {
+		t.Fatal("expected word not in course to be reported:", unknown[2])
+	}
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	report, err := Analyze(db, "")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if report.Tokens != 0 || report.Coverage != 0 {
+		t.Fatal("unexpected report:", report)
+	}
+}
@@ -4,8 +4,8 @@
 package api
 
 import (
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 
 	"github.com/polycloze/polycloze/auth"
@@ -32,8 +32,7 @@ func handleSetCourse(w http.ResponseWriter, r *http.Request) {
 	// Read request data.
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Could not read request.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
 		return
 	}
 
@@ -49,8 +48,7 @@ func handleSetCourse(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check csrf token.
-	token := r.Header.Get("X-CSRF-Token")
-	if !sessions.CheckCSRFToken(s.ID, token) {
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
 		http.Error(w, "Forbidden.", http.StatusForbidden)
 		return
 	}
@@ -59,20 +57,18 @@ func handleSetCourse(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenUserDB(basedir.UserData(userID))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
 
 	// Set active course.
 	if err := setActiveCourse(db, userID, data.L1Code, data.L2Code); err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	sendJSON(w, SetCourseResponse{
+	sendJSON(w, r, SetCourseResponse{
 		Ok: true,
 	})
 }
@@ -0,0 +1,209 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Admin API.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type DailyCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD (UTC)
+	Count int    `json:"count"`
+}
+
+type CourseAdoption struct {
+	L1    string `json:"l1"`
+	L2    string `json:"l2"`
+	Users int    `json:"users"`
+}
+
+type UserDiskUsage struct {
+	UserID   int    `json:"userID"`
+	Username string `json:"username"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Instance-wide stats.
+type AdminStats struct {
+	Users       int              `json:"users"`
+	ActiveUsers []DailyCount     `json:"activeUsers"`
+	Reviews     int              `json:"reviews"`
+	Courses     []CourseAdoption `json:"courses"`
+	DiskUsage   []UserDiskUsage  `json:"diskUsage"`
+}
+
+// Returns total size of files in directory.
+// Returns 0 if the directory doesn't exist.
+func diskUsage(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// Returns number of reviews in review DB, and days (as UNIX day numbers) in
+// the interval when the user reviewed.
+// Includes archived reviews (see `history.Archive`).
+func reviewActivity(path string, from, to time.Time) (int, []int64, error) {
+	db, err := database.Open(path + "?mode=ro")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	reviews, err := history.CountReviews(db)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := `
+		SELECT reviewed / 86400 FROM history
+		WHERE reviewed BETWEEN @from AND @to
+		UNION
+		SELECT day FROM history_summary
+		WHERE day BETWEEN @from / 86400 AND @to / 86400
+	`
+	rows, err := db.Query(query, sql.Named("from", from.Unix()), sql.Named("to", to.Unix()))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var days []int64
+	for rows.Next() {
+		var day int64
+		if err := rows.Scan(&day); err != nil {
+			return 0, nil, err
+		}
+		days = append(days, day)
+	}
+	return reviews, days, rows.Err()
+}
+
+// Computes instance-wide stats from the auth DB and user files.
+// Active users are counted in the interval [from, to].
+func summarizeInstance(db *sql.DB, from, to time.Time) (AdminStats, error) {
+	var stats AdminStats
+
+	rows, err := db.Query(`SELECT id, username FROM user ORDER BY id`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to summarize instance: %w", err)
+	}
+	var users []UserDiskUsage
+	for rows.Next() {
+		var u UserDiskUsage
+		if err := rows.Scan(&u.UserID, &u.Username); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to summarize instance: %w", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+
+	active := make(map[int64]map[int]bool) // day -> set of users
+	adoption := make(map[string]int)       // "l1-l2" -> users
+
+	for i, u := range users {
+		users[i].Bytes = diskUsage(basedir.User(u.UserID))
+
+		paths, _ := filepath.Glob(filepath.Join(basedir.User(u.UserID), "reviews", "*.db"))
+		for _, path := range paths {
+			reviews, days, err := reviewActivity(path, from, to)
+			if err != nil {
+				return stats, fmt.Errorf("failed to summarize instance: %w", err)
+			}
+			stats.Reviews += reviews
+			if reviews > 0 {
+				adoption[strings.TrimSuffix(filepath.Base(path), ".db")]++
+			}
+			for _, day := range days {
+				if active[day] == nil {
+					active[day] = make(map[int]bool)
+				}
+				active[day][u.UserID] = true
+			}
+		}
+	}
+
+	stats.Users = len(users)
+	stats.DiskUsage = users
+
+	for day, set := range active {
+		stats.ActiveUsers = append(stats.ActiveUsers, DailyCount{
+			Date:  time.Unix(day*86400, 0).UTC().Format("2006-01-02"),
+			Count: len(set),
+		})
+	}
+	sort.Slice(stats.ActiveUsers, func(i, j int) bool {
+		return stats.ActiveUsers[i].Date < stats.ActiveUsers[j].Date
+	})
+
+	for course, count := range adoption {
+		l1, l2, _ := strings.Cut(course, "-")
+		stats.Courses = append(stats.Courses, CourseAdoption{L1: l1, L2: l2, Users: count})
+	}
+	sort.Slice(stats.Courses, func(i, j int) bool {
+		return stats.Courses[i].Users > stats.Courses[j].Users
+	})
+	return stats, nil
+}
+
+type adminSessionKey struct{}
+
+// Only lets admins through.
+// Responds with 404 to everyone else, so the endpoints aren't discoverable.
+// The admin's session can be retrieved with `adminSession`.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil || !s.IsSignedIn() || !auth.IsAdmin(db, s.Data["userID"].(int)) {
+			http.NotFound(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), adminSessionKey{}, s)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Returns session of the admin who made the request.
+// Only works in handlers behind `requireAdmin`.
+func adminSession(r *http.Request) *sessions.Session {
+	return r.Context().Value(adminSessionKey{}).(*sessions.Session)
+}
+
+// Responds with instance-wide stats.
+// Only available to admins (see `requireAdmin`).
+// Active users are counted in the range given by the `from` and `to` search
+// params (default: last week).
+func handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := summarizeInstance(auth.GetDB(r), getFrom(r), getTo(r))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, stats)
+}
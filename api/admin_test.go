@@ -0,0 +1,69 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestSummarizeInstanceUsers(t *testing.T) {
+	// Should count registered users.
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	for _, username := range []string{"summarize-a", "summarize-b"} {
+		if err := auth.Register(db, username, "password"); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	now := time.Now()
+	stats, err := summarizeInstance(db, now.AddDate(0, 0, -7), now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if stats.Users != 2 || len(stats.DiskUsage) != 2 {
+		t.Fatal("expected two users:", stats)
+	}
+}
+
+func TestAdminEndpointsRequireAdmin(t *testing.T) {
+	// Signed-out users shouldn't be able to find the endpoints.
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	router, err := Router(Config{}, db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	for _, path := range []string{
+		"/api/admin/stats",
+		"/api/admin/provision",
+		"/api/admin/quarantine",
+		"/api/admin/quarantine/1",
+	} {
+		r := httptest.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Fatal("expected endpoint to be hidden:", path, w.Code)
+		}
+	}
+}
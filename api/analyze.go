@@ -0,0 +1,187 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/analysis"
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/mining"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/wordlist"
+)
+
+// Max size of analyzed text in bytes.
+const maxAnalyzeSize = 1 << 20
+
+type AnalyzeRequest struct {
+	Text string `json:"text"`
+}
+
+type WordListRequest struct {
+	Words []string `json:"words"`
+}
+
+// Opens connection to user's review DB with the course DB attached.
+// Caller should close both the connection and the DB.
+func openCourseConnection(r *http.Request, userID int, l1, l2 string) (*database.Connection, func(), error) {
+	db, err := database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hook := database.AttachCourse(basedir.Course(l1, l2))
+	con, err := database.NewConnection(db, r.Context(), hook)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return con, func() {
+		con.Close()
+		db.Close()
+	}, nil
+}
+
+// Responds with the user's vocabulary coverage of the text in the request.
+func handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAnalyzeSize)
+	var data AnalyzeRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	report, err := analysis.Analyze(con, data.Text)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, report)
+}
+
+// Saves sentences in the text that contain the user's new or due words, so
+// they can be used as cloze sentences.
+func handleMine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAnalyzeSize)
+	var data AnalyzeRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	result, err := mining.Mine(con, data.Text, time.Now())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, result)
+}
+
+// GET: responds with words in the user's word list.
+// POST: adds words to the user's word list.
+func handleWordList(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data WordListRequest
+	if r.Method == "POST" && !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	added := 0
+	if r.Method == "POST" {
+		added, err = wordlist.Add(con, data.Words)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	words, err := wordlist.List(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{
+		"added": added,
+		"words": words,
+	})
+}
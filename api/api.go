@@ -5,11 +5,10 @@ package api
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 
 	"github.com/polycloze/polycloze/auth"
 	"github.com/polycloze/polycloze/sessions"
@@ -34,38 +33,38 @@ func handleHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get active course.
-	userID := s.Data["userID"].(int)
-	course, err := getUserActiveCourse(userID)
+	data, err := templateData(s)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
-	s.Data["course"] = course
-	s.Data["csrfToken"] = sessions.CSRFToken(s.ID)
-	renderTemplate(w, "home.html", s.Data)
+	data["anonymous"] = auth.IsAnonymous(db, data["userID"].(int))
+	renderTemplate(w, r, "home.html", data)
 }
 
-func handleAbout(w http.ResponseWriter, r *http.Request) {
-	var data map[string]any
-	db := auth.GetDB(r)
-	if s, err := sessions.StartOrResumeSession(db, w, r); err == nil {
-		data = s.Data
-
-		if s.IsSignedIn() {
-			// Get active course.
-			userID := data["userID"].(int)
-			course, err := getUserActiveCourse(userID)
-			if err != nil {
-				log.Println(err)
-				http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-				return
+// trials: whether visitors can start anonymous trials
+func handleAbout(trials bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := map[string]any{"trials": trials}
+		db := auth.GetDB(r)
+		if s, err := sessions.StartOrResumeSession(db, w, r); err == nil {
+			data = s.Data
+			data["trials"] = trials
+			data["csrfToken"] = sessions.CSRFToken(s.ID)
+
+			if s.IsSignedIn() {
+				// Get active course.
+				userID := data["userID"].(int)
+				course, err := getUserActiveCourse(userID)
+				if err != nil {
+					serverError(w, r, err)
+					return
+				}
+				data["course"] = course
 			}
-			data["course"] = course
 		}
+		renderTemplate(w, r, "about.html", data)
 	}
-	renderTemplate(w, "about.html", data)
 }
 
 func handleStudy(w http.ResponseWriter, r *http.Request) {
@@ -76,18 +75,12 @@ func handleStudy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get active course.
-	userID := s.Data["userID"].(int)
-	course, err := getUserActiveCourse(userID)
+	data, err := templateData(s)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
-
-	s.Data["course"] = course
-	s.Data["csrfToken"] = sessions.CSRFToken(s.ID)
-	renderTemplate(w, "study.html", s.Data)
+	renderTemplate(w, r, "study.html", data)
 }
 
 func handleVocabularyPage(w http.ResponseWriter, r *http.Request) {
@@ -98,37 +91,58 @@ func handleVocabularyPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get active course.
-	userID := s.Data["userID"].(int)
-	course, err := getUserActiveCourse(userID)
+	data, err := templateData(s)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
-
-	s.Data["course"] = course
-	s.Data["csrfToken"] = sessions.CSRFToken(s.ID)
-	renderTemplate(w, "vocab.html", s.Data)
+	renderTemplate(w, r, "vocab.html", data)
 }
 
 // db: user DB for authentication
 func Router(config Config, db *sql.DB) (chi.Router, error) {
+	trusted, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
+	r.Use(trustProxies(trusted))
 	if config.AllowCORS {
 		r.Use(cors)
 	}
-	r.Use(middleware.Logger)
+	r.Use(accessLog)
 	r.Use(auth.Middleware(db))
+	r.Use(negotiateLocale)
 
 	r.HandleFunc("/", handleHome)
 	r.HandleFunc("/study", handleStudy)
 	r.HandleFunc("/vocab", handleVocabularyPage)
-	r.HandleFunc("/about", handleAbout)
+	r.HandleFunc("/about", handleAbout(config.AllowTrials))
 	r.HandleFunc("/welcome", handleWelcome)
 	r.HandleFunc("/settings", handleSettings)
+	r.HandleFunc("/stats/{l1}/{l2}", handleStatsPage)
+	r.HandleFunc("/u/{username}", handleProfilePage)
+
+	if config.DebugEndpoints {
+		r.Mount("/debug", debugRouter())
+	}
 
+	if config.DemoCourse != "" {
+		if _, _, ok := parseDemoCourse(config.DemoCourse); !ok {
+			return nil, fmt.Errorf("invalid demo course: %v", config.DemoCourse)
+		}
+		limiter := newRateLimiter(30, 10)
+		r.HandleFunc("/demo", handleDemoPage(config.DemoCourse))
+		r.HandleFunc("/api/demo/flashcards", handleDemoFlashcards(config.DemoCourse, limiter))
+	}
+
+	if config.AllowTrials {
+		r.HandleFunc("/trial", handleTrial)
+	}
+	r.HandleFunc("/claim", handleClaim)
 	r.HandleFunc("/register", handleRegister)
+	r.HandleFunc("/setup", handleSetup)
 	r.HandleFunc("/signin", handleSignIn)
 	r.HandleFunc("/signout", handleSignOut)
 
@@ -143,18 +157,78 @@ func Router(config Config, db *sql.DB) (chi.Router, error) {
 	r.Handle("/robots.txt", http.StripPrefix("/", servePublic()))
 
 	r.HandleFunc("/api/sentences", handleSentences)
+	r.HandleFunc("/api/sentences/{l1}/{l2}", handleSentencesWithWord)
+	r.HandleFunc("/api/audio/{l1}/{l2}/{id}", handleAudio(config.TTS, config.TTSCacheSize))
+	r.HandleFunc("/api/search/{l1}/{l2}", handleSearch)
+	r.HandleFunc("/api/autocomplete/{l1}/{l2}", handleAutocomplete)
+	r.HandleFunc("/api/words/{l1}/{l2}", handleWords)
+	r.HandleFunc("/api/examples/{l1}/{l2}/{word}", handleWordExamples)
 
 	r.HandleFunc("/api/flashcards/{l1}/{l2}", handleFlashcards)
+	r.HandleFunc("/api/study/{l1}/{l2}", handleStudySocket)
 	r.HandleFunc("/api/vocabulary/{l1}/{l2}", handleVocabulary)
+	r.HandleFunc("/api/vocabulary/{l1}/{l2}/forget", handleForgetWord)
+	r.HandleFunc("/api/restore/{l1}/{l2}", handleRestore)
+	r.HandleFunc("/api/bundle/{l1}/{l2}", handleBundle(config.MaxUploadSize))
+	r.HandleFunc("/api/review-db/{l1}/{l2}", handleReviewDB(config.MaxUploadSize))
+	r.HandleFunc("/api/vocabulary/{l1}/{l2}/priority", handleWordPriority)
+	r.HandleFunc("/api/vocabulary/{l1}/{l2}/assumed", handleAssumed)
+	r.HandleFunc("/api/dictionary/{l1}/{l2}/{word}", handleDictionary(config.Dictionary))
+	r.HandleFunc("/api/notes/{l1}/{l2}", handleNotes)
+	r.HandleFunc("/api/notes/{l1}/{l2}/{word}", handleNote)
+	r.HandleFunc("/api/analyze/{l1}/{l2}", handleAnalyze)
+	r.HandleFunc("/api/mine/{l1}/{l2}", handleMine)
+	r.HandleFunc("/api/wordlist/{l1}/{l2}", handleWordList)
+	r.HandleFunc("/api/user-sentences/{l1}/{l2}", handleUserSentences)
+	r.HandleFunc("/api/user-sentences/{l1}/{l2}/{id}/delete", handleDeleteUserSentence)
 	r.HandleFunc("/api/stats/activity/{l1}/{l2}", handleStatsActivity)
 	r.HandleFunc("/api/stats/vocab/{l1}/{l2}", handleStatsVocab)
 	r.HandleFunc("/api/stats/estimate/{l1}/{l2}", handleStatsEstimatedLevel)
+	r.HandleFunc("/api/stats/modes/{l1}/{l2}", handleStatsModes)
+	r.HandleFunc("/api/stats/eta/{l1}/{l2}", handleStatsETA)
+	r.HandleFunc("/api/achievements/{l1}/{l2}", handleAchievements)
+	r.HandleFunc("/api/today/{l1}/{l2}", handleToday)
+	r.HandleFunc("/api/goals/{l1}/{l2}", handleGoal)
+	r.HandleFunc("/api/tuner/{l1}/{l2}", handleTuner)
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(requireAdmin)
+		r.HandleFunc("/stats", handleAdminStats)
+		r.HandleFunc("/provision", handleProvision(config.MaxUploadSize))
+		r.HandleFunc("/quarantine", handleQuarantine)
+		r.HandleFunc("/quarantine/{id}", handleQuarantinedBatch)
+	})
+
+	r.HandleFunc("/scim/v2/ServiceProviderConfig", requireSCIMToken(config.SCIMToken, handleSCIMServiceProviderConfig))
+	r.HandleFunc("/scim/v2/ResourceTypes", requireSCIMToken(config.SCIMToken, handleSCIMResourceTypes))
+	r.HandleFunc("/scim/v2/Users", requireSCIMToken(config.SCIMToken, handleSCIMUsers))
+	r.HandleFunc("/scim/v2/Users/{id}", requireSCIMToken(config.SCIMToken, handleSCIMUser))
+
+	r.HandleFunc("/api/classes", handleClasses)
+	r.HandleFunc("/api/classes/join", handleJoinClass)
+	r.HandleFunc("/api/classes/{id}/leave", handleLeaveClass)
+	r.HandleFunc("/api/classes/{id}/assignments", handleClassAssignments)
+	r.HandleFunc("/api/classes/{id}/progress/{l1}/{l2}", handleClassProgress)
 
 	r.HandleFunc("/api/languages", serveLanguagesJSON())
 	r.HandleFunc("/api/courses", serveCoursesJSON())
+	r.HandleFunc("/api/courses/{l1}/{l2}/stats", handleCourseStats)
+	r.HandleFunc("/api/courses/{l1}/{l2}/summary", handleCourseSummary)
+	r.HandleFunc("/api/courses/{l1}/{l2}/coverage", handleCourseCoverage)
 
 	r.HandleFunc("/api/actions/set-course", handleSetCourse)
-	r.HandleFunc("/api/settings/upload/{l1}/{l2}", handleUpload)
+	r.HandleFunc("/api/settings/upload/{l1}/{l2}", handleUpload(config.MaxUploadSize))
+	r.HandleFunc("/api/settings/frequency-list/{l1}/{l2}", handleFrequencyList(config.MaxUploadSize))
 	r.HandleFunc("/api/settings/reset/{l1}/{l2}", handleResetProgress)
+	r.HandleFunc("/api/settings/preferences", handlePreferences)
+	r.HandleFunc("/api/settings/reminders", handleReminders)
+	r.HandleFunc("/api/settings/digest", handleDigest)
+	r.HandleFunc("/api/settings/profile", handleProfileSettings)
+	r.HandleFunc("/api/settings/merge-account", handleMergeAccount)
+	r.HandleFunc("/api/settings/daily-goal/{l1}/{l2}", handleDailyGoal)
+	r.HandleFunc("/api/settings/tuner/{l1}/{l2}", handleTunerSettings)
+	r.HandleFunc("/api/settings/tuner/{l1}/{l2}/algorithm", handleTunerAlgorithm)
+	r.HandleFunc("/api/settings/{l1}/{l2}", handleCourseSettings)
+	r.HandleFunc("/api/tuner/{l1}/{l2}/level", handleDifficultyOverride)
 	return r, nil
 }
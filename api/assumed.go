@@ -0,0 +1,95 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/assumed"
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type AssumedRequest struct {
+	FrequencyClasses int `json:"frequencyClasses"`
+}
+
+type AssumedResponse struct {
+	assumed.Status
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// GET: responds with the user's "assume I know the top N frequency classes"
+// setting.
+// POST: changes the setting, which creates or undoes reviews of assumed words
+// (see package assumed).
+func handleAssumed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data AssumedRequest
+	if r.Method == "POST" {
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if data.FrequencyClasses < 0 || data.FrequencyClasses > assumed.MaxFrequencyClasses {
+			http.Error(w, "Invalid number of frequency classes.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	var resp AssumedResponse
+	if r.Method == "POST" {
+		path := basedir.Review(userID, l1, l2)
+		resp.Added, resp.Removed, err = setAssumed(con, path, data.FrequencyClasses, time.Now())
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	resp.Status, err = assumed.Get(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, resp)
+}
+
+// Changes the assumed words setting in the review DB in `path`.
+// Holds the lock on the review DB, because assumed words are saved as reviews.
+func setAssumed[T database.Querier](q T, path string, n int, now time.Time) (added, removed int, err error) {
+	defer database.LockReviewDB(path)()
+	return assumed.Set(q, n, now)
+}
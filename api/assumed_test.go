@@ -0,0 +1,54 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestSetAssumedLocksReviewDB(t *testing.T) {
+	// Assumed words shouldn't be saved while another writer holds the lock.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `INSERT INTO word (word, frequency_class) VALUES ('a', 0), ('b', 1)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "review.db")
+	unlock := database.LockReviewDB(path)
+
+	done := make(chan int)
+	go func() {
+		added, _, err := setAssumed(db, path, 2, time.Now())
+		if err != nil {
+			t.Error("expected err to be nil:", err)
+		}
+		done <- added
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected assumed words to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case added := <-done:
+		if added != 2 {
+			t.Fatal("expected 2 assumed words:", added)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected assumed words to be saved after the lock is released")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/tts"
+)
+
+// Returns path to recording of sentence, or an empty string if there's none.
+func findRecording(l1, l2 string, id int) string {
+	dir := basedir.Recordings(l1, l2)
+	for _, ext := range []string{".mp3", ".ogg", ".wav"} {
+		file := path.Join(dir, strconv.Itoa(id)+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// Max number of texts that get synthesized at the same time.
+// Synthesizers like Piper run a process for each text, so requests for
+// sentences that aren't in the cache could otherwise use up the server.
+var maxSyntheses = runtime.NumCPU()
+
+// Serves audio of sentence.
+// Serves recordings if available, or else synthesized speech if a synthesizer
+// is configured.
+// maxCacheSize: see `Config.TTSCacheSize`.
+func handleAudio(synthesizer tts.Synthesizer, maxCacheSize int64) http.HandlerFunc {
+	var cache *tts.Cache
+	if synthesizer != nil {
+		cache = &tts.Cache{
+			Synthesizer: tts.Limit(synthesizer, maxSyntheses),
+			Dir:         basedir.SpeechCache,
+			MaxSize:     maxCacheSize,
+			Root:        basedir.SpeechCacheRoot(),
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		file := findRecording(l1, l2, id)
+		if file == "" && cache != nil {
+			db, err := database.Open(basedir.Course(l1, l2))
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			defer db.Close()
+
+			sentence, err := sentences.Get(db, id)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			file, err = cache.Get(r.Context(), l2, sentence.Text)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+		}
+		if file == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", tts.ContentType(file))
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		http.ServeFile(w, r, file)
+	}
+}
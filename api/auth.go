@@ -9,9 +9,11 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"time"
 
 	"github.com/polycloze/polycloze/auth"
 	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/hooks"
 	"github.com/polycloze/polycloze/sessions"
 )
 
@@ -21,7 +23,7 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 	db := auth.GetDB(r)
 	s, err := sessions.StartOrResumeSession(db, w, r)
 	if err != nil {
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	if s.IsSignedIn() {
@@ -31,13 +33,19 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
-		csrfToken := r.FormValue("csrf-token")
 
-		if !sessions.CheckCSRFToken(s.ID, csrfToken) {
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
 			_ = s.ErrorMessage("Something went wrong. Please try again.", "register")
 			goto fail
 		}
 		if auth.Register(db, username, password) == nil {
+			if userID, err := auth.GetUserID(db, username); err == nil {
+				hooks.UserCreated(hooks.UserCreatedEvent{
+					UserID:   userID,
+					Username: username,
+					Time:     time.Now(),
+				})
+			}
 			// `StatusTemporaryRedirect` also resends POST data to the next page.
 			http.Redirect(w, r, "/signin", http.StatusTemporaryRedirect)
 			return
@@ -54,7 +62,7 @@ fail:
 		"csrfToken": sessions.CSRFToken(s.ID),
 		"messages":  messages,
 	}
-	renderTemplate(w, "register.html", data)
+	renderTemplate(w, r, "register.html", data)
 }
 
 // HandlerFunc for signing in.
@@ -62,7 +70,7 @@ func handleSignIn(w http.ResponseWriter, r *http.Request) {
 	db := auth.GetDB(r)
 	s, err := sessions.StartOrResumeSession(db, w, r)
 	if err != nil {
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
@@ -74,9 +82,8 @@ func handleSignIn(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		username := r.FormValue("username")
 		password := r.FormValue("password")
-		csrfToken := r.FormValue("csrf-token")
 
-		if !sessions.CheckCSRFToken(s.ID, csrfToken) {
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
 			_ = s.ErrorMessage("Authentication failed.", "sign-in")
 			goto fail
 		}
@@ -86,9 +93,10 @@ func handleSignIn(w http.ResponseWriter, r *http.Request) {
 			goto fail
 		}
 
+		// Rotate session ID and CSRF token on sign in.
 		s.Data["userID"] = userID
 		s.Data["username"] = username
-		if sessions.SaveData(db, s) != nil {
+		if sessions.RotateSession(db, w, r, s) != nil {
 			_ = s.ErrorMessage("Authentication failed.", "sign-in")
 			goto fail
 		}
@@ -97,7 +105,7 @@ func handleSignIn(w http.ResponseWriter, r *http.Request) {
 
 fail:
 	messages, _ = s.Messages("sign-in")
-	renderTemplate(w, "signin.html", map[string]any{
+	renderTemplate(w, r, "signin.html", map[string]any{
 		"csrfToken": sessions.CSRFToken(s.ID),
 		"messages":  messages,
 	})
@@ -105,7 +113,7 @@ fail:
 
 success:
 	if err := initUserDirectory(s.Data["userID"].(int)); err != nil {
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	http.Redirect(w, r, "/welcome", http.StatusTemporaryRedirect)
@@ -125,9 +133,13 @@ func handleSignOut(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := sessions.EndSession(db, w, r); err != nil {
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
+	hooks.SessionEnd(hooks.SessionEndEvent{
+		UserID: s.Data["userID"].(int),
+		Time:   time.Now(),
+	})
 
 done:
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -136,6 +148,7 @@ done:
 func initUserDirectory(userID int) error {
 	base := basedir.User(userID)
 	reviews := path.Join(base, "reviews")
+	production := path.Join(base, "production")
 
 	if err := os.MkdirAll(base, 0o700); err != nil {
 		return fmt.Errorf("failed to create user directory: %w", err)
@@ -143,5 +156,8 @@ func initUserDirectory(userID int) error {
 	if err := os.MkdirAll(reviews, 0o700); err != nil {
 		return fmt.Errorf("failed to create user directory: %w", err)
 	}
+	if err := os.MkdirAll(production, 0o700); err != nil {
+		return fmt.Errorf("failed to create user directory: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,127 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/bundle"
+	"github.com/polycloze/polycloze/merge"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Downloads (GET) or imports (POST) an offline bundle of the course and the
+// user's data in it (see package bundle).
+// Bundles are uploaded as the request body (Content-Type: application/zip),
+// with the CSRF token in the X-CSRF-Token header.
+func handleBundle(maxSize int64) http.HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil || !s.IsSignedIn() {
+			http.NotFound(w, r)
+			return
+		}
+
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+		userID := s.Data["userID"].(int)
+
+		switch r.Method {
+		case "GET":
+			exportBundle(w, r, userID, l1, l2)
+		case "POST":
+			if !sessions.CheckCSRFToken(s.ID, r.Header.Get("X-CSRF-Token")) {
+				http.Error(w, "Forbidden.", http.StatusForbidden)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			importBundle(w, r, userID, l1, l2, maxSize)
+		default:
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func exportBundle(w http.ResponseWriter, r *http.Request, userID int, l1, l2 string) {
+	// The bundle gets written to a temporary file first, so that errors can
+	// still be reported.
+	tmp, err := os.CreateTemp("", "polycloze-bundle-*.zip")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	now := time.Now()
+	if err := bundle.Export(tmp, userID, l1, l2, now); err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	name := fmt.Sprintf("polycloze-%s-%s.zip", l1, l2)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	http.ServeContent(w, r, name, now, tmp)
+}
+
+func importBundle(w http.ResponseWriter, r *http.Request, userID int, l1, l2 string, maxSize int64) {
+	// Zip files can't be read as a stream.
+	tmp, err := os.CreateTemp("", "polycloze-bundle-*.zip")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r.Body)
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		message := fmt.Sprintf("File is too big (>%vMB).", maxSize/(1024*1024))
+		http.Error(w, message, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	err = bundle.Import(tmp, size, maxSize, userID, l1, l2)
+	if errors.Is(err, bundle.ErrInvalidBundle) {
+		http.Error(w, "Invalid bundle.", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, merge.ErrArchivedHistory) {
+		message := "Can't import bundle, because some of the review history has been archived."
+		http.Error(w, message, http.StatusConflict)
+		return
+	}
+	if errors.Is(err, bundle.ErrWrongCourse) {
+		http.Error(w, "Bundle is for a different course.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
@@ -9,7 +9,8 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"path/filepath"
+	"net/http"
+	"path"
 	"strings"
 )
 
@@ -50,15 +51,19 @@ func computeHashes() error {
 		// This shouldn't happen.
 		panic(err)
 	}
-	_ = fs.WalkDir(sub, ".", func(path string, d fs.DirEntry, _ error) error {
-		file, err := sub.Open(path)
+	_ = fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, _ error) error {
+		if d == nil || d.IsDir() {
+			return nil
+		}
+
+		file, err := sub.Open(name)
 		if err != nil {
 			// Ignore error.
 			return nil
 		}
 		defer file.Close()
 
-		_, _ = cachedHashFile(filepath.Join("/dist", path), file)
+		_, _ = cachedHashFile(path.Join("/dist", name), file)
 		return nil
 	})
 
@@ -68,19 +73,23 @@ func computeHashes() error {
 		// This shouldn't happen.
 		panic(err)
 	}
-	_ = fs.WalkDir(sub, ".", func(path string, d fs.DirEntry, _ error) error {
+	_ = fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, _ error) error {
+		if d == nil || d.IsDir() {
+			return nil
+		}
+
 		// Ignore fonts and icons.
-		if strings.HasPrefix(path, "fonts") || strings.HasPrefix(path, "svg") {
+		if strings.HasPrefix(name, "fonts") || strings.HasPrefix(name, "svg") {
 			return nil
 		}
 
-		file, err := sub.Open(path)
+		file, err := sub.Open(name)
 		if err != nil {
 			// Ignore error.
 			return nil
 		}
 		defer file.Close()
-		_, _ = cachedHashFile(filepath.Join("/public", path), file)
+		_, _ = cachedHashFile(path.Join("/public", name), file)
 		return nil
 	})
 	return nil
@@ -95,3 +104,29 @@ func versionedURL(url string) string {
 	}
 	return url
 }
+
+// Sets caching headers on fingerprinted files.
+// `prefix` is the URL prefix that was stripped from the request path (e.g.
+// "/dist").
+// Requests with a `v` search param that matches the content hash get cached
+// forever.
+// Other requests for hashed files have to be revalidated using the ETag, so
+// that the client doesn't have to re-download unchanged files.
+// Files that haven't been hashed don't get caching instructions.
+func fingerprinted(prefix string, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash, ok := fileHashes[path.Join(prefix, r.URL.Path)]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, hash))
+		if r.URL.Query().Get("v") == hash {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFingerprinted(t *testing.T) {
+	// Only requests with the current content hash should be cached forever.
+	fileHashes = map[string]string{"/dist/index.js": "abc"}
+
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := fingerprinted("/dist", noop)
+
+	cases := []struct {
+		target       string
+		cacheControl string
+		etag         string
+	}{
+		{"/index.js?v=abc", "public, max-age=31536000, immutable", `"abc"`},
+		{"/index.js?v=old", "no-cache", `"abc"`},
+		{"/index.js", "no-cache", `"abc"`},
+		{"/unknown.js?v=abc", "", ""},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.target, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if v := w.Header().Get("Cache-Control"); v != c.cacheControl {
+			t.Fatal("unexpected Cache-Control header:", c.target, v)
+		}
+		if v := w.Header().Get("ETag"); v != c.etag {
+			t.Fatal("unexpected ETag header:", c.target, v)
+		}
+	}
+}
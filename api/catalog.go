@@ -0,0 +1,126 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+var ErrNoCourses = errors.New("couldn't find installed courses")
+
+// Catalog of installed courses.
+// Finding courses opens every course DB, so the catalog is kept in memory and
+// only gets rebuilt when it's reloaded (see `ReloadCatalog`).
+type catalog struct {
+	mu        sync.RWMutex
+	courses   []Course
+	languages []Language // L1 languages
+	version   string     // Changes when the catalog changes
+}
+
+var courseCatalog catalog
+
+// Returns copy of list of courses.
+func (c *catalog) Courses() []Course {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Course(nil), c.courses...)
+}
+
+// Returns copy of list of L1 languages.
+func (c *catalog) Languages() []Language {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Language(nil), c.languages...)
+}
+
+// Finds course in the catalog.
+func (c *catalog) Course(l1, l2 string) (Course, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, course := range c.courses {
+		if course.L1.Code == l1 && course.L2.Code == l2 {
+			return course, true
+		}
+	}
+	return Course{}, false
+}
+
+// Replaces contents of the catalog.
+// The catalog is left unchanged on error.
+func (c *catalog) set(courses []Course, languages []Language) error {
+	data, err := json.Marshal(map[string]any{
+		"courses":   courses,
+		"languages": languages,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update course catalog: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.courses = courses
+	c.languages = languages
+	c.version = hex.EncodeToString(sum[:])[:16]
+	return nil
+}
+
+// Looks for installed courses and replaces the course catalog.
+// Should be called after courses get installed or removed.
+// The current catalog is kept if there are no courses or if it can't be
+// rebuilt.
+func ReloadCatalog(config Config) error {
+	courses := findCourses()
+	if config.ReverseCourses {
+		courses = append(courses, buildReverseCourses(courses)...)
+	}
+	languages := findL1Languages(courses)
+	if len(languages) <= 0 {
+		return ErrNoCourses
+	}
+	sort.Sort(ByCode(languages))
+	return courseCatalog.set(courses, languages)
+}
+
+// Serves JSON from the catalog with language names localized to the
+// language from `displayLanguage`.
+// Responses can be revalidated using the ETag, which changes when the catalog
+// gets reloaded.
+func (c *catalog) serve(get func(courses []Course, languages []Language) any) http.HandlerFunc {
+	return cacheUntilBusted(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := displayLanguage(r)
+
+		c.mu.RLock()
+		courses, languages, version := c.courses, c.languages, c.version
+		c.mu.RUnlock()
+
+		etag := fmt.Sprintf(`"%s-%s"`, version, tag)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		sendJSON(w, r, get(localizeCourses(courses, tag), localizeLanguages(languages, tag)))
+	}))
+}
+
+func serveLanguagesJSON() http.HandlerFunc {
+	return courseCatalog.serve(func(_ []Course, languages []Language) any {
+		return map[string][]Language{"languages": languages}
+	})
+}
+
+func serveCoursesJSON() http.HandlerFunc {
+	return courseCatalog.serve(func(courses []Course, _ []Language) any {
+		return map[string][]Course{"courses": courses}
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCatalogServe(t *testing.T) {
+	// Responses should be revalidated using the ETag, which should change
+	// when the catalog changes.
+	t.Parallel()
+
+	var c catalog
+	eng := Language{Code: "eng", Name: "English", BCP47: "en"}
+	spa := Language{Code: "spa", Name: "Spanish", BCP47: "es"}
+	if err := c.set([]Course{{L1: eng, L2: spa}}, []Language{eng}); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	handler := c.serve(func(courses []Course, _ []Language) any {
+		return map[string][]Course{"courses": courses}
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/courses", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || !strings.Contains(w.Body.String(), `"spa"`) {
+		t.Fatal("unexpected response:", w.Code, etag, w.Body.String())
+	}
+
+	r := httptest.NewRequest("GET", "/api/courses", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Fatal("expected 304 Not Modified:", w.Code)
+	}
+
+	if err := c.set([]Course{{L1: spa, L2: eng}}, []Language{spa}); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Fatal("expected catalog to be reloaded:", w.Code, w.Header().Get("ETag"))
+	}
+}
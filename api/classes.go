@@ -0,0 +1,269 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Classroom API.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/classes"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type CreateClassRequest struct {
+	Name string `json:"name"`
+}
+
+type JoinClassRequest struct {
+	Code string `json:"code"`
+}
+
+type StudentProgress struct {
+	classes.Member
+
+	// Nil if the student hasn't started the course.
+	Stats *StatsSummary `json:"stats"`
+}
+
+// Reads JSON body of POST request into v.
+// Also checks the CSRF token.
+// Writes an error response and returns false if the request is invalid.
+func readPostJSON(w http.ResponseWriter, r *http.Request, s *sessions.Session, v any) bool {
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return false
+	}
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return false
+	}
+	return parseJSON(w, body, v) == nil
+}
+
+// Returns class ID in URL.
+func getClassID(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	return id, err == nil
+}
+
+// GET: responds with classes the user teaches or belongs to.
+// POST: creates a class taught by the user.
+func handleClasses(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	userID := s.Data["userID"].(int)
+
+	switch r.Method {
+	case "GET":
+		result, err := classes.List(db, userID)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, map[string]any{"classes": result})
+	case "POST":
+		var data CreateClassRequest
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if data.Name == "" {
+			http.Error(w, "missing class name", http.StatusBadRequest)
+			return
+		}
+
+		class, err := classes.Create(db, userID, data.Name)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, class)
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+	}
+}
+
+// Adds user to class using the class code.
+func handleJoinClass(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "expected POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data JoinClassRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	class, err := classes.Join(db, data.Code, s.Data["userID"].(int))
+	if errors.Is(err, classes.ErrNotFound) {
+		http.Error(w, "Invalid class code.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, class)
+}
+
+// Removes user from class.
+func handleLeaveClass(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "expected POST request", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	id, ok := getClassID(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := classes.Leave(db, id, s.Data["userID"].(int)); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
+
+// GET: responds with word lists assigned to the class (members only).
+// POST: assigns word list to the class (teacher only).
+func handleClassAssignments(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	userID := s.Data["userID"].(int)
+
+	id, ok := getClassID(r)
+	if !ok || !classes.IsMember(db, id, userID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		result, err := classes.Assignments(db, id)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, map[string]any{"assignments": result})
+	case "POST":
+		if !classes.IsTeacher(db, id, userID) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+
+		var data classes.Assignment
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if !courseExists(data.L1, data.L2) || data.Title == "" || len(data.Words) == 0 {
+			http.Error(w, "invalid assignment", http.StatusBadRequest)
+			return
+		}
+
+		assignment, err := classes.Assign(db, id, data)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, assignment)
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+	}
+}
+
+// Responds with read-only progress of students in the course.
+// Only the teacher can see this.
+func handleClassProgress(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	id, ok := getClassID(r)
+	if !ok || !classes.IsTeacher(db, id, s.Data["userID"].(int)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	members, err := classes.Members(db, id)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	progress := make([]StudentProgress, 0, len(members))
+	for _, m := range members {
+		p := StudentProgress{Member: m}
+
+		path := basedir.Review(m.UserID, l1, l2)
+		if _, err := os.Stat(path); err == nil {
+			reviewDB, err := database.OpenReviewDB(path)
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			summary, err := summarizeStats(reviewDB, now)
+			reviewDB.Close()
+			if err != nil {
+				serverError(w, r, err)
+				return
+			}
+			p.Stats = &summary
+		}
+		progress = append(progress, p)
+	}
+	sendJSON(w, r, map[string]any{"students": progress})
+}
@@ -3,7 +3,52 @@
 
 package api
 
+import (
+	"github.com/polycloze/polycloze/dictionary"
+	"github.com/polycloze/polycloze/tts"
+)
+
 type Config struct {
 	AllowCORS bool
 	Port      int
+
+	// IP addresses or CIDR blocks of reverse proxies whose X-Forwarded-For and
+	// X-Forwarded-Proto headers can be trusted.
+	TrustedProxies []string
+
+	// Synthesizes audio for sentences without recordings.
+	// Sentences without recordings have no audio if nil.
+	TTS tts.Synthesizer
+
+	// Max total size of synthesized speech in the cache, in bytes.
+	// Least recently used files get evicted first.
+	// Zero means unlimited.
+	TTSCacheSize int64
+
+	// External dictionary for word lookups (optional).
+	Dictionary dictionary.Source
+
+	// Offers reverse courses (e.g. spa-eng for eng-spa) for installed courses
+	// that don't have an installed reverse.
+	// Reverse courses are built on startup, which can take a while.
+	ReverseCourses bool
+
+	// Size limit of uploaded files in bytes.
+	// Uses `DefaultMaxUploadSize` if not positive.
+	MaxUploadSize int64
+
+	// Course (e.g. "eng-spa") that visitors can try at /demo without an
+	// account.
+	// Demo mode is disabled if empty.
+	DemoCourse string
+
+	// Let visitors study under anonymous trial accounts before registering.
+	AllowTrials bool
+
+	// Bearer token that identity providers use to access the SCIM API.
+	// SCIM is disabled if empty.
+	SCIMToken string
+
+	// Mounts pprof and expvar endpoints under /debug for admins.
+	DebugEndpoints bool
 }
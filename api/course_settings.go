@@ -0,0 +1,207 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Per-course study settings.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Max value of daily limits.
+const maxDailyLimit = 10000
+
+type CourseSettings struct {
+	// "recognition" (L2 sentences), "production" (L1 sentences) or "both".
+	Direction string `json:"direction"`
+
+	// How strictly answers are graded: "strict", "normal" or "lenient".
+	// Strict grading disables answer normalization rules (see
+	// `text.NormalizeAnswer`).
+	Leniency string `json:"leniency"`
+
+	// Daily limits (0: no limit).
+	NewWordsPerDay int `json:"newWordsPerDay"`
+	ReviewsPerDay  int `json:"reviewsPerDay"`
+
+	// When to show hints: "always", "on-request" or "never".
+	Hints string `json:"hints"`
+
+	// Skip new words with few example sentences (see package coverage).
+	SkipSparseWords bool `json:"skipSparseWords"`
+}
+
+// Returns default course settings.
+func defaultCourseSettings() CourseSettings {
+	return CourseSettings{
+		Direction:      "recognition",
+		Leniency:       "normal",
+		NewWordsPerDay: 0,
+		ReviewsPerDay:  0,
+		Hints:          "on-request",
+	}
+}
+
+// Checks if course settings have valid values.
+func (c CourseSettings) isValid() bool {
+	switch c.Direction {
+	case "recognition", "production", "both":
+	default:
+		return false
+	}
+	switch c.Leniency {
+	case "strict", "normal", "lenient":
+	default:
+		return false
+	}
+	switch c.Hints {
+	case "always", "on-request", "never":
+	default:
+		return false
+	}
+	return c.NewWordsPerDay >= 0 && c.NewWordsPerDay <= maxDailyLimit &&
+		c.ReviewsPerDay >= 0 && c.ReviewsPerDay <= maxDailyLimit
+}
+
+// Gets user's course settings from the review DB.
+// Returns default settings if the user hasn't set any.
+func getCourseSettings(db *sql.DB) (CourseSettings, error) {
+	c := defaultCourseSettings()
+	query := `
+		SELECT direction, leniency, new_words_per_day, reviews_per_day, hints,
+			skip_sparse_words
+		FROM course_settings
+	`
+	err := db.QueryRow(query).Scan(
+		&c.Direction,
+		&c.Leniency,
+		&c.NewWordsPerDay,
+		&c.ReviewsPerDay,
+		&c.Hints,
+		&c.SkipSparseWords,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c, fmt.Errorf("failed to get course settings: %w", err)
+	}
+	return c, nil
+}
+
+// Saves user's course settings in the review DB.
+func setCourseSettings(db *sql.DB, c CourseSettings) error {
+	if !c.isValid() {
+		return fmt.Errorf("failed to set course settings: invalid value: %v", c)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO course_settings
+			(direction, leniency, new_words_per_day, reviews_per_day, hints,
+				skip_sparse_words)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := db.Exec(
+		query,
+		c.Direction,
+		c.Leniency,
+		c.NewWordsPerDay,
+		c.ReviewsPerDay,
+		c.Hints,
+		c.SkipSparseWords,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set course settings: %w", err)
+	}
+	return nil
+}
+
+// Returns flashcard filter for the user's course settings.
+// `db` should be the review DB of the course (not the production review DB).
+func courseFlashcardFilter(db *sql.DB) (rs.Filter, error) {
+	c, err := getCourseSettings(db)
+	if err != nil {
+		return rs.Filter{}, fmt.Errorf("failed to get flashcard filter: %w", err)
+	}
+	return rs.Filter{SkipSparseWords: c.SkipSparseWords}, nil
+}
+
+// Returns answer normalization rules that the client should use in the
+// course.
+// Strict grading disables the rules.
+// `db` should be the review DB of the course (not the production review DB).
+func courseAnswerRules(db *sql.DB, l2 string) ([]text.Replacement, error) {
+	c, err := getCourseSettings(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answer rules: %w", err)
+	}
+	if c.Leniency == "strict" {
+		return []text.Replacement{}, nil
+	}
+	return text.AnswerRules(l2), nil
+}
+
+// GET: responds with user's settings in the course.
+// PUT: updates user's settings in the course.
+// Fields that are missing from the request keep their current values.
+func handleCourseSettings(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "PUT" {
+		http.Error(w, "expected GET or PUT request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	c, err := getCourseSettings(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if r.Method == "GET" {
+		sendJSON(w, r, c)
+		return
+	}
+
+	if !readPostJSON(w, r, s, &c) {
+		return
+	}
+	if !c.isValid() {
+		http.Error(w, "invalid course settings", http.StatusBadRequest)
+		return
+	}
+
+	if err := setCourseSettings(db, c); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, c)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestCourseSettingsSetGet(t *testing.T) {
+	// Should return default settings until the user saves some, and reject
+	// invalid values.
+	t.Parallel()
+
+	db, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	c, err := getCourseSettings(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if c != defaultCourseSettings() {
+		t.Fatal("expected default course settings:", c)
+	}
+
+	expected := CourseSettings{
+		Direction:      "both",
+		Leniency:       "lenient",
+		NewWordsPerDay: 10,
+		ReviewsPerDay:  200,
+		Hints:          "never",
+	}
+	if err := setCourseSettings(db, expected); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	invalid := expected
+	invalid.Direction = "sideways"
+	if err := setCourseSettings(db, invalid); err == nil {
+		t.Fatal("expected invalid course settings to be rejected")
+	}
+
+	c, err = getCourseSettings(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if c != expected {
+		t.Fatal("expected saved course settings:", c)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Public course statistics.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+)
+
+// Min number of learners in a course before its stats get published, so that
+// they can't be traced back to individual users.
+const minPublicLearners = 5
+
+// How long public course stats are cached.
+const courseStatsTTL = time.Hour
+
+type ClassAccuracy struct {
+	FrequencyClass int     `json:"frequencyClass"`
+	Reviews        int     `json:"reviews"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// Anonymized aggregates of reviews in a course.
+type PublicCourseStats struct {
+	Learners int             `json:"learners"`
+	Classes  []ClassAccuracy `json:"classes"` // Empty if there are too few learners
+	Updated  time.Time       `json:"updated"`
+}
+
+type cachedCourseStats struct {
+	stats   PublicCourseStats
+	expires time.Time
+}
+
+var courseStatsCache = struct {
+	sync.Mutex
+	entries map[string]cachedCourseStats
+}{entries: make(map[string]cachedCourseStats)}
+
+// Returns frequency class of each word in the course.
+func wordClasses(l1, l2 string) (map[string]int, error) {
+	db, err := database.Open(basedir.Course(l1, l2) + "?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT word, frequency_class FROM word`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := make(map[string]int)
+	for rows.Next() {
+		var word string
+		var class int
+		if err := rows.Scan(&word, &class); err != nil {
+			return nil, err
+		}
+		classes[word] = class
+	}
+	return classes, rows.Err()
+}
+
+// Adds review counts of each word in the review DB and its archive (see
+// `history.Archive`) to `reviews` and `correct`.
+// Returns false if the user has no reviews.
+func countWordReviews(path string, reviews, correct map[string]int) (bool, error) {
+	found, err := countHistory(path, reviews, correct)
+	if err != nil || !found {
+		return found, err
+	}
+
+	// The latest review of each word never gets archived, so the archive
+	// can only have reviews if the review DB does.
+	archive := basedir.ReviewArchive(path)
+	if _, err := os.Stat(archive); errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if _, err := countHistory(archive, reviews, correct); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Adds review counts of each word in the `history` table of the DB to
+// `reviews` and `correct`.
+// Returns false if the table is empty.
+func countHistory(path string, reviews, correct map[string]int) (bool, error) {
+	db, err := database.Open(path + "?mode=ro")
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	query := `SELECT word, count(*), sum(interval_after > 0) FROM history GROUP BY word`
+	rows, err := db.Query(query)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	found := false
+	for rows.Next() {
+		var word string
+		var n, c int
+		if err := rows.Scan(&word, &n, &c); err != nil {
+			return false, err
+		}
+		reviews[word] += n
+		correct[word] += c
+		found = true
+	}
+	return found, rows.Err()
+}
+
+// Computes course stats from the review DBs of all users.
+// Merged accounts are skipped, because their reviews are already in another
+// account.
+func computeCourseStats(db *sql.DB, l1, l2 string, now time.Time) (PublicCourseStats, error) {
+	stats := PublicCourseStats{Classes: []ClassAccuracy{}, Updated: now}
+
+	rows, err := db.Query(`SELECT id FROM user WHERE merged_into IS NULL`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to compute course stats: %w", err)
+	}
+	var users []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to compute course stats: %w", err)
+		}
+		users = append(users, id)
+	}
+	rows.Close()
+
+	reviews := make(map[string]int)
+	correct := make(map[string]int)
+	for _, userID := range users {
+		path := basedir.Review(userID, l1, l2)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		found, err := countWordReviews(path, reviews, correct)
+		if err != nil {
+			return stats, fmt.Errorf("failed to compute course stats: %w", err)
+		}
+		if found {
+			stats.Learners++
+		}
+	}
+	if stats.Learners < minPublicLearners {
+		return stats, nil
+	}
+
+	classes, err := wordClasses(l1, l2)
+	if err != nil {
+		return stats, fmt.Errorf("failed to compute course stats: %w", err)
+	}
+
+	byClass := make(map[int]*ClassAccuracy)
+	for word, n := range reviews {
+		class, ok := classes[word]
+		if !ok {
+			continue
+		}
+		if byClass[class] == nil {
+			byClass[class] = &ClassAccuracy{FrequencyClass: class}
+		}
+		byClass[class].Reviews += n
+		byClass[class].Accuracy += float64(correct[word])
+	}
+	for _, c := range byClass {
+		c.Accuracy /= float64(c.Reviews)
+		stats.Classes = append(stats.Classes, *c)
+	}
+	sort.Slice(stats.Classes, func(i, j int) bool {
+		return stats.Classes[i].FrequencyClass < stats.Classes[j].FrequencyClass
+	})
+	return stats, nil
+}
+
+// Returns cached course stats, and recomputes them if they've expired.
+func getCourseStats(db *sql.DB, l1, l2 string, now time.Time) (PublicCourseStats, error) {
+	key := l1 + "-" + l2
+
+	// The lock is held while computing stats, so that concurrent requests
+	// don't all scan the review DBs.
+	courseStatsCache.Lock()
+	defer courseStatsCache.Unlock()
+
+	if entry, ok := courseStatsCache.entries[key]; ok && now.Before(entry.expires) {
+		return entry.stats, nil
+	}
+
+	stats, err := computeCourseStats(db, l1, l2, now)
+	if err != nil {
+		return stats, err
+	}
+	courseStatsCache.entries[key] = cachedCourseStats{
+		stats:   stats,
+		expires: now.Add(courseStatsTTL),
+	}
+	return stats, nil
+}
+
+// Responds with anonymized stats of the course.
+// Doesn't require signing in.
+func handleCourseStats(w http.ResponseWriter, r *http.Request) {
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	stats, err := getCourseStats(auth.GetDB(r), l1, l2, time.Now())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%v", int(courseStatsTTL.Seconds())))
+	sendJSON(w, r, stats)
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+func TestCountWordReviews(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "eng-spa.db")
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	now := time.Now()
+	if err := review_scheduler.UpdateReviewAt(db, "foo", false, now.Add(-time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := review_scheduler.UpdateReviewAt(db, "foo", true, now); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.Close()
+
+	reviews := map[string]int{"foo": 1}
+	correct := map[string]int{"foo": 1}
+	found, err := countWordReviews(path, reviews, correct)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !found || reviews["foo"] != 3 || correct["foo"] != 2 {
+		t.Fatal("expected review counts to be added:", found, reviews, correct)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Course content summaries.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+)
+
+type ClassSize struct {
+	FrequencyClass int `json:"frequencyClass"`
+	Words          int `json:"words"`
+}
+
+// Size of the course's content, for comparing courses before starting one.
+type CourseSummary struct {
+	Words     int         `json:"words"`
+	Sentences int         `json:"sentences"`
+	Classes   []ClassSize `json:"classes"`
+
+	// Average number of example sentences per word.
+	SentencesPerWord float64 `json:"sentencesPerWord"`
+}
+
+type cachedCourseSummary struct {
+	summary  CourseSummary
+	modified time.Time // Modification time of the course file
+}
+
+// Summaries only change when the course file gets replaced or patched, so
+// they're cached until the file's modification time changes.
+var courseSummaryCache = struct {
+	sync.Mutex
+	entries map[string]cachedCourseSummary
+}{entries: make(map[string]cachedCourseSummary)}
+
+// Computes summary of course DB.
+func computeCourseSummary[T database.Querier](q T) (CourseSummary, error) {
+	summary := CourseSummary{Classes: []ClassSize{}}
+
+	var occurrences int
+	query := `
+		SELECT
+			(SELECT count(*) FROM word),
+			(SELECT count(*) FROM sentence),
+			(SELECT count(*) FROM contains)
+	`
+	err := q.QueryRow(query).Scan(&summary.Words, &summary.Sentences, &occurrences)
+	if err != nil {
+		return summary, fmt.Errorf("failed to compute course summary: %w", err)
+	}
+	if summary.Words > 0 {
+		summary.SentencesPerWord = float64(occurrences) / float64(summary.Words)
+	}
+
+	query = `
+		SELECT frequency_class, count(*) FROM word
+		GROUP BY frequency_class
+		ORDER BY frequency_class ASC
+	`
+	rows, err := q.Query(query)
+	if err != nil {
+		return summary, fmt.Errorf("failed to compute course summary: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c ClassSize
+		if err := rows.Scan(&c.FrequencyClass, &c.Words); err != nil {
+			return summary, fmt.Errorf("failed to compute course summary: %w", err)
+		}
+		summary.Classes = append(summary.Classes, c)
+	}
+	return summary, nil
+}
+
+// Returns cached course summary, and recomputes it if the course has changed.
+func getCourseSummary(l1, l2 string) (CourseSummary, error) {
+	path := basedir.Course(l1, l2)
+	info, err := os.Stat(path)
+	if err != nil {
+		return CourseSummary{}, fmt.Errorf("failed to get course summary: %w", err)
+	}
+
+	courseSummaryCache.Lock()
+	defer courseSummaryCache.Unlock()
+
+	key := l1 + "-" + l2
+	if entry, ok := courseSummaryCache.entries[key]; ok && entry.modified.Equal(info.ModTime()) {
+		return entry.summary, nil
+	}
+
+	db, err := database.Open(path + "?mode=ro")
+	if err != nil {
+		return CourseSummary{}, fmt.Errorf("failed to get course summary: %w", err)
+	}
+	defer db.Close()
+
+	summary, err := computeCourseSummary(db)
+	if err != nil {
+		return summary, err
+	}
+	courseSummaryCache.entries[key] = cachedCourseSummary{
+		summary:  summary,
+		modified: info.ModTime(),
+	}
+	return summary, nil
+}
+
+// Responds with a summary of the course's content.
+// Doesn't require signing in.
+func handleCourseSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	summary, err := getCourseSummary(l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, summary)
+}
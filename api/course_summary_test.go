@@ -0,0 +1,39 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestComputeCourseSummary(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'a', 0), (2, 'b', 0), (3, 'c', 2)`,
+		`INSERT INTO sentence (id, text, tokens, frequency_class) VALUES (1, 'a b', '[]', 0), (2, 'a', '[]', 0)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1), (1, 2), (2, 1)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	summary, err := computeCourseSummary(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if summary.Words != 3 || summary.Sentences != 2 || summary.SentencesPerWord != 1 {
+		t.Fatal("unexpected course summary:", summary)
+	}
+	if len(summary.Classes) != 2 || summary.Classes[0] != (ClassSize{0, 2}) || summary.Classes[1] != (ClassSize{2, 1}) {
+		t.Fatal("unexpected frequency class distribution:", summary.Classes)
+	}
+}
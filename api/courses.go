@@ -6,7 +6,7 @@ package api
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
@@ -43,8 +43,8 @@ func getUserActiveCourse(userID int) (Course, error) {
 		return Course{}, fmt.Errorf("failed to get active course: %w", err)
 	}
 
-	path := filepath.Join(basedir.DataDir, "courses", fmt.Sprintf("%v.db", code))
-	course, err := getCourseInfo(path)
+	l1, l2, _ := strings.Cut(code, "-")
+	course, err := getCourseInfo(basedir.Course(l1, l2))
 	if err != nil {
 		return Course{}, fmt.Errorf("failed to get active course: %w", err)
 	}
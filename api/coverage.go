@@ -0,0 +1,60 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/coverage"
+	"github.com/polycloze/polycloze/database"
+)
+
+// Max value of the `threshold` search param of the coverage report.
+const maxCoverageThreshold = 100
+
+// Responds with words in the course that have fewer example sentences than
+// the `threshold` search param (default: `coverage.MinSentences`), for course
+// maintainers.
+// Doesn't require signing in.
+func handleCourseCoverage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	threshold := coverage.MinSentences
+	if param := r.URL.Query().Get("threshold"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxCoverageThreshold {
+			http.Error(w, "Invalid threshold.", http.StatusBadRequest)
+			return
+		}
+		threshold = n
+	}
+
+	db, err := database.Open(basedir.Course(l1, l2) + "?mode=ro")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	words, err := coverage.Report(db, threshold)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"threshold": threshold, "words": words})
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Runtime debugging endpoints for admins.
+package api
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Returns router for pprof and expvar endpoints.
+// Should be mounted at /debug, because pprof's index page links to
+// /debug/pprof/*.
+func debugRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Use(requireAdmin)
+
+	r.HandleFunc("/pprof/", pprof.Index)
+	r.HandleFunc("/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/pprof/profile", pprof.Profile)
+	r.HandleFunc("/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/pprof/trace", pprof.Trace)
+
+	// Named profiles (e.g. heap, goroutine).
+	r.HandleFunc("/pprof/*", pprof.Index)
+
+	r.Handle("/vars", expvar.Handler())
+	return r
+}
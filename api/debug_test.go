@@ -0,0 +1,34 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestDebugEndpointsRequireAdmin(t *testing.T) {
+	// Signed-out users shouldn't be able to find the endpoints.
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	handler := auth.Middleware(db)(debugRouter())
+	for _, path := range []string{"/pprof/", "/pprof/heap", "/vars"} {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Fatal("expected endpoint to be hidden:", path, w.Code)
+		}
+	}
+}
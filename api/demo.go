@@ -0,0 +1,261 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Guest/demo mode.
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/flashcards"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/word_scheduler"
+)
+
+// Max number of demo sessions kept in memory.
+// The least recently used session gets dropped to make room for new ones.
+const maxDemoSessions = 500
+
+// Demo sessions that haven't been used for this long get dropped.
+const demoSessionTTL = 30 * time.Minute
+
+// Max number of flashcards per demo request.
+const maxDemoFlashcards = 20
+
+// Review state of an anonymous visitor.
+// Reviews are stored in an in-memory review DB, so they're lost when the
+// session expires or when the server restarts.
+type demoSession struct {
+	db       *sql.DB
+	lastUsed time.Time
+}
+
+type demoStore struct {
+	mu       sync.Mutex
+	sessions map[string]*demoSession
+}
+
+var demoSessions = demoStore{sessions: make(map[string]*demoSession)}
+
+// Opens in-memory review DB.
+func openDemoDB() (*sql.DB, error) {
+	db, err := database.Open(":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each connection to ":memory:" gets its own database, so the pool must
+	// not open or close connections.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if err := database.UpgradeReviewDB(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Drops expired sessions, and the least recently used one if the store is
+// still full.
+// The caller should hold the lock.
+func (s *demoStore) evict(now time.Time) {
+	var oldest string
+	for id, session := range s.sessions {
+		if now.Sub(session.lastUsed) > demoSessionTTL {
+			session.db.Close()
+			delete(s.sessions, id)
+			continue
+		}
+		if oldest == "" || session.lastUsed.Before(s.sessions[oldest].lastUsed) {
+			oldest = id
+		}
+	}
+	if len(s.sessions) >= maxDemoSessions && oldest != "" {
+		s.sessions[oldest].db.Close()
+		delete(s.sessions, oldest)
+	}
+}
+
+// Returns review DB of demo session.
+// Creates a new one if there's none.
+func (s *demoStore) get(id string, now time.Time) (*sql.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[id]; ok && now.Sub(session.lastUsed) <= demoSessionTTL {
+		session.lastUsed = now
+		return session.db, nil
+	}
+
+	s.evict(now)
+	db, err := openDemoDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo session: %w", err)
+	}
+	s.sessions[id] = &demoSession{db: db, lastUsed: now}
+	return db, nil
+}
+
+// Returns L1 and L2 codes of the demo course (e.g. "eng-spa").
+func parseDemoCourse(course string) (string, string, bool) {
+	l1, l2, ok := strings.Cut(course, "-")
+	if !ok || l1 == "" || l2 == "" {
+		return "", "", false
+	}
+	return l1, l2, true
+}
+
+// Shows study page for the demo course to visitors who aren't signed in.
+func handleDemoPage(demoCourse string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l1, l2, _ := parseDemoCourse(demoCourse)
+		course, ok := courseCatalog.Course(l1, l2)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		db := auth.GetDB(r)
+		s, err := sessions.StartOrResumeSession(db, w, r)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		if s.IsSignedIn() {
+			http.Redirect(w, r, "/study", http.StatusTemporaryRedirect)
+			return
+		}
+
+		renderTemplate(w, r, "study.html", map[string]any{
+			"course":    course,
+			"csrfToken": sessions.CSRFToken(s.ID),
+			"demo":      true,
+		})
+	}
+}
+
+// Generates flashcards from the demo course, and saves reviews in the
+// visitor's in-memory review DB.
+// Requests are rate-limited by IP address.
+func handleDemoFlashcards(demoCourse string, limiter *rateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+			return
+		}
+
+		l1, l2, _ := parseDemoCourse(demoCourse)
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+
+		now := time.Now()
+		if !limiter.allow(clientIP(r), now) {
+			http.Error(w, "Too many requests.", http.StatusTooManyRequests)
+			return
+		}
+
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			serverError(w, r, fmt.Errorf("could not read request: %w", err))
+			return
+		}
+
+		var data FlashcardsRequest
+		if err := parseJSON(w, body, &data); err != nil {
+			return
+		}
+		if data.Limit > maxDemoFlashcards {
+			data.Limit = maxDemoFlashcards
+		}
+
+		if len(data.Reviews) > 0 {
+			token := sessions.RequestCSRFToken(r)
+			if token == "" {
+				token = data.CSRFToken
+			}
+			if !sessions.CheckCSRFToken(s.ID, token) {
+				http.Error(w, "Forbidden.", http.StatusForbidden)
+				return
+			}
+		}
+
+		reviewDB, err := demoSessions.get(s.ID, now)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		// Demo sessions only support cloze flashcards, so production reviews are
+		// ignored.
+		hook := database.AttachCourse(basedir.Course(l1, l2))
+		con, err := database.NewConnection(reviewDB, r.Context(), hook)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		defer con.Close()
+
+		reviews, production := splitReviews(data.Reviews)
+		recognitionResults, err := word_scheduler.BulkSaveWords(con, reviews, now)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		productionResults := make([]ReviewSaveResult, 0, len(production))
+		for _, review := range production {
+			productionResults = append(productionResults, ReviewSaveResult{
+				Word:  review.Word,
+				Error: "not supported in demo",
+			})
+		}
+		results := mergeSaveResults(data.Reviews, recognitionResults, productionResults)
+		if len(reviews) > 0 && data.Difficulty != nil {
+			if err := difficulty.Update(con, *data.Difficulty); err != nil {
+				serverError(w, r, err)
+				return
+			}
+		}
+
+		items := flashcards.GetFiltered(con, data.Limit, rs.Filter{Exclude: excludeWords(data.Exclude)})
+		for i := range items {
+			items[i].Mode = flashcards.ModeCloze
+		}
+		newDiff := difficulty.GetLatest(con)
+		thresholds, err := difficulty.GetThresholds(con)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		sendJSON(w, r, FlashcardsResponse{
+			Items:      items,
+			Difficulty: &newDiff,
+			Thresholds: &thresholds,
+			Algorithm:  "wilson",
+			Results:    results,
+		})
+	}
+}
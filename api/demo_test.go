@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDemoStore(t *testing.T) {
+	t.Parallel()
+
+	store := demoStore{sessions: make(map[string]*demoSession)}
+	now := time.Now()
+
+	db, err := store.get("a", now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	same, err := store.get("a", now.Add(time.Minute))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if db != same {
+		t.Fatal("expected session to reuse its review DB")
+	}
+
+	// Expired sessions start over with an empty review DB.
+	later := now.Add(time.Minute + demoSessionTTL + time.Second)
+	fresh, err := store.get("a", later)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if fresh == db {
+		t.Fatal("expected expired session to get a new review DB")
+	}
+	if len(store.sessions) != 1 {
+		t.Fatal("expected expired session to be dropped:", len(store.sessions))
+	}
+	fresh.Close()
+}
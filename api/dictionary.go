@@ -0,0 +1,65 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/dictionary"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Responds with dictionary entry of word in the course.
+// Definitions from the external dictionary are omitted if the lookup fails.
+func handleDictionary(source dictionary.Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := sessions.ResumeSession(auth.GetDB(r), w, r)
+		if err != nil || !s.IsSignedIn() {
+			http.NotFound(w, r)
+			return
+		}
+
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+
+		db, err := database.Open(basedir.Course(l1, l2))
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		defer db.Close()
+
+		entry, err := dictionary.Lookup(db, chi.URLParam(r, "word"), 5)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		if source != nil {
+			definitions, err := source.Define(r.Context(), l1, l2, entry.Word)
+			if err != nil {
+				log.Println(err)
+			} else if definitions != nil {
+				entry.Definitions = definitions
+			}
+		}
+		sendJSON(w, r, entry)
+	}
+}
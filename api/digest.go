@@ -0,0 +1,79 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/digest"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// GET: responds with user's weekly digest settings.
+// POST: updates user's weekly digest settings.
+// Digests get sent to the email address in the reminder settings.
+func handleDigest(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "POST":
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Open user data DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	// Fields that are missing from the request keep their current values.
+	settings, err := digest.Get(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if r.Method == "GET" {
+		sendJSON(w, r, settings)
+		return
+	}
+
+	if !readPostJSON(w, r, s, &settings) {
+		return
+	}
+	if !settings.IsValid() {
+		http.Error(w, "invalid digest settings", http.StatusBadRequest)
+		return
+	}
+	if settings.Enabled {
+		rs, err := reminders.Get(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		if rs.Email == "" {
+			http.Error(w, "Set an email address in the reminder settings first.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := digest.Set(db, settings); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, settings)
+}
@@ -4,34 +4,220 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/polycloze/polycloze/achievements"
 	"github.com/polycloze/polycloze/auth"
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
 	"github.com/polycloze/polycloze/difficulty"
 	"github.com/polycloze/polycloze/flashcards"
+	"github.com/polycloze/polycloze/hooks"
 	"github.com/polycloze/polycloze/sessions"
 	"github.com/polycloze/polycloze/text"
 	"github.com/polycloze/polycloze/word_scheduler"
 )
 
-// Returns predicate to pass to item generator.
-func excludeWords(words []string) func(string) bool {
-	exclude := make(map[string]bool)
+// Casefolds words to exclude from flashcards (e.g. words already on the
+// client).
+func excludeWords(words []string) []string {
+	exclude := make([]string, 0, len(words))
 	for _, word := range words {
-		exclude[text.Casefold(word)] = true
+		exclude = append(exclude, text.Casefold(word))
 	}
-	return func(word string) bool {
-		_, found := exclude[text.Casefold(word)]
-		return !found
+	return exclude
+}
+
+// Returns set of words in reviews that have been reviewed before.
+func seenWords(con *database.Connection, reviews []ReviewResult) map[string]bool {
+	seen := make(map[string]bool)
+	query := `SELECT 1 FROM review WHERE item = ?`
+	for _, review := range reviews {
+		word := text.Casefold(review.Word)
+		var found int
+		if err := con.QueryRow(query, word).Scan(&found); err == nil {
+			seen[word] = true
+		}
+	}
+	return seen
+}
+
+func emitReviewEvents(userID int, l1, l2 string, reviews []ReviewResult, seen map[string]bool, now time.Time) {
+	for _, review := range reviews {
+		word := text.Casefold(review.Word)
+		hooks.Review(hooks.ReviewEvent{
+			UserID:  userID,
+			L1:      l1,
+			L2:      l2,
+			Word:    word,
+			Correct: review.Correct,
+			Time:    now,
+		})
+		if !seen[word] {
+			seen[word] = true
+			hooks.WordLearned(hooks.WordLearnedEvent{
+				UserID: userID,
+				L1:     l1,
+				L2:     l2,
+				Word:   word,
+				Time:   now,
+			})
+		}
+	}
+}
+
+// Awards achievements earned by the latest reviews.
+// Errors are only logged, because they shouldn't prevent the user from
+// studying.
+func awardAchievements(db *sql.DB, userID int, l1, l2 string, now time.Time) {
+	awarded, err := achievements.Check(db, now)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, a := range awarded {
+		hooks.Achievement(hooks.AchievementEvent{
+			UserID:      userID,
+			L1:          l1,
+			L2:          l2,
+			Achievement: a.Name,
+			Time:        now,
+		})
+	}
+}
+
+// Returns path to the review DB that schedules reviews in the mode.
+func reviewDBPath(userID int, l1, l2, mode string) string {
+	if mode == flashcards.ModeProduction {
+		return basedir.ProductionReview(userID, l1, l2)
+	}
+	return basedir.Review(userID, l1, l2)
+}
+
+// Splits reviews into recognition and production reviews, which are saved in
+// different review DBs.
+func splitReviews(reviews []ReviewResult) (recognition, production []ReviewResult) {
+	for _, review := range reviews {
+		if review.Mode == flashcards.ModeProduction {
+			production = append(production, review)
+		} else {
+			recognition = append(recognition, review)
+		}
+	}
+	return recognition, production
+}
+
+// Opens review DB of the mode, and a connection to it with the course DB
+// attached.
+// Caller should close both the connection and the DB.
+func openReviewStream(r *http.Request, userID int, l1, l2, mode string) (*sql.DB, *database.Connection, error) {
+	path := reviewDBPath(userID, l1, l2, mode)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, nil, fmt.Errorf("could not create review directory (%v-%v): %w", l1, l2, err)
+	}
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err)
+	}
+
+	// Create database connection with access to review and course DB.
+	hook := database.AttachCourse(basedir.Course(l1, l2))
+	con, err := database.NewConnection(db, r.Context(), hook)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, con, nil
+}
+
+// Saves reviews in the review DB of the mode.
+// Returns the outcome of each review.
+// Hooks and achievements are only used for recognition reviews, so production
+// reviews don't count twice.
+func saveReviews(
+	r *http.Request,
+	userID int,
+	l1, l2, mode string,
+	reviews []ReviewResult,
+	now time.Time,
+) ([]ReviewSaveResult, error) {
+	db, con, err := openReviewStream(r, userID, l1, l2, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	defer con.Close()
+	return saveReviewsTo(db, con, userID, l1, l2, mode, reviews, now)
+}
+
+// Like `saveReviews`, but uses review stream that's already open.
+func saveReviewsTo(
+	db *sql.DB,
+	con *database.Connection,
+	userID int,
+	l1, l2, mode string,
+	reviews []ReviewResult,
+	now time.Time,
+) ([]ReviewSaveResult, error) {
+	// Flushes from other tabs and study sessions of the same course shouldn't
+	// interleave with this one.
+	defer database.LockReviewDB(reviewDBPath(userID, l1, l2, mode))()
+
+	recognition := mode != flashcards.ModeProduction
+
+	var seen map[string]bool
+	if recognition && hooks.Enabled() {
+		seen = seenWords(con, reviews)
+	}
+	results, err := word_scheduler.BulkSaveWords(con, reviews, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only count reviews that got saved.
+	saved := make([]ReviewResult, 0, len(reviews))
+	for i, result := range results {
+		if result.OK {
+			saved = append(saved, reviews[i])
+		}
+	}
+
+	if recognition && hooks.Enabled() {
+		emitReviewEvents(userID, l1, l2, saved, seen, now)
+	}
+	if err := flashcards.SaveModeStats(con, saved); err != nil {
+		return nil, err
+	}
+	if recognition {
+		awardAchievements(db, userID, l1, l2, now)
 	}
+	return results, nil
+}
+
+// Merges save results of recognition and production reviews, so that they're
+// in the same order as the reviews in the request.
+func mergeSaveResults(reviews []ReviewResult, recognition, production []ReviewSaveResult) []ReviewSaveResult {
+	results := make([]ReviewSaveResult, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Mode == flashcards.ModeProduction {
+			results = append(results, production[0])
+			production = production[1:]
+		} else {
+			results = append(results, recognition[0])
+			recognition = recognition[1:]
+		}
+	}
+	return results
 }
 
 func handleFlashcards(w http.ResponseWriter, r *http.Request) {
@@ -56,32 +242,12 @@ func handleFlashcards(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-
-	// Open user's review DB.
 	userID := s.Data["userID"].(int)
-	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
-	if err != nil {
-		log.Println(fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
-	defer db.Close()
-
-	// Create database connection with access to review and course DB.
-	hook := database.AttachCourse(basedir.Course(l1, l2))
-	con, err := database.NewConnection(db, r.Context(), hook)
-	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
-	defer con.Close()
 
 	// Read request data.
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Could not read request.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
 		return
 	}
 
@@ -89,11 +255,19 @@ func handleFlashcards(w http.ResponseWriter, r *http.Request) {
 	if err := parseJSON(w, body, &data); err != nil {
 		return
 	}
+	if data.Mode == "" {
+		data.Mode = flashcards.ModeCloze
+	}
+	if !flashcards.IsValidMode(data.Mode) {
+		http.Error(w, "Invalid mode.", http.StatusBadRequest)
+		return
+	}
 
-	// Save uploaded reviews and difficulty stats.
+	// Save uploaded reviews.
+	var results []ReviewSaveResult
 	if len(data.Reviews) > 0 {
-		// Look for csrf token in request headers or in the request body.
-		token := r.Header.Get("X-CSRF-Token")
+		// Look for csrf token in request headers, form or in the request body.
+		token := sessions.RequestCSRFToken(r)
 		if token == "" {
 			token = data.CSRFToken
 		}
@@ -104,27 +278,102 @@ func handleFlashcards(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Save review results.
-		if err := word_scheduler.BulkSaveWords(con, data.Reviews, time.Now()); err != nil {
-			log.Println(err)
-			http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		// Hold suspicious reviews for an admin to approve.
+		now := time.Now()
+		results, err = quarantineReviews(auth.GetDB(r), userID, l1, l2, data.Reviews, now)
+		if err != nil {
+			serverError(w, r, err)
 			return
 		}
 
-		if data.Difficulty != nil {
-			if err := difficulty.Update(con, *data.Difficulty); err != nil {
-				log.Println(err)
-				http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-				return
+		// Save review results.
+		// Reviews can come from both streams if the user switched modes.
+		if results == nil {
+			recognition, production := splitReviews(data.Reviews)
+			var recognitionResults, productionResults []ReviewSaveResult
+			if len(recognition) > 0 {
+				recognitionResults, err = saveReviews(r, userID, l1, l2, flashcards.ModeCloze, recognition, now)
+				if err != nil {
+					serverError(w, r, err)
+					return
+				}
 			}
+			if len(production) > 0 {
+				productionResults, err = saveReviews(r, userID, l1, l2, flashcards.ModeProduction, production, now)
+				if err != nil {
+					serverError(w, r, err)
+					return
+				}
+			}
+			results = mergeSaveResults(data.Reviews, recognitionResults, productionResults)
+		}
+	}
+
+	// Open review DB of the requested mode.
+	db, con, err := openReviewStream(r, userID, l1, l2, data.Mode)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+	defer con.Close()
+
+	// Difficulty stats come from the flashcards of the requested mode.
+	if len(data.Reviews) > 0 && data.Difficulty != nil {
+		unlock := database.LockReviewDB(reviewDBPath(userID, l1, l2, data.Mode))
+		err := difficulty.Update(con, *data.Difficulty)
+		unlock()
+		if err != nil {
+			serverError(w, r, err)
+			return
 		}
 	}
 
 	// Generate flashcards.
-	items := flashcards.Get(con, data.Limit, excludeWords(data.Exclude))
+	// Course settings are in the recognition review DB.
+	settingsDB := db
+	if data.Mode == flashcards.ModeProduction {
+		settingsDB, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		defer settingsDB.Close()
+	}
+	filter, err := courseFlashcardFilter(settingsDB)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	filter.Exclude = excludeWords(data.Exclude)
+	items := flashcards.GetFiltered(con, data.Limit, filter)
+	for i := range items {
+		items[i].Mode = data.Mode
+	}
 	newDiff := difficulty.GetLatest(con)
-	sendJSON(w, FlashcardsResponse{
-		Items:      items,
-		Difficulty: &newDiff,
+	thresholds, err := difficulty.GetThresholds(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	algorithm, err := difficulty.GetAlgorithm(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	answerRules, err := courseAnswerRules(settingsDB, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	sendJSON(w, r, FlashcardsResponse{
+		Items:       items,
+		Difficulty:  &newDiff,
+		Thresholds:  &thresholds,
+		Algorithm:   algorithm,
+		Results:     results,
+		AnswerRules: answerRules,
 	})
 }
@@ -0,0 +1,55 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/flashcards"
+)
+
+func TestSplitReviews(t *testing.T) {
+	t.Parallel()
+
+	reviews := []ReviewResult{
+		{Word: "a", Correct: true},
+		{Word: "b", Correct: true, Mode: flashcards.ModeProduction},
+		{Word: "c", Correct: false, Mode: flashcards.ModeListening},
+	}
+	recognition, production := splitReviews(reviews)
+	if len(recognition) != 2 || len(production) != 1 || production[0].Word != "b" {
+		t.Fatal("unexpected split:", recognition, production)
+	}
+}
+
+func TestReviewDBPath(t *testing.T) {
+	t.Parallel()
+
+	if reviewDBPath(1, "eng", "spa", flashcards.ModeCloze) != basedir.Review(1, "eng", "spa") {
+		t.Fatal("expected cloze reviews to use the main review DB")
+	}
+	if reviewDBPath(1, "eng", "spa", flashcards.ModeProduction) == basedir.Review(1, "eng", "spa") {
+		t.Fatal("expected production reviews to use a separate review DB")
+	}
+}
+
+func TestMergeSaveResults(t *testing.T) {
+	t.Parallel()
+
+	reviews := []ReviewResult{
+		{Word: "a"},
+		{Word: "b", Mode: flashcards.ModeProduction},
+		{Word: "c"},
+	}
+	recognition := []ReviewSaveResult{{Word: "a", OK: true}, {Word: "c", OK: false}}
+	production := []ReviewSaveResult{{Word: "b", OK: true}}
+
+	results := mergeSaveResults(reviews, recognition, production)
+	for i, review := range reviews {
+		if results[i].Word != review.Word {
+			t.Fatal("expected results to be in the same order as reviews:", results)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/frequency_list"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// GET: responds with the number of words in the user's frequency list.
+// POST: replaces the user's frequency list with the uploaded file
+// (`frequency-list` form field).
+// DELETE: deletes the user's frequency list.
+func handleFrequencyList(maxSize int64) http.HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil || !s.IsSignedIn() {
+			http.NotFound(w, r)
+			return
+		}
+
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Check size before the form gets parsed for the CSRF token.
+		if r.Method == "POST" {
+			r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			if err := r.ParseMultipartForm(maxSize); err != nil {
+				var tooBig *http.MaxBytesError
+				if errors.As(err, &tooBig) {
+					message := fmt.Sprintf("File is too big (>%vMB).", maxSize/(1024*1024))
+					http.Error(w, message, http.StatusBadRequest)
+					return
+				}
+				http.Error(w, "Missing file.", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Check CSRF token.
+		if r.Method == "POST" || r.Method == "DELETE" {
+			if !sessions.CheckRequestCSRFToken(s.ID, r) {
+				http.Error(w, "Forbidden.", http.StatusForbidden)
+				return
+			}
+		}
+
+		var entries []frequency_list.Entry
+		if r.Method == "POST" {
+			file, _, err := r.FormFile("frequency-list")
+			if err != nil {
+				http.Error(w, "Missing file.", http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+
+			entries, err = frequency_list.Parse(file)
+			if errors.Is(err, frequency_list.ErrEmpty) {
+				http.Error(w, "Frequency list is empty.", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid frequency list.", http.StatusBadRequest)
+				return
+			}
+		}
+
+		userID := s.Data["userID"].(int)
+		con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		defer closeAll()
+
+		switch r.Method {
+		case "POST":
+			_, err = frequency_list.Save(con, entries)
+		case "DELETE":
+			err = frequency_list.Clear(con)
+		}
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		count, err := frequency_list.Count(con)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, map[string]int{"words": count})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/goals"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// GET: responds with progress towards the user's vocabulary goal in the
+// course (null if the user hasn't set one).
+// POST: sets the user's vocabulary goal.
+// DELETE: removes the user's vocabulary goal.
+func handleGoal(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data goals.Goal
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if !data.IsValid() {
+			http.Error(w, "Invalid goal.", http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+	default:
+		http.Error(w, "expected GET, POST or DELETE request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "POST":
+		err = goals.Set(db, data)
+	case "DELETE":
+		err = goals.Delete(db)
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	progress, err := goals.GetProgress(db, time.Now())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"goal": progress})
+}
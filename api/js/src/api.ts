@@ -8,22 +8,32 @@ import { getL1, getL2 } from "./language";
 import { fetchJson, resolve, submitFormData, submitJson } from "./request";
 import {
   ActivitySchema,
+  AnalysisReport,
+  AuthoredSentence,
   ActivitySummary,
   Course,
   CoursesSchema,
   DataPoint,
+  DictionaryEntry,
   EstimatedLevelSchema,
   FlashcardsResponse,
+  GoalProgress,
+  GoalSchema,
   Language,
   LanguagesSchema,
+  MineResult,
   RandomSentence,
   RandomSentencesSchema,
   ReviewResult,
+  SearchResults,
   SetCourseResponse,
+  TodaySummary,
   Word,
   UploadCSVFileResponse,
   VocabularySchema,
   VocabularySizeSchema,
+  WordExamples,
+  WordListSchema,
 } from "./schema";
 
 type FetchVocabularyOptions = {
@@ -63,6 +73,144 @@ export async function fetchVocabulary(
   return json.words || [];
 }
 
+// Deletes review history of word in the current course.
+export async function forgetWord(word: string): Promise<boolean> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/vocabulary/${l1}/${l2}/forget`);
+  try {
+    await submitJson<{ ok: boolean }>(url, { word });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+// Boosts (positive) or deprioritizes (negative) a new word in the current
+// course.
+// Priority 0 removes the override.
+export function setWordPriority(
+  word: string,
+  priority: number
+): Promise<{ priorities: { word: string; priority: number }[] }> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/vocabulary/${l1}/${l2}/priority`);
+  return submitJson(url, { word, priority });
+}
+
+// Sets the user's note on word in the current course.
+// Empty text deletes the note.
+export function setNote(word: string, text: string): Promise<unknown> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/notes/${l1}/${l2}/${encodeURIComponent(word)}`);
+  return submitJson(url, { text });
+}
+
+// Looks up word in the current course.
+export function fetchDictionaryEntry(word: string): Promise<DictionaryEntry> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(
+    `/api/dictionary/${l1}/${l2}/${encodeURIComponent(word)}`
+  );
+  return fetchJson<DictionaryEntry>(url, {
+    mode: "cors" as RequestMode,
+  });
+}
+
+// Computes how much of the text the user knows.
+export function analyzeText(text: string): Promise<AnalysisReport> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/analyze/${l1}/${l2}`);
+  return submitJson<AnalysisReport>(url, { text });
+}
+
+// Saves sentences in the text that contain new or due words.
+export function mineText(text: string): Promise<MineResult> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/mine/${l1}/${l2}`);
+  return submitJson<MineResult>(url, { text });
+}
+
+// Adds sentence for word in the current course.
+// The word has to appear in the sentence.
+export function addUserSentence(
+  word: string,
+  text: string,
+  translation = ""
+): Promise<{ sentences: AuthoredSentence[] }> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/user-sentences/${l1}/${l2}`);
+  return submitJson(url, { word, text, translation });
+}
+
+// Adds words to the user's word list in the current course.
+export function addToWordList(words: string[]): Promise<WordListSchema> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/wordlist/${l1}/${l2}`);
+  return submitJson<WordListSchema>(url, { words });
+}
+
+// Fetches today's review summary in the current course.
+export function fetchToday(): Promise<TodaySummary> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/today/${l1}/${l2}`);
+  url.searchParams.set("tz", Intl.DateTimeFormat().resolvedOptions().timeZone);
+  return fetchJson<TodaySummary>(url, {
+    mode: "cors" as RequestMode,
+  });
+}
+
+// Fetches progress towards the vocabulary goal in the current course.
+export async function fetchGoal(): Promise<GoalProgress | null> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/goals/${l1}/${l2}`);
+  const json = await fetchJson<GoalSchema>(url, {
+    mode: "cors" as RequestMode,
+  });
+  return json.goal;
+}
+
+// Searches words and sentences in the current course.
+// Supports prefix (`word*`) and phrase (`"some words"`) queries.
+export function search(query: string): Promise<SearchResults> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/search/${l1}/${l2}`);
+  url.searchParams.set("q", query);
+  return fetchJson<SearchResults>(url, {
+    mode: "cors" as RequestMode,
+  });
+}
+
+// Suggests course words that start with the prefix.
+export async function autocomplete(
+  prefix: string,
+  limit = 10
+): Promise<SearchResults["words"]> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/autocomplete/${l1}/${l2}`);
+  url.searchParams.set("q", prefix);
+  url.searchParams.set("limit", limit.toString());
+  const json = await fetchJson<Pick<SearchResults, "words">>(url, {
+    mode: "cors" as RequestMode,
+  });
+  return json.words;
+}
+
+// Fetches example sentences (with translations) of a word in the current
+// course.
+export function fetchWordExamples(
+  word: string,
+  page = 0,
+  limit = 10
+): Promise<WordExamples> {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const url = resolve(`/api/examples/${l1}/${l2}/${encodeURIComponent(word)}`);
+  url.searchParams.set("page", page.toString());
+  url.searchParams.set("limit", limit.toString());
+  return fetchJson<WordExamples>(url, {
+    mode: "cors" as RequestMode,
+  });
+}
+
 type FetchActivityOptions = {
   l1?: string;
   l2?: string;
@@ -202,6 +350,7 @@ type FetchFlashcardsOptions = {
   exclude?: string[]; // Words to exclude in flashcards
   reviews?: ReviewResult[];
   difficulty?: Difficulty;
+  mode?: string; // Exercise type
 };
 
 function defaultFetchFlashcardsOptions(): FetchFlashcardsOptions {
@@ -216,16 +365,24 @@ function defaultFetchFlashcardsOptions(): FetchFlashcardsOptions {
 
 // Returns a copy of the review result containing only the necessary fields.
 function minimizeReviewResult(review: ReviewResult): ReviewResult {
-  const { word, correct, timestamp } = review;
-  return { word, correct, timestamp };
+  const { word, correct, timestamp, mode } = review;
+  return { word, correct, timestamp, mode };
+}
+
+// Returns URL of flashcards endpoint.
+// Uses the demo endpoint on the guest demo page.
+function flashcardsURL(l1?: string, l2?: string): string {
+  if (document.querySelector('meta[name="polycloze-demo"]') != null) {
+    return resolve("/api/demo/flashcards");
+  }
+  return resolve(`/api/flashcards/${l1}/${l2}`);
 }
 
 export function fetchFlashcards(
   options: FetchFlashcardsOptions = {}
 ): Promise<FlashcardsResponse> {
   options = { ...defaultFetchFlashcardsOptions(), ...options };
-  const { l1, l2 } = options;
-  const url = resolve(`/api/flashcards/${l1}/${l2}`);
+  const url = flashcardsURL(options.l1, options.l2);
   const data = {
     limit: options.limit,
     exclude: options.exclude,
@@ -234,6 +391,7 @@ export function fetchFlashcards(
         ? options.reviews.map(minimizeReviewResult)
         : undefined,
     difficulty: options.difficulty,
+    mode: options.mode,
     timestamp: Math.floor(Date.now() / 1000),
   };
   return submitJson<FlashcardsResponse>(url, data);
@@ -254,7 +412,7 @@ export function sendReviewResults(
 ) {
   const l1 = getL1().code;
   const l2 = getL2().code;
-  const url = resolve(`/api/flashcards/${l1}/${l2}`);
+  const url = flashcardsURL(l1, l2);
   const data = {
     limit: 0,
     reviews,
@@ -329,6 +487,19 @@ export function uploadCSVFile(
   return submitFormData<UploadCSVFileResponse>(url, formData);
 }
 
+// Replaces the user's frequency list in the current course.
+// Responds with the number of words in the list that are in the course.
+export function uploadFrequencyList(file: File): Promise<{ words: number }> {
+  const [l1, l2] = [getL1().code, getL2().code];
+
+  const formData = new FormData();
+  formData.append("csrf-token", csrf());
+  formData.append("frequency-list", file);
+
+  const url = resolve(`/api/settings/frequency-list/${l1}/${l2}`);
+  return submitFormData<{ words: number }>(url, formData);
+}
+
 export async function setActiveCourse(
   l1: string,
   l2: string
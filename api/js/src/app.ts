@@ -1,10 +1,10 @@
 import "./app.css";
-import { ItemBuffer } from "./buffer";
+import { ItemSource } from "./buffer";
 import { createEmptyItem, createItem } from "./item";
 import { TTS } from "./tts";
 
 export async function createApp(
-  buffer: ItemBuffer
+  buffer: ItemSource
 ): Promise<[HTMLDivElement, () => void]> {
   const tts = new TTS();
   await tts.init();
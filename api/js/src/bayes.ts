@@ -0,0 +1,74 @@
+// For computing Beta-Binomial posterior probabilities.
+
+// Computes log(n!).
+function logFactorial(n: number): number {
+  let sum = 0;
+  for (let i = 2; i <= n; i++) {
+    sum += Math.log(i);
+  }
+  return sum;
+}
+
+// Computes the posterior probability that the true success proportion is at
+// most x, assuming a uniform prior.
+// See polycloze/bayes/bayes.go.
+function cdf(success: number, fail: number, x: number): number {
+  if (x <= 0) {
+    return 0;
+  }
+  if (x >= 1) {
+    return 1;
+  }
+
+  const a = success + 1;
+  const n = success + fail + 1;
+  const lgn = logFactorial(n);
+
+  let sum = 0;
+  for (let j = a; j <= n; j++) {
+    const logTerm =
+      lgn -
+      logFactorial(j) -
+      logFactorial(n - j) +
+      j * Math.log(x) +
+      (n - j) * Math.log1p(-x);
+    sum += Math.exp(logTerm);
+  }
+  return Math.min(sum, 1);
+}
+
+// Parameters of the posterior probability tests.
+// See `Thresholds` in polycloze/bayes/bayes.go.
+export type Thresholds = {
+  easyBound: number;
+  easyConfidence: number;
+  hardBound: number;
+  hardConfidence: number;
+};
+
+export function defaultThresholds(): Thresholds {
+  return {
+    easyBound: 0.8,
+    easyConfidence: 0.7,
+    hardBound: 0.7,
+    hardConfidence: 0.95,
+  };
+}
+
+export function isTooEasy(
+  correct: number,
+  incorrect: number,
+  thresholds: Thresholds = defaultThresholds()
+): boolean {
+  const p = 1 - cdf(correct, incorrect, thresholds.easyBound);
+  return p > thresholds.easyConfidence;
+}
+
+export function isTooHard(
+  correct: number,
+  incorrect: number,
+  thresholds: Thresholds = defaultThresholds()
+): boolean {
+  const p = cdf(correct, incorrect, thresholds.hardBound);
+  return p > thresholds.hardConfidence;
+}
@@ -1,6 +1,7 @@
 import "./blank.css";
 import { substituteDigraphs } from "./digraph";
 import { getFont, getWidth } from "./font";
+import { Replacement } from "./schema";
 
 import { distance } from "fastest-levenshtein";
 
@@ -52,12 +53,25 @@ function resizeInput(input: HTMLInputElement, text: string) {
   input.style.setProperty("width", width);
 }
 
+// Answer normalization rules of the current course.
+let answerRules: Replacement[] = [];
+
+// Sets answer normalization rules sent by the server.
+export function setAnswerRules(rules: Replacement[]) {
+  answerRules = rules;
+}
+
 // Removes soft-hyphens and unnecessary surrounding characters (whitespace,
 // zero-width spaces, no-break spaces, etc.
+// Also applies the course's answer normalization rules.
+// See `NormalizeAnswer` in text/answers.go.
 function normalize(word: string): string {
   // Remove soft-hyphens.
   word = word.trim().replace(/\u00AD/g, "");
 
+  // Phrases match regardless of the whitespace between words.
+  word = word.replace(/\s+/g, " ");
+
   const zeroWidthSpace = "\u200B";
   while (word.startsWith(zeroWidthSpace)) {
     word = word.slice(zeroWidthSpace.length);
@@ -73,6 +87,11 @@ function normalize(word: string): string {
   while (word.endsWith(noBreakSpace)) {
     word = word.slice(0, word.length - noBreakSpace.length);
   }
+
+  word = word.normalize("NFKC");
+  for (const rule of answerRules) {
+    word = word.split(rule.from).join(rule.to);
+  }
   return word;
 }
 
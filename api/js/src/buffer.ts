@@ -1,9 +1,9 @@
 // Item buffer
 
 import { fetchFlashcards, sendReviewResults } from "./api";
-import { PartWithAnswers, hasAnswers } from "./blank";
+import { PartWithAnswers, hasAnswers, setAnswerRules } from "./blank";
 import { Difficulty, DifficultyTuner } from "./difficulty";
-import { Item } from "./item";
+import { Item, getMode } from "./item";
 import { ReviewResult } from "./schema";
 import { Sentence } from "./sentence";
 
@@ -15,7 +15,12 @@ function* getBlankParts(sentence: Sentence): IterableIterator<PartWithAnswers> {
   }
 }
 
-export class ItemBuffer {
+// Source of flashcards for the app.
+export interface ItemSource {
+  take(): Promise<Item | undefined>;
+}
+
+export class ItemBuffer implements ItemSource {
   buffer: Item[];
   keys: Set<string>;
   difficultyTuner: DifficultyTuner;
@@ -29,6 +34,7 @@ export class ItemBuffer {
 
     const listener = (event: Event) => {
       const review = (event as CustomEvent).detail;
+      review.mode = getMode();
       this.reviews.push(review);
 
       if (!review.new) {
@@ -102,15 +108,34 @@ export class ItemBuffer {
   // Fetches flashcards from the server and stores them in the buffer.
   async fetch(limit: number): Promise<Item[]> {
     const reviews = this.reviews.splice(0);
-    const { items, difficulty } = await fetchFlashcards({
-      limit,
-      reviews,
-      difficulty: this.difficultyTuner.difficulty,
-      exclude: Array.from(this.keys),
-    });
+    const { items, difficulty, thresholds, algorithm, results, answerRules } =
+      await fetchFlashcards({
+        limit,
+        reviews,
+        difficulty: this.difficultyTuner.difficulty,
+        exclude: Array.from(this.keys),
+        mode: getMode(),
+      });
     items.forEach((item) => this.add(item));
     reviews.forEach((review) => this.keys.delete(review.word));
+
+    // Resend reviews that the server failed to save.
+    // Quarantined reviews are kept on the server until an admin approves them.
+    (results || []).forEach((result, i) => {
+      if (!result.ok && !result.quarantined && reviews[i] != null) {
+        this.reviews.push(reviews[i]);
+      }
+    });
     this.difficultyTuner.reset(difficulty);
+    if (thresholds != null) {
+      this.difficultyTuner.thresholds = thresholds;
+    }
+    if (algorithm != null) {
+      this.difficultyTuner.algorithm = algorithm;
+    }
+    if (answerRules != null) {
+      setAnswerRules(answerRules);
+    }
     return items;
   }
 
@@ -2,30 +2,46 @@ import {
   fetchActivity,
   fetchCourses,
   fetchEstimatedLevel,
+  fetchGoal,
   fetchVocabularySize,
 } from "./api";
 import { createApp } from "./app";
-import { ItemBuffer } from "./buffer";
+import { ItemBuffer, ItemSource } from "./buffer";
 import { setButtonLink } from "./button";
 import { createScoreCounter } from "./counter";
 import { createDiacriticButtonSettingsSection } from "./diacritic";
+import { createModeSettingsSection } from "./item";
 import { getL2 } from "./language";
+import { LiveItemBuffer } from "./live";
 import { createResponsiveMenu } from "./menu";
 import { createOverviewPage } from "./overview";
-import { ActivitySummary, Course, DataPoint } from "./schema";
+import { ActivitySummary, Course, DataPoint, GoalProgress } from "./schema";
 import { createCourseSelectButton } from "./select";
 import { createVoiceSettingsSection, TTS } from "./tts";
 import { createFileBrowser } from "./upload";
 import { createVocabularyList } from "./vocab";
 
+// Uses a live study session if possible, and falls back to fetching
+// flashcards in batches.
+async function createItemSource(): Promise<ItemSource> {
+  const demo = document.querySelector('meta[name="polycloze-demo"]') != null;
+  if (!demo && "WebSocket" in window) {
+    try {
+      return await LiveItemBuffer.connect();
+    } catch (error) {
+      console.warn(error);
+    }
+  }
+  return new ItemBuffer();
+}
+
 export class ClozeApp extends HTMLElement {
   promise: Promise<[HTMLDivElement, () => void]>;
 
   constructor() {
     super();
 
-    const buffer = new ItemBuffer();
-    this.promise = createApp(buffer);
+    this.promise = createItemSource().then(createApp);
   }
 
   async connectedCallback() {
@@ -63,12 +79,14 @@ export class Overview extends HTMLElement {
   activity: Promise<ActivitySummary[]>;
   vocabularySize: Promise<DataPoint[]>;
   estimatedLevel: Promise<DataPoint[]>;
+  goal: Promise<GoalProgress | null>;
 
   constructor() {
     super();
     this.activity = fetchActivity();
     this.vocabularySize = fetchVocabularySize();
     this.estimatedLevel = fetchEstimatedLevel();
+    this.goal = fetchGoal().catch(() => null);
   }
 
   async connectedCallback() {
@@ -76,9 +94,15 @@ export class Overview extends HTMLElement {
       this.activity,
       this.vocabularySize,
       this.estimatedLevel,
+      this.goal,
     ]);
-    const [activity, vocabularySize, estimatedLevel] = resolved;
-    const page = createOverviewPage(activity, vocabularySize, estimatedLevel);
+    const [activity, vocabularySize, estimatedLevel, goal] = resolved;
+    const page = createOverviewPage(
+      activity,
+      vocabularySize,
+      estimatedLevel,
+      goal
+    );
     this.appendChild(page);
   }
 }
@@ -142,7 +166,9 @@ export class CourseSettings extends HTMLElement {
     this.append(
       createDiacriticButtonSettingsSection(),
       document.createElement("br"),
-      createVoiceSettingsSection(this.tts)
+      createVoiceSettingsSection(this.tts),
+      document.createElement("br"),
+      createModeSettingsSection(this.tts)
     );
   }
 }
@@ -1,3 +1,5 @@
+import { locale } from "./locale";
+
 export const millisecond = 1;
 export const second = 1000 * millisecond;
 export const minute = 60 * second;
@@ -17,7 +19,7 @@ export function relative(date: Date): string {
 
 export function createDateTime(date: Date): HTMLSpanElement {
   const span = document.createElement("span");
-  span.title = date.toLocaleString();
+  span.title = date.toLocaleString(locale());
   span.textContent = relative(date);
   return span;
 }
@@ -1,6 +1,15 @@
 // Difficulty tuner.
 
-import { isTooEasy, isTooHard } from "./wilson";
+import * as bayes from "./bayes";
+import {
+  Thresholds,
+  defaultThresholds,
+  isTooEasy,
+  isTooHard,
+} from "./wilson";
+
+// Name of tuning algorithm ("wilson" or "bayes").
+export type Algorithm = string;
 
 export type Difficulty = {
   level?: number;
@@ -8,6 +17,7 @@ export type Difficulty = {
   incorrect?: number;
   min?: number;
   max?: number;
+  pinned?: boolean;
 };
 
 export class DifficultyTuner {
@@ -21,13 +31,23 @@ export class DifficultyTuner {
   min: number;
   // @ts-ignore
   max: number;
+  // @ts-ignore
+  pinned: boolean;
+  thresholds: Thresholds;
+  algorithm: Algorithm;
 
-  constructor(difficulty: Difficulty = {}) {
+  constructor(
+    difficulty: Difficulty = {},
+    thresholds: Thresholds = defaultThresholds(),
+    algorithm: Algorithm = "wilson"
+  ) {
     this.reset(difficulty);
+    this.thresholds = thresholds;
+    this.algorithm = algorithm;
   }
 
   reset(difficulty: Difficulty) {
-    let { level, correct, incorrect, min, max } = difficulty;
+    let { level, correct, incorrect, min, max, pinned } = difficulty;
     if (min == null || min < 0) {
       min = 0;
     }
@@ -49,6 +69,23 @@ export class DifficultyTuner {
     this.level = level;
     this.correct = correct;
     this.incorrect = incorrect;
+    this.pinned = pinned === true;
+  }
+
+  // Checks if current level is too easy using the selected algorithm.
+  isTooEasy(): boolean {
+    if (this.algorithm === "bayes") {
+      return bayes.isTooEasy(this.correct, this.incorrect);
+    }
+    return isTooEasy(this.correct, this.incorrect, this.thresholds);
+  }
+
+  // Checks if current level is too hard using the selected algorithm.
+  isTooHard(): boolean {
+    if (this.algorithm === "bayes") {
+      return bayes.isTooHard(this.correct, this.incorrect);
+    }
+    return isTooHard(this.correct, this.incorrect, this.thresholds);
   }
 
   // Updates level statistics.
@@ -62,8 +99,13 @@ export class DifficultyTuner {
       this.incorrect++;
     }
 
+    // Manually set levels don't get tuned until they're unpinned.
+    if (this.pinned) {
+      return false;
+    }
+
     const level = this.level;
-    if (isTooEasy(this.correct, this.incorrect)) {
+    if (this.isTooEasy()) {
       this.level = Math.min(level + 1, this.max);
       if (level === this.level) {
         return false;
@@ -73,7 +115,7 @@ export class DifficultyTuner {
       return true;
     }
 
-    if (isTooHard(this.correct, this.incorrect)) {
+    if (this.isTooHard()) {
       this.level = Math.max(level - 1, this.min);
       if (level === this.level) {
         return false;
@@ -93,6 +135,7 @@ export class DifficultyTuner {
       incorrect: this.incorrect,
       min: this.min,
       max: this.max,
+      pinned: this.pinned,
     };
   }
 }
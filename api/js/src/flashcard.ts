@@ -0,0 +1,84 @@
+// Classic flashcards: the prompt is the bare word, and the user grades
+// themself.
+// Results are sent to the same scheduler as cloze reviews.
+
+import { hasAnswers, PartWithAnswers } from "./blank";
+import { announceResult } from "./buffer";
+import { createButton } from "./button";
+import { createNotes, Item } from "./item";
+import { getL1, getL2 } from "./language";
+import { TTS } from "./tts";
+
+function getBlankPart(item: Item): PartWithAnswers | undefined {
+  for (const part of item.sentence.parts) {
+    if (hasAnswers(part)) {
+      return part as PartWithAnswers;
+    }
+  }
+  return undefined;
+}
+
+function createParagraph(
+  text: string,
+  className: string,
+  lang: string
+): HTMLParagraphElement {
+  const p = document.createElement("p");
+  p.classList.add(className);
+  p.lang = lang;
+  p.textContent = text;
+  return p;
+}
+
+export function createFlashcardItem(
+  tts: TTS,
+  item: Item,
+  next: () => void
+): [HTMLDivElement, () => void] {
+  const div = document.createElement("div");
+  div.classList.add("item");
+
+  const part = getBlankPart(item);
+  if (part == null) {
+    return [div, () => undefined];
+  }
+
+  const body = document.createElement("div");
+  body.append(createParagraph(part.text, "flashcard-word", getL2().bcp47));
+
+  const footer = document.createElement("div");
+  footer.classList.add("button-group");
+
+  const grade = (correct: boolean) => {
+    const answer = part.answers[0];
+    announceResult({
+      word: answer.normalized,
+      correct,
+      new: answer.new,
+      timestamp: Math.floor(Date.now() / 1000),
+    });
+    next();
+  };
+
+  const reveal = () => {
+    const text = item.sentence.parts.map((part) => part.text).join("");
+    body.append(
+      createParagraph(text, "sentence", getL2().bcp47),
+      createParagraph(item.translation.text, "translation", getL1().bcp47)
+    );
+    if (item.notes != null && item.notes.length > 0) {
+      body.appendChild(createNotes(item.notes));
+    }
+    tts.speak(text);
+
+    const again = createButton("Again", () => grade(false));
+    const good = createButton("Good", () => grade(true));
+    footer.replaceChildren(again, good);
+    good.focus();
+  };
+
+  const show = createButton("Show answer", reveal);
+  footer.append(show);
+  div.append(body, footer);
+  return [div, () => undefined];
+}
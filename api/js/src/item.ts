@@ -1,7 +1,9 @@
 import "./item.css";
 import { createButton } from "./button";
 import { createDiacriticButtonGroup } from "./diacritic";
+import { createFlashcardItem } from "./flashcard";
 import { getL1, getL2 } from "./language";
+import { resolve } from "./request";
 import { Sentence, createSentence } from "./sentence";
 import { TTS } from "./tts";
 
@@ -10,11 +12,92 @@ export type Translation = {
   text: string;
 };
 
+// "cloze", "listening", "flashcard" or "production"
+export type Mode = string;
+
+export type Note = {
+  source: string; // "course" or "user"
+  text: string;
+};
+
 export type Item = {
   sentence: Sentence;
   translation: Translation;
+  mode?: Mode;
+  notes?: Note[];
 };
 
+// Shows notes on the word after the user answers.
+export function createNotes(notes: Note[]): HTMLUListElement {
+  const ul = document.createElement("ul");
+  ul.classList.add("notes");
+  for (const note of notes) {
+    const li = document.createElement("li");
+    li.classList.add(`note-${note.source}`);
+    li.textContent = note.text;
+    ul.appendChild(li);
+  }
+  return ul;
+}
+
+// Returns preferred exercise type.
+export function getMode(): Mode {
+  return localStorage.getItem("study.mode") || "cloze";
+}
+
+export function setMode(mode: Mode) {
+  localStorage.setItem("study.mode", mode);
+}
+
+// Returns form for choosing the exercise type.
+// Listening mode is only available if there are TTS voices for the language.
+export function createModeSettingsSection(tts: TTS): HTMLFormElement {
+  const form = document.createElement("form");
+  form.classList.add("signin");
+  form.innerHTML = `
+        <label for="study-mode">Exercise type</label>
+        <select id="study-mode" name="study-mode">
+          <option value="cloze">Cloze</option>
+          <option value="listening">Listening</option>
+          <option value="flashcard">Flashcards (self-graded)</option>
+          <option value="production">Production (translation only)</option>
+        </select>
+    `;
+
+  const select = form.querySelector("select") as HTMLSelectElement;
+  select.value = getMode();
+  if (tts.voices.size === 0) {
+    const option = select.querySelector('option[value="listening"]');
+    (option as HTMLOptionElement).disabled = true;
+    if (select.value === "listening") {
+      select.value = "cloze";
+    }
+  }
+  select.addEventListener("change", () => setMode(select.value));
+  return form;
+}
+
+function getSentenceText(item: Item): string {
+  return item.sentence.parts.map((part) => part.text).join("");
+}
+
+// Plays recorded or server-synthesized audio of the sentence.
+// Falls back to the browser's TTS if the server has no audio.
+function playSentence(tts: TTS, item: Item) {
+  const [l1, l2] = [getL1().code, getL2().code];
+  const audio = new Audio(
+    resolve(`/api/audio/${l1}/${l2}/${item.sentence.id}`)
+  );
+  audio.play().catch(() => tts.speak(getSentenceText(item)));
+}
+
+// In listening mode, the sentence only gets shown after the user answers.
+function createListenButton(tts: TTS, item: Item): HTMLButtonElement {
+  const button = createButton("Listen", () => playSentence(tts, item));
+  button.type = "button";
+  return button;
+}
+
 function showTranslationLink(translation: Translation, body: HTMLDivElement) {
   if (translation.tatoebaID == null || translation.tatoebaID <= 0) {
     return;
@@ -96,14 +179,23 @@ export function createItem(
   item: Item,
   next: () => void
 ): [HTMLDivElement, () => void] {
+  if (item.mode === "flashcard") {
+    return createFlashcardItem(tts, item, next);
+  }
+
   const [submitBtn, enable] = createSubmitButton();
 
+  const listening = item.mode === "listening";
+  const production = item.mode === "production";
   const done = () => {
-    const text = item.sentence.parts.map((part) => part.text).join("");
-    tts.speak(text);
+    div.classList.remove("listening", "production");
+    tts.speak(getSentenceText(item));
 
     hideDiacriticButtonGroup(getBody());
     showTranslationLink(item.translation, getBody());
+    if (item.notes != null && item.notes.length > 0) {
+      getBody().appendChild(createNotes(item.notes));
+    }
     const btn = createButton("Next", next);
     submitBtn.replaceWith(btn);
     btn.focus();
@@ -116,6 +208,14 @@ export function createItem(
   const div = document.createElement("div");
   div.classList.add("item");
   div.append(body, footer);
+  if (listening) {
+    div.classList.add("listening");
+    footer.prepend(createListenButton(tts, item));
+    playSentence(tts, item);
+  }
+  if (production) {
+    div.classList.add("production");
+  }
 
   function getBody(): HTMLDivElement {
     return body;
@@ -0,0 +1,136 @@
+// Live item buffer
+// Streams flashcards from the server one at a time over a WebSocket.
+// Reviews get sent as soon as they're announced, so the server can adjust the
+// difficulty before it picks the next flashcard.
+
+import { setAnswerRules } from "./blank";
+import { ItemBuffer, ItemSource } from "./buffer";
+import { csrf } from "./csrf";
+import { Difficulty } from "./difficulty";
+import { Item, getMode } from "./item";
+import { getL1, getL2 } from "./language";
+import { resolve } from "./request";
+import { ReviewResult, StudyResponse } from "./schema";
+
+function studySocketURL(): URL {
+  const url = resolve(`/api/study/${getL1().code}/${getL2().code}`);
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+  url.searchParams.set("mode", getMode());
+  return url;
+}
+
+export class LiveItemBuffer implements ItemSource {
+  socket: WebSocket;
+  difficulty: Difficulty;
+
+  // Resolvers of `take` calls that are waiting for an item.
+  waiting: ((item: Item | undefined) => void)[];
+
+  // Reviews that were sent but haven't been saved yet, in the order they were
+  // sent, and reviews that the server failed to save.
+  unsaved: ReviewResult[];
+  failed: ReviewResult[];
+
+  // Used after the socket closes.
+  fallback?: ItemBuffer;
+
+  constructor(socket: WebSocket, difficulty: Difficulty = {}) {
+    this.socket = socket;
+    this.difficulty = difficulty;
+    this.waiting = [];
+    this.unsaved = [];
+    this.failed = [];
+
+    socket.addEventListener("message", (event: MessageEvent) => {
+      this.receive(JSON.parse(event.data) as StudyResponse);
+    });
+    socket.addEventListener("close", () => this.close());
+
+    // NOTE this never gets removed
+    window.addEventListener("polycloze-review", (event: Event) => {
+      if (this.fallback != null) {
+        return;
+      }
+      const { word, correct, timestamp } = (event as CustomEvent).detail;
+      const review = { word, correct, timestamp, mode: getMode() };
+      this.unsaved.push(review);
+      this.socket.send(
+        JSON.stringify({ type: "review", review, csrfToken: csrf() })
+      );
+    });
+  }
+
+  // Opens live study session.
+  // Rejects if the session can't be started, so that the caller can fall back
+  // to an `ItemBuffer`.
+  static connect(): Promise<LiveItemBuffer> {
+    return new Promise((resolve, reject) => {
+      const socket = new WebSocket(studySocketURL());
+      const onClose = () => reject(new Error("live study session closed"));
+      const onReady = (event: MessageEvent) => {
+        socket.removeEventListener("message", onReady);
+        socket.removeEventListener("close", onClose);
+
+        const message = JSON.parse(event.data) as StudyResponse;
+        if (message.type !== "ready") {
+          socket.close();
+          reject(new Error(message.error));
+          return;
+        }
+        if (message.answerRules != null) {
+          setAnswerRules(message.answerRules);
+        }
+        resolve(new LiveItemBuffer(socket, message.difficulty));
+      };
+      socket.addEventListener("message", onReady);
+      socket.addEventListener("close", onClose);
+    });
+  }
+
+  // The server replies to messages in the order they were sent, and replies
+  // to reviews with either a result or an error.
+  receive(message: StudyResponse) {
+    switch (message.type) {
+      case "item": {
+        const resolve = this.waiting.shift();
+        if (resolve != null) {
+          resolve(message.item);
+        }
+        break;
+      }
+      case "result":
+      case "error": {
+        const review = this.unsaved.shift();
+        if (review != null && !message.result?.ok) {
+          this.failed.push(review);
+        }
+        if (message.difficulty != null) {
+          this.difficulty = message.difficulty;
+        }
+        break;
+      }
+    }
+  }
+
+  // Switches to fetching flashcards in batches.
+  // Unsaved reviews get sent with the next batch.
+  close() {
+    const fallback = new ItemBuffer(this.difficulty);
+    fallback.reviews.push(...this.failed.splice(0), ...this.unsaved.splice(0));
+    this.fallback = fallback;
+
+    for (const resolve of this.waiting.splice(0)) {
+      fallback.take().then(resolve);
+    }
+  }
+
+  take(): Promise<Item | undefined> {
+    if (this.fallback != null) {
+      return this.fallback.take();
+    }
+    return new Promise((resolve) => {
+      this.waiting.push(resolve);
+      this.socket.send(JSON.stringify({ type: "next" }));
+    });
+  }
+}
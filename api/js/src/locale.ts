@@ -0,0 +1,8 @@
+// Returns display locale from header meta, or undefined (browser default).
+export function locale(): string | undefined {
+  const meta = document.querySelector('meta[name="polycloze-locale"]');
+  if (meta == null) {
+    return undefined;
+  }
+  return (meta as HTMLMetaElement).content || undefined;
+}
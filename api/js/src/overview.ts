@@ -2,7 +2,13 @@ import { createActivityChart, createVocabularyChart } from "./chart";
 import { startOfDay, endOfDay } from "./datetime";
 import { getL1, getL2 } from "./language";
 import { createLink } from "./link";
-import { Activity, ActivitySummary, DataPoint } from "./schema";
+import { locale } from "./locale";
+import {
+  Activity,
+  ActivitySummary,
+  DataPoint,
+  GoalProgress,
+} from "./schema";
 
 function createOverviewHeader(): HTMLHeadingElement {
   const l1 = getL1();
@@ -38,6 +44,22 @@ function createVocabularySummary(vocabularySize: number): HTMLParagraphElement {
   return p;
 }
 
+function createGoalSummary(goal: GoalProgress): HTMLParagraphElement {
+  const deadline = new Date(goal.deadline).toLocaleDateString(locale());
+  const p = document.createElement("p");
+  if (goal.reached) {
+    p.textContent = `You've reached your goal of ${goal.words} words!`;
+  } else if (goal.requiredPerDay < 0) {
+    p.textContent = `Your goal of ${goal.words} words was due on ${deadline}.`;
+  } else {
+    const status = goal.onTrack
+      ? "You're on track."
+      : `Learn ${Math.ceil(goal.requiredPerDay)} new words a day to get there.`;
+    p.textContent = `Goal: ${goal.words} words by ${deadline}. ${status}`;
+  }
+  return p;
+}
+
 function createActionButtons(vocabularySize: number): HTMLParagraphElement {
   const text = vocabularySize <= 0 ? "Start learning" : "Continue learning";
 
@@ -146,7 +168,8 @@ function createStreakSummary(
 export function createOverviewPage(
   activity: ActivitySummary[],
   vocabularySize: DataPoint[],
-  estimatedLevel: DataPoint[]
+  estimatedLevel: DataPoint[],
+  goal: GoalProgress | null = null
 ): DocumentFragment {
   const size = vocabularySize[vocabularySize.length - 1].value;
   const [streak, active] = computeActiveStreak(activity);
@@ -158,7 +181,12 @@ export function createOverviewPage(
   fragment.append(
     createOverviewHeader(),
     createVocabularyChart(vocabularySize, estimatedLevel),
-    createVocabularySummary(size),
+    createVocabularySummary(size)
+  );
+  if (goal) {
+    fragment.append(createGoalSummary(goal));
+  }
+  fragment.append(
     createActionButtons(size),
     h2,
     createActivityChart(activity),
@@ -2,6 +2,7 @@
 
 import { Difficulty } from "./difficulty";
 import { Item } from "./item";
+import { Thresholds } from "./wilson";
 
 export type ItemsSchema = {
   items: Item[];
@@ -10,6 +11,40 @@ export type ItemsSchema = {
 export type FlashcardsResponse = {
   items: Item[];
   difficulty: Difficulty;
+  thresholds?: Thresholds;
+  algorithm?: string;
+
+  // Outcome of each uploaded review, in the same order.
+  results?: ReviewSaveResult[];
+
+  // Rules for normalizing answers before grading.
+  answerRules?: Replacement[];
+};
+
+// See `text.Replacement` in text/answers.go.
+export type Replacement = {
+  from: string;
+  to: string;
+};
+
+export type ReviewSaveResult = {
+  word: string;
+  ok: boolean;
+  error?: string;
+  quarantined?: boolean;
+};
+
+// Message sent by the server during live study sessions.
+// See `StudyResponse` in api/schema.go.
+export type StudyResponse = {
+  type: "ready" | "item" | "result" | "error";
+  item?: Item;
+  result?: ReviewSaveResult;
+  difficulty?: Difficulty;
+  thresholds?: Thresholds;
+  algorithm?: string;
+  answerRules?: Replacement[];
+  error?: string;
 };
 
 export type SetCourseRequest = {
@@ -23,8 +58,11 @@ export type SetCourseResponse = {
 
 export type Language = {
   code: string;
-  name: string;
+  name: string; // in English
   bcp47: string;
+
+  // Only in /api/languages and /api/courses responses.
+  localizedName?: string;
 };
 
 export type LanguagesSchema = {
@@ -125,6 +163,7 @@ export type ReviewResult = {
   word: string;
   correct: boolean;
   timestamp: number;
+  mode?: string;
 
   // This field doesn't need to be sent to the server.
   new?: boolean;
@@ -133,4 +172,108 @@ export type ReviewResult = {
 export type UploadCSVFileResponse = {
   message: string;
   success: boolean;
+
+  // Only in responses to zip file uploads.
+  results?: UploadResult[];
+};
+
+// Result of importing a file in a zip file upload.
+export type UploadResult = {
+  file: string;
+  course: string; // e.g. eng-spa
+  message: string;
+  success: boolean;
+};
+
+// from /api/dictionary/<l1>/<l2>/<word>
+export type DictionaryEntry = {
+  word: string;
+  frequencyClass: number;
+  definitions: {
+    partOfSpeech: string;
+    translations: string[];
+  }[];
+  examples: {
+    text: string;
+    translation: string;
+  }[];
+};
+
+export type AnalysisReport = {
+  tokens: number;
+  known: number;
+  unknown: number;
+  coverage: number;
+  unknownWords: {
+    word: string;
+    count: number;
+    inCourse: boolean;
+  }[];
+};
+
+export type WordListSchema = {
+  added: number;
+  words: string[];
+};
+
+export type MineResult = {
+  sentences: number;
+  words: string[];
+};
+
+export type TodaySummary = {
+  reviews: number;
+  newWords: number;
+  due: number;
+  streak: number;
+  goal: {
+    reviews: number;
+    done: boolean;
+  };
+};
+
+export type GoalProgress = {
+  words: number;
+  deadline: string;
+  created: string;
+  vocabSize: number;
+  reached: boolean;
+  requiredPerDay: number; // -1 if the deadline has passed
+  learnedPerDay: number;
+  onTrack: boolean;
+};
+
+export type GoalSchema = {
+  goal: GoalProgress | null;
+};
+
+export type AuthoredSentence = {
+  id: number;
+  word: string;
+  text: string;
+  translation: string;
+};
+
+export type SearchResults = {
+  words: {
+    word: string;
+    frequencyClass: number;
+  }[];
+  sentences: {
+    id: number;
+    tatoebaID: number;
+    text: string;
+    translation: string;
+  }[];
+};
+
+export type WordExamples = {
+  word: string;
+  page: number;
+  examples: {
+    id: number;
+    tatoebaID: number;
+    text: string;
+    translation: string;
+  }[];
 };
@@ -17,7 +17,7 @@ function createLanguageSelect(
   for (const language of languages) {
     const option = document.createElement("option");
     option.value = language.code;
-    option.textContent = language.name;
+    option.textContent = language.localizedName || language.name;
     if (selected === language.code) {
       option.selected = true;
     }
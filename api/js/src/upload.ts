@@ -4,13 +4,7 @@ import { createButton } from "./button";
 import { createLabeledIcon } from "./icon";
 import { resolve } from "./request";
 
-// Checks if the file the user wants to upload is too big.
-function isTooBig(file: File): boolean {
-  // Returns true if file size > 8MB.
-  return file.size > 8 * 1024 * 1024;
-}
-
-// Creates a hidden file input element for uploading CSV files.
+// Creates a hidden file input element for uploading CSV or zip files.
 function createHiddenFileInput(
   name: string,
   onError: (message: string) => void
@@ -18,7 +12,7 @@ function createHiddenFileInput(
   const input = document.createElement("input");
   input.name = name;
   input.type = "file";
-  input.accept = ".csv";
+  input.accept = ".csv,.zip";
   input.required = true;
   input.hidden = true;
 
@@ -34,7 +28,7 @@ function createDragAndDropText(): HTMLSpanElement {
   const span = document.createElement("span");
   span.style.fontSize = "1.25em";
   span.style.textAlign = "center";
-  span.textContent = "Drag and drop CSV file to upload or";
+  span.textContent = "Drag and drop CSV or zip file to upload or";
   return span;
 }
 
@@ -113,8 +107,23 @@ export function createFileBrowser(name: string): HTMLDivElement {
   }
 }
 
+// File types that browsers use for CSV and zip files.
+// See `csvContentTypes` in replay/validate.go and `isZip` in upload.go.
+const fileTypes = [
+  "",
+  "text/csv",
+  "text/plain",
+  "application/csv",
+  "application/vnd.ms-excel",
+  "application/octet-stream",
+  "application/zip",
+  "application/x-zip",
+  "application/x-zip-compressed",
+];
+
 // Wrapper around `uploadCSVFile` that checks for file validity and refreshes
 // the page after a successful upload.
+// The server checks the file size, because the limit is configurable.
 async function uploadFile(
   name: string,
   file: File | undefined,
@@ -124,16 +133,16 @@ async function uploadFile(
     onError("Something went wrong. Please try again.");
     return;
   }
-  if (file.type !== "text/csv") {
-    onError("Not a CSV file.");
+  // Browsers don't agree on the type of CSV files, so the server checks the
+  // contents instead.
+  if (!fileTypes.includes(file.type)) {
+    onError("Not a CSV or zip file.");
     return;
   }
-  if (isTooBig(file)) {
-    onError("The file is too big.");
-    return;
-  }
-  const { message, success } = await uploadCSVFile(name, file);
-  if (success) {
+  const { message, success, results } = await uploadCSVFile(name, file);
+
+  // The settings page shows the result of each file in a zip file.
+  if (success || (results && results.length > 0)) {
     window.location.href = window.location.href;
   }
   onError(message);
@@ -14,26 +14,42 @@ function wilson(success: number, fail: number, z: number): number {
   );
 }
 
-export function isTooEasy(correct: number, incorrect: number): boolean {
+// Parameters of the Wilson score tests.
+// See `Thresholds` in polycloze/wilson/wilson.go.
+export type Thresholds = {
+  easyZ: number;
+  easyBound: number;
+  hardZ: number;
+  hardBound: number;
+};
+
+export function defaultThresholds(): Thresholds {
   // Threshold can't be too high or the tuner will be too conservative.
   // Only uses 0.85 confidence, higher values require too many samples.
-
-  // z-score for one-sided confidence interval (85% confidence)
-  const z = -1.035;
-  const lower = wilson(correct, incorrect, z);
-
-  // 85% likelihood that the true proportion is bounded below by `lower`.
   // It's too hard to level up with a 0.9 test when incorrect > 0.
-  return lower > 0.85;
-
   // 0.85 threshold is chosen so tuner won't trigger with < 5 samples.
+  return {
+    easyZ: -1.035, // 85% confidence
+    easyBound: 0.85,
+    hardZ: 3.1, // 99.9% confidence
+    hardBound: 0.8,
+  };
 }
 
-export function isTooHard(correct: number, incorrect: number): boolean {
-  // z-score for one-sided confidence interval.
-  const z = 3.1;
-  const upper = wilson(correct, incorrect, z);
+export function isTooEasy(
+  correct: number,
+  incorrect: number,
+  thresholds: Thresholds = defaultThresholds()
+): boolean {
+  const lower = wilson(correct, incorrect, thresholds.easyZ);
+  return lower > thresholds.easyBound;
+}
 
-  // 99.9% confident that the true proportion is bounded above by `upper`.
-  return upper < 0.8;
+export function isTooHard(
+  correct: number,
+  incorrect: number,
+  thresholds: Thresholds = defaultThresholds()
+): boolean {
+  const upper = wilson(correct, incorrect, thresholds.hardZ);
+  return upper < thresholds.hardBound;
 }
@@ -6,47 +6,24 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 )
 
 // Sends JSON response.
 // The caller shouldn't write to w afterwards.
-func sendJSON(w http.ResponseWriter, data any) {
+func sendJSON(w http.ResponseWriter, r *http.Request, data any) {
 	bytes, err := json.Marshal(data)
 	if err != nil {
-		log.Println("failed to encode to JSON:", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("failed to encode to JSON: %w", err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write(bytes); err != nil {
-		log.Println("failed to send JSON:", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		logError(r, fmt.Errorf("failed to send JSON: %w", err))
 	}
 }
 
-// Writes JSON to file.
-func writeJSON(name string, data any) error {
-	bytes, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to encode to JSON: %w", err)
-	}
-
-	f, err := os.Create(name)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer f.Close()
-
-	if _, err := f.Write(bytes); err != nil {
-		return fmt.Errorf("failed to write JSON: %w", err)
-	}
-	return nil
-}
-
 // Parses JSON.
 // Writes error to ResponseWriter on error (caller shouldn't write more data).
 func parseJSON(w http.ResponseWriter, data []byte, v any) error {
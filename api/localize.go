@@ -0,0 +1,163 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/polycloze/polycloze/sessions"
+)
+
+var displayMatcher = language.NewMatcher(display.Supported.Tags())
+
+// Name of cookie that stores the locale chosen with the `locale` search
+// param.
+const localeCookieName = "locale"
+
+type localeKey struct{}
+
+// Returns the closest supported locale, or false if there's none.
+func matchLocale(s string) (language.Tag, bool) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return language.Und, false
+	}
+	tag, _, confidence := displayMatcher.Match(tag)
+	return tag, confidence != language.No
+}
+
+// Resolves the user's display locale and stores it in the request context
+// (see `getLocale`).
+// In order of precedence, the locale comes from:
+// - the `locale` search param,
+// - the locale cookie (set when the `locale` search param is used), or
+// - the Accept-Language header.
+// Use `locale=auto` to clear the cookie.
+func negotiateLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language, Cookie")
+		ctx := context.WithValue(r.Context(), localeKey{}, resolveLocale(w, r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func resolveLocale(w http.ResponseWriter, r *http.Request) language.Tag {
+	if param := r.URL.Query().Get("locale"); param == "auto" {
+		setLocaleCookie(w, r, "", -1)
+	} else if tag, ok := matchLocale(param); ok {
+		setLocaleCookie(w, r, tag.String(), 0)
+		return tag
+	} else if c, err := r.Cookie(localeCookieName); err == nil {
+		if tag, ok := matchLocale(c.Value); ok {
+			return tag
+		}
+	}
+	tag, _ := language.MatchStrings(displayMatcher, r.Header.Get("Accept-Language"))
+	return tag
+}
+
+// Sets locale cookie, which lasts until the browser session ends.
+// Pass a negative maxAge to delete the cookie.
+func setLocaleCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	options := sessions.GetCookieOptions()
+	http.SetCookie(w, &http.Cookie{
+		Name:     localeCookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   options.Domain,
+		SameSite: options.SameSite,
+		HttpOnly: true,
+		Secure:   sessions.IsSecure(r),
+		MaxAge:   maxAge,
+	})
+}
+
+// Gets display locale from the request context.
+// Falls back to the Accept-Language header if `negotiateLocale` isn't used.
+func getLocale(r *http.Request) language.Tag {
+	if tag, ok := r.Context().Value(localeKey{}).(language.Tag); ok {
+		return tag
+	}
+	tag, _ := language.MatchStrings(displayMatcher, r.Header.Get("Accept-Language"))
+	return tag
+}
+
+// Gets language to display language names in.
+// Uses the L1 in the `l1` search param (ISO 639-3) if it's an installed L1,
+// or else the user's display locale (see `negotiateLocale`).
+func displayLanguage(r *http.Request) language.Tag {
+	if code := r.URL.Query().Get("l1"); code != "" {
+		for _, l := range courseCatalog.Languages() {
+			if l.Code == code {
+				if tag, err := language.Parse(l.BCP47); err == nil {
+					tag, _, _ = displayMatcher.Match(tag)
+					return tag
+				}
+			}
+		}
+	}
+	return getLocale(r)
+}
+
+// Returns copy of languages with names localized using CLDR data.
+// `Name` stays in English, so it can be used as a fallback.
+func localizeLanguages(languages []Language, tag language.Tag) []Language {
+	namer := display.Languages(tag)
+
+	result := make([]Language, len(languages))
+	for i, l := range languages {
+		result[i] = l
+		if namer == nil {
+			continue
+		}
+		if t, err := language.Parse(l.BCP47); err == nil {
+			result[i].LocalizedName = namer.Name(t)
+		}
+	}
+	return result
+}
+
+// Returns copy of courses with localized language names.
+// See `localizeLanguages`.
+func localizeCourses(courses []Course, tag language.Tag) []Course {
+	result := make([]Course, len(courses))
+	for i, course := range courses {
+		languages := localizeLanguages([]Language{course.L1, course.L2}, tag)
+		result[i] = Course{L1: languages[0], L2: languages[1]}
+	}
+	return result
+}
+
+// Returns collator for sorting words in the course's L2, or nil if the course
+// isn't in the catalog.
+// Collators aren't safe for concurrent use, so don't share them between
+// requests.
+func courseCollator(l1, l2 string) *collate.Collator {
+	course, ok := courseCatalog.Course(l1, l2)
+	if !ok {
+		return nil
+	}
+	tag, err := language.Parse(course.L2.BCP47)
+	if err != nil {
+		return nil
+	}
+	return collate.New(tag)
+}
+
+// Compares words using the collator, or byte order if the collator is nil or
+// if the words collate equally.
+func compareWords(c *collate.Collator, a, b string) int {
+	if c != nil {
+		if result := c.CompareString(a, b); result != 0 {
+			return result
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestLocalizeLanguages(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest("GET", "/api/languages", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	tag := displayLanguage(r)
+
+	languages := localizeLanguages([]Language{
+		{Code: "spa", Name: "Spanish", BCP47: "es"},
+		{Code: "xxx", Name: "Unknown", BCP47: "not a tag"},
+	}, tag)
+	if languages[0].LocalizedName != "Spanisch" || languages[0].Name != "Spanish" {
+		t.Fatal("expected German name of Spanish:", languages[0])
+	}
+	if languages[1].LocalizedName != "" || languages[1].Name != "Unknown" {
+		t.Fatal("expected English name to be kept:", languages[1])
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	t.Parallel()
+
+	var got language.Tag
+	handler := negotiateLocale(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = getLocale(r)
+	}))
+
+	// Accept-Language is used when there's no explicit choice.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if base, _ := got.Base(); base.String() != "de" {
+		t.Fatal("expected locale from Accept-Language:", got)
+	}
+
+	// The search param overrides Accept-Language and gets saved in a cookie.
+	r = httptest.NewRequest("GET", "/?locale=fr", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if base, _ := got.Base(); base.String() != "fr" {
+		t.Fatal("expected locale from search param:", got)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != localeCookieName {
+		t.Fatal("expected locale cookie to be set:", cookies)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	r.AddCookie(cookies[0])
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if base, _ := got.Base(); base.String() != "fr" {
+		t.Fatal("expected locale from cookie:", got)
+	}
+
+	// Invalid cookies are ignored.
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	r.AddCookie(&http.Cookie{Name: localeCookieName, Value: "not a tag"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if base, _ := got.Base(); base.String() != "de" {
+		t.Fatal("expected invalid cookie to be ignored:", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Request IDs and access logs.
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type requestIDKey struct{}
+
+// Returns random request ID.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "-"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns ID of the request, or an empty string if it doesn't have one (i.e.
+// `accessLog` isn't used).
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logs error along with the request ID.
+func logError(r *http.Request, err error) {
+	if id := requestID(r); id != "" {
+		log.Printf("[%v] %v\n", id, err)
+		return
+	}
+	log.Println(err)
+}
+
+// Logs error along with the request ID, and responds with a generic error
+// message that contains the ID, so that users can report it.
+func serverError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r, err)
+	id := requestID(r)
+	if id == "" {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Something went wrong. (request ID: "+id+")", http.StatusInternalServerError)
+}
+
+// Records response status and the user who made the request.
+type logResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	username string
+}
+
+func (w *logResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *logResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Called by `sessions.ResumeSession`.
+func (w *logResponseWriter) RecordUser(username string) {
+	w.username = username
+}
+
+func (w *logResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Needed by websocket handlers.
+func (w *logResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer can't be hijacked")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Returns host part of the request's RemoteAddr, which is the client's address
+// if the request came from a trusted proxy (see `trustProxies`).
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" {
+		return "-"
+	}
+	return host
+}
+
+// Assigns an ID to each request, and logs the remote address, method, route,
+// status, duration and user of the request.
+// The ID is also sent in the X-Request-ID response header.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := newRequestID()
+		w.Header().Set("X-Request-ID", id)
+
+		lw := &logResponseWriter{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+		next.ServeHTTP(lw, r)
+
+		route := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		username := lw.username
+		if username == "" {
+			username = "-"
+		}
+		log.Printf(
+			"[%v] %v %v %v %v %v user=%v\n",
+			id,
+			remoteHost(r),
+			r.Method,
+			route,
+			status,
+			time.Since(start).Round(time.Microsecond),
+			username,
+		)
+	})
+}
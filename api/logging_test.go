@@ -0,0 +1,75 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogRequestID(t *testing.T) {
+	// Error responses should contain the same request ID as the header.
+	t.Parallel()
+
+	handler := accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverError(w, r, errors.New("test error"))
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	id := w.Header().Get("X-Request-ID")
+	if id == "" {
+		t.Fatal("expected response to have a request ID")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("unexpected status:", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), id) {
+		t.Fatal("expected error message to contain request ID:", w.Body.String())
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	// Should log the host without the port.
+	t.Parallel()
+
+	for addr, expected := range map[string]string{
+		"192.0.2.1:1234":   "192.0.2.1",
+		"[2001:db8::1]:80": "2001:db8::1",
+		"192.0.2.1":        "192.0.2.1",
+		"":                 "-",
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = addr
+		if host := remoteHost(r); host != expected {
+			t.Fatal("unexpected remote host:", addr, host, expected)
+		}
+	}
+}
+
+func TestSendJSONRequestID(t *testing.T) {
+	// JSON encoding errors should also be reported with the request ID.
+	t.Parallel()
+
+	handler := accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendJSON(w, r, map[string]any{"foo": make(chan int)})
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	id := w.Header().Get("X-Request-ID")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatal("unexpected status:", w.Code)
+	}
+	if id == "" || !strings.Contains(w.Body.String(), id) {
+		t.Fatal("expected error message to contain request ID:", w.Body.String())
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Account merging.
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/merge"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Credentials of the duplicate account, as proof that it belongs to the user.
+type MergeAccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Merges another account owned by the user into the signed in account.
+// The other account gets tombstoned.
+func handleMergeAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "expected POST request", http.StatusBadRequest)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+	userID := s.Data["userID"].(int)
+
+	var data MergeAccountRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	duplicateID, err := auth.Authenticate(db, data.Username, data.Password)
+	if err != nil {
+		http.Error(w, "Incorrect username or password.", http.StatusForbidden)
+		return
+	}
+	if duplicateID == userID {
+		http.Error(w, "Can't merge account into itself.", http.StatusBadRequest)
+		return
+	}
+
+	report, err := merge.Accounts(db, userID, duplicateID)
+	if errors.Is(err, merge.ErrArchivedHistory) {
+		message := "Can't merge accounts, because some of the review history has been archived."
+		http.Error(w, message, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, report)
+}
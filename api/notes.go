@@ -0,0 +1,139 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/notes"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type NoteRequest struct {
+	Text string `json:"text"`
+}
+
+// Max length of a note in bytes.
+const maxNoteSize = 4096
+
+// Gets `since` UNIX timestamp from URL search params.
+// Default value: UNIX epoch.
+func getSince(r *http.Request) time.Time {
+	parsed, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		return time.Unix(0, 0)
+	}
+	return time.Unix(parsed, 0)
+}
+
+// Responds with user's notes in the course.
+// Clients on other devices can pass the `since` URL search param to only get
+// notes that changed since they last fetched them.
+func handleNotes(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	result, err := notes.GetUserNotes(db, getSince(r))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"notes": result})
+}
+
+// GET: responds with user's note on the word.
+// POST: sets user's note on the word (empty text deletes the note).
+// DELETE: deletes user's note on the word.
+func handleNote(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	word := chi.URLParam(r, "word")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data NoteRequest
+	switch r.Method {
+	case "POST":
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if len(data.Text) > maxNoteSize {
+			http.Error(w, "Note is too long.", http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	if r.Method == "POST" || r.Method == "DELETE" {
+		if err := notes.SetUserNote(db, word, data.Text); err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	note, err := notes.GetUserNote(db, word)
+	if errors.Is(err, sql.ErrNoRows) {
+		if r.Method == "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		sendJSON(w, r, map[string]any{"note": nil})
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"note": note})
+}
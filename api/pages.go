@@ -4,6 +4,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/polycloze/polycloze/auth"
@@ -17,6 +18,27 @@ func ShowPage(name string) func(http.ResponseWriter, *http.Request) {
 		if s, err := sessions.StartOrResumeSession(db, w, r); err == nil {
 			data = s.Data
 		}
-		renderTemplate(w, name, data)
+		renderTemplate(w, r, name, data)
 	}
 }
+
+// Returns template data for signed-in users.
+// Adds the user's active course, display preferences and CSRF token to the
+// session data.
+func templateData(s *sessions.Session) (map[string]any, error) {
+	userID := s.Data["userID"].(int)
+	course, err := getUserActiveCourse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template data: %w", err)
+	}
+
+	preferences, err := getUserPreferences(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template data: %w", err)
+	}
+
+	s.Data["course"] = course
+	s.Data["preferences"] = preferences
+	s.Data["csrfToken"] = sessions.CSRFToken(s.ID)
+	return s.Data, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Display preferences.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type Preferences struct {
+	Theme            string `json:"theme"`    // "system", "light" or "dark"
+	FontSize         int    `json:"fontSize"` // In pixels
+	ShowTranslations bool   `json:"showTranslations"`
+}
+
+// Returns default preferences.
+func defaultPreferences() Preferences {
+	return Preferences{
+		Theme:            "system",
+		FontSize:         16,
+		ShowTranslations: true,
+	}
+}
+
+// Checks if preferences have valid values.
+func (p Preferences) isValid() bool {
+	switch p.Theme {
+	case "system", "light", "dark":
+	default:
+		return false
+	}
+	return p.FontSize >= 12 && p.FontSize <= 24
+}
+
+// Gets user's display preferences from the user DB.
+// Returns default preferences if the user hasn't set any.
+func getPreferences(db *sql.DB) (Preferences, error) {
+	p := defaultPreferences()
+	query := `SELECT theme, font_size, show_translations FROM preferences`
+	err := db.QueryRow(query).Scan(&p.Theme, &p.FontSize, &p.ShowTranslations)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	return p, nil
+}
+
+// Saves user's display preferences in the user DB.
+func setPreferences(db *sql.DB, p Preferences) error {
+	if !p.isValid() {
+		return fmt.Errorf("failed to set preferences: invalid value: %v", p)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO preferences (theme, font_size, show_translations)
+		VALUES (?, ?, ?)
+	`
+	if _, err := db.Exec(query, p.Theme, p.FontSize, p.ShowTranslations); err != nil {
+		return fmt.Errorf("failed to set preferences: %w", err)
+	}
+	return nil
+}
+
+// Gets user's display preferences.
+// Similar to `getPreferences`, but takes the user ID instead of the user DB.
+func getUserPreferences(userID int) (Preferences, error) {
+	db, err := database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		return Preferences{}, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	defer db.Close()
+	return getPreferences(db)
+}
+
+// GET: responds with user's display preferences.
+// POST: updates user's display preferences.
+func handlePreferences(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	// Open user data DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "GET":
+		p, err := getPreferences(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, p)
+		return
+	case "POST":
+		break
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	// Fields that are missing from the request keep their current values.
+	p, err := getPreferences(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if err := parseJSON(w, body, &p); err != nil {
+		return
+	}
+	if !p.isValid() {
+		http.Error(w, "invalid preferences", http.StatusBadRequest)
+		return
+	}
+
+	if err := setPreferences(db, p); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, p)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestPreferencesDefault(t *testing.T) {
+	// Should return default preferences if the user hasn't set any.
+	t.Parallel()
+
+	db, err := database.OpenUserDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	p, err := getPreferences(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if p != defaultPreferences() {
+		t.Fatal("expected default preferences:", p)
+	}
+}
+
+func TestPreferencesSetGet(t *testing.T) {
+	// Saved preferences should be returned, and invalid values rejected.
+	t.Parallel()
+
+	db, err := database.OpenUserDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	expected := Preferences{Theme: "dark", FontSize: 20, ShowTranslations: false}
+	if err := setPreferences(db, expected); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	invalid := Preferences{Theme: "neon", FontSize: 20}
+	if err := setPreferences(db, invalid); err == nil {
+		t.Fatal("expected invalid preferences to be rejected")
+	}
+
+	p, err := getPreferences(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if p != expected {
+		t.Fatal("expected saved preferences:", p, expected)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Public profiles.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Privacy settings of public profile.
+// The profile is only visible if `Public` is set.
+type ProfileSettings struct {
+	Public         bool `json:"public"`
+	ShowStreak     bool `json:"showStreak"`
+	ShowVocabulary bool `json:"showVocabulary"`
+	ShowHeatmap    bool `json:"showHeatmap"`
+}
+
+func defaultProfileSettings() ProfileSettings {
+	return ProfileSettings{
+		ShowStreak:     true,
+		ShowVocabulary: true,
+		ShowHeatmap:    true,
+	}
+}
+
+// Gets profile settings from the user DB.
+func getProfileSettings(db *sql.DB) (ProfileSettings, error) {
+	p := defaultProfileSettings()
+	query := `SELECT public, show_streak, show_vocabulary, show_heatmap FROM profile`
+	err := db.QueryRow(query).Scan(&p.Public, &p.ShowStreak, &p.ShowVocabulary, &p.ShowHeatmap)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("failed to get profile settings: %w", err)
+	}
+	return p, nil
+}
+
+// Saves profile settings in the user DB.
+func setProfileSettings(db *sql.DB, p ProfileSettings) error {
+	query := `
+		INSERT OR REPLACE INTO profile
+			(public, show_streak, show_vocabulary, show_heatmap)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := db.Exec(query, p.Public, p.ShowStreak, p.ShowVocabulary, p.ShowHeatmap); err != nil {
+		return fmt.Errorf("failed to set profile settings: %w", err)
+	}
+	return nil
+}
+
+type CourseProgress struct {
+	Course    Course
+	VocabSize int
+}
+
+// Number of reviews on a day.
+type HeatmapCell struct {
+	Date  string // YYYY-MM-DD (UTC)
+	Count int
+	Level int // 0 (no reviews) to 4
+}
+
+// Stats shown on a public profile.
+// Fields hidden by the user are left empty.
+type Profile struct {
+	Username   string
+	Settings   ProfileSettings
+	Streak     int
+	Courses    []CourseProgress
+	Heatmap    []HeatmapCell
+	TotalCount int // Total reviews in the heatmap
+}
+
+// Returns heatmap level of review count.
+func heatmapLevel(count int) int {
+	switch {
+	case count <= 0:
+		return 0
+	case count < 10:
+		return 1
+	case count < 50:
+		return 2
+	case count < 100:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Adds daily review counts in the review DB to counts (day -> count).
+func countDailyReviews(db *sql.DB, from time.Time, counts map[int64]int) error {
+	query := `
+		SELECT reviewed / 86400 AS day, count(*) FROM history
+		WHERE reviewed >= @from
+		GROUP BY day
+		UNION ALL
+		SELECT day, unimproved + learned + forgotten + crammed + strengthened
+		FROM history_summary
+		WHERE day * 86400 >= @from
+	`
+	rows, err := db.Query(query, sql.Named("from", from.Unix()))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day int64
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return err
+		}
+		counts[day] += count
+	}
+	return nil
+}
+
+// Builds user's public profile from their review DBs.
+// Only includes stats allowed by the settings.
+func buildProfile(userID int, username string, settings ProfileSettings, now time.Time) (Profile, error) {
+	profile := Profile{Username: username, Settings: settings}
+
+	paths, err := filepath.Glob(filepath.Join(basedir.User(userID), "reviews", "*.db"))
+	if err != nil {
+		return profile, fmt.Errorf("failed to build profile: %w", err)
+	}
+
+	// Heatmap covers the last 52 weeks.
+	days := 52 * 7
+	from := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	counts := make(map[int64]int)
+
+	for _, path := range paths {
+		l1, l2, found := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".db"), "-")
+		if !found || !courseExists(l1, l2) {
+			continue
+		}
+
+		db, err := database.OpenReviewDB(path)
+		if err != nil {
+			return profile, fmt.Errorf("failed to build profile: %w", err)
+		}
+
+		if settings.ShowStreak {
+			streak, err := history.Streak(db, now)
+			if err != nil {
+				db.Close()
+				return profile, fmt.Errorf("failed to build profile: %w", err)
+			}
+			if streak > profile.Streak {
+				profile.Streak = streak
+			}
+		}
+		if settings.ShowVocabulary {
+			size, err := history.CurrentVocabSize(db)
+			if err != nil {
+				db.Close()
+				return profile, fmt.Errorf("failed to build profile: %w", err)
+			}
+			course, err := getCourseInfo(basedir.Course(l1, l2))
+			if err != nil {
+				db.Close()
+				return profile, fmt.Errorf("failed to build profile: %w", err)
+			}
+			profile.Courses = append(profile.Courses, CourseProgress{Course: course, VocabSize: size})
+		}
+		if settings.ShowHeatmap {
+			if err := countDailyReviews(db, from, counts); err != nil {
+				db.Close()
+				return profile, fmt.Errorf("failed to build profile: %w", err)
+			}
+		}
+		db.Close()
+	}
+
+	if settings.ShowHeatmap {
+		for i := 0; i < days; i++ {
+			day := from.AddDate(0, 0, i)
+			count := counts[day.Unix()/86400]
+			profile.TotalCount += count
+			profile.Heatmap = append(profile.Heatmap, HeatmapCell{
+				Date:  day.Format("2006-01-02"),
+				Count: count,
+				Level: heatmapLevel(count),
+			})
+		}
+	}
+	return profile, nil
+}
+
+// Shows user's public profile.
+// Responds with 404 if the user doesn't exist or if the profile isn't public.
+func handleProfilePage(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	username := chi.URLParam(r, "username")
+	userID, err := auth.GetUserID(db, username)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := basedir.UserData(userID)
+	if _, err := os.Stat(path); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	userDB, err := database.OpenUserDB(path)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	settings, err := getProfileSettings(userDB)
+	userDB.Close()
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if !settings.Public {
+		http.NotFound(w, r)
+		return
+	}
+
+	profile, err := buildProfile(userID, username, settings, time.Now())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	data := make(map[string]any)
+	if s, err := sessions.ResumeSession(db, w, r); err == nil && s.IsSignedIn() {
+		data, err = templateData(s)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+	data["profile"] = profile
+	renderTemplate(w, r, "profile.html", data)
+}
+
+// GET: responds with user's profile settings.
+// POST: updates user's profile settings.
+func handleProfileSettings(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	// Open user data DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "GET":
+		p, err := getProfileSettings(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, p)
+		return
+	case "POST":
+		break
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	// Fields that are missing from the request keep their current values.
+	p, err := getProfileSettings(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if err := parseJSON(w, body, &p); err != nil {
+		return
+	}
+
+	if err := setProfileSettings(db, p); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, p)
+}
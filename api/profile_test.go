@@ -0,0 +1,48 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestProfileSettings(t *testing.T) {
+	// Profiles should be private by default.
+	t.Parallel()
+
+	db, err := database.OpenUserDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	p, err := getProfileSettings(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if p.Public {
+		t.Fatal("expected profile to be private by default:", p)
+	}
+
+	p = ProfileSettings{Public: true, ShowHeatmap: true}
+	if err := setProfileSettings(db, p); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if v, err := getProfileSettings(db); err != nil || v != p {
+		t.Fatal("expected settings to be saved:", v, err)
+	}
+}
+
+func TestHeatmapLevel(t *testing.T) {
+	t.Parallel()
+
+	cases := map[int]int{0: 0, 1: 1, 10: 2, 99: 3, 1000: 4}
+	for count, level := range cases {
+		if v := heatmapLevel(count); v != level {
+			t.Fatal("unexpected heatmap level:", count, v)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Bulk user provisioning (e.g. for classrooms).
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/hooks"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Max number of accounts per provisioning request.
+const maxProvisionedAccounts = 1000
+
+// Account setup links expire after this long.
+const setupLinkTTL = 7 * 24 * time.Hour
+
+// Row in the CSV file of accounts to create.
+// The email address is optional.
+type ProvisionEntry struct {
+	Username string
+	Email    string
+}
+
+// Credentials of created account.
+// Contains either a temporary password or a setup link, depending on the
+// provisioning method.
+type ProvisionedAccount struct {
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	SetupURL string `json:"setupURL,omitempty"`
+
+	// Reason why the account wasn't created.
+	Error string `json:"error,omitempty"`
+}
+
+type ProvisionReport struct {
+	Created  int                  `json:"created"`
+	Accounts []ProvisionedAccount `json:"accounts"`
+}
+
+// Parses CSV file with columns "username" and "email".
+// The header row is optional.
+func parseProvisionCSV(r io.Reader) ([]ProvisionEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []ProvisionEntry
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV file: %w", err)
+		}
+		if len(record) > 2 {
+			return nil, fmt.Errorf("failed to parse CSV file: too many fields on line %v", i+1)
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "username") {
+			continue
+		}
+
+		entry := ProvisionEntry{Username: strings.TrimSpace(record[0])}
+		if len(record) > 1 {
+			entry.Email = strings.TrimSpace(record[1])
+		}
+		entries = append(entries, entry)
+		if len(entries) > maxProvisionedAccounts {
+			return nil, fmt.Errorf("failed to parse CSV file: more than %v accounts", maxProvisionedAccounts)
+		}
+	}
+	return entries, nil
+}
+
+// Generates random password for provisioned accounts.
+func temporaryPassword() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Returns origin of the server the request was sent to.
+func requestOrigin(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host
+}
+
+// Saves email address in the user's reminder settings.
+// Doesn't enable reminders.
+func saveProvisionedEmail(userID int, email string) error {
+	db, err := database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	settings, err := reminders.Get(db)
+	if err != nil {
+		return err
+	}
+	settings.Email = email
+	return reminders.Set(db, settings)
+}
+
+// Creates account for the entry.
+// Creates a setup link instead of a temporary password if `link` is set.
+// Errors specific to the entry are reported in the result instead of being
+// returned.
+func provisionAccount(
+	db *sql.DB,
+	entry ProvisionEntry,
+	link bool,
+	origin string,
+	now time.Time,
+) (ProvisionedAccount, error) {
+	account := ProvisionedAccount{Username: entry.Username, Email: entry.Email}
+	if entry.Username == "" {
+		account.Error = "missing username"
+		return account, nil
+	}
+	if entry.Email != "" && !mail.IsValidAddress(entry.Email) {
+		account.Error = "invalid email address"
+		return account, nil
+	}
+
+	password, err := temporaryPassword()
+	if err != nil {
+		return account, fmt.Errorf("failed to provision account: %w", err)
+	}
+	if err := auth.Register(db, entry.Username, password); err != nil {
+		account.Error = "username unavailable"
+		return account, nil
+	}
+	userID, err := auth.GetUserID(db, entry.Username)
+	if err != nil {
+		return account, fmt.Errorf("failed to provision account: %w", err)
+	}
+	if err := initUserDirectory(userID); err != nil {
+		return account, fmt.Errorf("failed to provision account: %w", err)
+	}
+	if entry.Email != "" {
+		if err := saveProvisionedEmail(userID, entry.Email); err != nil {
+			return account, fmt.Errorf("failed to provision account: %w", err)
+		}
+	}
+	hooks.UserCreated(hooks.UserCreatedEvent{
+		UserID:   userID,
+		Username: entry.Username,
+		Time:     now,
+	})
+
+	if !link {
+		account.Password = password
+		return account, nil
+	}
+	token, err := auth.CreateSignInToken(db, userID, now.Add(setupLinkTTL))
+	if err != nil {
+		return account, fmt.Errorf("failed to provision account: %w", err)
+	}
+	account.SetupURL = origin + "/setup?" + url.Values{"token": {token}}.Encode()
+	return account, nil
+}
+
+// Creates accounts from uploaded CSV file, and responds with their credentials.
+// Only available to admins (see `requireAdmin`).
+// Form fields:
+// - file: CSV file with columns "username" and "email" (optional)
+// - method: "password" (default) for temporary passwords, or "link" for setup
+// links
+func handleProvision(maxSize int64) http.HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := auth.GetDB(r)
+		s := adminSession(r)
+		if r.Method != "POST" {
+			http.Error(w, "expected POST request", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+		if err := r.ParseMultipartForm(maxSize); err != nil {
+			http.Error(w, "Invalid form.", http.StatusBadRequest)
+			return
+		}
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+
+		var link bool
+		switch r.FormValue("method") {
+		case "", "password":
+		case "link":
+			link = true
+		default:
+			http.Error(w, "Invalid method.", http.StatusBadRequest)
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file.", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		entries, err := parseProvisionCSV(file)
+		if err != nil {
+			http.Error(w, "Invalid file.", http.StatusBadRequest)
+			return
+		}
+
+		// Accounts created before an error are still in the report.
+		report := ProvisionReport{Accounts: []ProvisionedAccount{}}
+		origin := requestOrigin(r)
+		now := time.Now()
+		for _, entry := range entries {
+			account, err := provisionAccount(db, entry, link, origin, now)
+			if err != nil {
+				log.Println(err)
+				account.Error = "something went wrong"
+			}
+			if account.Error == "" {
+				report.Created++
+			}
+			report.Accounts = append(report.Accounts, account)
+		}
+		sendJSON(w, r, report)
+	}
+}
+
+// HandlerFunc for setting up provisioned accounts using setup links.
+// Users choose a password, and get signed in.
+func handleSetup(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.StartOrResumeSession(db, w, r)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	token := r.FormValue("token")
+	if !auth.CheckSignInToken(db, token, time.Now()) {
+		_ = s.ErrorMessage("This link is invalid or has expired.", "sign-in")
+		http.Redirect(w, r, "/signin", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == "POST" {
+		password := r.FormValue("password")
+		if !sessions.CheckRequestCSRFToken(s.ID, r) || password == "" {
+			_ = s.ErrorMessage("Something went wrong. Please try again.", "setup")
+			goto fail
+		}
+
+		userID, username, err := auth.RedeemSignInToken(db, token, time.Now())
+		if err != nil {
+			_ = s.ErrorMessage("This link is invalid or has expired.", "setup")
+			goto fail
+		}
+		if err := auth.ChangePassword(db, userID, password); err != nil {
+			serverError(w, r, err)
+			return
+		}
+
+		s.Data["userID"] = userID
+		s.Data["username"] = username
+		if err := sessions.RotateSession(db, w, r, s); err != nil {
+			log.Println(err)
+			http.Redirect(w, r, "/signin", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+		return
+	}
+
+fail:
+	messages, _ := s.Messages("setup")
+	renderTemplate(w, r, "setup.html", map[string]any{
+		"csrfToken": sessions.CSRFToken(s.ID),
+		"messages":  messages,
+		"token":     token,
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseProvisionCSV(t *testing.T) {
+	t.Parallel()
+
+	file := "username,email\nfoo, foo@example.com\nbar\n"
+	entries, err := parseProvisionCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(entries) != 2 {
+		t.Fatal("expected header row to be skipped:", entries)
+	}
+	if entries[0].Email != "foo@example.com" || entries[1].Email != "" {
+		t.Fatal("unexpected entries:", entries)
+	}
+
+	if _, err := parseProvisionCSV(strings.NewReader("foo,bar,baz\n")); err == nil {
+		t.Fatal("expected rows with extra fields to be rejected")
+	}
+}
+
+func TestRequestOrigin(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest("GET", "http://example.com/api/admin/provision", nil)
+	r.URL.Scheme = ""
+	if origin := requestOrigin(r); origin != "http://example.com" {
+		t.Fatal("unexpected origin:", origin)
+	}
+
+	// Scheme set by trusted proxy.
+	r.URL.Scheme = "https"
+	if origin := requestOrigin(r); origin != "https://example.com" {
+		t.Fatal("unexpected origin:", origin)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Handling of headers set by reverse proxies.
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Parses list of trusted proxy addresses.
+// Each entry can be an IP address or a CIDR block.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %v", proxy)
+			}
+			bits := 8 * len(ip.To4())
+			if bits == 0 {
+				bits = 8 * net.IPv6len
+			}
+			proxy = fmt.Sprintf("%v/%v", proxy, bits)
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func isTrusted(trusted []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns address of client given the X-Forwarded-For header.
+// The client is the rightmost untrusted address in the header.
+func forwardedFor(trusted []*net.IPNet, header string) string {
+	addrs := strings.Split(header, ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addrs[i])
+		if !isTrusted(trusted, addr) {
+			return addr
+		}
+	}
+	return strings.TrimSpace(addrs[0])
+}
+
+// Middleware for using X-Forwarded-For and X-Forwarded-Proto headers set by
+// trusted proxies.
+// Sets the request's RemoteAddr (used in access logs) and URL scheme.
+// Ignores headers from untrusted sources, because they're easy to spoof.
+func trustProxies(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil || !isTrusted(trusted, host) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if header := r.Header.Get("X-Forwarded-For"); header != "" {
+				r.RemoteAddr = net.JoinHostPort(forwardedFor(trusted, header), "0")
+			}
+			switch proto := r.Header.Get("X-Forwarded-Proto"); proto {
+			case "http", "https":
+				r.URL.Scheme = proto
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
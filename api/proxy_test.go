@@ -0,0 +1,56 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustProxies(t *testing.T) {
+	// Should only use forwarded headers from trusted proxies.
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	var remoteAddr, scheme string
+	handler := trustProxies(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		scheme = r.URL.Scheme
+	}))
+
+	cases := []struct {
+		remoteAddr    string
+		forwardedFor  string
+		expectedAddr  string
+		expectedProto string
+	}{
+		{"127.0.0.1:1234", "1.2.3.4", "1.2.3.4:0", "https"},
+		{"127.0.0.1:1234", "5.6.7.8, 1.2.3.4, 10.0.0.2", "1.2.3.4:0", "https"},
+		{"1.1.1.1:1234", "1.2.3.4", "1.1.1.1:1234", ""},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remoteAddr
+		r.Header.Set("X-Forwarded-For", c.forwardedFor)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if remoteAddr != c.expectedAddr {
+			t.Fatal("unexpected remote address:", c, remoteAddr)
+		}
+		if scheme != c.expectedProto {
+			t.Fatal("unexpected scheme:", c, scheme)
+		}
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	if _, err := parseTrustedProxies([]string{"foo"}); err == nil {
+		t.Fatal("expected invalid proxy address to be rejected")
+	}
+}
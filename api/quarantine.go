@@ -0,0 +1,177 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Quarantine for suspicious review uploads.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/flashcards"
+	"github.com/polycloze/polycloze/quarantine"
+)
+
+// Returns the time when each reviewed item was last reviewed, keyed by mode
+// and item.
+// Review DBs that don't exist yet are skipped.
+func lastReviewTimes(userID int, l1, l2 string, reviews []ReviewResult) (map[[2]string]time.Time, error) {
+	times := make(map[[2]string]time.Time)
+	for _, mode := range []string{flashcards.ModeCloze, flashcards.ModeProduction} {
+		path := reviewDBPath(userID, l1, l2, mode)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		db, err := database.Open(path + "?mode=ro")
+		if err != nil {
+			return nil, err
+		}
+		for _, review := range reviews {
+			if reviewMode(review) != mode {
+				continue
+			}
+			var reviewed int64
+			query := `SELECT reviewed FROM review WHERE item = ?`
+			if err := db.QueryRow(query, review.Word).Scan(&reviewed); err == nil {
+				times[[2]string{mode, review.Word}] = time.Unix(reviewed, 0)
+			}
+		}
+		db.Close()
+	}
+	return times, nil
+}
+
+// Returns the mode of the review DB the review gets saved in.
+func reviewMode(review ReviewResult) string {
+	if review.Mode == flashcards.ModeProduction {
+		return flashcards.ModeProduction
+	}
+	return flashcards.ModeCloze
+}
+
+// Quarantines uploaded reviews if they look suspicious.
+// Returns results to send back if the reviews got quarantined, or nil if they
+// should be saved.
+func quarantineReviews(
+	authDB *sql.DB,
+	userID int,
+	l1, l2 string,
+	reviews []ReviewResult,
+	now time.Time,
+) ([]ReviewSaveResult, error) {
+	times, err := lastReviewTimes(userID, l1, l2, reviews)
+	if err != nil {
+		return nil, err
+	}
+	lastReviewed := func(review ReviewResult) (time.Time, bool) {
+		t, ok := times[[2]string{reviewMode(review), review.Word}]
+		return t, ok
+	}
+
+	reasons := quarantine.Check(reviews, lastReviewed, now)
+	if reasons == nil {
+		return nil, nil
+	}
+	if _, err := quarantine.Add(authDB, userID, l1, l2, reviews, reasons); err != nil {
+		return nil, err
+	}
+
+	results := make([]ReviewSaveResult, 0, len(reviews))
+	for _, review := range reviews {
+		results = append(results, ReviewSaveResult{
+			Word:        review.Word,
+			Error:       "quarantined",
+			Quarantined: true,
+		})
+	}
+	return results, nil
+}
+
+// Saves quarantined batch as if it had just been uploaded.
+func approveBatch(r *http.Request, batch quarantine.Batch, now time.Time) error {
+	recognition, production := splitReviews(batch.Reviews)
+	if len(recognition) > 0 {
+		_, err := saveReviews(r, batch.UserID, batch.L1, batch.L2, flashcards.ModeCloze, recognition, now)
+		if err != nil {
+			return err
+		}
+	}
+	if len(production) > 0 {
+		_, err := saveReviews(r, batch.UserID, batch.L1, batch.L2, flashcards.ModeProduction, production, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lists quarantined batches of reviews.
+// Only available to admins (see `requireAdmin`).
+func handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	batches, err := quarantine.List(auth.GetDB(r))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, batches)
+}
+
+// Approves or discards a quarantined batch of reviews.
+// Only available to admins (see `requireAdmin`).
+// Expects POST request with JSON body: {"action": "approve" | "discard"}.
+func handleQuarantinedBatch(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s := adminSession(r)
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data struct {
+		Action string `json:"action"`
+	}
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+	if data.Action != "approve" && data.Action != "discard" {
+		http.Error(w, "Invalid action.", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := quarantine.Get(db, id)
+	if errors.Is(err, quarantine.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	if data.Action == "approve" {
+		if err := approveBatch(r, batch, time.Now()); err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+	if err := quarantine.Delete(db, id); err != nil && !errors.Is(err, quarantine.ErrNotFound) {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := newRateLimiter(60, 2)
+	now := time.Unix(1000000, 0)
+
+	if !l.allow("a", now) || !l.allow("a", now) {
+		t.Fatal("expected burst to be allowed")
+	}
+	if l.allow("a", now) {
+		t.Fatal("expected request over the burst to be rejected")
+	}
+	if !l.allow("b", now) {
+		t.Fatal("expected clients to be limited separately")
+	}
+	if !l.allow("a", now.Add(time.Second)) {
+		t.Fatal("expected token to be refilled after a second")
+	}
+}
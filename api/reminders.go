@@ -0,0 +1,90 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// GET: responds with user's reminder settings.
+// POST: updates user's reminder settings.
+func handleReminders(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	// Open user data DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "GET":
+		settings, err := reminders.Get(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, settings)
+		return
+	case "POST":
+		break
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	// Fields that are missing from the request keep their current values.
+	settings, err := reminders.Get(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if err := parseJSON(w, body, &settings); err != nil {
+		return
+	}
+	if !settings.IsValid() {
+		http.Error(w, "invalid reminder settings", http.StatusBadRequest)
+		return
+	}
+
+	if err := reminders.Set(db, settings); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, settings)
+}
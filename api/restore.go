@@ -0,0 +1,67 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/maintenance"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type RestoreRequest struct {
+	// UNIX timestamp.
+	Timestamp int64 `json:"timestamp"`
+}
+
+type RestoreResponse struct {
+	// Number of items whose review state changed.
+	Changed int `json:"changed"`
+}
+
+// Restores the user's review state in the course to what it was at the given
+// time (e.g. before a botched import).
+// The review event log is kept, so the restore can be undone by restoring to
+// a later time.
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data RestoreRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+	if data.Timestamp <= 0 {
+		http.Error(w, "Invalid timestamp.", http.StatusBadRequest)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	changed, err := maintenance.RestoreCourse(userID, l1, l2, time.Unix(data.Timestamp, 0))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, RestoreResponse{Changed: changed})
+}
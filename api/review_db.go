@@ -0,0 +1,152 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/replay"
+	"github.com/polycloze/polycloze/review_restore"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Downloads (GET) or restores (POST) the user's review DB for the course as
+// is, so that users can inspect or archive their raw data with SQLite tools.
+// Databases are uploaded as the request body (Content-Type:
+// application/vnd.sqlite3), with the CSRF token in the X-CSRF-Token header.
+// See package review_restore for the checks done on uploaded databases.
+func handleReviewDB(maxSize int64) http.HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil || !s.IsSignedIn() {
+			http.NotFound(w, r)
+			return
+		}
+
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+		userID := s.Data["userID"].(int)
+
+		switch r.Method {
+		case "GET":
+			downloadReviewDB(w, r, userID, l1, l2)
+		case "POST":
+			if !sessions.CheckCSRFToken(s.ID, r.Header.Get("X-CSRF-Token")) {
+				http.Error(w, "Forbidden.", http.StatusForbidden)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			restoreReviewDB(w, r, userID, l1, l2, maxSize)
+		default:
+			http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func downloadReviewDB(w http.ResponseWriter, r *http.Request, userID int, l1, l2 string) {
+	path := basedir.Review(userID, l1, l2)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "No reviews found.", http.StatusNotFound)
+		return
+	}
+
+	// Copy the database first, so that the download is consistent even if the
+	// user is studying at the same time.
+	dir, err := os.MkdirTemp("", "polycloze-review-db-")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "reviews.db")
+	// The lock keeps restores and resets from replacing the file mid-copy.
+	unlock := database.RLockReviewDB(path)
+	err = database.Backup(path, dest)
+	unlock()
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("polycloze-%s-%s.db", l1, l2)
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	http.ServeContent(w, r, name, time.Now(), f)
+}
+
+func restoreReviewDB(w http.ResponseWriter, r *http.Request, userID int, l1, l2 string, maxSize int64) {
+	dir, err := os.MkdirTemp("", "polycloze-review-db-")
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "reviews.db")
+	f, err := os.Create(src)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	_, err = io.Copy(f, r.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		message := fmt.Sprintf("File is too big (>%vMB).", maxSize/(1024*1024))
+		http.Error(w, message, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	dest := basedir.Review(userID, l1, l2)
+	unlock := database.LockReviewDB(dest)
+	err = review_restore.Install(src, dest, time.Now())
+	unlock()
+	if errors.Is(err, review_restore.ErrInvalidDatabase) {
+		http.Error(w, fmt.Sprintf("Invalid file (%v).", err), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, replay.ErrHasExistingReviews) {
+		message := "Can't import data, because existing reviews were found. Try resetting your progress first."
+		http.Error(w, message, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
@@ -8,10 +8,14 @@ import (
 	"github.com/polycloze/polycloze/difficulty"
 	"github.com/polycloze/polycloze/flashcards"
 	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/text"
+	"github.com/polycloze/polycloze/wilson"
 )
 
 type ReviewResult = review_scheduler.Result
 
+type ReviewSaveResult = review_scheduler.SaveResult
+
 // JSON request schema.
 type FlashcardsRequest struct {
 	Limit      int                    `json:"limit"`
@@ -19,6 +23,9 @@ type FlashcardsRequest struct {
 	Reviews    []ReviewResult         `json:"reviews"`
 	Exclude    []string               `json:"exclude"`
 
+	// Exercise type of generated flashcards (default: "cloze").
+	Mode string `json:"mode"`
+
 	// Sometimes used by client if for some reason they can't pass the token via
 	// HTTP headers (e.g. `sendBeacon`).
 	CSRFToken string `json:"csrfToken"`
@@ -28,6 +35,36 @@ type FlashcardsRequest struct {
 type FlashcardsResponse struct {
 	Items      []flashcards.Item      `json:"items"`
 	Difficulty *difficulty.Difficulty `json:"difficulty"`
+	Thresholds *wilson.Thresholds     `json:"thresholds"`
+	Algorithm  string                 `json:"algorithm"` // "wilson" or "bayes"
+
+	// Outcome of each uploaded review, in the same order as the request.
+	// Clients should resend the reviews that failed.
+	Results []ReviewSaveResult `json:"results,omitempty"`
+
+	// Rules for normalizing answers before grading (see
+	// `text.NormalizeAnswer`).
+	AnswerRules []text.Replacement `json:"answerRules"`
+}
+
+// JSON response schema of tuner state.
+type TunerResponse struct {
+	Algorithm  string                  `json:"algorithm"`
+	Difficulty difficulty.Difficulty   `json:"difficulty"`
+	Thresholds wilson.Thresholds       `json:"thresholds"`
+	Stats      []difficulty.ClassStats `json:"stats"`
+	Decisions  []difficulty.Decision   `json:"decisions"`
+}
+
+type TunerAlgorithmRequest struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// JSON request schema for overriding the difficulty level.
+// Omitting `level` unpins the current level.
+type DifficultyOverrideRequest struct {
+	Level  *int `json:"level"`
+	Pinned bool `json:"pinned"`
 }
 
 type SetCourseRequest struct {
@@ -38,3 +75,37 @@ type SetCourseRequest struct {
 type SetCourseResponse struct {
 	Ok bool `json:"ok"`
 }
+
+// Message sent by the client during live study sessions.
+type StudyRequest struct {
+	// "review" to save a review result, or "next" to request the next
+	// flashcard.
+	Type   string        `json:"type"`
+	Review *ReviewResult `json:"review,omitempty"`
+
+	// Required in "review" messages.
+	CSRFToken string `json:"csrfToken,omitempty"`
+}
+
+// Message sent by the server during live study sessions.
+type StudyResponse struct {
+	// "ready", "item", "result" or "error".
+	Type string `json:"type"`
+
+	// Next flashcard in "item" messages.
+	// Nil if there are no flashcards left.
+	Item *flashcards.Item `json:"item,omitempty"`
+
+	// Outcome of the review in "result" messages.
+	Result *ReviewSaveResult `json:"result,omitempty"`
+
+	// Sent in "ready" and "result" messages.
+	Difficulty *difficulty.Difficulty `json:"difficulty,omitempty"`
+
+	// Only sent in the "ready" message.
+	Thresholds  *wilson.Thresholds `json:"thresholds,omitempty"`
+	Algorithm   string             `json:"algorithm,omitempty"`
+	AnswerRules []text.Replacement `json:"answerRules,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// SCIM 2.0 endpoints for identity providers.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/scim"
+)
+
+// Max size of SCIM request bodies.
+const maxSCIMRequestSize = 64 * 1024
+
+// Sends SCIM resource or error.
+func sendSCIM(w http.ResponseWriter, status int, data any) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		log.Println("failed to encode to JSON:", err)
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	if _, err := w.Write(bytes); err != nil {
+		log.Println("failed to send JSON:", err)
+	}
+}
+
+// Sends SCIM error response.
+// Errors that aren't SCIM errors get logged, and are reported as internal
+// server errors.
+func sendSCIMError(w http.ResponseWriter, err error) {
+	var e *scim.Error
+	if !errors.As(err, &e) {
+		log.Println(err)
+		e = scim.NewError(http.StatusInternalServerError, "", "Something went wrong.")
+	}
+	sendSCIM(w, e.StatusCode(), e)
+}
+
+// Reads JSON request body.
+func readSCIMRequest(r *http.Request, v any) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSCIMRequestSize))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidSyntax", "Invalid JSON.")
+	}
+	return nil
+}
+
+// Only lets requests with the bearer token through.
+// Responds with 404 if SCIM is disabled (empty token).
+func requireSCIMToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		header := r.Header.Get("Authorization")
+		bearer := strings.TrimPrefix(header, "Bearer ")
+		if bearer == header || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			sendSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "Invalid token."))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Describes supported SCIM features.
+func handleSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]any {
+		return map[string]any{"supported": ok}
+	}
+	sendSCIM(w, http.StatusOK, map[string]any{
+		"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":   supported(true),
+		"bulk": map[string]any{
+			"supported":      false,
+			"maxOperations":  0,
+			"maxPayloadSize": 0,
+		},
+		"filter": map[string]any{
+			"supported":  true,
+			"maxResults": scim.MaxCount,
+		},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer token",
+				"description": "Token set by the server admin.",
+			},
+		},
+	})
+}
+
+// Lists supported resource types.
+func handleSCIMResourceTypes(w http.ResponseWriter, r *http.Request) {
+	sendSCIM(w, http.StatusOK, map[string]any{
+		"schemas":      []string{scim.ListResponseSchema},
+		"totalResults": 1,
+		"Resources": []map[string]any{
+			{
+				"schemas":  []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+				"id":       "User",
+				"name":     "User",
+				"endpoint": "/Users",
+				"schema":   scim.UserSchema,
+			},
+		},
+	})
+}
+
+// Returns value of integer search param, or the default value if it's missing
+// or invalid.
+func intParam(r *http.Request, name string, fallback int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Lists users (GET) or creates a user (POST).
+// Search params of list requests:
+// - filter: `userName eq "..."` or `externalId eq "..."`
+// - startIndex: 1-based index of first result (default: 1)
+// - count: max number of results (default: max)
+func handleSCIMUsers(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+
+	switch r.Method {
+	case "GET":
+		var filter *scim.Filter
+		if value := r.URL.Query().Get("filter"); value != "" {
+			f, err := scim.ParseFilter(value)
+			if err != nil {
+				sendSCIMError(w, err)
+				return
+			}
+			filter = &f
+		}
+
+		startIndex := intParam(r, "startIndex", 1)
+		count := intParam(r, "count", scim.MaxCount)
+		list, err := scim.List(db, filter, startIndex, count)
+		if err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		sendSCIM(w, http.StatusOK, list)
+	case "POST":
+		var u scim.User
+		if err := readSCIMRequest(r, &u); err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		created, err := scim.Create(db, u)
+		if err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		w.Header().Set("Location", created.Meta.Location)
+		sendSCIM(w, http.StatusCreated, created)
+	default:
+		sendSCIMError(w, scim.NewError(http.StatusMethodNotAllowed, "", "Method not allowed."))
+	}
+}
+
+// Gets (GET), replaces (PUT), modifies (PATCH) or deletes (DELETE) a user.
+func handleSCIMUser(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		sendSCIMError(w, scim.NewError(http.StatusNotFound, "", "User not found."))
+		return
+	}
+
+	var u scim.User
+	switch r.Method {
+	case "GET":
+		u, err = scim.Get(db, id)
+	case "PUT":
+		var replacement scim.User
+		if err := readSCIMRequest(r, &replacement); err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		u, err = scim.Replace(db, id, replacement)
+	case "PATCH":
+		var patch scim.PatchRequest
+		if err := readSCIMRequest(r, &patch); err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		u, err = scim.Patch(db, id, patch.Operations)
+	case "DELETE":
+		if err := scim.Delete(db, id); err != nil {
+			sendSCIMError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		err = scim.NewError(http.StatusMethodNotAllowed, "", "Method not allowed.")
+	}
+
+	if err != nil {
+		sendSCIMError(w, err)
+		return
+	}
+	sendSCIM(w, http.StatusOK, u)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireSCIMToken(t *testing.T) {
+	t.Parallel()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for _, test := range []struct {
+		token  string
+		header string
+		status int
+	}{
+		{"secret", "Bearer secret", http.StatusOK},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"", "Bearer ", http.StatusNotFound},
+	} {
+		r := httptest.NewRequest("GET", "/scim/v2/Users", nil)
+		r.Header.Set("Authorization", test.header)
+		w := httptest.NewRecorder()
+		requireSCIMToken(test.token, ok)(w, r)
+		if w.Code != test.status {
+			t.Fatal("unexpected status:", test, w.Code)
+		}
+	}
+}
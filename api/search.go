@@ -0,0 +1,79 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/search"
+)
+
+// Searches words, sentences and translations in the course.
+// Query syntax: `word`, `prefix*` and `"exact phrase"`.
+// Doesn't require sign in, like /api/sentences.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := getSentencesLimit(q)
+	result, err := search.Search(
+		basedir.SearchIndex(l1, l2),
+		basedir.Course(l1, l2),
+		q.Get("q"),
+		limit,
+	)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, result)
+}
+
+// Suggests course words that start with the `q` search param.
+// Used by the word list UI.
+func handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	db, err := database.Open(basedir.Course(l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	q := r.URL.Query()
+	words, err := search.Autocomplete(db, q.Get("q"), getSentencesLimit(q))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	// Sort words with the same frequency class using the course language's
+	// collation rules.
+	c := courseCollator(l1, l2)
+	sort.SliceStable(words, func(i, j int) bool {
+		if words[i].FrequencyClass != words[j].FrequencyClass {
+			return words[i].FrequencyClass < words[j].FrequencyClass
+		}
+		return compareWords(c, words[i].Word, words[j].Word) < 0
+	})
+	sendJSON(w, r, map[string]any{
+		"words": words,
+	})
+}
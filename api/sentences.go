@@ -4,11 +4,14 @@
 package api
 
 import (
-	"log"
+	"database/sql"
+	"errors"
 	"net/http"
 	"net/url"
 	"strconv"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
 	"github.com/polycloze/polycloze/sentences"
@@ -44,8 +47,7 @@ func handleSentences(w http.ResponseWriter, r *http.Request) {
 
 	db, err := database.Open(basedir.Course(l1, l2))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
@@ -53,12 +55,74 @@ func handleSentences(w http.ResponseWriter, r *http.Request) {
 	limit := getSentencesLimit(q)
 	result, err := sentences.RandomSentences(db, limit)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	sendJSON(w, map[string][]sentences.Sentence{
+	sendJSON(w, r, map[string][]sentences.Sentence{
 		"sentences": result,
 	})
 }
+
+// Gets page number from URL query.
+// Pages start at 0.
+func getPage(q url.Values) int {
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 0 {
+		return 0
+	}
+	return page
+}
+
+// Returns course sentences (with translations) that contain the word.
+// Doesn't require sign in, like /api/sentences.
+func handleWordExamples(w http.ResponseWriter, r *http.Request) {
+	sendWordExamples(w, r, chi.URLParam(r, "word"))
+}
+
+// Like `handleWordExamples`, but takes the word from the `word` search param.
+// For review screens that show more context on demand.
+func handleSentencesWithWord(w http.ResponseWriter, r *http.Request) {
+	word := r.URL.Query().Get("word")
+	if word == "" {
+		http.Error(w, "missing word", http.StatusBadRequest)
+		return
+	}
+	sendWordExamples(w, r, word)
+}
+
+// Sends page of course sentences that contain the word.
+// Uses the `page` and `limit` search params.
+func sendWordExamples(w http.ResponseWriter, r *http.Request, word string) {
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	db, err := database.Open(basedir.Course(l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	q := r.URL.Query()
+	page := getPage(q)
+	examples, err := sentences.Examples(db, word, page, getSentencesLimit(q))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	sendJSON(w, r, map[string]any{
+		"word":     word,
+		"page":     page,
+		"examples": examples,
+	})
+}
@@ -5,6 +5,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -30,9 +31,8 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 		username := s.Data["username"].(string)
 		currentPassword := r.FormValue("current-password")
 		newPassword := r.FormValue("new-password")
-		csrfToken := r.FormValue("csrf-token")
 
-		if !sessions.CheckCSRFToken(s.ID, csrfToken) {
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
 			_ = s.ErrorMessage(
 				"Something went wrong. Please try again.",
 				"change-password",
@@ -59,23 +59,32 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 fail:
-	// Get active course.
-	userID := s.Data["userID"].(int)
-	course, err := getUserActiveCourse(userID)
+	data, err := templateData(s)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	s.Data["course"] = course
-	s.Data["csrfToken"] = sessions.CSRFToken(s.ID)
-	s.Data["changePasswordMessages"], _ = s.Messages("change-password")
-	s.Data["csvUploadMessages"], _ = s.Messages("csv-upload")
-	s.Data["resetProgressMessages"], _ = s.Messages("reset-progress")
-	renderTemplate(w, "settings.html", s.Data)
+	data["changePasswordMessages"], _ = s.Messages("change-password")
+	data["csvUploadMessages"], _ = s.Messages("csv-upload")
+	data["resetProgressMessages"], _ = s.Messages("reset-progress")
+	renderTemplate(w, r, "settings.html", data)
+}
+
+// Returns confirmation string the user has to type to reset progress in a
+// course.
+func resetConfirmation(username, l1, l2 string) string {
+	return fmt.Sprintf("%v/%v-%v", username, l1, l2)
+}
+
+type ResetProgressRequest struct {
+	// Should be "<username>/<l1>-<l2>".
+	Confirm string `json:"confirm"`
 }
 
+// Resets progress in course.
+// Accepts form submissions from the settings page, or JSON requests from API
+// clients.
 func handleResetProgress(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "expected POST request", http.StatusBadRequest)
@@ -99,11 +108,16 @@ func handleResetProgress(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := s.Data["userID"].(int)
 	username := s.Data["username"].(string)
-	csrfToken := r.FormValue("csrf-token")
+
+	if r.Header.Get("Content-Type") == "application/json" {
+		handleResetProgressJSON(w, r, s, userID, username, l1, l2)
+		return
+	}
+
 	confirm := r.FormValue("confirm")
 
 	// Check CSRF token.
-	if !sessions.CheckCSRFToken(s.ID, csrfToken) {
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
 		_ = s.ErrorMessage(
 			"Something went wrong. Please try again.",
 			"reset-progress",
@@ -112,7 +126,7 @@ func handleResetProgress(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check confirmation string.
-	if confirm != fmt.Sprintf("%v/%v-%v", username, l1, l2) {
+	if confirm != resetConfirmation(username, l1, l2) {
 		_ = s.ErrorMessage("Incorrect confirmation string.", "reset-progress")
 		goto fail
 	}
@@ -132,14 +146,50 @@ fail:
 	http.Redirect(w, r, "/settings", http.StatusSeeOther)
 }
 
+// JSON version of handleResetProgress.
+func handleResetProgressJSON(
+	w http.ResponseWriter,
+	r *http.Request,
+	s *sessions.Session,
+	userID int,
+	username, l1, l2 string,
+) {
+	var data ResetProgressRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+	if data.Confirm != resetConfirmation(username, l1, l2) {
+		http.Error(w, "Incorrect confirmation string.", http.StatusBadRequest)
+		return
+	}
+
+	if err := resetProgress(userID, l1, l2); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
+
 // Resets course progress by deleting the review DB and re-initializing it.
 func resetProgress(userID int, l1, l2 string) error {
 	// TODO make this operation atomic
-
-	// Delete review DB.
-	path := basedir.Review(userID, l1, l2)
-	if err := os.Remove(path); err != nil {
-		return fmt.Errorf("failed to reset progress: %w", err)
+	defer database.LockReviewDB(basedir.Review(userID, l1, l2))()
+	defer database.LockReviewDB(basedir.ProductionReview(userID, l1, l2))()
+
+	// Delete review DBs and archives, including leftover journal files.
+	paths := []string{
+		basedir.Review(userID, l1, l2),
+		basedir.ProductionReview(userID, l1, l2),
+		basedir.ReviewArchive(basedir.Review(userID, l1, l2)),
+		basedir.ReviewArchive(basedir.ProductionReview(userID, l1, l2)),
+	}
+	for _, path := range paths {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err := os.Remove(path + suffix)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to reset progress: %w", err)
+			}
+		}
 	}
 
 	// Re-initialize review DB.
@@ -4,13 +4,18 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/reverse"
+	"github.com/polycloze/polycloze/sample_course"
 )
 
 // Version string of course files.
@@ -20,6 +25,10 @@ type Language struct {
 	Code  string `json:"code"` // ISO 639-3
 	Name  string `json:"name"` // in english
 	BCP47 string `json:"bcp47"`
+
+	// Name in the display language of the request, if available (see
+	// `localizeLanguages`).
+	LocalizedName string `json:"localizedName,omitempty"`
 }
 
 // For sorting languages by code.
@@ -38,41 +47,33 @@ func (a ByCode) Less(i, j int) bool {
 }
 
 // Look for installed languages and courses.
-func Startup() {
+func Startup(config Config) error {
 	// Look for courses and languages.
-	courses := findCourses()
-	languages := findL1Languages(courses)
-	if len(languages) <= 0 {
-		log.Fatal("Couldn't find installed courses. Please visit https://github.com/polycloze/polycloze/tree/main/python")
+	// Install the sample course on first run, so that new instances work
+	// without installing course files.
+	err := ReloadCatalog(config)
+	if errors.Is(err, ErrNoCourses) {
+		log.Println("Couldn't find installed courses, installing sample course. Visit https://github.com/polycloze/polycloze/tree/main/python for more courses.")
+		if err := sample_course.Install(); err != nil {
+			return err
+		}
+		err = ReloadCatalog(config)
+	}
+	if err != nil {
+		return err
 	}
 
 	// Set version string.
 	versionFile := filepath.Join(basedir.DataDir, "version.txt")
 	version, err := os.ReadFile(versionFile)
 	if err != nil {
-		log.Fatal("Couldn't set version number.")
+		return fmt.Errorf("couldn't set version number: %w", err)
 	}
 	dataVersion = string(version)
 
-	// Generate courses.json.
-	coursesJSON := filepath.Join(basedir.StateDir, "courses.json")
-	err = writeJSON(coursesJSON, map[string][]Course{
-		"courses": courses,
-	})
-	if err != nil {
-		log.Fatal("failed to write courses.json:", err)
-	}
-
-	languagesJSON := filepath.Join(basedir.StateDir, "languages.json")
-	err = writeJSON(languagesJSON, map[string][]Language{
-		"languages": languages,
-	})
-	if err != nil {
-		log.Fatal("failed to write languages.json:", err)
-	}
-
 	// Compute hashes of static files.
 	_ = computeHashes()
+	return nil
 }
 
 // Input: path to course db file.
@@ -141,3 +142,77 @@ func findL1Languages(courses []Course) []Language {
 	}
 	return result
 }
+
+// Builds reverse courses of installed courses, unless the reverse course is
+// also installed or the built reverse course is up-to-date.
+// Returns list of reverse courses.
+// Courses are built concurrently, because building a course can take a while.
+// Failures are only logged, so that they don't prevent the server from
+// starting.
+func buildReverseCourses(courses []Course) []Course {
+	installed := make(map[string]bool)
+	for _, course := range courses {
+		installed[course.L1.Code+"-"+course.L2.Code] = true
+	}
+
+	var reverses []Course
+	for _, course := range courses {
+		if !installed[course.L2.Code+"-"+course.L1.Code] {
+			reverses = append(reverses, Course{L1: course.L2, L2: course.L1})
+		}
+	}
+
+	built := make([]bool, len(reverses))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < runtime.NumCPU(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				built[i] = buildReverseCourse(reverses[i])
+			}
+		}()
+	}
+	for i := range reverses {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var result []Course
+	for i, course := range reverses {
+		if built[i] {
+			result = append(result, course)
+		}
+	}
+	return result
+}
+
+// Builds reverse course if it's outdated.
+// Returns false if the course couldn't be built.
+func buildReverseCourse(course Course) bool {
+	l1, l2 := course.L1.Code, course.L2.Code
+	src := basedir.Course(l2, l1)
+	dst := basedir.ReverseCourse(l1, l2)
+	if !isOutdated(dst, src) {
+		return true
+	}
+
+	log.Printf("Building reverse course: %s-%s\n", l1, l2)
+	if err := reverse.Build(src, dst); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Checks if file doesn't exist or is older than its source.
+func isOutdated(name, source string) bool {
+	info, err := os.Stat(name)
+	if err != nil {
+		return true
+	}
+	sourceInfo, err := os.Stat(source)
+	return err == nil && info.ModTime().Before(sourceInfo.ModTime())
+}
@@ -28,7 +28,7 @@ func serveDist() http.Handler {
 	if err != nil {
 		panic(err)
 	}
-	return cacheUntilBusted(http.FileServer(http.FS(sub)))
+	return fingerprinted("/dist", http.FileServer(http.FS(sub)))
 }
 
 // Usage: http.Handle("/public/*", http.StripPrefix("/public/", servePublic()))
@@ -37,7 +37,7 @@ func servePublic() http.Handler {
 	if err != nil {
 		panic(err)
 	}
-	return cacheUntilBusted(http.FileServer(http.FS(sub)))
+	return fingerprinted("/public", http.FileServer(http.FS(sub)))
 }
 
 // Usage: http.Handle("/svg/*", http.StripPrefix("/svg/", serveSVG()))
@@ -84,22 +84,6 @@ func serveShare() http.Handler {
 	return versioned(cacheUntilBusted(http.FileServer(http.Dir(basedir.DataDir))))
 }
 
-func serveLanguagesJSON() http.HandlerFunc {
-	name := filepath.Join(basedir.StateDir, "languages.json")
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, name)
-	}
-	return cacheUntilBusted(http.HandlerFunc(handler))
-}
-
-func serveCoursesJSON() http.HandlerFunc {
-	name := filepath.Join(basedir.StateDir, "courses.json")
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, name)
-	}
-	return cacheUntilBusted(http.HandlerFunc(handler))
-}
-
 func serveUserData(w http.ResponseWriter, r *http.Request) {
 	// Page redirects to itself recursively without this check...
 	if r.URL.Path == "" || r.URL.Path == "/" {
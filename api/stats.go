@@ -4,18 +4,23 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/polycloze/polycloze/achievements"
 	"github.com/polycloze/polycloze/auth"
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/flashcards"
 	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/review_scheduler"
 	"github.com/polycloze/polycloze/sessions"
 )
 
@@ -63,8 +68,7 @@ func handleStatsActivity(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
@@ -76,12 +80,11 @@ func handleStatsActivity(w http.ResponseWriter, r *http.Request) {
 		getStep(r),
 	)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	sendJSON(w, map[string]any{
+	sendJSON(w, r, map[string]any{
 		"activity": result,
 		// TODO use unix timestamps?
 	})
@@ -106,8 +109,7 @@ func handleStatsVocab(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
@@ -119,12 +121,11 @@ func handleStatsVocab(w http.ResponseWriter, r *http.Request) {
 		getStep(r),
 	)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	sendJSON(w, map[string]any{
+	sendJSON(w, r, map[string]any{
 		"vocabSize": result,
 		// TODO use unix timestamps?
 	})
@@ -149,8 +150,7 @@ func handleStatsEstimatedLevel(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
@@ -162,16 +162,323 @@ func handleStatsEstimatedLevel(w http.ResponseWriter, r *http.Request) {
 		getStep(r),
 	)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 
-	sendJSON(w, map[string]any{
+	sendJSON(w, r, map[string]any{
 		"estimatedLevel": result,
 	})
 }
 
+// Responds with number of correct and incorrect answers per exercise type.
+func handleStatsModes(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	result, err := flashcards.GetModeStats(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	sendJSON(w, r, map[string]any{
+		"modes": result,
+	})
+}
+
+// Responds with list of earned and locked achievements in the course.
+func handleAchievements(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	result, err := achievements.List(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	sendJSON(w, r, map[string]any{
+		"achievements": result,
+	})
+}
+
+// Default vocabulary milestones in learning ETA estimates.
+var defaultMilestones = []int{1000, 2000, 5000}
+
+// Estimates fraction of word occurrences in text covered by the `n` most
+// frequent words in the course DB, for each `n`.
+// Word frequencies are approximated from their frequency classes, which are
+// computed as floor(0.5 - log2(frequency / max frequency)).
+func wordCoverage(course *sql.DB, ns []int) ([]float64, error) {
+	query := `
+		SELECT frequency_class, count(*) FROM word
+		GROUP BY frequency_class
+		ORDER BY frequency_class ASC
+	`
+	rows, err := course.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute word coverage: %w", err)
+	}
+	defer rows.Close()
+
+	type class struct {
+		words  int
+		weight float64 // relative frequency of each word in the class
+	}
+	var classes []class
+	total := 0.0
+	for rows.Next() {
+		var frequencyClass, words int
+		if err := rows.Scan(&frequencyClass, &words); err != nil {
+			return nil, fmt.Errorf("failed to compute word coverage: %w", err)
+		}
+		weight := math.Exp2(-float64(frequencyClass))
+		classes = append(classes, class{words: words, weight: weight})
+		total += float64(words) * weight
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compute word coverage: %w", err)
+	}
+
+	coverage := make([]float64, len(ns))
+	if total == 0 {
+		return coverage, nil
+	}
+	for i, n := range ns {
+		covered := 0.0
+		for _, c := range classes {
+			words := c.words
+			if words > n {
+				words = n
+			}
+			covered += float64(words) * c.weight
+			n -= words
+			if n <= 0 {
+				break
+			}
+		}
+		coverage[i] = covered / total
+	}
+	return coverage, nil
+}
+
+// Gets vocabulary milestones from URL search params (e.g.
+// "?milestones=1000,3000").
+func getMilestones(r *http.Request) []int {
+	v := r.URL.Query().Get("milestones")
+	if v == "" {
+		return defaultMilestones
+	}
+
+	var milestones []int
+	for _, field := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return defaultMilestones
+		}
+		milestones = append(milestones, n)
+	}
+	return milestones
+}
+
+// Responds with estimates of when the user will reach vocabulary milestones
+// at their current pace.
+// The pace is computed from reviews in the last 30 days, or in the range
+// given by the `from` and `to` search params.
+func handleStatsETA(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -30)
+	if r.URL.Query().Has("from") {
+		from = getFrom(r)
+	}
+	pace, err := history.GetPace(db, from, getTo(r))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	vocabSize, err := history.CurrentVocabSize(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	milestones := history.EstimateMilestones(pace, vocabSize, now, getMilestones(r))
+
+	course, err := database.Open(basedir.Course(l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer course.Close()
+
+	words := make([]int, len(milestones))
+	for i, milestone := range milestones {
+		words[i] = milestone.Words
+	}
+	coverage, err := wordCoverage(course, words)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	for i := range milestones {
+		milestones[i].Coverage = coverage[i]
+	}
+
+	sendJSON(w, r, map[string]any{
+		"pace":       pace,
+		"vocabSize":  vocabSize,
+		"milestones": milestones,
+	})
+}
+
+// Key numbers shown on the stats page.
+type StatsSummary struct {
+	DueToday  int `json:"dueToday"`
+	Streak    int `json:"streak"`
+	VocabSize int `json:"vocabSize"`
+	Level     int `json:"level"`
+}
+
+// Computes key numbers from the user's review DB.
+func summarizeStats(db *sql.DB, now time.Time) (StatsSummary, error) {
+	var summary StatsSummary
+
+	// Count words due before the end of the day.
+	year, month, day := now.Date()
+	tomorrow := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+
+	var err error
+	summary.DueToday, err = review_scheduler.CountDue(db, tomorrow)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize stats: %w", err)
+	}
+
+	summary.Streak, err = history.Streak(db, now)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize stats: %w", err)
+	}
+
+	summary.VocabSize, err = history.CurrentVocabSize(db)
+	if err != nil {
+		return summary, fmt.Errorf("failed to summarize stats: %w", err)
+	}
+
+	query := `SELECT coalesce(max(v), 0) FROM estimated_level`
+	if err := db.QueryRow(query).Scan(&summary.Level); err != nil {
+		return summary, fmt.Errorf("failed to summarize stats: %w", err)
+	}
+	return summary, nil
+}
+
+// Shows stats page.
+// Works without JavaScript.
+func handleStatsPage(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Redirect(w, r, "/signin", http.StatusTemporaryRedirect)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	summary, err := summarizeStats(db, time.Now())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	course, err := getCourseInfo(basedir.Course(l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	data, err := templateData(s)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	data["statsCourse"] = course
+	data["stats"] = summary
+	renderTemplate(w, r, "stats.html", data)
+}
+
 // Gets `from` UNIX timestamp from URL search params.
 // Default value: last week.
 func getFrom(r *http.Request) time.Time {
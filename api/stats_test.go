@@ -0,0 +1,41 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"math"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestWordCoverage(t *testing.T) {
+	// Words in frequency class 0 occur twice as often as words in class 1.
+	t.Parallel()
+
+	db, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	query := `
+		CREATE TABLE word (id INTEGER PRIMARY KEY, word TEXT, frequency_class INTEGER);
+		INSERT INTO word (word, frequency_class) VALUES
+			('a', 0), ('b', 1), ('c', 1);
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	coverage, err := wordCoverage(db, []int{1, 2, 10})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	for i, expected := range []float64{0.5, 0.75, 1} {
+		if math.Abs(coverage[i]-expected) > 1e-9 {
+			t.Fatal("unexpected coverage:", coverage)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Live study sessions over WebSocket.
+// The server keeps the scheduler state, streams one flashcard at a time and
+// saves each review as soon as the client sends it, so the difficulty gets
+// adjusted before the next flashcard is generated.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/flashcards"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Live study sessions get closed after being idle for this long.
+const studyIdleTimeout = 30 * time.Minute
+
+// Max size of messages sent by the client.
+const maxStudyMessageSize = 16 * 1024
+
+// Registry of live study sessions, so that they can be ended on shutdown.
+// `http.Server.Shutdown` doesn't wait for them, because their connections have
+// been hijacked.
+type studyRegistry struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	conns   map[*websocket.Conn]bool
+	closing bool
+}
+
+func newStudyRegistry() *studyRegistry {
+	return &studyRegistry{conns: make(map[*websocket.Conn]bool)}
+}
+
+var liveStudySessions = newStudyRegistry()
+
+var errStudyShutdown = errors.New("server is shutting down")
+
+// Adds connection to the registry.
+// Returns false if the server is shutting down.
+func (sr *studyRegistry) track(ws *websocket.Conn) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if sr.closing {
+		return false
+	}
+	sr.conns[ws] = true
+	sr.wg.Add(1)
+	return true
+}
+
+func (sr *studyRegistry) untrack(ws *websocket.Conn) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	delete(sr.conns, ws)
+	sr.wg.Done()
+}
+
+// Extends the read deadline of the connection before waiting for the next
+// message.
+// Fails if the server is shutting down.
+func (sr *studyRegistry) extendDeadline(ws *websocket.Conn) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if sr.closing {
+		return errStudyShutdown
+	}
+	return ws.SetReadDeadline(time.Now().Add(studyIdleTimeout))
+}
+
+// Ends sessions once they're done handling the current message, so that
+// reviews don't get cut off mid-write.
+// Waits until the sessions have ended, or until the context is done.
+// New sessions get rejected after this gets called.
+func (sr *studyRegistry) close(ctx context.Context) error {
+	sr.mu.Lock()
+	sr.closing = true
+	for ws := range sr.conns {
+		// Interrupts sessions that are waiting for the next message.
+		_ = ws.SetReadDeadline(time.Now())
+	}
+	sr.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		sr.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to close live study sessions: %w", ctx.Err())
+	}
+}
+
+// Ends live study sessions (see `studyRegistry.close`).
+func CloseStudySessions(ctx context.Context) error {
+	return liveStudySessions.close(ctx)
+}
+
+type studySession struct {
+	db  *sql.DB
+	con *database.Connection
+
+	// For holding suspicious reviews in the quarantine.
+	authDB *sql.DB
+
+	userID int
+	l1     string
+	l2     string
+	mode   string
+
+	tuner *difficulty.Tuner
+
+	// Set from the user's course settings when the session is ready.
+	filter rs.Filter
+
+	// Words in flashcards that were sent but haven't been reviewed yet.
+	pending map[string]bool
+}
+
+// Returns words that get reviewed in the flashcard.
+func itemWords(item flashcards.Item) []string {
+	var words []string
+	for _, part := range item.Sentence.Parts {
+		for _, answer := range part.Answers {
+			words = append(words, text.Casefold(answer.Normalized))
+		}
+	}
+	return words
+}
+
+// Returns the "ready" message, which contains the tuner settings.
+func (s *studySession) ready() (StudyResponse, error) {
+	// Course settings are in the recognition review DB.
+	settingsDB := s.db
+	if s.mode == flashcards.ModeProduction {
+		db, err := database.OpenReviewDB(basedir.Review(s.userID, s.l1, s.l2))
+		if err != nil {
+			return StudyResponse{}, err
+		}
+		defer db.Close()
+		settingsDB = db
+	}
+	answerRules, err := courseAnswerRules(settingsDB, s.l2)
+	if err != nil {
+		return StudyResponse{}, err
+	}
+	s.filter, err = courseFlashcardFilter(settingsDB)
+	if err != nil {
+		return StudyResponse{}, err
+	}
+
+	diff := s.tuner.Difficulty
+	return StudyResponse{
+		Type:        "ready",
+		Difficulty:  &diff,
+		Thresholds:  &s.tuner.Thresholds,
+		Algorithm:   s.tuner.Algorithm,
+		AnswerRules: answerRules,
+	}, nil
+}
+
+// Returns the "item" message with the next flashcard.
+func (s *studySession) next() StudyResponse {
+	filter := s.filter
+	filter.Exclude = make([]string, 0, len(s.pending))
+	for word := range s.pending {
+		filter.Exclude = append(filter.Exclude, word)
+	}
+	items := flashcards.GetFiltered(s.con, 1, filter)
+	if len(items) == 0 {
+		return StudyResponse{Type: "item"}
+	}
+
+	item := items[0]
+	item.Mode = s.mode
+	for _, word := range itemWords(item) {
+		s.pending[word] = true
+	}
+	return StudyResponse{Type: "item", Item: &item}
+}
+
+// Saves review and tunes the difficulty if the word is new.
+// Returns the "result" message.
+func (s *studySession) review(review ReviewResult, now time.Time) (StudyResponse, error) {
+	review.Mode = s.mode
+	word := text.Casefold(review.Word)
+	reviews := []ReviewResult{review}
+
+	// Same checks as reviews uploaded with flashcard requests.
+	results, err := quarantineReviews(s.authDB, s.userID, s.l1, s.l2, reviews, now)
+	if err != nil {
+		return StudyResponse{}, err
+	}
+	if results != nil {
+		delete(s.pending, word)
+		diff := s.tuner.Difficulty
+		return StudyResponse{Type: "result", Result: &results[0], Difficulty: &diff}, nil
+	}
+
+	isNew := !seenWords(s.con, reviews)[word]
+	results, err = saveReviewsTo(s.db, s.con, s.userID, s.l1, s.l2, s.mode, reviews, now)
+	if err != nil {
+		return StudyResponse{}, err
+	}
+	result := results[0]
+	delete(s.pending, word)
+
+	if result.OK && isNew {
+		changed := s.tuner.Update(review.Correct)
+		if err := s.updateDifficulty(); err != nil {
+			return StudyResponse{}, err
+		}
+		if changed {
+			// Refresh the bounds, because some levels might have run out of
+			// new words.
+			s.tuner.Difficulty = difficulty.GetLatest(s.con)
+		}
+	}
+
+	diff := s.tuner.Difficulty
+	return StudyResponse{Type: "result", Result: &result, Difficulty: &diff}, nil
+}
+
+// Saves the tuner's difficulty in the review DB.
+func (s *studySession) updateDifficulty() error {
+	defer database.LockReviewDB(reviewDBPath(s.userID, s.l1, s.l2, s.mode))()
+	return difficulty.Update(s.con, s.tuner.Difficulty)
+}
+
+// Handles messages until the client disconnects or stays idle for too long.
+func (s *studySession) serve(ws *websocket.Conn, sessionID string) {
+	ready, err := s.ready()
+	if err != nil {
+		log.Println(err)
+		_ = websocket.JSON.Send(ws, StudyResponse{Type: "error", Error: "Something went wrong."})
+		return
+	}
+	if err := websocket.JSON.Send(ws, ready); err != nil {
+		return
+	}
+
+	for {
+		if err := liveStudySessions.extendDeadline(ws); err != nil {
+			return
+		}
+
+		var req StudyRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Println(fmt.Errorf("live study session ended: %w", err))
+			}
+			return
+		}
+
+		var resp StudyResponse
+		switch req.Type {
+		case "next":
+			resp = s.next()
+		case "review":
+			if req.Review == nil {
+				resp = StudyResponse{Type: "error", Error: "Missing review."}
+				break
+			}
+			if !sessions.CheckCSRFToken(sessionID, req.CSRFToken) {
+				resp = StudyResponse{Type: "error", Error: "Forbidden."}
+				break
+			}
+			resp, err = s.review(*req.Review, time.Now())
+			if err != nil {
+				log.Println(err)
+				resp = StudyResponse{Type: "error", Error: "Something went wrong."}
+			}
+		default:
+			resp = StudyResponse{Type: "error", Error: "Unknown message type."}
+		}
+
+		if err := websocket.JSON.Send(ws, resp); err != nil {
+			return
+		}
+	}
+}
+
+// Rejects WebSocket handshakes from other sites.
+// Browsers send cookies with cross-site WebSocket requests, and the same-origin
+// policy doesn't apply to them.
+func checkStudyOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil || origin.Host != r.Host {
+		return errors.New("cross-origin WebSocket request")
+	}
+	config.Origin = origin
+	return nil
+}
+
+// HandlerFunc for live study sessions.
+// Query params:
+// - mode: exercise type (default: "cloze")
+func handleStudySocket(w http.ResponseWriter, r *http.Request) {
+	// Check if course exists.
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sign in.
+	s, err := sessions.ResumeSession(auth.GetDB(r), w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+	userID := s.Data["userID"].(int)
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = flashcards.ModeCloze
+	}
+	if !flashcards.IsValidMode(mode) {
+		http.Error(w, "Invalid mode.", http.StatusBadRequest)
+		return
+	}
+
+	db, con, err := openReviewStream(r, userID, l1, l2, mode)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+	defer con.Close()
+
+	thresholds, err := difficulty.GetThresholds(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	algorithm, err := difficulty.GetAlgorithm(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	session := studySession{
+		db:      db,
+		con:     con,
+		authDB:  auth.GetDB(r),
+		userID:  userID,
+		l1:      l1,
+		l2:      l2,
+		mode:    mode,
+		tuner:   difficulty.NewTuner(difficulty.GetLatest(con), thresholds, algorithm),
+		pending: make(map[string]bool),
+	}
+	server := websocket.Server{
+		Handshake: checkStudyOrigin,
+		Handler: func(ws *websocket.Conn) {
+			if !liveStudySessions.track(ws) {
+				return
+			}
+			defer liveStudySessions.untrack(ws)
+
+			ws.MaxPayloadBytes = maxStudyMessageSize
+			session.serve(ws, s.ID)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
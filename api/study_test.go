@@ -0,0 +1,100 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/polycloze/polycloze/flashcards"
+)
+
+func TestItemWords(t *testing.T) {
+	t.Parallel()
+
+	item := flashcards.Item{
+		Sentence: flashcards.Sentence{
+			Parts: []flashcards.Part{
+				{Text: "Hola, "},
+				{Text: "Mundo", Answers: []flashcards.Answer{{Text: "Mundo", Normalized: "mundo"}}},
+				{Text: "."},
+			},
+		},
+	}
+	words := itemWords(item)
+	if len(words) != 1 || words[0] != "mundo" {
+		t.Fatal("expected words in blanks:", words)
+	}
+}
+
+func TestCheckStudyOrigin(t *testing.T) {
+	t.Parallel()
+
+	for origin, ok := range map[string]bool{
+		"http://example.com":      true,
+		"https://evil.example.io": false,
+		"":                        false,
+	} {
+		r := httptest.NewRequest("GET", "http://example.com/api/study/eng/spa", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+
+		var config websocket.Config
+		err := checkStudyOrigin(&config, r)
+		if ok != (err == nil) {
+			t.Fatal("unexpected result of origin check:", origin, err)
+		}
+	}
+}
+
+func TestStudyRegistryClose(t *testing.T) {
+	// Should interrupt idle sessions and wait for them to end.
+	t.Parallel()
+
+	sr := newStudyRegistry()
+	started := make(chan struct{})
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		if !sr.track(ws) {
+			return
+		}
+		defer sr.untrack(ws)
+		close(started)
+
+		for {
+			if err := sr.extendDeadline(ws); err != nil {
+				return
+			}
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer ws.Close()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sr.close(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	// New sessions should be rejected.
+	if sr.track(ws) {
+		t.Fatal("expected new session to be rejected after closing")
+	}
+}
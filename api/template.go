@@ -7,7 +7,6 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
-	"log"
 	"net/http"
 )
 
@@ -23,11 +22,15 @@ var templates *template.Template = template.Must(
 )
 
 // Renders template.
+// Adds the user's display locale (see `negotiateLocale`) to the template data.
 // Replies with an internal server error when template execution fails.
 // Caller shouldn't make further writes in this case.
-func renderTemplate(w http.ResponseWriter, name string, data map[string]any) {
+func renderTemplate(w http.ResponseWriter, r *http.Request, name string, data map[string]any) {
+	if data == nil {
+		data = make(map[string]any)
+	}
+	data["locale"] = getLocale(r).String()
 	if err := templates.ExecuteTemplate(w, name, data); err != nil {
-		log.Println(fmt.Errorf("template execution error: %w", err))
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("template execution error: %w", err))
 	}
 }
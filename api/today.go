@@ -0,0 +1,181 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Max daily review goal.
+const maxDailyGoal = 10000
+
+type DailyGoal struct {
+	Reviews int  `json:"reviews"` // 0 if the user hasn't set a goal
+	Done    bool `json:"done"`
+}
+
+// Numbers shown in the study page header.
+type TodaySummary struct {
+	Reviews  int       `json:"reviews"`
+	NewWords int       `json:"newWords"`
+	Due      int       `json:"due"`
+	Streak   int       `json:"streak"`
+	Goal     DailyGoal `json:"goal"`
+}
+
+// Returns user's daily review goal (0 if none).
+func getDailyGoal(db *sql.DB) (int, error) {
+	var reviews int
+	err := db.QueryRow(`SELECT reviews FROM daily_goal`).Scan(&reviews)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get daily goal: %w", err)
+	}
+	return reviews, nil
+}
+
+func setDailyGoal(db *sql.DB, reviews int) error {
+	if reviews < 0 || reviews > maxDailyGoal {
+		return fmt.Errorf("failed to set daily goal: invalid value: %v", reviews)
+	}
+	query := `INSERT OR REPLACE INTO daily_goal (reviews) VALUES (?)`
+	if _, err := db.Exec(query, reviews); err != nil {
+		return fmt.Errorf("failed to set daily goal: %w", err)
+	}
+	return nil
+}
+
+// Computes today's summary from the user's review DB.
+func summarizeToday(db *sql.DB, now time.Time) (TodaySummary, error) {
+	var summary TodaySummary
+
+	activity, err := history.Today(db, now)
+	if err != nil {
+		return summary, err
+	}
+	summary.Reviews = activity.Reviews
+	summary.NewWords = activity.NewWords
+
+	summary.Due, err = review_scheduler.CountDue(db, now)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Streak, err = history.Streak(db, now)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Goal.Reviews, err = getDailyGoal(db)
+	if err != nil {
+		return summary, err
+	}
+	summary.Goal.Done = summary.Goal.Reviews > 0 && summary.Reviews >= summary.Goal.Reviews
+	return summary, nil
+}
+
+// Gets time zone from the `tz` URL search param (IANA time zone name).
+// Default: UTC.
+func getTimezone(r *http.Request) *time.Location {
+	if loc, err := time.LoadLocation(r.URL.Query().Get("tz")); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Responds with today's review summary.
+// Days are computed using the time zone in the `tz` URL search param.
+func handleToday(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	summary, err := summarizeToday(db, time.Now().In(getTimezone(r)))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, summary)
+}
+
+// GET: responds with user's daily review goal in the course.
+// POST: sets user's daily review goal (0 to remove the goal).
+func handleDailyGoal(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data DailyGoal
+	if r.Method == "POST" {
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if data.Reviews < 0 || data.Reviews > maxDailyGoal {
+			http.Error(w, "Invalid goal.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	if r.Method == "POST" {
+		if err := setDailyGoal(db, data.Reviews); err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	reviews, err := getDailyGoal(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]int{"reviews": reviews})
+}
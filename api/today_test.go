@@ -0,0 +1,48 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+func TestSummarizeToday(t *testing.T) {
+	// Goal should be done after enough reviews.
+	t.Parallel()
+
+	db, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	if err := setDailyGoal(db, 2); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := setDailyGoal(db, -1); err == nil {
+		t.Fatal("expected negative goal to be rejected")
+	}
+
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	for _, word := range []string{"foo", "bar"} {
+		if err := review_scheduler.UpdateReviewAt(db, word, false, now.Add(-time.Hour)); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	summary, err := summarizeToday(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if summary.Reviews != 2 || summary.NewWords != 2 || summary.Due != 2 {
+		t.Fatal("unexpected summary:", summary)
+	}
+	if summary.Streak != 1 || !summary.Goal.Done {
+		t.Fatal("unexpected summary:", summary)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Anonymous trial accounts.
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/hooks"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// HandlerFunc for starting a trial without registering.
+// Signs the visitor in to a new anonymous account.
+func handleTrial(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.NotFound(w, r)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.StartOrResumeSession(db, w, r)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if s.IsSignedIn() {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	userID, username, err := auth.RegisterAnonymous(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	s.Data["userID"] = userID
+	s.Data["username"] = username
+	if err := sessions.RotateSession(db, w, r, s); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if err := initUserDirectory(userID); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/welcome", http.StatusSeeOther)
+}
+
+// Moves user directory of anonymous account to the user's new ID.
+func migrateUserDirectory(anonymousID, userID int) error {
+	if _, err := os.Stat(basedir.User(userID)); !errors.Is(err, os.ErrNotExist) {
+		return errors.New("failed to migrate user directory: destination already exists")
+	}
+	return os.Rename(basedir.User(anonymousID), basedir.User(userID))
+}
+
+// HandlerFunc for converting trial accounts into permanent accounts.
+func handleClaim(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.Redirect(w, r, "/signin", http.StatusTemporaryRedirect)
+		return
+	}
+
+	anonymousID := s.Data["userID"].(int)
+	if !auth.IsAnonymous(db, anonymousID) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == "POST" {
+		username := r.FormValue("username")
+		password := r.FormValue("password")
+
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
+			_ = s.ErrorMessage("Something went wrong. Please try again.", "claim")
+			goto fail
+		}
+
+		// Move the files back if the claim fails after they've been moved.
+		var moved int
+		migrate := func(userID int) error {
+			if err := migrateUserDirectory(anonymousID, userID); err != nil {
+				return err
+			}
+			moved = userID
+			return nil
+		}
+		userID, err := auth.Claim(db, anonymousID, username, password, migrate)
+		if err != nil {
+			if moved != 0 {
+				if err := os.Rename(basedir.User(moved), basedir.User(anonymousID)); err != nil {
+					log.Println(err)
+				}
+			}
+			_ = s.ErrorMessage(
+				"This username is unavailable. Try another one.",
+				"claim",
+			)
+			goto fail
+		}
+
+		hooks.UserCreated(hooks.UserCreatedEvent{
+			UserID:   userID,
+			Username: username,
+			Time:     time.Now(),
+		})
+
+		// The anonymous account's sessions got deleted along with it.
+		s.Data["userID"] = userID
+		s.Data["username"] = username
+		if err := sessions.RotateSession(db, w, r, s); err != nil {
+			log.Println(err)
+			http.Redirect(w, r, "/signin", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+fail:
+	messages, _ := s.Messages("claim")
+	renderTemplate(w, r, "claim.html", map[string]any{
+		"csrfToken": sessions.CSRFToken(s.ID),
+		"messages":  messages,
+		"username":  s.Data["username"],
+	})
+}
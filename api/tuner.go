@@ -0,0 +1,326 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Difficulty tuner API.
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/wilson"
+)
+
+// GET: responds with the course's tuner thresholds.
+// POST: updates the course's tuner thresholds.
+func handleTunerSettings(w http.ResponseWriter, r *http.Request) {
+	// Check if course exists.
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sign in.
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Open user's review DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "GET":
+		thresholds, err := difficulty.GetThresholds(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, thresholds)
+		return
+	case "POST":
+		break
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	// An empty object resets the thresholds to the default values.
+	thresholds := wilson.DefaultThresholds()
+	if err := parseJSON(w, body, &thresholds); err != nil {
+		return
+	}
+	if !thresholds.IsValid() {
+		http.Error(w, "invalid thresholds", http.StatusBadRequest)
+		return
+	}
+
+	if err := difficulty.SetThresholds(db, thresholds); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, thresholds)
+}
+
+// Responds with the state of the difficulty tuner.
+// Tuner stats are computed from reviews in the range given by the `from` and
+// `to` search params (default: last week).
+func handleTuner(w http.ResponseWriter, r *http.Request) {
+	// Check if course exists.
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sign in.
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Open user's review DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	// Create database connection with access to review and course DB.
+	hook := database.AttachCourse(basedir.Course(l1, l2))
+	con, err := database.NewConnection(db, r.Context(), hook)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer con.Close()
+
+	thresholds, err := difficulty.GetThresholds(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	stats, err := difficulty.StatsByClass(con, getFrom(r), getTo(r))
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	decisions, err := difficulty.RecentDecisions(con, 10)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	algorithm, err := difficulty.GetAlgorithm(con)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	sendJSON(w, r, TunerResponse{
+		Algorithm:  algorithm,
+		Difficulty: difficulty.GetLatest(con),
+		Thresholds: thresholds,
+		Stats:      stats,
+		Decisions:  decisions,
+	})
+}
+
+// GET: responds with the course's tuner algorithm.
+// POST: sets the course's tuner algorithm ("wilson" or "bayes").
+func handleTunerAlgorithm(w http.ResponseWriter, r *http.Request) {
+	// Check if course exists.
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sign in.
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Open user's review DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	switch r.Method {
+	case "GET":
+		algorithm, err := difficulty.GetAlgorithm(db)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		sendJSON(w, r, TunerAlgorithmRequest{Algorithm: algorithm})
+		return
+	case "POST":
+		break
+	default:
+		http.Error(w, "expected GET or POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	var data TunerAlgorithmRequest
+	if err := parseJSON(w, body, &data); err != nil {
+		return
+	}
+	if !difficulty.IsValidAlgorithm(data.Algorithm) {
+		http.Error(w, "invalid tuner algorithm", http.StatusBadRequest)
+		return
+	}
+
+	if err := difficulty.SetAlgorithm(db, data.Algorithm); err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, data)
+}
+
+// Sets the difficulty level manually (e.g. to start at a higher frequency
+// class). If `pinned` is set, the tuner won't change the level until the user
+// unpins it. Omitting `level` unpins the current level.
+func handleDifficultyOverride(w http.ResponseWriter, r *http.Request) {
+	// Check request method and content type.
+	if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "expected JSON body in POST request", http.StatusBadRequest)
+		return
+	}
+
+	// Check if course exists.
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Sign in.
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Check csrf token.
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	// Read request data.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not read request: %w", err))
+		return
+	}
+
+	var data DifficultyOverrideRequest
+	if err := parseJSON(w, body, &data); err != nil {
+		return
+	}
+	if data.Level != nil && *data.Level < 0 {
+		http.Error(w, "invalid level", http.StatusBadRequest)
+		return
+	}
+
+	// Open user's review DB.
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	// Create database connection with access to review and course DB.
+	hook := database.AttachCourse(basedir.Course(l1, l2))
+	con, err := database.NewConnection(db, r.Context(), hook)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer con.Close()
+
+	if data.Level == nil {
+		err = difficulty.Unpin(con)
+	} else {
+		err = difficulty.Override(con, *data.Level, data.Pinned)
+	}
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, difficulty.GetLatest(con))
+}
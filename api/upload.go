@@ -4,10 +4,16 @@
 package api
 
 import (
+	"archive/zip"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -18,100 +24,305 @@ import (
 	"github.com/polycloze/polycloze/sessions"
 )
 
-// Checks if uploaded file size is too big.
-func isTooBig(size int64) bool {
-	// Limit to 8MB.
-	return size > 8*1024*1024
+// Default size limit of uploaded files (8MB).
+const DefaultMaxUploadSize = 8 * 1024 * 1024
+
+var errForbidden = errors.New("invalid CSRF token")
+
+// Error that can be shown to the user.
+type uploadError string
+
+func (e uploadError) Error() string {
+	return string(e)
+}
+
+// Result of importing a file in a zip upload.
+type uploadResult struct {
+	File    string `json:"file"`
+	Course  string `json:"course"` // e.g. eng-spa
+	Message string `json:"message"`
+	Success bool   `json:"success"`
 }
 
-func handleUpload(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "expected POST request", http.StatusBadRequest)
-		return
+// Turns error from importing an upload into a message for the user.
+func uploadErrorMessage(err error, maxSize int64) string {
+	var tooBig *http.MaxBytesError
+	var ue uploadError
+	switch {
+	case errors.As(err, &ue):
+		return ue.Error()
+	case errors.As(err, &tooBig):
+		return fmt.Sprintf("File is too big (>%vMB).", maxSize/(1024*1024))
+	case errors.Is(err, replay.ErrInvalidFormat), errors.Is(err, replay.ErrInvalidStats):
+		return fmt.Sprintf("Invalid file (%v).", err)
+	case errors.Is(err, replay.ErrHasExistingReviews):
+		return "Can't import data, because existing reviews were found. Try resetting your progress first."
+	default:
+		log.Println(err)
+		return "Something went wrong. Please try again."
 	}
+}
 
-	// Check if course exists.
-	l1 := chi.URLParam(r, "l1")
-	l2 := chi.URLParam(r, "l2")
-	if !courseExists(l1, l2) {
-		http.NotFound(w, r)
-		return
+// Checks if the uploaded file is a zip file.
+func isZip(part *multipart.Part) bool {
+	if strings.EqualFold(path.Ext(part.FileName()), ".zip") {
+		return true
 	}
+	mediaType, _, _ := strings.Cut(part.Header.Get("Content-Type"), ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "application/zip", "application/x-zip", "application/x-zip-compressed":
+		return true
+	}
+	return false
+}
 
-	// Check if user is signed in.
-	db := auth.GetDB(r)
-	s, err := sessions.ResumeSession(db, w, r)
-	if err != nil || !s.IsSignedIn() {
-		http.NotFound(w, r)
-		return
+// Suffix of tuner stats files in zip uploads (see `replay.ExportStats`).
+const statsFileSuffix = ".stats.json"
+
+// Gets course of exported reviews from the file name (e.g. eng-spa.csv).
+func courseFromFileName(name string) (string, string, bool) {
+	base := path.Base(name)
+	if !strings.EqualFold(path.Ext(base), ".csv") {
+		return "", "", false
+	}
+	l1, l2, ok := strings.Cut(strings.TrimSuffix(base, path.Ext(base)), "-")
+	if !ok {
+		return "", "", false
 	}
-	var message string
-	var success bool
-	userID := s.Data["userID"].(int)
+	return l1, l2, true
+}
 
-	// Check CSRF token.
-	csrfToken := r.FormValue("csrf-token")
-	if !sessions.CheckCSRFToken(s.ID, csrfToken) {
-		http.Error(w, "Forbidden.", http.StatusForbidden)
-		return
+// Imports CSV file into the review DB.
+func importCSV(r io.Reader, reviewDB string) error {
+	defer database.LockReviewDB(reviewDB)()
+
+	// TODO import into a new db instead?
+	db, err := database.OpenReviewDB(reviewDB)
+	if err != nil {
+		return fmt.Errorf("failed to import upload: %w", err)
 	}
+	defer db.Close()
+
+	// TODO connect to course db to filter out reviews that are not in the
+	// course database?
+	return replay.Replay(db, r)
+}
 
-	// Handle upload.
-	file, header, err := r.FormFile("csv-upload")
+// Imports tuner stats file into the review DB.
+func importStats(r io.Reader, reviewDB string) error {
+	defer database.LockReviewDB(reviewDB)()
+
+	db, err := database.OpenReviewDB(reviewDB)
 	if err != nil {
-		log.Println(err)
-		message = "Something went wrong. Please try again."
-		_ = s.ErrorMessage(message, "csv-upload")
-		goto fail
+		return fmt.Errorf("failed to import upload: %w", err)
 	}
+	defer db.Close()
+	return replay.ReplayStats(db, r)
+}
+
+// Imports a CSV file in a zip upload into the review DB of the course in its
+// name.
+// Tuner stats files (e.g. eng-spa.stats.json) also get imported.
+func importZipEntry(f *zip.File, userID int, maxSize int64) uploadResult {
+	result := uploadResult{File: f.Name}
 
-	if header.Header.Get("Content-Type") != "text/csv" {
-		message = "Not a CSV file."
-		_ = s.ErrorMessage(message, "csv-upload")
-		goto fail
+	name := f.Name
+	isStats := strings.HasSuffix(strings.ToLower(name), statsFileSuffix)
+	if isStats {
+		name = name[:len(name)-len(statsFileSuffix)] + ".csv"
 	}
 
-	if isTooBig(header.Size) {
-		message = "File is too big (>8MB)."
-		_ = s.ErrorMessage(message, "csv-upload")
-		goto fail
+	l1, l2, ok := courseFromFileName(name)
+	if !ok || !courseExists(l1, l2) {
+		result.Message = "Unknown course (the file should be named after the course, e.g. eng-spa.csv)."
+		return result
 	}
+	result.Course = fmt.Sprintf("%v-%v", l1, l2)
 
-	// Open user's review DB.
-	// TODO import into a new db instead?
-	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	// The zip reader checks that the file isn't bigger than this.
+	if f.UncompressedSize64 > uint64(maxSize) {
+		result.Message = fmt.Sprintf("File is too big (>%vMB).", maxSize/(1024*1024))
+		return result
+	}
+
+	rc, err := f.Open()
 	if err != nil {
-		log.Println(fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
-		message = "Something went wrong. Please try again."
-		_ = s.ErrorMessage(message, "csv-upload")
-		goto fail
+		result.Message = "Invalid file (can't be extracted)."
+		return result
 	}
-	defer db.Close()
+	defer rc.Close()
+
+	importFile := importCSV
+	if isStats {
+		importFile = importStats
+	}
+	if err := importFile(rc, basedir.Review(userID, l1, l2)); err != nil {
+		result.Message = uploadErrorMessage(err, maxSize)
+		return result
+	}
+	result.Message = "File uploaded."
+	result.Success = true
+	return result
+}
+
+// Imports CSV files in a zip upload into the review DBs of their courses.
+func importZip(r io.Reader, userID int, maxSize int64) ([]uploadResult, error) {
+	// Zip files can't be read as a stream, so the upload gets written to a
+	// temporary file first.
+	// The request body is already size-limited.
+	tmp, err := os.CreateTemp("", "polycloze-upload-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to import zip file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import zip file: %w", err)
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, uploadError("Invalid zip file.")
+	}
+
+	var results []uploadResult
+	for _, f := range zr.File {
+		// Skip directories and files added by archivers (e.g. __MACOSX/).
+		if f.FileInfo().IsDir() ||
+			strings.HasPrefix(path.Base(f.Name), ".") ||
+			strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		results = append(results, importZipEntry(f, userID, maxSize))
+	}
+	if len(results) == 0 {
+		return nil, uploadError("Zip file is empty.")
+	}
+	return results, nil
+}
 
-	// TODO connect to course db to filter out reviews that are not in the course
-	// database?
-	if err := replay.Replay(db, file); err != nil {
-		if errors.Is(err, replay.ErrHasExistingReviews) {
-			message = "Can't import data, because existing reviews were found. Try resetting your progress first."
+// Imports uploaded file into the review DB.
+// CSV files get imported into the current course.
+// Zip files may contain CSV files for several courses (e.g. eng-spa.csv), so
+// the result of each file is returned.
+// The multipart body is streamed part by part into the replay parser, so CSV
+// files don't get buffered in memory or on disk.
+// The CSRF token has to come before the file if it's not in the header.
+func importUpload(r *http.Request, sessionID string, userID int, l1, l2 string, maxSize int64) ([]uploadResult, error) {
+	token := r.Header.Get("X-CSRF-Token")
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to import upload: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, uploadError("No file uploaded.")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to import upload: %w", err)
+		}
+
+		switch part.FormName() {
+		case "csrf-token":
+			value, err := io.ReadAll(io.LimitReader(part, 1024))
+			if err != nil {
+				return nil, fmt.Errorf("failed to import upload: %w", err)
+			}
+			token = string(value)
+		case "csv-upload":
+			if !sessions.CheckCSRFToken(sessionID, token) {
+				return nil, errForbidden
+			}
+			if isZip(part) {
+				return importZip(part, userID, maxSize)
+			}
+
+			// Browsers don't agree on the content type of CSV files, so the
+			// content has to be checked.
+			if !replay.IsCSVContentType(part.Header.Get("Content-Type")) {
+				return nil, uploadError("Not a CSV or zip file.")
+			}
+			return nil, importCSV(part, basedir.Review(userID, l1, l2))
+		}
+	}
+}
+
+func handleUpload(maxSize int64) http.HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "expected POST request", http.StatusBadRequest)
+			return
+		}
+
+		// Check if course exists.
+		l1 := chi.URLParam(r, "l1")
+		l2 := chi.URLParam(r, "l2")
+		if !courseExists(l1, l2) {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Check if user is signed in.
+		db := auth.GetDB(r)
+		s, err := sessions.ResumeSession(db, w, r)
+		if err != nil || !s.IsSignedIn() {
+			http.NotFound(w, r)
+			return
+		}
+		userID := s.Data["userID"].(int)
+
+		// Handle upload.
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+		results, err := importUpload(r, s.ID, userID, l1, l2, maxSize)
+		if errors.Is(err, errForbidden) {
+			http.Error(w, "Forbidden.", http.StatusForbidden)
+			return
+		}
+
+		// Don't redirect to settings page.
+		// Client might use this API by using fetch.
+		if err != nil {
+			message := uploadErrorMessage(err, maxSize)
 			_ = s.ErrorMessage(message, "csv-upload")
-			goto fail
+			sendJSON(w, r, map[string]any{
+				"message": message,
+				"success": false,
+			})
+			return
+		}
+		if results == nil {
+			message := "File uploaded."
+			_ = s.SuccessMessage(message, "csv-upload")
+			sendJSON(w, r, map[string]any{
+				"message": message,
+				"success": true,
+			})
+			return
 		}
 
-		log.Println(err)
-		message = "Something went wrong. Please try again."
-		_ = s.ErrorMessage(message, "csv-upload")
-		goto fail
-	}
-
-	success = true
-	message = "File uploaded."
-	_ = s.SuccessMessage(message, "csv-upload")
-
-fail:
-	// Don't redirect to settings page.
-	// Client might use this API by using fetch.
-	sendJSON(w, map[string]any{
-		"message": message,
-		"success": success,
-	})
+		// Summarize results of zip upload.
+		imported := 0
+		for _, result := range results {
+			message := fmt.Sprintf("%v: %v", result.File, result.Message)
+			if result.Success {
+				imported++
+				_ = s.SuccessMessage(message, "csv-upload")
+			} else {
+				_ = s.ErrorMessage(message, "csv-upload")
+			}
+		}
+		sendJSON(w, r, map[string]any{
+			"message": fmt.Sprintf("Imported %v of %v files.", imported, len(results)),
+			"success": imported == len(results),
+			"results": results,
+		})
+	}
 }
@@ -0,0 +1,63 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCourseFromFileName(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		l1   string
+		l2   string
+		ok   bool
+	}{
+		{"eng-spa.csv", "eng", "spa", true},
+		{"exports/eng-deu.CSV", "eng", "deu", true},
+		{"eng-spa.txt", "", "", false},
+		{"reviews.csv", "", "", false},
+	}
+	for _, c := range cases {
+		l1, l2, ok := courseFromFileName(c.name)
+		if l1 != c.l1 || l2 != c.l2 || ok != c.ok {
+			t.Fatal("unexpected course:", c.name, l1, l2, ok)
+		}
+	}
+}
+
+func TestImportZip(t *testing.T) {
+	// Files for unknown courses should be reported, and files added by
+	// archivers should be skipped.
+	t.Parallel()
+
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range []string{"xxx-yyy.csv", "__MACOSX/._xxx-yyy.csv", ".DS_Store"} {
+		if _, err := w.Create(name); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	results, err := importZip(&b, 1, DefaultMaxUploadSize)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results) != 1 || results[0].File != "xxx-yyy.csv" || results[0].Success {
+		t.Fatal("expected one failed result:", results)
+	}
+
+	var ue uploadError
+	if _, err := importZip(bytes.NewReader([]byte("not a zip")), 1, DefaultMaxUploadSize); !errors.As(err, &ue) {
+		t.Fatal("expected uploadError:", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+type UserSentenceRequest struct {
+	Word        string `json:"word"`
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+}
+
+// GET: responds with sentences written by the user (optionally only for the
+// word in the `word` URL search param).
+// POST: adds sentence for a word.
+func handleUserSentences(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data UserSentenceRequest
+	if r.Method == "POST" && !readPostJSON(w, r, s, &data) {
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	word := r.URL.Query().Get("word")
+	if r.Method == "POST" {
+		_, err := sentences.AddAuthoredSentence(con, data.Word, data.Text, data.Translation)
+		switch {
+		case errors.Is(err, sentences.ErrUnknownWord):
+			http.Error(w, "Word is not in the course.", http.StatusBadRequest)
+			return
+		case errors.Is(err, sentences.ErrWordNotInSentence):
+			http.Error(w, "Word does not appear in the sentence.", http.StatusBadRequest)
+			return
+		case err != nil:
+			serverError(w, r, err)
+			return
+		}
+		word = data.Word
+	}
+
+	result, err := sentences.GetAuthoredSentences(con, word)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"sentences": result})
+}
+
+// Deletes sentence written by the user, or a sentence mined from their texts.
+func handleDeleteUserSentence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if !courseExists(l1, l2) || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !sessions.CheckRequestCSRFToken(s.ID, r) {
+		http.Error(w, "Forbidden.", http.StatusForbidden)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	con, closeAll, err := openCourseConnection(r, userID, l1, l2)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer closeAll()
+
+	found, err := sentences.DeleteUserSentence(con, id)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
@@ -5,19 +5,25 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/text/collate"
 
 	"github.com/polycloze/polycloze/auth"
 	"github.com/polycloze/polycloze/basedir"
 	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
 	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/text"
+	"github.com/polycloze/polycloze/word_scheduler"
 )
 
 type Word struct {
@@ -46,24 +52,153 @@ func handleVocabulary(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
 	if err != nil {
-		log.Println(fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
 		return
 	}
 	defer db.Close()
 
 	q := r.URL.Query()
-	results, err := searchVocabulary(db, getLimit(q), getAfter(q), getSortBy(q))
+	sortBy := getSortBy(q)
+	var results []Word
+	if sortBy == "word" {
+		results, err = searchVocabularyCollated(db, getLimit(q), getAfter(q), courseCollator(l1, l2))
+	} else {
+		results, err = searchVocabulary(db, getLimit(q), getAfter(q), sortBy)
+	}
 	if err != nil {
-		log.Println(fmt.Errorf("search error: %w", err))
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, fmt.Errorf("search error: %w", err))
 		return
 	}
-	sendJSON(w, map[string][]Word{
+	sendJSON(w, r, map[string][]Word{
 		"words": results,
 	})
 }
 
+type ForgetRequest struct {
+	Word string `json:"word"`
+}
+
+// Deletes review history of a word (in both recognition and production
+// reviews), so that it gets introduced as a new word again.
+func handleForgetWord(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data ForgetRequest
+	if !readPostJSON(w, r, s, &data) {
+		return
+	}
+	word := text.Casefold(data.Word)
+	if word == "" {
+		http.Error(w, "Missing word.", http.StatusBadRequest)
+		return
+	}
+
+	userID := s.Data["userID"].(int)
+	forgotten := false
+	for _, path := range []string{
+		basedir.Review(userID, l1, l2),
+		basedir.ProductionReview(userID, l1, l2),
+	} {
+		found, err := forgetWord(path, word)
+		if err != nil {
+			serverError(w, r, err)
+			return
+		}
+		forgotten = forgotten || found
+	}
+
+	if !forgotten {
+		http.NotFound(w, r)
+		return
+	}
+	sendJSON(w, r, map[string]any{"ok": true})
+}
+
+// Deletes review history of word in review DB.
+// Skips review DBs that don't exist.
+func forgetWord(path, word string) (bool, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	defer database.LockReviewDB(path)()
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	return review_scheduler.Forget(db, word)
+}
+
+// GET: responds with user's word priority overrides.
+// POST: sets priority of a word (`{"word": ..., "priority": ...}`).
+func handleWordPriority(w http.ResponseWriter, r *http.Request) {
+	db := auth.GetDB(r)
+	s, err := sessions.ResumeSession(db, w, r)
+	if err != nil || !s.IsSignedIn() {
+		http.NotFound(w, r)
+		return
+	}
+
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var data word_scheduler.Priority
+	if r.Method == "POST" {
+		if !readPostJSON(w, r, s, &data) {
+			return
+		}
+		if data.Priority < word_scheduler.MinPriority || data.Priority > word_scheduler.MaxPriority {
+			http.Error(w, "Invalid priority.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := s.Data["userID"].(int)
+	db, err = database.OpenReviewDB(basedir.Review(userID, l1, l2))
+	if err != nil {
+		serverError(w, r, fmt.Errorf("could not open review database (%v-%v): %w", l1, l2, err))
+		return
+	}
+	defer db.Close()
+
+	if r.Method == "POST" {
+		if err := word_scheduler.SetPriority(db, data.Word, data.Priority); err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	priorities, err := word_scheduler.GetPriorities(db)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	sendJSON(w, r, map[string]any{"priorities": priorities})
+}
+
 // Gets limit from URL query.
 // If the limit is not in the URL query or is invalid, returns the default (20).
 func getLimit(q url.Values) int {
@@ -181,3 +316,73 @@ func searchVocabulary(db *sql.DB, limit int, after string, sortBy string) ([]Wor
 	}
 	return words, nil
 }
+
+// Like `searchVocabulary` with `sortBy = "word"`, but sorts words using the
+// collation rules of the course language (e.g. so that Spanish ñ comes after
+// n instead of after z).
+// Words after `after` in collation order are returned.
+// SQLite only sorts strings by byte order, so all words are sorted in Go.
+func searchVocabularyCollated(db *sql.DB, limit int, after string, c *collate.Collator) ([]Word, error) {
+	// Cap limit.
+	if limit < 10 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	query := `SELECT item FROM review`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("vocabulary search failed: %w", err)
+	}
+	defer rows.Close()
+
+	var items []string
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, fmt.Errorf("vocabulary search failed: %w", err)
+		}
+		if after == "" || compareWords(c, item, after) > 0 {
+			items = append(items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("vocabulary search failed: %w", err)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return compareWords(c, items[i], items[j]) < 0
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	intervals, err := queryIntervalStrengths(db)
+	if err != nil {
+		return nil, fmt.Errorf("vocabulary search failed: %w", err)
+	}
+
+	query = `
+		SELECT learned, reviewed, due, interval
+		FROM review JOIN interval USING (interval)
+		WHERE item = ?
+	`
+	words := make([]Word, 0, len(items))
+	for _, item := range items {
+		vocab := Word{Word: item}
+		var learned, reviewed, due int64
+		var interval int
+		err := db.QueryRow(query, item).Scan(&learned, &reviewed, &due, &interval)
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary search failed: %w", err)
+		}
+		vocab.Learned = time.Unix(learned, 0)
+		vocab.Reviewed = time.Unix(reviewed, 0)
+		vocab.Due = time.Unix(due, 0)
+		vocab.Strength = intervals[interval]
+		words = append(words, vocab)
+	}
+	return words, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestSearchVocabularyCollated(t *testing.T) {
+	// Spanish ñ should come after n instead of after z.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Now()
+	for _, word := range []string{"zorro", "ñu", "oso", "nube"} {
+		if err := review_scheduler.UpdateReviewAt(db, word, true, now); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	c := collate.New(language.Spanish)
+	words, err := searchVocabularyCollated(db, 10, "", c)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	expected := []string{"nube", "ñu", "oso", "zorro"}
+	if len(words) != len(expected) {
+		t.Fatal("unexpected number of words:", words)
+	}
+	for i, word := range words {
+		if word.Word != expected[i] {
+			t.Fatal("unexpected order:", words)
+		}
+	}
+
+	// Pagination should use the same order.
+	words, err = searchVocabularyCollated(db, 10, "nube", c)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 3 || words[0].Word != "ñu" {
+		t.Fatal("expected words after nube:", words)
+	}
+}
+
+func TestForgetWordLocksReviewDB(t *testing.T) {
+	// Reviews shouldn't be deleted while another writer holds the lock.
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "review.db")
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := review_scheduler.UpdateReviewAt(db, "foo", true, time.Now()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.Close()
+
+	unlock := database.LockReviewDB(path)
+
+	done := make(chan bool)
+	go func() {
+		found, err := forgetWord(path, "foo")
+		if err != nil {
+			t.Error("expected err to be nil:", err)
+		}
+		done <- found
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected forget to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case found := <-done:
+		if !found {
+			t.Fatal("expected word to be forgotten")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected word to be forgotten after the lock is released")
+	}
+}
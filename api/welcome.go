@@ -5,13 +5,9 @@ package api
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"sort"
 
 	"github.com/polycloze/polycloze/auth"
@@ -77,16 +73,14 @@ func handleWelcome(w http.ResponseWriter, r *http.Request) {
 	userID := s.Data["userID"].(int)
 	db, err = database.OpenUserDB(basedir.UserData(userID))
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	}
 	defer db.Close()
 
 	// Redirect if the user has already been welcomed (i.e. course has been set).
 	if course, err := getActiveCourse(db); err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		serverError(w, r, err)
 		return
 	} else if course != "" {
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -97,14 +91,13 @@ func handleWelcome(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		selectedL1 := r.FormValue("l1")
 		selectedL2 := r.FormValue("l2")
-		csrfToken := r.FormValue("csrf-token")
 
 		if !courseExists(selectedL1, selectedL2) {
 			// This happens when user gets redirected from the sign-in page.
 			goto show
 		}
 
-		if !sessions.CheckCSRFToken(s.ID, csrfToken) {
+		if !sessions.CheckRequestCSRFToken(s.ID, r) {
 			_ = s.ErrorMessage("Something went wrong. Please try again.", "welcome")
 			goto show
 		}
@@ -120,29 +113,7 @@ func handleWelcome(w http.ResponseWriter, r *http.Request) {
 
 show:
 
-	// Read and parse courses.json to get list of courses.
-	path := filepath.Join(basedir.StateDir, "courses.json")
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
-
-	var data map[string][]Course
-	if err := json.Unmarshal(bytes, &data); err != nil {
-		log.Println(err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
-
-	// Extract courses from data.
-	courses, ok := data["courses"]
-	if !ok {
-		log.Println("malformed courses.json")
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
+	courses := localizeCourses(courseCatalog.Courses(), displayLanguage(r))
 
 	// Get L1 and L2 languages.
 	var l1Options []Language
@@ -171,5 +142,5 @@ show:
 	s.Data["l2Options"] = l2Options
 	s.Data["courses"] = courses
 	s.Data["messages"], _ = s.Messages("welcome")
-	renderTemplate(w, "welcome.html", s.Data)
+	renderTemplate(w, r, "welcome.html", s.Data)
 }
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/search"
+)
+
+// Returns ETag of course file.
+// Changes when the course gets updated.
+func courseETag(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute ETag of course: %w", err)
+	}
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// Serves list of words in the course, sorted by frequency class.
+// Responds with CSV (word, frequency class) if the `format` search param is
+// "csv", or else with JSON.
+// The list is streamed from the course DB, and can be revalidated using the
+// ETag.
+// Doesn't require sign in, like /api/sentences.
+func handleWords(w http.ResponseWriter, r *http.Request) {
+	l1 := chi.URLParam(r, "l1")
+	l2 := chi.URLParam(r, "l2")
+	if !courseExists(l1, l2) {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := basedir.Course(l1, l2)
+	etag, err := courseETag(path)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	db, err := database.Open(path)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer db.Close()
+
+	query := `SELECT word, frequency_class FROM word ORDER BY frequency_class, id`
+	rows, err := db.Query(query)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	// The response can't be changed into an error response after it has
+	// started, so errors below only get logged.
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = streamWordsCSV(w, rows)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = streamWordsJSON(w, rows)
+	}
+	if err != nil {
+		log.Println(fmt.Errorf("failed to send word list (%v-%v): %w", l1, l2, err))
+	}
+}
+
+func streamWordsCSV(w http.ResponseWriter, rows *sql.Rows) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"word", "frequency_class"}); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var word search.Word
+		if err := rows.Scan(&word.Word, &word.FrequencyClass); err != nil {
+			return err
+		}
+		record := []string{word.Word, strconv.Itoa(word.FrequencyClass)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// Writes `{"words": [...]}` one word at a time.
+func streamWordsJSON(w http.ResponseWriter, rows *sql.Rows) error {
+	if _, err := w.Write([]byte(`{"words":[`)); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for i := 0; rows.Next(); i++ {
+		var word search.Word
+		if err := rows.Scan(&word.Word, &word.FrequencyClass); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(word); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err := w.Write([]byte("]}\n"))
+	return err
+}
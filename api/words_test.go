@@ -0,0 +1,61 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/polycloze/polycloze/search"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestStreamWords(t *testing.T) {
+	// Streamed word lists should be valid CSV and JSON.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `INSERT INTO word (word, frequency_class) VALUES ('foo', 1), ('bar', 0)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	query = `SELECT word, frequency_class FROM word ORDER BY frequency_class, id`
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	w := httptest.NewRecorder()
+	if err := streamWordsCSV(w, rows); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	rows.Close()
+	if body := w.Body.String(); body != "word,frequency_class\nbar,0\nfoo,1\n" {
+		t.Fatal("unexpected CSV:", body)
+	}
+
+	rows, err = db.Query(query)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	w = httptest.NewRecorder()
+	if err := streamWordsJSON(w, rows); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	rows.Close()
+
+	var data struct {
+		Words []search.Word `json:"words"`
+	}
+	if err := json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&data); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(data.Words) != 2 || data.Words[0].Word != "bar" || data.Words[1].FrequencyClass != 1 {
+		t.Fatal("unexpected words:", data.Words)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Words that the user is assumed to know, so that advanced learners can skip
+// easy words.
+// Words in the most frequent frequency classes get mature reviews without
+// being studied (see `review_scheduler.AssumeKnownTx`).
+// The reviews are tagged in the `assumed` table until the user reviews the
+// word, so they can be undone by lowering the setting.
+// Assumed words that the user fails get demoted like any other word.
+package assumed
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+// Max value of the setting.
+const MaxFrequencyClasses = 100
+
+type Status struct {
+	// Number of most frequent frequency classes that the user is assumed to
+	// know.
+	FrequencyClasses int `json:"frequencyClasses"`
+
+	// Number of assumed words that the user hasn't reviewed yet.
+	Words int `json:"words"`
+}
+
+// Returns the user's setting and the number of unconfirmed assumed words.
+func Get[T database.Querier](q T) (Status, error) {
+	var status Status
+	query := `SELECT frequency_classes FROM assumed_setting`
+	err := q.QueryRow(query).Scan(&status.FrequencyClasses)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status, fmt.Errorf("failed to get assumed words: %w", err)
+	}
+
+	query = `SELECT count(*) FROM assumed`
+	if err := q.QueryRow(query).Scan(&status.Words); err != nil {
+		return status, fmt.Errorf("failed to get assumed words: %w", err)
+	}
+	return status, nil
+}
+
+// Assumes that the user knows words in the n most frequent frequency classes
+// (i.e. words with frequency class < n), and undoes unconfirmed assumed words
+// in the other frequency classes.
+// Set n to 0 to undo all unconfirmed assumed words.
+// Database connection should have access to course and review data.
+// Returns the number of words that got added and removed.
+func Set[T database.Querier](q T, n int, now time.Time) (added, removed int, err error) {
+	if n < 0 || n > MaxFrequencyClasses {
+		return 0, 0, fmt.Errorf("failed to set assumed words: invalid number of frequency classes: %v", n)
+	}
+
+	tx, err := q.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to set assumed words: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed, err = undo(tx, n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to set assumed words: %w", err)
+	}
+	added, err = assume(tx, n, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to set assumed words: %w", err)
+	}
+
+	query := `INSERT OR REPLACE INTO assumed_setting (frequency_classes) VALUES (?)`
+	if _, err := tx.Exec(query, n); err != nil {
+		return 0, 0, fmt.Errorf("failed to set assumed words: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to set assumed words: %w", err)
+	}
+	return added, removed, nil
+}
+
+// Deletes reviews of unconfirmed assumed words with frequency class >= n.
+// The triggers on the review table remove them from the `assumed` table.
+func undo(tx *sql.Tx, n int) (int, error) {
+	query := `
+		DELETE FROM review WHERE item IN (
+			SELECT item FROM assumed
+			WHERE item NOT IN (SELECT word FROM word WHERE frequency_class < ?)
+		)
+	`
+	result, err := tx.Exec(query, n)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// Creates reviews of unreviewed words with frequency class < n.
+func assume(tx *sql.Tx, n int, now time.Time) (int, error) {
+	query := `
+		SELECT word FROM word
+		WHERE frequency_class < ? AND word NOT IN (SELECT item FROM review)
+		ORDER BY id
+	`
+	rows, err := tx.Query(query, n)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var words []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return 0, err
+		}
+		words = append(words, word)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	added, err := rs.AssumeKnownTx(tx, words, now)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `INSERT OR IGNORE INTO assumed (item, added) VALUES (?, ?)`
+	for _, word := range added {
+		if _, err := tx.Exec(query, word, now.Unix()); err != nil {
+			return 0, err
+		}
+	}
+	return len(added), nil
+}
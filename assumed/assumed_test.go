@@ -0,0 +1,68 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package assumed
+
+import (
+	"testing"
+	"time"
+
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `
+		INSERT INTO word (word, frequency_class) VALUES
+			('a', 0), ('b', 0), ('c', 1), ('d', 2)
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := rs.UpdateReview(db, "b", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	now := time.Now()
+	added, removed, err := Set(db, 2, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if added != 2 || removed != 0 {
+		t.Fatal("expected unreviewed words in top two frequency classes to be assumed:", added, removed)
+	}
+
+	// Reviewed words should no longer be undone.
+	if err := rs.UpdateReviewAt(db, "c", false, now.Add(time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	status, err := Get(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if status.FrequencyClasses != 2 || status.Words != 1 {
+		t.Fatal("unexpected status:", status)
+	}
+
+	added, removed, err = Set(db, 0, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if added != 0 || removed != 1 {
+		t.Fatal("expected unconfirmed assumed word to be undone:", added, removed)
+	}
+
+	items, err := rs.ScheduleReview(db, now.Add(24*time.Hour), -1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 2 {
+		t.Fatal("expected reviews of b and c to be kept:", items)
+	}
+}
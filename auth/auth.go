@@ -5,10 +5,14 @@
 package auth
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/polycloze/polycloze/sessions"
 )
 
 func saltHashPassword(password string) string {
@@ -34,7 +38,10 @@ func Register(db *sql.DB, username, password string) error {
 func Authenticate(db *sql.DB, username, password string) (int, error) {
 	var id int
 	var hash string
-	query := `SELECT id, password FROM user WHERE username = ?`
+	query := `
+		SELECT id, password FROM user
+		WHERE username = ? AND merged_into IS NULL AND NOT anonymous AND active
+	`
 	err := db.QueryRow(query, username).Scan(&id, &hash)
 
 	if err != nil && hash != "" {
@@ -54,3 +61,203 @@ func ChangePassword(db *sql.DB, userID int, password string) error {
 	}
 	return nil
 }
+
+// Returns ID of user with the given username.
+func GetUserID(db *sql.DB, username string) (int, error) {
+	var id int
+	query := `SELECT id FROM user WHERE username = ?`
+	if err := db.QueryRow(query, username).Scan(&id); err != nil {
+		return 0, errors.New("unable to find user")
+	}
+	return id, nil
+}
+
+// Deletes user from the auth DB.
+// Also deletes the user's sessions.
+// Doesn't delete the user's files.
+func DeleteUser(db *sql.DB, userID int) error {
+	query := `DELETE FROM user WHERE id = ?`
+	if _, err := db.Exec(query, userID); err != nil {
+		return errors.New("unable to delete user")
+	}
+	if err := sessions.DeleteUserSessions(db, userID); err != nil {
+		return errors.New("unable to delete user")
+	}
+	return nil
+}
+
+// Checks if user is an admin.
+func IsAdmin(db *sql.DB, userID int) bool {
+	var admin bool
+	query := `SELECT admin FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&admin); err != nil {
+		return false
+	}
+	return admin
+}
+
+// Grants or revokes admin privileges.
+func SetAdmin(db *sql.DB, userID int, admin bool) error {
+	query := `UPDATE user SET admin = ? WHERE id = ?`
+	if _, err := db.Exec(query, admin, userID); err != nil {
+		return errors.New("unable to update admin status")
+	}
+	return nil
+}
+
+// Activates or deactivates user.
+// Deactivated users can't sign in, and get signed out of all sessions.
+func SetActive(db *sql.DB, userID int, active bool) error {
+	query := `UPDATE user SET active = ? WHERE id = ?`
+	if _, err := db.Exec(query, active, userID); err != nil {
+		return errors.New("unable to update active status")
+	}
+	if !active {
+		if err := sessions.DeleteUserSessions(db, userID); err != nil {
+			return errors.New("unable to update active status")
+		}
+	}
+	return nil
+}
+
+// Checks if user is active.
+func IsActive(db *sql.DB, userID int) bool {
+	var active bool
+	query := `SELECT active FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&active); err != nil {
+		return false
+	}
+	return active
+}
+
+// Marks user as merged into another account.
+// The merged user can no longer sign in, and gets signed out of all sessions.
+func Tombstone(db *sql.DB, userID, mergedInto int) error {
+	if userID == mergedInto {
+		return errors.New("unable to merge user into itself")
+	}
+
+	query := `UPDATE user SET merged_into = ? WHERE id = ? AND merged_into IS NULL`
+	result, err := db.Exec(query, mergedInto, userID)
+	if err != nil {
+		return errors.New("unable to tombstone user")
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return errors.New("unable to tombstone user")
+	}
+	if err := sessions.DeleteUserSessions(db, userID); err != nil {
+		return errors.New("unable to tombstone user")
+	}
+	return nil
+}
+
+// Checks if user has been merged into another account.
+func IsTombstoned(db *sql.DB, userID int) bool {
+	var mergedInto sql.NullInt64
+	query := `SELECT merged_into FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&mergedInto); err != nil {
+		return false
+	}
+	return mergedInto.Valid
+}
+
+// Returns random hex string with n bytes of entropy.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Creates anonymous trial account with a generated username.
+// Nobody can sign in to the account, so it's only accessible from the session
+// it gets assigned to.
+// Returns user ID and username of the new account.
+func RegisterAnonymous(db *sql.DB) (int, string, error) {
+	suffix, err := randomHex(8)
+	if err != nil {
+		return 0, "", errors.New("unable to register anonymous user")
+	}
+	password, err := randomHex(16)
+	if err != nil {
+		return 0, "", errors.New("unable to register anonymous user")
+	}
+
+	username := "guest-" + suffix
+	query := `INSERT INTO user (username, password, anonymous) VALUES (?, ?, 1)`
+	result, err := db.Exec(query, username, saltHashPassword(password))
+	if err != nil {
+		return 0, "", errors.New("unable to register anonymous user")
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, "", errors.New("unable to register anonymous user")
+	}
+	return int(id), username, nil
+}
+
+// Checks if user is an anonymous trial account.
+func IsAnonymous(db *sql.DB, userID int) bool {
+	var anonymous bool
+	query := `SELECT anonymous FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&anonymous); err != nil {
+		return false
+	}
+	return anonymous
+}
+
+// Converts anonymous trial account into a permanent account with the given
+// username and password.
+// The permanent account gets a new user ID, and the anonymous account gets
+// deleted along with its sessions.
+// `migrate` gets called with the new user ID before the changes are committed,
+// so the caller can move the user's files. The claim fails if `migrate`
+// returns an error.
+// Returns the new user ID.
+func Claim(
+	db *sql.DB,
+	anonymousID int,
+	username string,
+	password string,
+	migrate func(userID int) error,
+) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+	defer tx.Rollback()
+
+	// Insert the permanent account first, so that it doesn't reuse the
+	// anonymous account's ID.
+	query := `INSERT INTO user (username, password) VALUES (?, ?)`
+	result, err := tx.Exec(query, username, saltHashPassword(password))
+	if err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+
+	query = `DELETE FROM user WHERE id = ? AND anonymous`
+	result, err = tx.Exec(query, anonymousID)
+	if err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return 0, errors.New("unable to claim account")
+	}
+
+	if err := migrate(int(id)); err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, errors.New("unable to claim account")
+	}
+
+	// The claim has already been committed, and sessions of deleted users get
+	// rejected anyway, so errors are ignored.
+	_ = sessions.DeleteUserSessions(db, anonymousID)
+	return int(id), nil
+}
@@ -5,8 +5,10 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/polycloze/polycloze/database"
 )
@@ -199,3 +201,185 @@ func TestChangePasswordStorage(t *testing.T) {
 		t.Fatal("password should not be stored in plaintext")
 	}
 }
+
+func TestDeleteUser(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	if err := Register(db, "foo", "bar"); err != nil {
+		t.Fatal("initial registration should succeed:", err)
+	}
+
+	id, err := GetUserID(db, "foo")
+	if err != nil {
+		t.Fatal("expected to find registered user:", err)
+	}
+	if err := DeleteUser(db, id); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := GetUserID(db, "foo"); err == nil {
+		t.Fatal("expected deleted user to be gone")
+	}
+}
+
+func TestSetAdmin(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	if err := Register(db, "foo", "bar"); err != nil {
+		t.Fatal("initial registration should succeed:", err)
+	}
+	id, err := GetUserID(db, "foo")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if IsAdmin(db, id) {
+		t.Fatal("new users shouldn't be admins")
+	}
+	if err := SetAdmin(db, id, true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !IsAdmin(db, id) {
+		t.Fatal("expected user to be an admin")
+	}
+}
+
+func TestAuthenticateTombstoned(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	for _, username := range []string{"foo", "bar"} {
+		if err := Register(db, username, "baz"); err != nil {
+			t.Fatal("initial registration should succeed:", err)
+		}
+	}
+	foo, _ := GetUserID(db, "foo")
+	bar, _ := GetUserID(db, "bar")
+
+	if err := Tombstone(db, bar, bar); err == nil {
+		t.Fatal("user shouldn't be merged into itself")
+	}
+	if err := Tombstone(db, bar, foo); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !IsTombstoned(db, bar) || IsTombstoned(db, foo) {
+		t.Fatal("expected only merged user to be tombstoned")
+	}
+	if _, err := Authenticate(db, "bar", "baz"); err == nil {
+		t.Fatal("authentication should fail if user has been merged")
+	}
+	if err := Register(db, "bar", "baz"); err == nil {
+		t.Fatal("username of merged user shouldn't be reusable")
+	}
+}
+
+func TestClaimAnonymous(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	anonymousID, username, err := RegisterAnonymous(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !IsAnonymous(db, anonymousID) {
+		t.Fatal("expected trial account to be anonymous")
+	}
+	if _, err := Authenticate(db, username, ""); err == nil {
+		t.Fatal("authentication should fail for anonymous users")
+	}
+
+	// Failed migrations should roll back the claim.
+	fail := func(int) error { return errors.New("failed") }
+	if _, err := Claim(db, anonymousID, "foo", "bar", fail); err == nil {
+		t.Fatal("claim should fail if migration fails")
+	}
+	if !IsAnonymous(db, anonymousID) {
+		t.Fatal("expected trial account to be kept after failed claim")
+	}
+
+	var migrated int
+	migrate := func(userID int) error {
+		migrated = userID
+		return nil
+	}
+	userID, err := Claim(db, anonymousID, "foo", "bar", migrate)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if userID == anonymousID || userID != migrated {
+		t.Fatal("expected claimed account to get a new user ID:", userID, migrated)
+	}
+	if _, err := Authenticate(db, "foo", "bar"); err != nil {
+		t.Fatal("authentication should succeed after claiming account:", err)
+	}
+	if _, err := GetUserID(db, username); err == nil {
+		t.Fatal("expected anonymous account to be deleted")
+	}
+	if _, err := Claim(db, userID, "baz", "bar", migrate); err == nil {
+		t.Fatal("claim should fail if account isn't anonymous")
+	}
+}
+
+func TestSignInToken(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	if err := Register(db, "foo", "bar"); err != nil {
+		t.Fatal("initial registration should succeed:", err)
+	}
+	id, _ := GetUserID(db, "foo")
+
+	now := time.Now()
+	token, err := CreateSignInToken(db, id, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !CheckSignInToken(db, token, now) || CheckSignInToken(db, token, now.Add(2*time.Hour)) {
+		t.Fatal("expected token to be valid until it expires")
+	}
+
+	userID, username, err := RedeemSignInToken(db, token, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if userID != id || username != "foo" {
+		t.Fatal("expected token to belong to user:", userID, username)
+	}
+	if _, _, err := RedeemSignInToken(db, token, now); err == nil {
+		t.Fatal("token should only be usable once")
+	}
+}
+
+func TestAuthenticateDeactivated(t *testing.T) {
+	t.Parallel()
+	db := openDB()
+	defer db.Close()
+
+	if err := Register(db, "foo", "bar"); err != nil {
+		t.Fatal("initial registration should succeed:", err)
+	}
+	id, _ := GetUserID(db, "foo")
+
+	if err := SetActive(db, id, false); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if IsActive(db, id) {
+		t.Fatal("expected user to be deactivated")
+	}
+	if _, err := Authenticate(db, "foo", "bar"); err == nil {
+		t.Fatal("authentication should fail if user has been deactivated")
+	}
+
+	if err := SetActive(db, id, true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := Authenticate(db, "foo", "bar"); err != nil {
+		t.Fatal("authentication should succeed after reactivating user:", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Creates one-time sign-in token for the user.
+// Only the token's hash gets stored.
+func CreateSignInToken(db *sql.DB, userID int, expires time.Time) (string, error) {
+	token, err := randomHex(24)
+	if err != nil {
+		return "", errors.New("unable to create sign-in token")
+	}
+
+	query := `INSERT INTO sign_in_token (token_hash, user_id, expires) VALUES (?, ?, ?)`
+	if _, err := db.Exec(query, hashToken(token), userID, expires.Unix()); err != nil {
+		return "", errors.New("unable to create sign-in token")
+	}
+	return token, nil
+}
+
+// Checks if sign-in token is valid without using it up.
+func CheckSignInToken(db *sql.DB, token string, now time.Time) bool {
+	var found int
+	query := `
+		SELECT 1 FROM sign_in_token
+		JOIN user ON user.id = user_id
+		WHERE token_hash = ? AND expires > ? AND merged_into IS NULL AND active
+	`
+	return db.QueryRow(query, hashToken(token), now.Unix()).Scan(&found) == nil
+}
+
+// Uses up sign-in token.
+// Returns the user ID and username of the token's owner.
+func RedeemSignInToken(db *sql.DB, token string, now time.Time) (int, string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, "", errors.New("unable to redeem sign-in token")
+	}
+	defer tx.Rollback()
+
+	var id int
+	var username string
+	query := `
+		SELECT user.id, username FROM sign_in_token
+		JOIN user ON user.id = user_id
+		WHERE token_hash = ? AND expires > ? AND merged_into IS NULL AND active
+	`
+	if err := tx.QueryRow(query, hashToken(token), now.Unix()).Scan(&id, &username); err != nil {
+		return 0, "", errors.New("invalid sign-in token")
+	}
+
+	query = `DELETE FROM sign_in_token WHERE token_hash = ? OR expires <= ?`
+	if _, err := tx.Exec(query, hashToken(token), now.Unix()); err != nil {
+		return 0, "", errors.New("unable to redeem sign-in token")
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, "", errors.New("unable to redeem sign-in token")
+	}
+	return id, username, nil
+}
@@ -4,18 +4,33 @@
 package basedir
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 )
 
 var (
 	Home     string
 	DataDir  string
 	StateDir string
+	CacheDir string
 )
 
+// Name of the data directory next to the executable that turns on portable
+// mode.
+const portableDirName = "polycloze-data"
+
 func init() {
+	if dir := portableDir(); dir != "" {
+		if err := UsePortableDir(dir); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatal(err)
@@ -23,11 +38,137 @@ func init() {
 	Home = home
 
 	DataDir = path.Join(xdgDataHome(), "polycloze")
+	CacheDir = path.Join(xdgCacheHome(), "polycloze")
+
+	// Explicit directories (e.g. mounted volumes in containers) take precedence
+	// over XDG directories.
+	if dir := os.Getenv("POLYCLOZE_DATA_DIR"); dir != "" {
+		if err := SetDataDir(dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if dir := os.Getenv("POLYCLOZE_CACHE_DIR"); dir != "" {
+		if err := SetCacheDir(dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if dir := os.Getenv("POLYCLOZE_STATE_DIR"); dir != "" {
+		if err := SetStateDir(dir); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if err := initStateDir(); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// Returns directory for portable mode, or an empty string if portable mode is
+// off.
+// Portable mode is on if POLYCLOZE_PORTABLE_DIR is set, or if there's a
+// `polycloze-data` directory next to the executable.
+func portableDir() string {
+	if dir := os.Getenv("POLYCLOZE_PORTABLE_DIR"); dir != "" {
+		return dir
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(filepath.Dir(exe), portableDirName)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// Keeps all files (courses, user data and cache) in subdirectories of dir,
+// e.g. so the app can run from a USB stick.
+// XDG directories aren't used in portable mode.
+func UsePortableDir(dir string) error {
+	abs, err := prepareDir(dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("invalid portable directory: %w", err)
+	}
+	if err := SetDataDir(filepath.Join(abs, "data")); err != nil {
+		return err
+	}
+	if err := SetStateDir(filepath.Join(abs, "state")); err != nil {
+		return err
+	}
+	CacheDir = filepath.Join(abs, "cache")
+	return nil
+}
+
+// Creates directory if it doesn't exist yet.
+// Returns absolute path to the directory.
+func prepareDir(dir string, perm os.FileMode) (string, error) {
+	if dir == "" {
+		return "", errors.New("empty path")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %v", abs)
+	}
+	if err := os.MkdirAll(abs, perm); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+// Checks if files can be created in the directory.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".polycloze-*")
+	if err != nil {
+		return fmt.Errorf("directory isn't writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Uses dir for installed courses and other read-only data.
+// Creates the directory if it doesn't exist.
+func SetDataDir(dir string) error {
+	abs, err := prepareDir(dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("invalid data directory: %w", err)
+	}
+	DataDir = abs
+	return nil
+}
+
+// Uses dir for files that can be regenerated (e.g. reverse courses, search
+// indexes and synthesized speech).
+// Creates the directory if it doesn't exist.
+func SetCacheDir(dir string) error {
+	abs, err := prepareDir(dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("invalid cache directory: %w", err)
+	}
+	CacheDir = abs
+	return nil
+}
+
+// Uses dir for user data (auth DB, review DBs, etc.).
+// Creates the directory if it doesn't exist, and checks if it's writable.
+func SetStateDir(dir string) error {
+	abs, err := prepareDir(dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("invalid state directory: %w", err)
+	}
+	if err := os.MkdirAll(path.Join(abs, "users"), 0o700); err != nil {
+		return fmt.Errorf("invalid state directory: %w", err)
+	}
+	if err := checkWritable(abs); err != nil {
+		return fmt.Errorf("invalid state directory: %w", err)
+	}
+	StateDir = abs
+	return nil
+}
+
 func xdgDataHome() string {
 	val := os.Getenv("XDG_DATA_HOME")
 	if val != "" {
@@ -36,6 +177,14 @@ func xdgDataHome() string {
 	return path.Join(Home, ".local", "share")
 }
 
+func xdgCacheHome() string {
+	val := os.Getenv("XDG_CACHE_HOME")
+	if val != "" {
+		return val
+	}
+	return path.Join(Home, ".cache")
+}
+
 func xdgStateHome() string {
 	val := os.Getenv("XDG_STATE_HOME")
 	if val != "" {
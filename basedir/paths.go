@@ -5,6 +5,7 @@ package basedir
 
 import (
 	"fmt"
+	"os"
 	"path"
 )
 
@@ -25,12 +26,71 @@ func Review(userID int, l1, l2 string) string {
 	return path.Join(User(userID), "reviews", fmt.Sprintf("%s-%s.db", l1, l2))
 }
 
+// Returns path to review database for production (L1 to L2) reviews.
+// These are scheduled separately from the reviews in `Review`.
+func ProductionReview(userID int, l1, l2 string) string {
+	return path.Join(User(userID), "production", fmt.Sprintf("%s-%s.db", l1, l2))
+}
+
+// Returns path to archive of old reviews in the review DB (see `Review` and
+// `ProductionReview`).
+// Archives are stored in a separate directory, so they don't get mistaken for
+// review DBs.
+func ReviewArchive(reviewDB string) string {
+	dir, name := path.Split(reviewDB)
+	user := path.Dir(path.Clean(dir))
+	return path.Join(user, "archive", path.Base(dir), name)
+}
+
 // Returns path to database for course.
 // l1 and l2 are ISO 639-3 codes.
+// Falls back to the reverse course built from the l2-l1 course if the course
+// isn't installed.
 func Course(l1, l2 string) string {
-	return path.Join(DataDir, "courses", fmt.Sprintf("%s-%s.db", l1, l2))
+	installed := path.Join(DataDir, "courses", fmt.Sprintf("%s-%s.db", l1, l2))
+	if fileExists(installed) {
+		return installed
+	}
+	if reverse := ReverseCourse(l1, l2); fileExists(reverse) {
+		return reverse
+	}
+	return installed
+}
+
+// Returns path to reverse course built from the l2-l1 course.
+// See package reverse.
+func ReverseCourse(l1, l2 string) string {
+	return path.Join(CacheDir, "courses", fmt.Sprintf("%s-%s.db", l1, l2))
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
 }
 
 func Auth() string {
 	return path.Join(StateDir, "auth.db")
 }
+
+// Returns directory of recorded sentence audio in the course.
+// Recordings are named after the sentence ID (e.g. "123.mp3").
+func Recordings(l1, l2 string) string {
+	return path.Join(DataDir, "audio", fmt.Sprintf("%s-%s", l1, l2))
+}
+
+// Returns directory of cached synthesized speech.
+// Contains a subdirectory for each language (see `SpeechCache`).
+func SpeechCacheRoot() string {
+	return path.Join(CacheDir, "tts")
+}
+
+// Returns directory of cached synthesized speech in the language.
+func SpeechCache(lang string) string {
+	return path.Join(SpeechCacheRoot(), lang)
+}
+
+// Returns path to full-text search index of the course.
+// The index can be rebuilt from the course DB.
+func SearchIndex(l1, l2 string) string {
+	return path.Join(CacheDir, "search", fmt.Sprintf("%s-%s.db", l1, l2))
+}
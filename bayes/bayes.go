@@ -0,0 +1,72 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// For computing Beta-Binomial posterior probabilities.
+package bayes
+
+import (
+	"math"
+)
+
+// Computes the posterior probability that the true success proportion is at
+// most x, given the number of successes and failures.
+// Assumes a uniform prior, so the posterior is Beta(success+1, fail+1).
+//
+// Since the parameters are integers, the regularized incomplete beta function
+// can be computed as a binomial sum:
+// I_x(a, b) = sum_{j=a}^{a+b-1} C(a+b-1, j) x^j (1-x)^(a+b-1-j)
+// See https://en.wikipedia.org/wiki/Beta_distribution#Cumulative_distribution_function
+func CDF(success, fail int, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	a := success + 1
+	n := success + fail + 1
+	lgn, _ := math.Lgamma(float64(n + 1))
+
+	sum := 0.0
+	for j := a; j <= n; j++ {
+		lgj, _ := math.Lgamma(float64(j + 1))
+		lgnj, _ := math.Lgamma(float64(n - j + 1))
+		logTerm := lgn - lgj - lgnj +
+			float64(j)*math.Log(x) +
+			float64(n-j)*math.Log1p(-x)
+		sum += math.Exp(logTerm)
+	}
+	return math.Min(sum, 1)
+}
+
+// Parameters of the posterior probability tests used for tuning.
+type Thresholds struct {
+	// Too easy if P(p > EasyBound) > EasyConfidence.
+	EasyBound      float64 `json:"easyBound"`
+	EasyConfidence float64 `json:"easyConfidence"`
+
+	// Too hard if P(p < HardBound) > HardConfidence.
+	HardBound      float64 `json:"hardBound"`
+	HardConfidence float64 `json:"hardConfidence"`
+}
+
+func DefaultThresholds() Thresholds {
+	// Needs 5 consecutive correct answers to level up, and 2 consecutive
+	// incorrect answers to level down (compared to 7 and 3 with the Wilson score
+	// tests).
+	return Thresholds{
+		EasyBound:      0.8,
+		EasyConfidence: 0.7,
+		HardBound:      0.7,
+		HardConfidence: 0.95,
+	}
+}
+
+func (t Thresholds) IsTooEasy(correct, incorrect int) bool {
+	return 1-CDF(correct, incorrect, t.EasyBound) > t.EasyConfidence
+}
+
+func (t Thresholds) IsTooHard(correct, incorrect int) bool {
+	return CDF(correct, incorrect, t.HardBound) > t.HardConfidence
+}
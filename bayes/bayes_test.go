@@ -0,0 +1,43 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package bayes
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCDFUniform(t *testing.T) {
+	// Posterior without observations should be the uniform distribution.
+	t.Parallel()
+
+	for _, x := range []float64{0.1, 0.5, 0.9} {
+		if p := CDF(0, 0, x); math.Abs(p-x) > 1e-9 {
+			t.Fatal("expected CDF(0, 0, x) to be x:", x, p)
+		}
+	}
+}
+
+func TestCDFAllSuccesses(t *testing.T) {
+	// Posterior after n successes is Beta(n+1, 1), so the CDF is x^(n+1).
+	t.Parallel()
+
+	x := 0.8
+	if p := CDF(4, 0, x); math.Abs(p-math.Pow(x, 5)) > 1e-9 {
+		t.Fatal("expected CDF(4, 0, x) to be x^5:", p)
+	}
+}
+
+func TestDefaultThresholds(t *testing.T) {
+	// Check number of samples needed to level up or down.
+	t.Parallel()
+
+	th := DefaultThresholds()
+	if th.IsTooEasy(4, 0) || !th.IsTooEasy(5, 0) {
+		t.Fatal("expected 5 correct answers to be needed to level up")
+	}
+	if th.IsTooHard(0, 1) || !th.IsTooHard(0, 2) {
+		t.Fatal("expected 2 incorrect answers to be needed to level down")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Offline bundles of a course and the user's data in it, for studying offline
+// in a companion client.
+//
+// A bundle is a zip file that contains:
+//   - manifest.json: course and format version (see `Manifest`)
+//   - course.db: copy of the course database
+//   - reviews.db and production.db: copies of the user's review databases, if
+//     they exist
+//   - word-list.txt: words in the user's word list, one per line
+//
+// Bundles can be imported back later.
+// Reviews done offline get merged into the user's review databases (see
+// package merge).
+// Only the review databases are read on import, so clients may leave out the
+// other files to keep uploads small.
+package bundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/merge"
+	"github.com/polycloze/polycloze/review_restore"
+	"github.com/polycloze/polycloze/wordlist"
+)
+
+// Version of the bundle format.
+const Version = 1
+
+var (
+	ErrInvalidBundle = errors.New("invalid bundle")
+	ErrWrongCourse   = errors.New("bundle is for a different course")
+)
+
+type Manifest struct {
+	Version int    `json:"version"`
+	L1      string `json:"l1"`
+	L2      string `json:"l2"`
+	Created int64  `json:"created"` // UNIX timestamp
+}
+
+type reviewDB struct {
+	name string // File name in bundle
+	path string
+}
+
+// Returns the user's review DBs in the course.
+func reviewDBs(userID int, l1, l2 string) []reviewDB {
+	return []reviewDB{
+		{name: "reviews.db", path: basedir.Review(userID, l1, l2)},
+		{name: "production.db", path: basedir.ProductionReview(userID, l1, l2)},
+	}
+}
+
+// Writes bundle of the course and the user's data in it into w.
+func Export(w io.Writer, userID int, l1, l2 string, now time.Time) error {
+	if err := export(w, userID, l1, l2, now); err != nil {
+		return fmt.Errorf("failed to export bundle: %w", err)
+	}
+	return nil
+}
+
+func export(w io.Writer, userID int, l1, l2 string, now time.Time) error {
+	// Databases are copied first, so that the bundle is consistent even if the
+	// user is studying at the same time.
+	dir, err := os.MkdirTemp("", "polycloze-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{"course.db"}
+	if err := database.Backup(basedir.Course(l1, l2), filepath.Join(dir, "course.db")); err != nil {
+		return err
+	}
+	for _, db := range reviewDBs(userID, l1, l2) {
+		if _, err := os.Stat(db.path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := database.Backup(db.path, filepath.Join(dir, db.name)); err != nil {
+			return err
+		}
+		files = append(files, db.name)
+	}
+
+	words, err := readWordList(filepath.Join(dir, "reviews.db"))
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	manifest := Manifest{Version: Version, L1: l1, L2: l2, Created: now.Unix()}
+	if err := writeJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err := writeFile(zw, name, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	f, err := zw.Create("word-list.txt")
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		if _, err := fmt.Fprintln(f, word); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Returns words in the word list of the review DB, or nothing if the DB
+// doesn't exist.
+func readWordList(path string) ([]string, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return wordlist.List(db)
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(f).Encode(v)
+}
+
+func writeFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// Imports reviews in bundle into the user's review DBs in the course.
+// Reviews that are already in the user's review DBs (e.g. reviews done before
+// the bundle was exported) are skipped.
+// Review DBs in the bundle can't be bigger than `maxSize` bytes when
+// extracted, and get the same checks as restored review DBs (see
+// `review_restore.Check`).
+// Bundles are replayed as a whole, so they can't be held for approval like
+// uploaded reviews (see package quarantine). Instead, review DBs with reviews
+// in the future get rejected by the checks.
+func Import(r io.ReaderAt, size, maxSize int64, userID int, l1, l2 string) error {
+	if err := importBundle(r, size, maxSize, userID, l1, l2); err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+	return nil
+}
+
+func importBundle(r io.ReaderAt, size, maxSize int64, userID int, l1, l2 string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	manifest, err := readManifest(zr)
+	if err != nil {
+		return err
+	}
+	if manifest.L1 != l1 || manifest.L2 != l2 {
+		return ErrWrongCourse
+	}
+
+	dir, err := os.MkdirTemp("", "polycloze-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for _, db := range reviewDBs(userID, l1, l2) {
+		src := filepath.Join(dir, db.name)
+		err := extract(zr, db.name, src, maxSize)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = review_restore.Check(src, now)
+		if errors.Is(err, review_restore.ErrInvalidDatabase) {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(db.path), 0o700); err != nil {
+			return err
+		}
+		if err := mergeFile(db.path, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merges extracted review DB into the user's review DB.
+func mergeFile(dst, src string) error {
+	defer database.LockReviewDB(dst)()
+	return merge.Files(dst, src)
+}
+
+func readManifest(zr *zip.Reader) (Manifest, error) {
+	var manifest Manifest
+	f, err := zr.Open("manifest.json")
+	if err != nil {
+		return manifest, fmt.Errorf("%w: missing manifest", ErrInvalidBundle)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Version != Version {
+		return manifest, fmt.Errorf("%w: unsupported version: %v", ErrInvalidBundle, manifest.Version)
+	}
+	return manifest, nil
+}
+
+// Extracts file in the bundle into `path`.
+// Returns `os.ErrNotExist` if the bundle doesn't have the file, and
+// `ErrInvalidBundle` if the file is bigger than `maxSize` bytes.
+func extract(zr *zip.Reader, name, path string, maxSize int64) error {
+	var file *zip.File
+	for _, f := range zr.File {
+		if f.Name == name {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return os.ErrNotExist
+	}
+
+	// The zip reader checks that the file isn't bigger than this, but the
+	// size in the header can't be trusted, so the copy is limited too.
+	if file.UncompressedSize64 > uint64(maxSize) {
+		return fmt.Errorf("%w: %v is too big", ErrInvalidBundle, name)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer r.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if n > maxSize {
+		return fmt.Errorf("%w: %v is too big", ErrInvalidBundle, name)
+	}
+	return f.Close()
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sample_course"
+)
+
+// Size limit of extracted review DBs in tests.
+const maxSize = 8 * 1024 * 1024
+
+func countHistory(t *testing.T, path string) int {
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM history`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	return count
+}
+
+func TestExportImport(t *testing.T) {
+	if err := basedir.SetDataDir(t.TempDir()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := basedir.SetStateDir(t.TempDir()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := sample_course.Install(); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	l1, l2 := sample_course.L1, sample_course.L2
+
+	path := basedir.Review(1, l1, l2)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := rs.UpdateReviewAt(db, "casa", true, time.Unix(1000000, 0)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.Close()
+
+	var b bytes.Buffer
+	if err := Export(&b, 1, l1, l2, time.Now()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	r := bytes.NewReader(b.Bytes())
+
+	if err := Import(r, r.Size(), maxSize, 1, l2, l1); !errors.Is(err, ErrWrongCourse) {
+		t.Fatal("expected bundle for another course to be rejected:", err)
+	}
+
+	// Reviews that are already in the review DB shouldn't be imported again.
+	if err := Import(r, r.Size(), maxSize, 1, l1, l2); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count := countHistory(t, path); count != 1 {
+		t.Fatal("expected review to be imported once:", count)
+	}
+
+	if err := Import(r, r.Size(), maxSize, 2, l1, l2); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count := countHistory(t, basedir.Review(2, l1, l2)); count != 1 {
+		t.Fatal("expected review to be imported into new review DB:", count)
+	}
+
+	// Review DBs that are bigger than the limit when extracted shouldn't be
+	// imported.
+	if err := Import(r, r.Size(), 1024, 3, l1, l2); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatal("expected ErrInvalidBundle:", err)
+	}
+	if _, err := os.Stat(basedir.Review(3, l1, l2)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected review DB to not be created:", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Teacher/classroom accounts.
+// A teacher creates a class, students join using the class code, and the
+// teacher can view the progress of students and assign word lists.
+// All functions take the auth DB.
+package classes
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/polycloze/polycloze/text"
+)
+
+var ErrNotFound = errors.New("class not found")
+
+type Class struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Teacher int       `json:"teacher"`
+	Code    string    `json:"code,omitempty"` // Only shown to the teacher
+	Created time.Time `json:"created"`
+}
+
+type Member struct {
+	UserID   int       `json:"userID"`
+	Username string    `json:"username"`
+	Joined   time.Time `json:"joined"`
+}
+
+type Assignment struct {
+	ID      int       `json:"id"`
+	L1      string    `json:"l1"`
+	L2      string    `json:"l2"`
+	Title   string    `json:"title"`
+	Words   []string  `json:"words"`
+	Created time.Time `json:"created"`
+}
+
+// Characters in class codes (no 0/O or 1/I to avoid typos).
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateCode() (string, error) {
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// Creates a class taught by the user.
+func Create(db *sql.DB, teacher int, name string) (Class, error) {
+	code, err := generateCode()
+	if err != nil {
+		return Class{}, fmt.Errorf("failed to create class: %w", err)
+	}
+
+	now := time.Now()
+	query := `INSERT INTO class (name, teacher, code, created) VALUES (?, ?, ?, ?)`
+	result, err := db.Exec(query, name, teacher, code, now.Unix())
+	if err != nil {
+		return Class{}, fmt.Errorf("failed to create class: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Class{}, fmt.Errorf("failed to create class: %w", err)
+	}
+	return Class{
+		ID:      int(id),
+		Name:    name,
+		Teacher: teacher,
+		Code:    code,
+		Created: time.Unix(now.Unix(), 0),
+	}, nil
+}
+
+// Returns class with the given ID.
+func Get(db *sql.DB, id int) (Class, error) {
+	var class Class
+	var created int64
+	query := `SELECT id, name, teacher, code, created FROM class WHERE id = ?`
+	err := db.QueryRow(query, id).Scan(&class.ID, &class.Name, &class.Teacher, &class.Code, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return class, ErrNotFound
+	}
+	if err != nil {
+		return class, fmt.Errorf("failed to get class: %w", err)
+	}
+	class.Created = time.Unix(created, 0)
+	return class, nil
+}
+
+// Adds user to the class with the given code.
+// Teachers can't join their own classes.
+func Join(db *sql.DB, code string, student int) (Class, error) {
+	var id int
+	query := `SELECT id FROM class WHERE code = ? AND teacher != ?`
+	err := db.QueryRow(query, code, student).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Class{}, ErrNotFound
+	}
+	if err != nil {
+		return Class{}, fmt.Errorf("failed to join class: %w", err)
+	}
+
+	query = `INSERT OR IGNORE INTO class_member (class, student) VALUES (?, ?)`
+	if _, err := db.Exec(query, id, student); err != nil {
+		return Class{}, fmt.Errorf("failed to join class: %w", err)
+	}
+
+	class, err := Get(db, id)
+	class.Code = ""
+	return class, err
+}
+
+// Removes student from the class.
+func Leave(db *sql.DB, id, student int) error {
+	query := `DELETE FROM class_member WHERE class = ? AND student = ?`
+	if _, err := db.Exec(query, id, student); err != nil {
+		return fmt.Errorf("failed to leave class: %w", err)
+	}
+	return nil
+}
+
+// Returns classes taught by the user and classes the user belongs to.
+// Class codes are only included in classes taught by the user.
+func List(db *sql.DB, userID int) ([]Class, error) {
+	query := `
+		SELECT id, name, teacher, CASE WHEN teacher = @user THEN code ELSE '' END,
+			created
+		FROM class
+		WHERE teacher = @user OR id IN (
+			SELECT class FROM class_member WHERE student = @user
+		)
+		ORDER BY id
+	`
+	rows, err := db.Query(query, sql.Named("user", userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := make([]Class, 0)
+	for rows.Next() {
+		var class Class
+		var created int64
+		if err := rows.Scan(&class.ID, &class.Name, &class.Teacher, &class.Code, &created); err != nil {
+			return nil, fmt.Errorf("failed to list classes: %w", err)
+		}
+		class.Created = time.Unix(created, 0)
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// Checks if the user teaches the class.
+func IsTeacher(db *sql.DB, id, userID int) bool {
+	class, err := Get(db, id)
+	return err == nil && class.Teacher == userID
+}
+
+// Checks if the user is the teacher or a student of the class.
+func IsMember(db *sql.DB, id, userID int) bool {
+	if IsTeacher(db, id, userID) {
+		return true
+	}
+	var found int
+	query := `SELECT 1 FROM class_member WHERE class = ? AND student = ?`
+	return db.QueryRow(query, id, userID).Scan(&found) == nil
+}
+
+// Returns students in the class.
+func Members(db *sql.DB, id int) ([]Member, error) {
+	query := `
+		SELECT student, username, joined FROM class_member
+		JOIN user ON (student = user.id)
+		WHERE class = ?
+		ORDER BY username
+	`
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]Member, 0)
+	for rows.Next() {
+		var m Member
+		var joined int64
+		if err := rows.Scan(&m.UserID, &m.Username, &joined); err != nil {
+			return nil, fmt.Errorf("failed to get class members: %w", err)
+		}
+		m.Joined = time.Unix(joined, 0)
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// Assigns word list to the class.
+func Assign(db *sql.DB, id int, a Assignment) (Assignment, error) {
+	for i, word := range a.Words {
+		a.Words[i] = text.Casefold(word)
+	}
+	words, err := json.Marshal(a.Words)
+	if err != nil {
+		return a, fmt.Errorf("failed to assign words: %w", err)
+	}
+
+	a.Created = time.Unix(time.Now().Unix(), 0)
+	query := `
+		INSERT INTO class_assignment (class, l1, l2, title, words, created)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := db.Exec(query, id, a.L1, a.L2, a.Title, string(words), a.Created.Unix())
+	if err != nil {
+		return a, fmt.Errorf("failed to assign words: %w", err)
+	}
+	assignmentID, err := result.LastInsertId()
+	if err != nil {
+		return a, fmt.Errorf("failed to assign words: %w", err)
+	}
+	a.ID = int(assignmentID)
+	return a, nil
+}
+
+// Returns word lists assigned to the class.
+func Assignments(db *sql.DB, id int) ([]Assignment, error) {
+	query := `
+		SELECT id, l1, l2, title, words, created FROM class_assignment
+		WHERE class = ?
+		ORDER BY id
+	`
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := make([]Assignment, 0)
+	for rows.Next() {
+		var a Assignment
+		var words string
+		var created int64
+		if err := rows.Scan(&a.ID, &a.L1, &a.L2, &a.Title, &words, &created); err != nil {
+			return nil, fmt.Errorf("failed to get assignments: %w", err)
+		}
+		if err := json.Unmarshal([]byte(words), &a.Words); err != nil {
+			return nil, fmt.Errorf("failed to get assignments: %w", err)
+		}
+		a.Created = time.Unix(created, 0)
+		assignments = append(assignments, a)
+	}
+	return assignments, nil
+}
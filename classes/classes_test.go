@@ -0,0 +1,92 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package classes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestClasses(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	for _, username := range []string{"teacher", "student", "outsider"} {
+		if err := auth.Register(db, username, "password"); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+	teacher, _ := auth.GetUserID(db, "teacher")
+	student, _ := auth.GetUserID(db, "student")
+	outsider, _ := auth.GetUserID(db, "outsider")
+
+	class, err := Create(db, teacher, "German 101")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if _, err := Join(db, "WRONG", student); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected invalid code to be rejected:", err)
+	}
+	if _, err := Join(db, class.Code, teacher); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected teacher to not be able to join own class:", err)
+	}
+	joined, err := Join(db, class.Code, student)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if joined.Code != "" {
+		t.Fatal("expected class code to be hidden from students:", joined.Code)
+	}
+
+	if !IsMember(db, class.ID, student) || IsMember(db, class.ID, outsider) {
+		t.Fatal("unexpected membership")
+	}
+	if IsTeacher(db, class.ID, student) || !IsTeacher(db, class.ID, teacher) {
+		t.Fatal("unexpected teacher")
+	}
+
+	members, err := Members(db, class.ID)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(members) != 1 || members[0].Username != "student" {
+		t.Fatal("unexpected members:", members)
+	}
+
+	classes, err := List(db, student)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(classes) != 1 || classes[0].Code != "" {
+		t.Fatal("unexpected classes:", classes)
+	}
+
+	_, err = Assign(db, class.ID, Assignment{L1: "eng", L2: "deu", Title: "Week 1", Words: []string{"Haus"}})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	assignments, err := Assignments(db, class.ID)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(assignments) != 1 || assignments[0].Words[0] != "haus" {
+		t.Fatal("unexpected assignments:", assignments)
+	}
+
+	if err := Leave(db, class.ID, student); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if IsMember(db, class.ID, student) {
+		t.Fatal("expected student to have left")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Replays a review log through the scheduler and the difficulty tuner, and
+// reports the simulated workload, retention and level changes.
+// Used for evaluating tuner parameters before changing the defaults.
+// Reviewed words come from the log, so the tuner's level doesn't change which
+// words get reviewed. Only the level changes depend on the tuner parameters.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/replay"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/wilson"
+	ws "github.com/polycloze/polycloze/word_scheduler"
+)
+
+type Args struct {
+	logFile string
+	l1      string
+	l2      string
+
+	algorithm  string
+	level      int
+	thresholds wilson.Thresholds
+}
+
+func parseArgs() Args {
+	args := Args{thresholds: wilson.DefaultThresholds()}
+	flag.StringVar(&args.l1, "l1", "eng", "ISO 639-3 code of the course's source language")
+	flag.StringVar(&args.l2, "l2", "", "ISO 639-3 code of the course's target language (default: inferred from the log file name, e.g. spa.csv)")
+	flag.StringVar(&args.algorithm, "a", "wilson", "tuner algorithm (wilson or bayes)")
+	flag.IntVar(&args.level, "l", 0, "initial difficulty level")
+	flag.Float64Var(&args.thresholds.EasyZ, "easy-z", args.thresholds.EasyZ, "z-score of too easy test")
+	flag.Float64Var(&args.thresholds.EasyBound, "easy-bound", args.thresholds.EasyBound, "bound of too easy test")
+	flag.Float64Var(&args.thresholds.HardZ, "hard-z", args.thresholds.HardZ, "z-score of too hard test")
+	flag.Float64Var(&args.thresholds.HardBound, "hard-bound", args.thresholds.HardBound, "bound of too hard test")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("missing arg: path to log file")
+	}
+	if !difficulty.IsValidAlgorithm(args.algorithm) {
+		log.Fatal("invalid tuner algorithm:", args.algorithm)
+	}
+	if !args.thresholds.IsValid() {
+		log.Fatal("invalid tuner thresholds")
+	}
+
+	args.logFile = flag.Arg(0)
+	if args.l2 == "" {
+		l2, ok := inferLanguage(args.logFile)
+		if !ok {
+			log.Fatal("couldn't infer language from log file name, use -l2")
+		}
+		args.l2 = l2
+	}
+	if _, err := os.Stat(basedir.Course(args.l1, args.l2)); err != nil {
+		log.Fatalf("course not found (%v-%v): %v", args.l1, args.l2, err)
+	}
+	return args
+}
+
+// Simulation results.
+type Report struct {
+	NewWords int // Number of newly seen words
+	Reviews  int // Number of reviews of previously seen words
+	Correct  int // Number of correct reviews of previously seen words
+
+	LevelUps   int
+	LevelDowns int
+	FinalLevel int
+
+	First time.Time
+	Last  time.Time
+
+	// Number of reviews due n days after the last review in the log.
+	Due map[int]int
+}
+
+// Fraction of correct reviews of previously seen words.
+func (r Report) Retention() float64 {
+	if r.Reviews == 0 {
+		return math.NaN()
+	}
+	return float64(r.Correct) / float64(r.Reviews)
+}
+
+// Average number of reviews per day (including new words).
+func (r Report) Workload() float64 {
+	days := math.Ceil(r.Last.Sub(r.First).Hours() / 24)
+	if days < 1 {
+		days = 1
+	}
+	return float64(r.NewWords+r.Reviews) / days
+}
+
+func (r Report) Print() {
+	fmt.Println("new words:", r.NewWords)
+	fmt.Println("reviews:", r.Reviews)
+	fmt.Printf("retention: %.4f\n", r.Retention())
+	fmt.Printf("workload: %.2f reviews/day\n", r.Workload())
+	fmt.Println("level ups:", r.LevelUps)
+	fmt.Println("level downs:", r.LevelDowns)
+	fmt.Println("final level:", r.FinalLevel)
+	for _, days := range []int{1, 7, 30} {
+		fmt.Printf("due in %v day(s): %v\n", days, r.Due[days])
+	}
+}
+
+// Checks if word has been reviewed before.
+func isSeen[T database.Querier](q T, word string) bool {
+	var count int
+	query := `SELECT count(*) FROM review WHERE item = ?`
+	_ = q.QueryRow(query, word).Scan(&count)
+	return count > 0
+}
+
+func simulate[T database.Querier](q T, r *replay.ReviewReader, tuner *difficulty.Tuner) (Report, error) {
+	report := Report{Due: make(map[int]int)}
+
+	first := true
+	for {
+		review, err := r.ReadReview()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && first && isHeader(err) {
+			first = false
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		first = false
+
+		if report.First.IsZero() {
+			report.First = review.Reviewed
+		}
+		report.Last = review.Reviewed
+
+		if isSeen(q, review.Word) {
+			report.Reviews++
+			if review.Correct {
+				report.Correct++
+			}
+		} else {
+			report.NewWords++
+			level := tuner.Level
+			if tuner.Update(review.Correct) {
+				if tuner.Level > level {
+					report.LevelUps++
+				} else {
+					report.LevelDowns++
+				}
+			}
+		}
+
+		if err := ws.UpdateWordAt(q, review.Word, review.Correct, review.Reviewed); err != nil {
+			return report, err
+		}
+	}
+
+	report.FinalLevel = tuner.Level
+	for _, days := range []int{1, 7, 30} {
+		due := report.Last.Add(time.Duration(days) * 24 * time.Hour)
+		count, err := review_scheduler.CountDue(q, due)
+		if err != nil {
+			return report, err
+		}
+		report.Due[days] = count
+	}
+	return report, nil
+}
+
+func main() {
+	args := parseArgs()
+
+	db, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	con, err := database.NewConnection(
+		db,
+		context.TODO(),
+		database.AttachCourse(basedir.Course(args.l1, args.l2)),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(args.logFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	tuner := difficulty.NewTuner(
+		difficulty.Difficulty{Level: args.level, Max: math.MaxInt},
+		args.thresholds,
+		args.algorithm,
+	)
+	report, err := simulate(con, replay.NewReviewReader(csv.NewReader(f)), tuner)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.Print()
+}
+
+// Checks if the error is from reading a header row.
+// Headers are CSV records that aren't valid reviews, so CSV syntax errors and
+// I/O errors (which get wrapped by `ReadReview`) aren't from headers.
+func isHeader(err error) bool {
+	return errors.Unwrap(err) == nil
+}
+
+// Returns the language code at the start of the log file name (e.g. "spa" in
+// "spa.csv" or "spa-user1.csv").
+func inferLanguage(logFile string) (string, bool) {
+	name := path.Base(logFile)
+	if len(name) < 3 {
+		return "", false
+	}
+	for _, c := range name[:3] {
+		if c < 'a' || c > 'z' {
+			return "", false
+		}
+	}
+	if len(name) > 3 && name[3] != '.' && name[3] != '-' && name[3] != '_' {
+		return "", false
+	}
+	return name[:3], true
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Applies patches to installed course databases, so that course updates don't
+// require downloading the whole course again.
+//
+// A patch is a list of SQL statements (e.g. the output of `sqldiff old.db
+// new.db`) that starts with a header:
+//
+//	-- base: <checksum of the old course>
+//	-- target: <checksum of the new course>
+//
+// Checksums are computed from the schema and contents of the course (see
+// `Checksum`), so they don't depend on how SQLite lays out the file.
+package course_patch
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// The course isn't the one the patch was made for.
+	ErrBaseMismatch = errors.New("course doesn't match base checksum of patch")
+
+	// Applying the patch didn't produce the expected course.
+	ErrTargetMismatch = errors.New("patched course doesn't match target checksum")
+)
+
+type Patch struct {
+	Base   string
+	Target string
+	SQL    string
+}
+
+// Reads patch with header.
+func Parse(r io.Reader) (Patch, error) {
+	var patch Patch
+	var body strings.Builder
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if body.Len() == 0 {
+			if value, ok := headerValue(line, "base"); ok {
+				patch.Base = value
+				continue
+			}
+			if value, ok := headerValue(line, "target"); ok {
+				patch.Target = value
+				continue
+			}
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return patch, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	if patch.Base == "" || patch.Target == "" {
+		return patch, fmt.Errorf("%w: missing checksum", ErrInvalidPatch)
+	}
+	patch.SQL = body.String()
+	return patch, nil
+}
+
+// Returns value of header line (e.g. "-- base: ...").
+func headerValue(line, key string) (string, bool) {
+	prefix := "-- " + key + ":"
+	if !strings.HasPrefix(line, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(prefix):]), true
+}
+
+// Applies patch to course DB.
+// The course is left unchanged if its checksum doesn't match the base
+// checksum of the patch, or if the patched course doesn't match the target
+// checksum.
+func Apply(db *sql.DB, patch Patch) error {
+	if err := apply(db, patch); err != nil {
+		return fmt.Errorf("failed to apply course patch: %w", err)
+	}
+	return nil
+}
+
+func apply(db *sql.DB, patch Patch) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	base, err := Checksum(tx)
+	if err != nil {
+		return err
+	}
+	if base == patch.Target {
+		// Already patched.
+		return nil
+	}
+	if base != patch.Base {
+		return ErrBaseMismatch
+	}
+
+	if _, err := tx.Exec(patch.SQL); err != nil {
+		return err
+	}
+
+	target, err := Checksum(tx)
+	if err != nil {
+		return err
+	}
+	if target != patch.Target {
+		return ErrTargetMismatch
+	}
+	return tx.Commit()
+}
+
+// Computes checksum of the schema and contents of the course.
+// Schema objects are hashed in order of type and name, tables in order of
+// name, and rows in order of primary key (or rowid if there's none).
+func Checksum(q querier) (string, error) {
+	h := sha256.New()
+	if err := hashSchema(q, h); err != nil {
+		return "", fmt.Errorf("failed to compute course checksum: %w", err)
+	}
+
+	tables, err := listTables(q)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute course checksum: %w", err)
+	}
+	for _, table := range tables {
+		fmt.Fprintf(h, "%s\n", table)
+		if err := hashTable(q, table, h); err != nil {
+			return "", fmt.Errorf("failed to compute course checksum: %w", err)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Writes schema objects (e.g. tables, indexes and triggers) into w.
+// Whitespace in the SQL is normalized, so the output doesn't depend on how the
+// statements were formatted.
+func hashSchema(q querier, w io.Writer) error {
+	query := `
+		SELECT type, name, tbl_name, coalesce(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY type, name
+	`
+	rows, err := q.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, name, table, sql string
+		if err := rows.Scan(&kind, &name, &table, &sql); err != nil {
+			return err
+		}
+		sql = strings.Join(strings.Fields(sql), " ")
+		fmt.Fprintf(w, "%s %s %s %s\n", kind, name, table, sql)
+	}
+	return rows.Err()
+}
+
+func listTables(q querier) ([]string, error) {
+	query := `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name
+	`
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// Returns names of the table's columns, and of its primary key columns.
+func listColumns(q querier, table string) ([]string, []string, error) {
+	query := `SELECT name, pk FROM pragma_table_info(?) ORDER BY cid`
+	rows, err := q.Query(query, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	pk := make(map[int]string)
+	for rows.Next() {
+		var column string
+		var i int
+		if err := rows.Scan(&column, &i); err != nil {
+			return nil, nil, err
+		}
+		columns = append(columns, column)
+		if i > 0 {
+			pk[i] = column
+		}
+	}
+
+	// Columns are ordered by their position in the primary key.
+	keys := make([]string, 0, len(pk))
+	for i := 1; i <= len(pk); i++ {
+		keys = append(keys, pk[i])
+	}
+	return columns, keys, rows.Err()
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Writes rows of table into w.
+// Values are formatted by SQLite's quote function, so the output doesn't
+// depend on the driver.
+// Rows are ordered by primary key, because WITHOUT ROWID tables don't have
+// rowids.
+func hashTable(q querier, table string, w io.Writer) error {
+	columns, keys, err := listColumns(q, table)
+	if err != nil {
+		return err
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, fmt.Sprintf(`quote(%s)`, quoteIdentifier(column)))
+	}
+	order := []string{"rowid"}
+	if len(keys) > 0 {
+		order = order[:0]
+		for _, key := range keys {
+			order = append(order, quoteIdentifier(key))
+		}
+	}
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s ORDER BY %s`,
+		strings.Join(quoted, ` || ',' || `),
+		quoteIdentifier(table),
+		strings.Join(order, ", "),
+	)
+
+	rows, err := q.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", row)
+	}
+	return rows.Err()
+}
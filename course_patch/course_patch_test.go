@@ -0,0 +1,138 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package course_patch
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func courseDB(t *testing.T, queries ...string) *sql.DB {
+	db, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	queries = append([]string{
+		`CREATE TABLE word (id integer primary key, word text unique not null, frequency_class integer not null)`,
+		`INSERT INTO word (word, frequency_class) VALUES ('hola', 0), ('mundo', 1)`,
+	}, queries...)
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+	return db
+}
+
+func checksum(t *testing.T, db *sql.DB) string {
+	sum, err := Checksum(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	return sum
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	diff := "UPDATE word SET frequency_class=2 WHERE id=2;\nINSERT INTO word(id,word,frequency_class) VALUES(3,'adiós',1);\n"
+
+	old := courseDB(t)
+	defer old.Close()
+	updated := courseDB(t, diff)
+	defer updated.Close()
+
+	text := fmt.Sprintf("-- base: %s\n-- target: %s\n%s", checksum(t, old), checksum(t, updated), diff)
+	patch, err := Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := Apply(old, patch); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if checksum(t, old) != patch.Target {
+		t.Fatal("expected course to be patched")
+	}
+
+	// Applying the same patch again does nothing.
+	if err := Apply(old, patch); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+}
+
+func TestApplyMismatch(t *testing.T) {
+	t.Parallel()
+
+	db := courseDB(t)
+	defer db.Close()
+	sum := checksum(t, db)
+
+	patch := Patch{Base: "foo", Target: "bar", SQL: `DELETE FROM word`}
+	if err := Apply(db, patch); !errors.Is(err, ErrBaseMismatch) {
+		t.Fatal("expected base checksum mismatch:", err)
+	}
+
+	patch = Patch{Base: sum, Target: "bar", SQL: `DELETE FROM word`}
+	if err := Apply(db, patch); !errors.Is(err, ErrTargetMismatch) {
+		t.Fatal("expected target checksum mismatch:", err)
+	}
+	if checksum(t, db) != sum {
+		t.Fatal("expected course to be unchanged")
+	}
+}
+
+func TestParseWithoutHeader(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Parse(strings.NewReader(`DELETE FROM word;`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Fatal("expected patch without checksums to be invalid:", err)
+	}
+}
+
+func TestChecksumSchema(t *testing.T) {
+	// Schema changes should change the checksum, but formatting shouldn't.
+	t.Parallel()
+
+	db := courseDB(t)
+	defer db.Close()
+	indexed := courseDB(t, `CREATE INDEX index_word_frequency_class ON word (frequency_class)`)
+	defer indexed.Close()
+	reformatted := courseDB(t, "CREATE INDEX index_word_frequency_class\n\tON word (frequency_class)")
+	defer reformatted.Close()
+
+	if checksum(t, db) == checksum(t, indexed) {
+		t.Fatal("expected new index to change checksum")
+	}
+	if checksum(t, indexed) != checksum(t, reformatted) {
+		t.Fatal("expected whitespace in schema to not change checksum")
+	}
+}
+
+func TestChecksumWithoutRowid(t *testing.T) {
+	// Rows of WITHOUT ROWID tables should be ordered by primary key.
+	t.Parallel()
+
+	a := courseDB(t,
+		`CREATE TABLE translation (l1 text, l2 text, PRIMARY KEY (l1, l2)) WITHOUT ROWID`,
+		`INSERT INTO translation VALUES ('b', 'x'), ('a', 'y')`,
+	)
+	defer a.Close()
+	b := courseDB(t,
+		`CREATE TABLE translation (l1 text, l2 text, PRIMARY KEY (l1, l2)) WITHOUT ROWID`,
+		`INSERT INTO translation VALUES ('a', 'y'), ('b', 'x')`,
+	)
+	defer b.Close()
+
+	if checksum(t, a) != checksum(t, b) {
+		t.Fatal("expected checksum to not depend on insertion order")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Sentence coverage of course words.
+// Words with few example sentences produce repetitive cloze items, and words
+// without any can't be studied at all.
+package coverage
+
+import (
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Words with fewer example sentences than this are sparse.
+const MinSentences = 3
+
+type Word struct {
+	Word           string `json:"word"`
+	FrequencyClass int    `json:"frequencyClass"`
+	Sentences      int    `json:"sentences"`
+}
+
+// Returns words with fewer than `threshold` example sentences, starting with
+// words with the fewest sentences, then the most frequent words.
+// `Querier` should have access to the course DB.
+func Report[T database.Querier](q T, threshold int) ([]Word, error) {
+	query := `
+		SELECT word.word, frequency_class, count(sentence) AS sentences
+		FROM word LEFT JOIN contains ON (contains.word = word.id)
+		GROUP BY word.id
+		HAVING sentences < ?
+		ORDER BY sentences ASC, frequency_class ASC, word.id ASC
+	`
+	rows, err := q.Query(query, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sentence coverage: %w", err)
+	}
+	defer rows.Close()
+
+	words := make([]Word, 0)
+	for rows.Next() {
+		var w Word
+		if err := rows.Scan(&w.Word, &w.FrequencyClass, &w.Sentences); err != nil {
+			return nil, fmt.Errorf("failed to get sentence coverage: %w", err)
+		}
+		words = append(words, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sentence coverage: %w", err)
+	}
+	return words, nil
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package coverage
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'a', 0), (2, 'b', 1), (3, 'c', 0)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1), (2, 1), (3, 1), (1, 2)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	words, err := Report(db, MinSentences)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	expected := []Word{{"c", 0, 0}, {"b", 1, 1}}
+	if len(words) != len(expected) || words[0] != expected[0] || words[1] != expected[1] {
+		t.Fatal("expected words with fewest sentences first:", words)
+	}
+}
@@ -23,7 +23,7 @@ func upgradeAuthDB(db *sql.DB) error {
 // The caller has to Close the db.
 func OpenAuthDB(path string) (*sql.DB, error) {
 	// Open DB with foreign key enforcement.
-	db, err := Open(path + "?_fk=1")
+	db, err := Open(withForeignKeys(path))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open auth database: %w", err)
 	}
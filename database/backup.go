@@ -0,0 +1,30 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Writes a consistent copy of the database in `src` into `dest`.
+// Safe to use while the server is running.
+// Fails if `dest` already exists.
+func Backup(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	db, err := Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
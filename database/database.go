@@ -9,7 +9,6 @@ import (
 	"database/sql"
 	"embed"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose/v3"
 )
 
@@ -26,7 +25,7 @@ func init() {
 
 // NOTE Caller has to Close the db.
 func Open(path string) (*sql.DB, error) {
-	return sql.Open("sqlite3", path)
+	return sql.Open(driverName, path)
 }
 
 // Attaches database to the connection.
@@ -0,0 +1,31 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+//go:build !sqlite_purego
+
+package database
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Name of SQLite driver.
+// Build with `-tags sqlite_purego` to use the pure-Go driver (no cgo) instead.
+const driverName = "sqlite3"
+
+// Returns DSN for opening DB with foreign key enforcement.
+func withForeignKeys(path string) string {
+	return path + "?_fk=1"
+}
+
+// Checks if err is caused by a UNIQUE or PRIMARY KEY constraint violation.
+func IsUniqueConstraintError(err error) bool {
+	var e sqlite3.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
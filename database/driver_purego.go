@@ -0,0 +1,34 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+//go:build sqlite_purego
+
+package database
+
+import (
+	"errors"
+
+	"modernc.org/sqlite"
+	lib "modernc.org/sqlite/lib"
+)
+
+// Name of SQLite driver.
+// Uses modernc.org/sqlite, which doesn't need cgo, so it's easier to
+// cross-compile.
+// Unlike mattn/go-sqlite3, it always includes FTS5.
+const driverName = "sqlite"
+
+// Returns DSN for opening DB with foreign key enforcement.
+func withForeignKeys(path string) string {
+	return path + "?_pragma=foreign_keys(1)"
+}
+
+// Checks if err is caused by a UNIQUE or PRIMARY KEY constraint violation.
+func IsUniqueConstraintError(err error) bool {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code() == lib.SQLITE_CONSTRAINT_UNIQUE ||
+		e.Code() == lib.SQLITE_CONSTRAINT_PRIMARYKEY
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package database
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Locks on review DBs, keyed by path.
+// Each user has their own review DBs for each course, so this serializes
+// writes (e.g. saving reviews, imports, merges, restores and maintenance) per
+// user and course, instead of letting their transactions interleave on the
+// same SQLite file.
+// Locks only work within the process, so maintenance commands that write to
+// review DBs should be run while the server is stopped.
+// Readers can hold the lock at the same time.
+// Locks only get kept in memory while they're being used.
+var reviewLocks = struct {
+	sync.Mutex
+	entries map[string]*reviewLock
+}{entries: make(map[string]*reviewLock)}
+
+type reviewLock struct {
+	sync.RWMutex
+	refs int // Number of goroutines holding or waiting for the lock
+}
+
+// Returns the lock on the review DB and increments its reference count.
+func acquireReviewLock(path string) (string, *reviewLock) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	reviewLocks.Lock()
+	defer reviewLocks.Unlock()
+
+	lock, ok := reviewLocks.entries[path]
+	if !ok {
+		lock = &reviewLock{}
+		reviewLocks.entries[path] = lock
+	}
+	lock.refs++
+	return path, lock
+}
+
+// Decrements the lock's reference count, and forgets the lock if it's no
+// longer used.
+func releaseReviewLock(path string, lock *reviewLock) {
+	reviewLocks.Lock()
+	defer reviewLocks.Unlock()
+
+	lock.refs--
+	if lock.refs <= 0 {
+		delete(reviewLocks.entries, path)
+	}
+}
+
+// Locks the review DB in `path` for writing.
+// Blocks until other writers and readers are done.
+// Returns function for unlocking the review DB.
+func LockReviewDB(path string) (unlock func()) {
+	key, lock := acquireReviewLock(path)
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		releaseReviewLock(key, lock)
+	}
+}
+
+// Locks the review DB in `path` for reading.
+// Blocks while there's a writer, but not while there are other readers.
+// Returns function for unlocking the review DB.
+func RLockReviewDB(path string) (unlock func()) {
+	key, lock := acquireReviewLock(path)
+	lock.RLock()
+	return func() {
+		lock.RUnlock()
+		releaseReviewLock(key, lock)
+	}
+}
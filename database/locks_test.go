@@ -0,0 +1,74 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockReviewDB(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "reviews.db")
+
+	// Readers don't block each other.
+	unlockA := RLockReviewDB(path)
+	unlockB := RLockReviewDB(path)
+
+	locked := make(chan struct{})
+	go func() {
+		unlock := LockReviewDB(path)
+		close(locked)
+		unlock()
+	}()
+
+	// Writers wait for readers.
+	select {
+	case <-locked:
+		t.Fatal("expected writer to wait for readers")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockA()
+	unlockB()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("expected writer to get the lock after readers are done")
+	}
+}
+
+func TestLockReviewDBSerializesWriters(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "reviews.db")
+
+	var wg sync.WaitGroup
+	writing := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := LockReviewDB(path)
+			defer unlock()
+
+			writing++
+			if writing != 1 {
+				t.Error("expected writers to be serialized:", writing)
+			}
+			time.Sleep(time.Millisecond)
+			writing--
+		}()
+	}
+	wg.Wait()
+
+	reviewLocks.Lock()
+	defer reviewLocks.Unlock()
+	if _, ok := reviewLocks.entries[path]; ok {
+		t.Fatal("expected unused lock to be forgotten")
+	}
+}
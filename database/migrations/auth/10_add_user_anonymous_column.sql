@@ -0,0 +1,13 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Trial accounts created for visitors who started studying without
+-- registering.
+-- They can only be accessed from the session that created them, until they
+-- get claimed with a username and password.
+ALTER TABLE user ADD COLUMN anonymous INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE user DROP COLUMN anonymous;
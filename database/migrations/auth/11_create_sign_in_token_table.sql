@@ -0,0 +1,16 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- One-time tokens for signing in without a password (e.g. account setup links
+-- for provisioned users).
+CREATE TABLE sign_in_token (
+	-- SHA-256 hash of the token.
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES user ON DELETE CASCADE,
+	expires INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE sign_in_token;
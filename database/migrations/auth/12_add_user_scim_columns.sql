@@ -0,0 +1,18 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Deactivated users can't sign in, but their data is kept.
+ALTER TABLE user ADD COLUMN active INTEGER NOT NULL DEFAULT 1;
+
+-- ID of the user in the identity provider that provisioned the account (see
+-- SCIM `externalId`).
+ALTER TABLE user ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX index_user_external_id ON user (external_id);
+
+-- +goose Down
+DROP INDEX index_user_external_id;
+ALTER TABLE user DROP COLUMN external_id;
+ALTER TABLE user DROP COLUMN active;
@@ -0,0 +1,19 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Suspicious batches of uploaded reviews that are waiting for an admin to
+-- approve or discard them.
+CREATE TABLE quarantine (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES user ON DELETE CASCADE,
+	l1 TEXT NOT NULL,
+	l2 TEXT NOT NULL,
+	reviews TEXT NOT NULL,	-- JSON array of review results
+	reasons TEXT NOT NULL,	-- JSON array of strings
+	created INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- +goose Down
+DROP TABLE quarantine;
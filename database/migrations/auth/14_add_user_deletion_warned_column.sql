@@ -0,0 +1,11 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- When the user was last warned that their inactive account would be deleted
+-- (NULL if never).
+ALTER TABLE user ADD COLUMN deletion_warned INTEGER;
+
+-- +goose Down
+ALTER TABLE user DROP COLUMN deletion_warned;
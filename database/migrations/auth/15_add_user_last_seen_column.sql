@@ -0,0 +1,11 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- When the user last used a signed in session (NULL if unknown).
+-- Only updated about once an hour.
+ALTER TABLE user ADD COLUMN last_seen INTEGER;
+
+-- +goose Down
+ALTER TABLE user DROP COLUMN last_seen;
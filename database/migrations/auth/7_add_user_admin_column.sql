@@ -0,0 +1,10 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Admins can see instance-wide stats.
+ALTER TABLE user ADD COLUMN admin INTEGER NOT NULL DEFAULT 0 CHECK (admin IN (0, 1));
+
+-- +goose Down
+ALTER TABLE user DROP COLUMN admin;
@@ -0,0 +1,41 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Classes created by teachers.
+CREATE TABLE IF NOT EXISTS class (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL CHECK (name != ''),
+	teacher INTEGER NOT NULL REFERENCES user ON DELETE CASCADE,
+
+	-- Students join the class using this code.
+	code TEXT UNIQUE NOT NULL CHECK (code != ''),
+	created INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+CREATE TABLE IF NOT EXISTS class_member (
+	class INTEGER NOT NULL REFERENCES class ON DELETE CASCADE,
+	student INTEGER NOT NULL REFERENCES user ON DELETE CASCADE,
+	joined INTEGER NOT NULL DEFAULT (unixepoch('now')),
+	PRIMARY KEY (class, student)
+);
+
+-- Word lists assigned to the class.
+CREATE TABLE IF NOT EXISTS class_assignment (
+	id INTEGER PRIMARY KEY,
+	class INTEGER NOT NULL REFERENCES class ON DELETE CASCADE,
+	l1 TEXT NOT NULL,
+	l2 TEXT NOT NULL,
+	title TEXT NOT NULL,
+	words TEXT NOT NULL,	-- JSON array of words
+	created INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+CREATE INDEX IF NOT EXISTS index_class_member_student ON class_member (student);
+
+-- +goose Down
+DROP INDEX IF EXISTS index_class_member_student;
+DROP TABLE IF EXISTS class_assignment;
+DROP TABLE IF EXISTS class_member;
+DROP TABLE IF EXISTS class;
@@ -0,0 +1,12 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Tombstone of accounts that have been merged into another account.
+-- Merged accounts can't sign in, but they're kept so that the username can't
+-- be taken by someone else.
+ALTER TABLE user ADD COLUMN merged_into INTEGER REFERENCES user ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE user DROP COLUMN merged_into;
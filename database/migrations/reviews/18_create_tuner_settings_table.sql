@@ -0,0 +1,18 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Parameters of the difficulty tuner.
+-- This should only contain one entry at most.
+-- Default parameters are used if the table is empty.
+CREATE TABLE IF NOT EXISTS tuner_settings (
+	id TEXT PRIMARY KEY DEFAULT 'tuner-settings' CHECK (id = 'tuner-settings'),
+	easy_z REAL NOT NULL CHECK (easy_z < 0),
+	easy_bound REAL NOT NULL CHECK (easy_bound > 0 AND easy_bound < 1),
+	hard_z REAL NOT NULL CHECK (hard_z > 0),
+	hard_bound REAL NOT NULL CHECK (hard_bound > 0 AND hard_bound < 1)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tuner_settings;
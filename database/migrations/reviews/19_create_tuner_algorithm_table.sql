@@ -0,0 +1,15 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Difficulty tuner algorithm used in the course.
+-- This should only contain one entry at most.
+-- Uses 'wilson' if the table is empty.
+CREATE TABLE IF NOT EXISTS tuner_algorithm (
+	id TEXT PRIMARY KEY DEFAULT 'tuner-algorithm' CHECK (id = 'tuner-algorithm'),
+	name TEXT NOT NULL CHECK (name IN ('wilson', 'bayes'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tuner_algorithm;
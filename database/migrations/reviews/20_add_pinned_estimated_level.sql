@@ -0,0 +1,10 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- If pinned, the difficulty tuner doesn't change the estimated level.
+ALTER TABLE estimated_level ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0 CHECK (pinned IN (0, 1));
+
+-- +goose Down
+ALTER TABLE estimated_level DROP COLUMN pinned;
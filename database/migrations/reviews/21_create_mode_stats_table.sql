@@ -0,0 +1,14 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Review results per exercise type (e.g. 'cloze', 'listening').
+CREATE TABLE IF NOT EXISTS mode_stats (
+	mode TEXT PRIMARY KEY,
+	correct INTEGER NOT NULL DEFAULT 0,
+	incorrect INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mode_stats;
@@ -0,0 +1,15 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- User's notes on words.
+-- Course notes are in the course DB (`note` table).
+CREATE TABLE IF NOT EXISTS user_note (
+	item TEXT PRIMARY KEY,
+	text TEXT NOT NULL,
+	updated INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_note;
@@ -0,0 +1,13 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Earned achievements (see package achievements).
+CREATE TABLE IF NOT EXISTS achievement (
+	name TEXT PRIMARY KEY,
+	earned INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS achievement;
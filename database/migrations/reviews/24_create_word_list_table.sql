@@ -0,0 +1,14 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Words the user wants to learn next.
+-- New words in this list are introduced before other new words.
+CREATE TABLE IF NOT EXISTS word_list (
+	item TEXT PRIMARY KEY,
+	added INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS word_list;
@@ -0,0 +1,28 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Sentences mined from texts submitted by the user.
+-- These are used as cloze sentences alongside sentences in the course.
+CREATE TABLE IF NOT EXISTS user_sentence (
+	id INTEGER PRIMARY KEY,
+	text TEXT UNIQUE NOT NULL,
+	tokens TEXT NOT NULL,	-- JSON array of strings
+	added INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- Words in user sentences.
+CREATE TABLE IF NOT EXISTS user_sentence_word (
+	sentence INTEGER NOT NULL REFERENCES user_sentence ON DELETE CASCADE,
+	item TEXT NOT NULL,
+	PRIMARY KEY (sentence, item)
+);
+
+CREATE INDEX IF NOT EXISTS index_user_sentence_word_item
+ON user_sentence_word (item);
+
+-- +goose Down
+DROP INDEX IF EXISTS index_user_sentence_word_item;
+DROP TABLE IF EXISTS user_sentence_word;
+DROP TABLE IF EXISTS user_sentence;
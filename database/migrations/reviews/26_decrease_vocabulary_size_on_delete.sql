@@ -0,0 +1,22 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+-- +goose StatementBegin
+
+-- Forgotten words (deleted reviews) no longer count towards the vocabulary
+-- size.
+CREATE TRIGGER trigger_vocabulary_size_after_delete_on_review
+AFTER DELETE ON review
+FOR EACH ROW
+	WHEN OLD.interval > 0
+		BEGIN
+			UPDATE vocabulary_size SET
+				t = unixepoch('now'),
+				v = max(v - 1, 0);
+		END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER trigger_vocabulary_size_after_delete_on_review;
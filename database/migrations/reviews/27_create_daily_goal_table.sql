@@ -0,0 +1,14 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Number of reviews the user wants to do every day.
+-- This should only contain one entry at most.
+CREATE TABLE IF NOT EXISTS daily_goal (
+	id TEXT PRIMARY KEY DEFAULT 'daily-goal' CHECK (id = 'daily-goal'),
+	reviews INTEGER NOT NULL CHECK (reviews >= 0)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS daily_goal;
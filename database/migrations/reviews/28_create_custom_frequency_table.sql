@@ -0,0 +1,15 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- User's own word frequency list.
+-- Overrides the frequency ordering and frequency classes in the course.
+CREATE TABLE IF NOT EXISTS custom_frequency (
+	item TEXT PRIMARY KEY,
+	rank INTEGER NOT NULL,	-- 1 = most frequent
+	frequency_class INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS custom_frequency;
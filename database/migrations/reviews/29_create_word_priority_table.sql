@@ -0,0 +1,15 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- User's priority overrides for new words.
+-- New words with higher priorities are introduced first, and words with
+-- negative priorities are introduced last.
+CREATE TABLE IF NOT EXISTS word_priority (
+	item TEXT PRIMARY KEY,
+	priority INTEGER NOT NULL CHECK (priority BETWEEN -100 AND 100 AND priority != 0)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS word_priority;
@@ -0,0 +1,13 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Sentences written by the user (instead of mined from texts).
+-- These are preferred over other sentences.
+ALTER TABLE user_sentence ADD COLUMN authored BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE user_sentence ADD COLUMN translation TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE user_sentence DROP COLUMN translation;
+ALTER TABLE user_sentence DROP COLUMN authored;
@@ -0,0 +1,21 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Daily summaries of archived reviews.
+-- Archived reviews get removed from `history`, so stats have to combine both
+-- tables.
+-- Columns are the same as in `history.Summary`.
+CREATE TABLE IF NOT EXISTS history_summary (
+	day INTEGER PRIMARY KEY,	-- Number of days since UNIX epoch (UTC)
+	unimproved INTEGER NOT NULL DEFAULT 0,
+	learned INTEGER NOT NULL DEFAULT 0,
+	forgotten INTEGER NOT NULL DEFAULT 0,
+	crammed INTEGER NOT NULL DEFAULT 0,
+	strengthened INTEGER NOT NULL DEFAULT 0,
+	new_words INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS history_summary;
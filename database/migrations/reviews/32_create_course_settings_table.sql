@@ -0,0 +1,18 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- User's settings for the course.
+-- This should only contain one entry at most.
+CREATE TABLE IF NOT EXISTS course_settings (
+	id TEXT PRIMARY KEY DEFAULT 'course-settings' CHECK (id = 'course-settings'),
+	direction TEXT NOT NULL CHECK (direction IN ('recognition', 'production', 'both')),
+	leniency TEXT NOT NULL CHECK (leniency IN ('strict', 'normal', 'lenient')),
+	new_words_per_day INTEGER NOT NULL CHECK (new_words_per_day >= 0),
+	reviews_per_day INTEGER NOT NULL CHECK (reviews_per_day >= 0),
+	hints TEXT NOT NULL CHECK (hints IN ('always', 'on-request', 'never'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS course_settings;
@@ -0,0 +1,19 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Kind of item being reviewed, so that other exercise types can share the
+-- review table with words.
+-- Item names are still unique across types.
+ALTER TABLE review ADD COLUMN type TEXT NOT NULL DEFAULT 'word'
+	CHECK (type IN ('word', 'phrase', 'sentence', 'grammar-point'));
+
+-- Phrases are the only other item type that existed before this migration.
+UPDATE review SET type = 'phrase' WHERE item LIKE '% %';
+
+CREATE INDEX IF NOT EXISTS index_review_type_due ON review (type, due);
+
+-- +goose Down
+DROP INDEX IF EXISTS index_review_type_due;
+ALTER TABLE review DROP COLUMN type;
@@ -0,0 +1,16 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Vocabulary size the user wants to reach by the deadline.
+-- This should only contain one entry at most.
+CREATE TABLE IF NOT EXISTS vocabulary_goal (
+	id TEXT PRIMARY KEY DEFAULT 'vocabulary-goal' CHECK (id = 'vocabulary-goal'),
+	words INTEGER NOT NULL CHECK (words > 0),
+	deadline INTEGER NOT NULL,
+	created INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS vocabulary_goal;
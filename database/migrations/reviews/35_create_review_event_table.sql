@@ -0,0 +1,86 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+-- +goose StatementBegin
+
+-- Append-only log of changes to the review table.
+-- Shouldn't be updated nor deleted from.
+-- Each event contains the state of the item after the change, so the review
+-- table can be rebuilt from the most recent event of each item (see
+-- `review_scheduler.Rebuild`).
+CREATE TABLE review_event (
+	id INTEGER PRIMARY KEY,
+	item TEXT NOT NULL,
+	deleted BOOLEAN NOT NULL DEFAULT 0,	-- Set if the item was forgotten
+	type TEXT,
+	learned INTEGER,
+	reviewed INTEGER,
+	interval INTEGER,										-- # of hours
+	recorded INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+CREATE INDEX index_review_event_item ON review_event (item, id);
+
+-- Existing reviews become the initial events.
+INSERT INTO review_event (item, type, learned, reviewed, interval, recorded)
+SELECT item, type, learned, reviewed, interval, reviewed FROM review
+ORDER BY reviewed;
+
+-- Changes that don't change the state of the item (e.g. when the review table
+-- gets rebuilt) aren't recorded.
+CREATE TRIGGER trigger_review_event_after_insert_on_review
+AFTER INSERT ON review
+FOR EACH ROW
+	WHEN NOT EXISTS (
+		SELECT 1 FROM review_event
+		WHERE id = (SELECT max(id) FROM review_event WHERE item = NEW.item)
+			AND NOT deleted
+			AND type IS NEW.type
+			AND learned IS NEW.learned
+			AND reviewed IS NEW.reviewed
+			AND interval IS NEW.interval
+	)
+		BEGIN
+			INSERT INTO review_event (item, type, learned, reviewed, interval)
+			VALUES (NEW.item, NEW.type, NEW.learned, NEW.reviewed, NEW.interval);
+		END;
+
+CREATE TRIGGER trigger_review_event_after_update_on_review
+AFTER UPDATE ON review
+FOR EACH ROW
+	WHEN NOT EXISTS (
+		SELECT 1 FROM review_event
+		WHERE id = (SELECT max(id) FROM review_event WHERE item = NEW.item)
+			AND NOT deleted
+			AND type IS NEW.type
+			AND learned IS NEW.learned
+			AND reviewed IS NEW.reviewed
+			AND interval IS NEW.interval
+	)
+		BEGIN
+			INSERT INTO review_event (item, type, learned, reviewed, interval)
+			VALUES (NEW.item, NEW.type, NEW.learned, NEW.reviewed, NEW.interval);
+		END;
+
+CREATE TRIGGER trigger_review_event_after_delete_on_review
+AFTER DELETE ON review
+FOR EACH ROW
+	WHEN NOT EXISTS (
+		SELECT 1 FROM review_event
+		WHERE id = (SELECT max(id) FROM review_event WHERE item = OLD.item)
+			AND deleted
+	)
+		BEGIN
+			INSERT INTO review_event (item, deleted) VALUES (OLD.item, 1);
+		END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+
+DROP TRIGGER trigger_review_event_after_delete_on_review;
+DROP TRIGGER trigger_review_event_after_update_on_review;
+DROP TRIGGER trigger_review_event_after_insert_on_review;
+DROP INDEX index_review_event_item;
+DROP TABLE review_event;
@@ -0,0 +1,44 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+-- +goose StatementBegin
+
+-- Items that the user is assumed to know without having studied them (see
+-- package assumed).
+-- Entries get deleted once the item gets reviewed or forgotten, so that only
+-- reviews that the user hasn't confirmed get undone.
+CREATE TABLE IF NOT EXISTS assumed (
+	item TEXT PRIMARY KEY,
+	added INTEGER NOT NULL DEFAULT (unixepoch('now'))
+);
+
+-- Number of frequency classes that the user is assumed to know.
+-- This should only contain one entry at most.
+CREATE TABLE IF NOT EXISTS assumed_setting (
+	id TEXT PRIMARY KEY DEFAULT 'assumed-setting' CHECK (id = 'assumed-setting'),
+	frequency_classes INTEGER NOT NULL CHECK (frequency_classes >= 0)
+);
+
+CREATE TRIGGER trigger_assumed_after_update_of_reviewed_on_review
+AFTER UPDATE OF reviewed ON review
+FOR EACH ROW
+	BEGIN
+		DELETE FROM assumed WHERE item = NEW.item;
+	END;
+
+CREATE TRIGGER trigger_assumed_after_delete_on_review
+AFTER DELETE ON review
+FOR EACH ROW
+	BEGIN
+		DELETE FROM assumed WHERE item = OLD.item;
+	END;
+
+-- +goose StatementEnd
+
+-- +goose Down
+
+DROP TRIGGER trigger_assumed_after_delete_on_review;
+DROP TRIGGER trigger_assumed_after_update_of_reviewed_on_review;
+DROP TABLE IF EXISTS assumed_setting;
+DROP TABLE IF EXISTS assumed;
@@ -0,0 +1,11 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Lets the new word queue scan the word list in the order that words were
+-- added, instead of scanning every word in the course.
+CREATE INDEX IF NOT EXISTS index_word_list_added ON word_list (added);
+
+-- +goose Down
+DROP INDEX IF EXISTS index_word_list_added;
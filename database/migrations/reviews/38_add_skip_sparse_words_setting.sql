@@ -0,0 +1,11 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- If set, new words with few example sentences aren't introduced (see package
+-- coverage).
+ALTER TABLE course_settings ADD COLUMN skip_sparse_words BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE course_settings DROP COLUMN skip_sparse_words;
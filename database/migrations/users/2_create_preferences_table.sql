@@ -0,0 +1,15 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+-- Display preferences of the user.
+-- This should only contain one entry at most.
+CREATE TABLE IF NOT EXISTS preferences (
+	id TEXT PRIMARY KEY DEFAULT 'preferences' CHECK (id = 'preferences'),
+	theme TEXT NOT NULL DEFAULT 'system' CHECK (theme IN ('system', 'light', 'dark')),
+	font_size INTEGER NOT NULL DEFAULT 16 CHECK (font_size BETWEEN 12 AND 24),
+	show_translations BOOLEAN NOT NULL DEFAULT 1
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS preferences;
@@ -0,0 +1,19 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Daily review reminder settings.
+CREATE TABLE IF NOT EXISTS reminder (
+	id TEXT PRIMARY KEY DEFAULT 'reminder' CHECK (id = 'reminder'),
+	enabled BOOLEAN NOT NULL DEFAULT 0,
+	email TEXT NOT NULL DEFAULT '',
+	timezone TEXT NOT NULL DEFAULT 'UTC',
+	hour INTEGER NOT NULL DEFAULT 9 CHECK (hour BETWEEN 0 AND 23),
+
+	-- UNIX timestamp of the last reminder sent.
+	last_sent INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS reminder;
@@ -0,0 +1,17 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Public profile settings.
+-- Each element of the profile can be hidden separately.
+CREATE TABLE IF NOT EXISTS profile (
+	id TEXT PRIMARY KEY DEFAULT 'profile' CHECK (id = 'profile'),
+	public BOOLEAN NOT NULL DEFAULT 0,
+	show_streak BOOLEAN NOT NULL DEFAULT 1,
+	show_vocabulary BOOLEAN NOT NULL DEFAULT 1,
+	show_heatmap BOOLEAN NOT NULL DEFAULT 1
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS profile;
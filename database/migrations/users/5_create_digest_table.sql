@@ -0,0 +1,18 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- +goose Up
+
+-- Weekly progress digest settings.
+-- Digests get sent to the email address in the reminder settings.
+CREATE TABLE IF NOT EXISTS digest (
+	id TEXT PRIMARY KEY DEFAULT 'digest' CHECK (id = 'digest'),
+	enabled BOOLEAN NOT NULL DEFAULT 0,
+	weekday INTEGER NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 0 AND 6), -- 0 = Sunday
+
+	-- UNIX timestamp of the last digest sent.
+	last_sent INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS digest;
@@ -19,6 +19,42 @@ func UpgradeReviewDB(db *sql.DB) error {
 	return nil
 }
 
+// Returns the schema version of review DBs created by this version of
+// polycloze.
+func LatestReviewDBVersion() (int64, error) {
+	migrations, err := goose.CollectMigrations("migrations/reviews", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest review database version: %w", err)
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest review database version: %w", err)
+	}
+	return last.Version, nil
+}
+
+// Returns the schema version of the review DB, or 0 if it has never been
+// upgraded.
+// Unlike `goose.GetDBVersion`, this doesn't create the version table, so it's
+// safe to use on read-only databases.
+func ReviewDBVersion(db *sql.DB) (int64, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_schema WHERE name = 'goose_db_version')`
+	if err := db.QueryRow(query).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to get review database version: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int64
+	query = `SELECT coalesce(max(version_id), 0) FROM goose_db_version WHERE is_applied`
+	if err := db.QueryRow(query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get review database version: %w", err)
+	}
+	return version, nil
+}
+
 // Opens review database.
 // The caller has to Close the db.
 func OpenReviewDB(path string) (*sql.DB, error) {
@@ -4,16 +4,13 @@
 package database
 
 import (
-	"database/sql"
 	"testing"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func TestUpgradeReviewDB(t *testing.T) {
 	t.Parallel()
 
-	db, _ := sql.Open("sqlite3", ":memory:")
+	db, _ := Open(":memory:")
 	if err := UpgradeReviewDB(db); err != nil {
 		t.Fatal("expected err to be nil", err)
 	}
@@ -23,7 +20,7 @@ func TestUpgradeReviewDBTwice(t *testing.T) {
 	// Migration should go smoothly both times, even if there are no changes.
 	t.Parallel()
 
-	db, _ := sql.Open("sqlite3", ":memory:")
+	db, _ := Open(":memory:")
 	if err := UpgradeReviewDB(db); err != nil {
 		t.Fatal("expected err to be nil on first upgrade", err)
 	}
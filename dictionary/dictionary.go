@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Word lookup.
+// Course DBs only contain example sentences, so translations and parts of
+// speech come from an optional external dictionary.
+package dictionary
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+type Example struct {
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+}
+
+type Definition struct {
+	PartOfSpeech string   `json:"partOfSpeech"`
+	Translations []string `json:"translations"`
+}
+
+type Entry struct {
+	Word           string       `json:"word"`
+	FrequencyClass int          `json:"frequencyClass"`
+	Definitions    []Definition `json:"definitions"`
+	Examples       []Example    `json:"examples"`
+}
+
+// External dictionary.
+// l1 and l2 are ISO 639-3 codes.
+type Source interface {
+	Define(ctx context.Context, l1, l2, word string) ([]Definition, error)
+}
+
+// Returns up to n example sentences containing the word.
+func examples[T database.Querier](q T, wordID, n int) ([]Example, error) {
+	query := `
+		SELECT sentence.text, coalesce(
+			(SELECT translation.text FROM translates
+			JOIN translation ON (target = translation.tatoeba_id)
+			WHERE source = sentence.tatoeba_id
+			LIMIT 1),
+			''
+		)
+		FROM contains JOIN sentence ON (sentence = sentence.id)
+		WHERE word = ?
+		ORDER BY sentence.frequency_class, sentence.id
+		LIMIT ?
+	`
+	rows, err := q.Query(query, wordID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Example, 0)
+	for rows.Next() {
+		var example Example
+		if err := rows.Scan(&example.Text, &example.Translation); err != nil {
+			return nil, err
+		}
+		result = append(result, example)
+	}
+	return result, rows.Err()
+}
+
+// Looks up word in the course DB.
+// Returns sql.ErrNoRows if the word isn't in the course.
+// n: max number of example sentences.
+func Lookup[T database.Querier](q T, word string, n int) (Entry, error) {
+	entry := Entry{
+		Word:        text.Casefold(word),
+		Definitions: make([]Definition, 0),
+	}
+
+	var id int
+	query := `SELECT id, frequency_class FROM word WHERE word = ?`
+	err := q.QueryRow(query, entry.Word).Scan(&id, &entry.FrequencyClass)
+	if err == sql.ErrNoRows {
+		return entry, err
+	}
+	if err != nil {
+		return entry, fmt.Errorf("failed to look up word (%v): %w", word, err)
+	}
+
+	entry.Examples, err = examples(q, id, n)
+	if err != nil {
+		return entry, fmt.Errorf("failed to look up word (%v): %w", word, err)
+	}
+	return entry, nil
+}
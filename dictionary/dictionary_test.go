@@ -0,0 +1,81 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package dictionary
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func populate(db *sql.DB) {
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'hallo', 0)`,
+		`INSERT INTO sentence (id, tatoeba_id, text, tokens, frequency_class)
+		VALUES (1, 10, 'Hallo!', '["Hallo", "!"]', 0)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1)`,
+		`INSERT INTO translation (tatoeba_id, text) VALUES (20, 'Hello!')`,
+		`INSERT INTO translates (source, target) VALUES (10, 20)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+	populate(db)
+
+	entry, err := Lookup(db, "Hallo", 5)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if entry.Word != "hallo" {
+		t.Fatal("expected word to be casefolded:", entry.Word)
+	}
+	if len(entry.Examples) != 1 || entry.Examples[0].Translation != "Hello!" {
+		t.Fatal("unexpected examples:", entry.Examples)
+	}
+
+	if _, err := Lookup(db, "tschüss", 5); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal("expected unknown word to be missing:", err)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("word") != "hallo" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"partOfSpeech": "interjection", "translations": ["hello"]}]`))
+	}))
+	defer server.Close()
+
+	source := HTTP{URL: server.URL}
+	definitions, err := source.Define(context.Background(), "eng", "deu", "hallo")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(definitions) != 1 || definitions[0].PartOfSpeech != "interjection" {
+		t.Fatal("unexpected definitions:", definitions)
+	}
+
+	definitions, err = source.Define(context.Background(), "eng", "deu", "foo")
+	if err != nil || len(definitions) != 0 {
+		t.Fatal("expected no definitions:", definitions, err)
+	}
+}
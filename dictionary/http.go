@@ -0,0 +1,61 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package dictionary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Looks up definitions from an HTTP API.
+// Sends GET requests to `URL?l1=...&l2=...&word=...` and expects a JSON
+// array of definitions in the response.
+type HTTP struct {
+	URL string
+
+	Client *http.Client // Uses http.DefaultClient if nil
+}
+
+func (h HTTP) Define(ctx context.Context, l1, l2, word string) ([]Definition, error) {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("l1", l1)
+	q.Set("l2", l2)
+	q.Set("word", word)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dictionary request: %w", err)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("dictionary request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dictionary request failed: %v", resp.Status)
+	}
+
+	var definitions []Definition
+	if err := json.NewDecoder(resp.Body).Decode(&definitions); err != nil {
+		return nil, fmt.Errorf("failed to decode dictionary response: %w", err)
+	}
+	return definitions, nil
+}
@@ -15,6 +15,9 @@ type Difficulty struct {
 	Incorrect int `json:"incorrect"`
 	Max       int `json:"max"`
 	Min       int `json:"min"`
+
+	// If set, the tuner doesn't change the level until it gets unpinned.
+	Pinned bool `json:"pinned"`
 }
 
 // Returns min difficulty (frequency class of easiest unseen word).
@@ -59,20 +62,27 @@ func GetLatest[T database.Querier](q T) Difficulty {
 		Max:   maxDifficulty(q),
 	}
 
-	query := `SELECT v, correct, incorrect FROM estimated_level`
+	query := `SELECT v, correct, incorrect, pinned FROM estimated_level`
 	_ = q.QueryRow(query).Scan(
 		&difficulty.Level,
 		&difficulty.Correct,
 		&difficulty.Incorrect,
+		&difficulty.Pinned,
 	)
 	return difficulty
 }
 
 // Updates difficulty table.
+// Doesn't change the level if it's pinned, and ignores `difficulty.Pinned`.
 func Update[T database.Querier](q T, difficulty Difficulty) error {
 	query := `
-		INSERT OR REPLACE INTO estimated_level (v, correct, incorrect)
+		INSERT INTO estimated_level (v, correct, incorrect)
 		VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			t = excluded.t,
+			v = CASE WHEN pinned THEN v ELSE excluded.v END,
+			correct = excluded.correct,
+			incorrect = excluded.incorrect
 	`
 	_, err := q.Exec(
 		query,
@@ -85,3 +95,30 @@ func Update[T database.Querier](q T, difficulty Difficulty) error {
 	}
 	return nil
 }
+
+// Sets the level manually and resets the tuner stats.
+// If `pinned` is true, the tuner won't change the level until it gets
+// unpinned.
+func Override[T database.Querier](q T, level int, pinned bool) error {
+	if level < 0 {
+		return fmt.Errorf("failed to override difficulty: invalid level: %v", level)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO estimated_level (v, correct, incorrect, pinned)
+		VALUES (?, 0, 0, ?)
+	`
+	if _, err := q.Exec(query, level, pinned); err != nil {
+		return fmt.Errorf("failed to override difficulty: %w", err)
+	}
+	return nil
+}
+
+// Lets the tuner change the level again.
+func Unpin[T database.Querier](q T) error {
+	query := `UPDATE estimated_level SET pinned = 0`
+	if _, err := q.Exec(query); err != nil {
+		return fmt.Errorf("failed to unpin difficulty: %w", err)
+	}
+	return nil
+}
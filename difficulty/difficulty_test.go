@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/polycloze/polycloze/utils"
+	"github.com/polycloze/polycloze/wilson"
 )
 
 func TestMinDifficultyEmptyTable(t *testing.T) {
@@ -143,3 +144,160 @@ func TestGetLatestLevelAboveMinLevel(t *testing.T) {
 		t.Fatal("expected level to be 5:", out)
 	}
 }
+
+func TestThresholdsDefault(t *testing.T) {
+	// Should return default thresholds if none have been set.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	thresholds, err := GetThresholds(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if thresholds != wilson.DefaultThresholds() {
+		t.Fatal("expected default thresholds:", thresholds)
+	}
+}
+
+func TestThresholdsSetGet(t *testing.T) {
+	// Should return saved thresholds, and reject invalid ones.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	expected := wilson.Thresholds{EasyZ: -0.845, EasyBound: 0.8, HardZ: 2.325, HardBound: 0.7}
+	if err := SetThresholds(db, expected); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	invalid := wilson.Thresholds{EasyZ: 1, EasyBound: 2, HardZ: -1, HardBound: 0}
+	if err := SetThresholds(db, invalid); err == nil {
+		t.Fatal("expected invalid thresholds to be rejected")
+	}
+
+	thresholds, err := GetThresholds(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if thresholds != expected {
+		t.Fatal("expected saved thresholds:", thresholds, expected)
+	}
+}
+
+func TestRecentDecisions(t *testing.T) {
+	// Should return level changes, starting with the most recent one.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	for _, level := range []int{3, 4, 2} {
+		if err := Update(db, Difficulty{Level: level}); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	decisions, err := RecentDecisions(db, 2)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(decisions) != 2 {
+		t.Fatal("expected two decisions:", decisions)
+	}
+	if decisions[0].Before != 4 || decisions[0].After != 2 {
+		t.Fatal("expected most recent decision to be 4 -> 2:", decisions[0])
+	}
+	if decisions[1].Before != 3 || decisions[1].After != 4 {
+		t.Fatal("expected second decision to be 3 -> 4:", decisions[1])
+	}
+}
+
+func TestAlgorithm(t *testing.T) {
+	// Should default to wilson, and only accept known algorithms.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	name, err := GetAlgorithm(db)
+	if err != nil || name != "wilson" {
+		t.Fatal("expected default algorithm to be wilson:", name, err)
+	}
+
+	if err := SetAlgorithm(db, "foo"); err == nil {
+		t.Fatal("expected invalid algorithm to be rejected")
+	}
+	if err := SetAlgorithm(db, "bayes"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	name, err = GetAlgorithm(db)
+	if err != nil || name != "bayes" {
+		t.Fatal("expected algorithm to be bayes:", name, err)
+	}
+}
+
+func TestOverridePinned(t *testing.T) {
+	// Update shouldn't change the level while it's pinned.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if err := Override(db, 10, true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := Update(db, Difficulty{Level: 3, Correct: 1}); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	difficulty := GetLatest(db)
+	if difficulty.Level != 10 || !difficulty.Pinned {
+		t.Fatal("expected level to stay pinned at 10:", difficulty)
+	}
+	if difficulty.Correct != 1 {
+		t.Fatal("expected stats to be updated:", difficulty)
+	}
+
+	if err := Unpin(db); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := Update(db, Difficulty{Level: 3}); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	difficulty = GetLatest(db)
+	if difficulty.Level != 3 || difficulty.Pinned {
+		t.Fatal("expected level to change after unpinning:", difficulty)
+	}
+}
+
+func TestTunerLevelsUp(t *testing.T) {
+	// Both algorithms should eventually level up if all answers are correct.
+	t.Parallel()
+
+	for _, algorithm := range []string{"wilson", "bayes"} {
+		tuner := NewTuner(Difficulty{Max: 10}, wilson.DefaultThresholds(), algorithm)
+		for i := 0; i < 10 && tuner.Level == 0; i++ {
+			tuner.Update(true)
+		}
+		if tuner.Level != 1 || tuner.Correct != 0 {
+			t.Fatal("expected tuner to level up:", algorithm, tuner.Difficulty)
+		}
+	}
+}
+
+func TestTunerPinned(t *testing.T) {
+	// Pinned levels shouldn't change.
+	t.Parallel()
+
+	tuner := NewTuner(Difficulty{Level: 5, Max: 10, Pinned: true}, wilson.DefaultThresholds(), "wilson")
+	for i := 0; i < 20; i++ {
+		if tuner.Update(false) {
+			t.Fatal("expected pinned level to stay the same:", tuner.Difficulty)
+		}
+	}
+}
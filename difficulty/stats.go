@@ -0,0 +1,100 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Difficulty tuner statistics.
+package difficulty
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/history"
+)
+
+// Results of reviews of new words in a frequency class.
+type ClassStats struct {
+	FrequencyClass int `json:"frequencyClass"`
+	Correct        int `json:"correct"`
+	Incorrect      int `json:"incorrect"`
+}
+
+// Change in estimated level.
+type Decision struct {
+	Time   time.Time `json:"time"`
+	Before int       `json:"before"`
+	After  int       `json:"after"`
+}
+
+// Counts correct and incorrect reviews of new words in the given time range,
+// grouped by frequency class.
+// `Querier` should have access to `history` and `word` tables.
+// Archived reviews (see `history.Archive`) don't have words, so the range
+// starts after the archived history, instead of silently undercounting.
+func StatsByClass[T database.Querier](q T, from, to time.Time) ([]ClassStats, error) {
+	archived, err := history.ArchivedUntil(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tuner stats: %w", err)
+	}
+	if from.Before(archived) {
+		from = archived
+	}
+
+	query := `
+		SELECT frequency_class,
+			sum(interval_after > 0),
+			sum(interval_after <= 0)
+		FROM history JOIN word USING (word)
+		WHERE interval_before IS NULL AND reviewed >= ? AND reviewed < ?
+		GROUP BY frequency_class
+		ORDER BY frequency_class ASC
+	`
+	rows, err := q.Query(query, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tuner stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]ClassStats, 0)
+	for rows.Next() {
+		var s ClassStats
+		if err := rows.Scan(&s.FrequencyClass, &s.Correct, &s.Incorrect); err != nil {
+			return nil, fmt.Errorf("failed to get tuner stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// Returns most recent changes to the estimated level, no more than `limit`.
+// The list starts with newer decisions.
+func RecentDecisions[T database.Querier](q T, limit int) ([]Decision, error) {
+	query := `
+		SELECT t, lag(v) OVER (ORDER BY id ASC), v
+		FROM estimated_level_history
+		ORDER BY id DESC
+		LIMIT ?
+	`
+	rows, err := q.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tuner decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := make([]Decision, 0)
+	for rows.Next() {
+		var t int64
+		var before sql.NullInt64
+		var d Decision
+		if err := rows.Scan(&t, &before, &d.After); err != nil {
+			return nil, fmt.Errorf("failed to get tuner decisions: %w", err)
+		}
+		d.Time = time.Unix(t, 0)
+		if before.Valid {
+			d.Before = int(before.Int64)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Difficulty tuner parameters and algorithm.
+package difficulty
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/wilson"
+)
+
+// Gets tuner thresholds from the review DB.
+// Returns default thresholds if the user hasn't set any.
+func GetThresholds[T database.Querier](q T) (wilson.Thresholds, error) {
+	var t wilson.Thresholds
+	query := `SELECT easy_z, easy_bound, hard_z, hard_bound FROM tuner_settings`
+	err := q.QueryRow(query).Scan(&t.EasyZ, &t.EasyBound, &t.HardZ, &t.HardBound)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return wilson.DefaultThresholds(), nil
+		}
+		return t, fmt.Errorf("failed to get tuner thresholds: %w", err)
+	}
+	return t, nil
+}
+
+// Saves tuner thresholds in the review DB.
+func SetThresholds[T database.Querier](q T, t wilson.Thresholds) error {
+	if !t.IsValid() {
+		return fmt.Errorf("failed to set tuner thresholds: invalid value: %v", t)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO tuner_settings (easy_z, easy_bound, hard_z, hard_bound)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := q.Exec(query, t.EasyZ, t.EasyBound, t.HardZ, t.HardBound); err != nil {
+		return fmt.Errorf("failed to set tuner thresholds: %w", err)
+	}
+	return nil
+}
+
+// Checks if the tuner algorithm name is valid.
+func IsValidAlgorithm(name string) bool {
+	switch name {
+	case "wilson", "bayes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Gets name of tuner algorithm used in the course ("wilson" or "bayes").
+// Returns "wilson" if the user hasn't picked one.
+func GetAlgorithm[T database.Querier](q T) (string, error) {
+	var name string
+	query := `SELECT name FROM tuner_algorithm`
+	if err := q.QueryRow(query).Scan(&name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "wilson", nil
+		}
+		return "", fmt.Errorf("failed to get tuner algorithm: %w", err)
+	}
+	return name, nil
+}
+
+// Sets tuner algorithm used in the course.
+func SetAlgorithm[T database.Querier](q T, name string) error {
+	if !IsValidAlgorithm(name) {
+		return fmt.Errorf("failed to set tuner algorithm: invalid name: %v", name)
+	}
+
+	query := `INSERT OR REPLACE INTO tuner_algorithm (name) VALUES (?)`
+	if _, err := q.Exec(query, name); err != nil {
+		return fmt.Errorf("failed to set tuner algorithm: %w", err)
+	}
+	return nil
+}
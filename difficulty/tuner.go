@@ -0,0 +1,76 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package difficulty
+
+import (
+	"github.com/polycloze/polycloze/bayes"
+	"github.com/polycloze/polycloze/wilson"
+)
+
+// Difficulty tuner.
+// Mirrors `DifficultyTuner` in the client (see js/src/difficulty.ts), so that
+// tuner parameters can be evaluated on the server (e.g. in simulations).
+type Tuner struct {
+	Difficulty
+	Thresholds wilson.Thresholds
+	Algorithm  string // "wilson" or "bayes"
+}
+
+func NewTuner(difficulty Difficulty, thresholds wilson.Thresholds, algorithm string) *Tuner {
+	return &Tuner{
+		Difficulty: difficulty,
+		Thresholds: thresholds,
+		Algorithm:  algorithm,
+	}
+}
+
+func (t *Tuner) isTooEasy() bool {
+	if t.Algorithm == "bayes" {
+		return bayes.DefaultThresholds().IsTooEasy(t.Correct, t.Incorrect)
+	}
+	return t.Thresholds.IsTooEasy(t.Correct, t.Incorrect)
+}
+
+func (t *Tuner) isTooHard() bool {
+	if t.Algorithm == "bayes" {
+		return bayes.DefaultThresholds().IsTooHard(t.Correct, t.Incorrect)
+	}
+	return t.Thresholds.IsTooHard(t.Correct, t.Incorrect)
+}
+
+// Updates level statistics.
+// Returns true if level changed.
+// Also resets `Correct` and `Incorrect` counters if so.
+// This method should only be called for newly seen words.
+func (t *Tuner) Update(correct bool) bool {
+	if correct {
+		t.Correct++
+	} else {
+		t.Incorrect++
+	}
+
+	if t.Pinned {
+		return false
+	}
+
+	level := t.Level
+	if t.isTooEasy() {
+		t.Level = level + 1
+		if t.Level > t.Max {
+			t.Level = t.Max
+		}
+	} else if t.isTooHard() {
+		t.Level = level - 1
+		if t.Level < t.Min {
+			t.Level = t.Min
+		}
+	}
+
+	if level == t.Level {
+		return false
+	}
+	t.Correct = 0
+	t.Incorrect = 0
+	return true
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Weekly progress digest emails.
+package digest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/goals"
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+const week = 7 * 24 * time.Hour
+
+type Settings struct {
+	Enabled bool         `json:"enabled"`
+	Weekday time.Weekday `json:"weekday"` // Local day to send digests on
+}
+
+func defaultSettings() Settings {
+	return Settings{Weekday: time.Monday}
+}
+
+func (s Settings) IsValid() bool {
+	return s.Weekday >= time.Sunday && s.Weekday <= time.Saturday
+}
+
+// Gets digest settings from the user DB.
+// Returns default settings if the user hasn't set any.
+func Get(db *sql.DB) (Settings, error) {
+	s := defaultSettings()
+	query := `SELECT enabled, weekday FROM digest`
+	err := db.QueryRow(query).Scan(&s.Enabled, &s.Weekday)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return s, fmt.Errorf("failed to get digest settings: %w", err)
+	}
+	return s, nil
+}
+
+// Saves digest settings in the user DB.
+func Set(db *sql.DB, s Settings) error {
+	if !s.IsValid() {
+		return fmt.Errorf("failed to set digest settings: invalid value: %v", s)
+	}
+
+	query := `
+		INSERT INTO digest (enabled, weekday) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			enabled = excluded.enabled,
+			weekday = excluded.weekday
+	`
+	if _, err := db.Exec(query, s.Enabled, s.Weekday); err != nil {
+		return fmt.Errorf("failed to set digest settings: %w", err)
+	}
+	return nil
+}
+
+// Summary of the past week in a course.
+type CourseDigest struct {
+	L1 string
+	L2 string
+
+	Reviews  int
+	NewWords int
+	Learned  int
+	Accuracy float64 // Fraction of correct reviews
+	Streak   int
+
+	// Forecast for next week.
+	DueNextWeek int
+
+	// Nil if the user hasn't set a vocabulary goal in the course.
+	Goal *goals.Progress
+}
+
+// Returns accuracy as a rounded percentage.
+func (d CourseDigest) Percent() int {
+	return int(math.Round(100 * d.Accuracy))
+}
+
+// Summarizes the week before `now` in the course.
+func summarize(db *sql.DB, now time.Time) (CourseDigest, error) {
+	var d CourseDigest
+
+	query := `
+		SELECT count(*),
+			coalesce(sum(interval_before IS NULL), 0),
+			coalesce(sum(interval_after > 0), 0)
+		FROM history
+		WHERE reviewed >= ? AND reviewed < ?
+	`
+	var correct int
+	err := db.QueryRow(query, now.Add(-week).Unix(), now.Unix()).Scan(
+		&d.Reviews,
+		&d.NewWords,
+		&correct,
+	)
+	if err != nil {
+		return d, fmt.Errorf("failed to summarize week: %w", err)
+	}
+
+	// Add archived reviews (see `history.Archive`).
+	// Archived days are in UTC, and correct reviews are estimated from the
+	// summary categories, so this is only approximate.
+	query = `
+		SELECT coalesce(sum(unimproved + learned + forgotten + crammed + strengthened), 0),
+			coalesce(sum(new_words), 0),
+			coalesce(sum(learned + crammed + strengthened), 0)
+		FROM history_summary
+		WHERE day * 86400 >= ? AND day * 86400 < ?
+	`
+	var archived, archivedNew, archivedCorrect int
+	err = db.QueryRow(query, now.Add(-week).Unix(), now.Unix()).Scan(
+		&archived,
+		&archivedNew,
+		&archivedCorrect,
+	)
+	if err != nil {
+		return d, fmt.Errorf("failed to summarize week: %w", err)
+	}
+	d.Reviews += archived
+	d.NewWords += archivedNew
+	correct += archivedCorrect
+
+	if d.Reviews > 0 {
+		d.Accuracy = float64(correct) / float64(d.Reviews)
+	}
+
+	summaries, err := history.Summarize(db, now.Add(-week), now, week)
+	if err != nil {
+		return d, fmt.Errorf("failed to summarize week: %w", err)
+	}
+	for _, s := range summaries {
+		d.Learned += s.Learned
+	}
+
+	d.Streak, err = history.Streak(db, now)
+	if err != nil {
+		return d, fmt.Errorf("failed to summarize week: %w", err)
+	}
+
+	d.DueNextWeek, err = review_scheduler.CountDue(db, now.Add(week))
+	if err != nil {
+		return d, fmt.Errorf("failed to summarize week: %w", err)
+	}
+
+	// The DB is opened read-only, so it may not have the goal table yet.
+	d.Goal, _ = goals.GetProgress(db, now)
+	return d, nil
+}
+
+// Summarizes the past week in each of the user's courses.
+// Skips courses without reviews or due words.
+func summarizeCourses(userID int, now time.Time) ([]CourseDigest, error) {
+	paths, err := filepath.Glob(filepath.Join(basedir.User(userID), "reviews", "*.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	var digests []CourseDigest
+	for _, path := range paths {
+		l1, l2, found := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".db"), "-")
+		if !found {
+			continue
+		}
+
+		db, err := database.Open(path + "?mode=ro")
+		if err != nil {
+			return nil, err
+		}
+		d, err := summarize(db, now)
+		db.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Reviews > 0 || d.DueNextWeek > 0 {
+			d.L1, d.L2 = l1, l2
+			digests = append(digests, d)
+		}
+	}
+	return digests, nil
+}
+
+// Returns the start of the local day.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Checks if it's time to send a digest.
+// Digests get sent at most once per local day, on the chosen weekday, after
+// the hour in the reminder settings.
+func isTime(s Settings, r reminders.Settings, lastSent, now time.Time) bool {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return false
+	}
+	local := now.In(loc)
+	return local.Weekday() == s.Weekday &&
+		local.Hour() >= r.Hour &&
+		lastSent.Before(startOfDay(local))
+}
+
+// Sends digest to user if needed.
+// Returns true if a digest was sent.
+func send(userID int, username string, now time.Time) (bool, error) {
+	db, err := database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	s, err := Get(db)
+	if err != nil || !s.Enabled {
+		return false, err
+	}
+	r, err := reminders.Get(db)
+	if err != nil || r.Email == "" {
+		return false, err
+	}
+
+	var lastSent int64
+	query := `SELECT last_sent FROM digest`
+	if err := db.QueryRow(query).Scan(&lastSent); err != nil {
+		return false, err
+	}
+	if !isTime(s, r, time.Unix(lastSent, 0), now) {
+		return false, nil
+	}
+
+	loc, _ := time.LoadLocation(r.Timezone)
+	courses, err := summarizeCourses(userID, now.In(loc))
+	if err != nil || len(courses) == 0 {
+		return false, err
+	}
+
+	m, err := mail.Render("digest.txt", r.Email, map[string]any{
+		"Username": username,
+		"Courses":  courses,
+	})
+	if err != nil {
+		return false, err
+	}
+	mail.Send(m)
+
+	query = `UPDATE digest SET last_sent = ?`
+	if _, err := db.Exec(query, now.Unix()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Sends digests to users who are due for one.
+// db: auth DB
+// Returns the number of digests sent.
+func Run(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query(`SELECT id, username FROM user WHERE merged_into IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send digests: %w", err)
+	}
+
+	type user struct {
+		id       int
+		username string
+	}
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.username); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to send digests: %w", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+
+	sent := 0
+	for _, u := range users {
+		ok, err := send(u.id, u.username, now)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to send digest to user %v: %w", u.id, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// Runs `Run` periodically in the background.
+// Call the returned function to stop.
+func Schedule(db *sql.DB, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if _, err := Run(db, now); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.OpenUserDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	s, err := Get(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if s != defaultSettings() {
+		t.Fatal("expected default settings:", s)
+	}
+
+	s = Settings{Enabled: true, Weekday: time.Friday}
+	if err := Set(db, s); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if v, err := Get(db); err != nil || v != s {
+		t.Fatal("expected settings to be saved:", v, err)
+	}
+	if err := Set(db, Settings{Weekday: 7}); err == nil {
+		t.Fatal("expected invalid settings to be rejected")
+	}
+}
+
+func TestIsTime(t *testing.T) {
+	t.Parallel()
+
+	s := Settings{Enabled: true, Weekday: time.Monday}
+	r := reminders.Settings{Timezone: "Asia/Manila", Hour: 9} // UTC+8
+	monday := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)   // 08:00 local time
+
+	cases := []struct {
+		lastSent time.Time
+		now      time.Time
+		expected bool
+	}{
+		{time.Unix(0, 0), monday, false},
+		{time.Unix(0, 0), monday.Add(2 * time.Hour), true},
+		// Already sent today.
+		{monday.Add(90 * time.Minute), monday.Add(2 * time.Hour), false},
+		// Tuesday.
+		{time.Unix(0, 0), monday.Add(26 * time.Hour), false},
+	}
+	for _, c := range cases {
+		if v := isTime(s, r, c.lastSent, c.now); v != c.expected {
+			t.Fatal("unexpected result:", c.lastSent, c.now, v)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Now()
+	for _, word := range []string{"foo", "bar", "baz", "qux"} {
+		err := review_scheduler.UpdateReviewAt(db, word, word != "qux", now.Add(-time.Hour))
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	d, err := summarize(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if d.Reviews != 4 || d.NewWords != 4 || d.Percent() != 75 || d.Streak != 1 {
+		t.Fatal("unexpected digest:", d)
+	}
+	if d.DueNextWeek == 0 {
+		t.Fatal("expected words to be due next week:", d)
+	}
+
+	d.L1, d.L2 = "eng", "spa"
+	m, err := mail.Render("digest.txt", "foo@example.com", map[string]any{
+		"Username": "foo",
+		"Courses":  []CourseDigest{d},
+	})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !strings.Contains(m.Body, "Reviews: 4 (75% correct)") {
+		t.Fatal("expected digest to contain number of reviews:", m.Body)
+	}
+}
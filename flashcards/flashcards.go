@@ -5,9 +5,13 @@ package flashcards
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand"
 
 	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/notes"
+	rs "github.com/polycloze/polycloze/review_scheduler"
 	"github.com/polycloze/polycloze/sentences"
 	"github.com/polycloze/polycloze/translator"
 	"github.com/polycloze/polycloze/word_scheduler"
@@ -15,7 +19,7 @@ import (
 
 // Different from sentences.Sentence
 type Sentence struct {
-	ID        int    `json:"id"`    // id in database
+	ID        int    `json:"id"`    // id in database (0 for user sentences)
 	Parts     []Part `json:"parts"` // Odd-numbers parts are blanks
 	TatoebaID int64  `json:"tatoebaID,omitempty"`
 }
@@ -23,6 +27,10 @@ type Sentence struct {
 type Item struct {
 	Sentence    Sentence               `json:"sentence"`
 	Translation translator.Translation `json:"translation"`
+	Mode        string                 `json:"mode"` // See IsValidMode
+
+	// Notes on the word, shown after answering.
+	Notes []notes.Note `json:"notes"`
 }
 
 type ItemGenerator struct {
@@ -38,20 +46,62 @@ func NewItemGenerator(db *sql.DB, courseDB string) ItemGenerator {
 	}
 }
 
+// Probability of picking a sentence mined from the user's texts (if there is
+// one) over a sentence from the course.
+const userSentenceRate = 0.5
+
+// Picks sentence for cloze item, and its translation if it's not from the
+// course.
+// Sentences written by the user are always preferred.
+// Mined sentences aren't translated, so they're only used some of the time.
+func pickSentence[T database.Querier](q T, word string) (sentences.Sentence, *translator.Translation, error) {
+	sentence, translation, err := sentences.PickAuthoredSentence(q, word)
+	if err == nil {
+		return sentence, &translator.Translation{Text: translation}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sentence, nil, err
+	}
+
+	if rand.Float64() < userSentenceRate {
+		sentence, err := sentences.PickUserSentence(q, word)
+		if err == nil {
+			return sentence, &translator.Translation{}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return sentence, nil, err
+		}
+	}
+	sentence, err = sentences.PickSentence(q, word)
+	return sentence, nil, err
+}
+
 func generateItem[T database.Querier](q T, word word_scheduler.Word) (Item, error) {
 	var item Item
 
-	sentence, err := sentences.PickSentence(q, word.Word)
+	sentence, userTranslation, err := pickSentence(q, word.Word)
 	if err != nil {
 		return item, err
 	}
 
-	translation, err := translator.Translate(q, sentence)
+	var translation translator.Translation
+	if userTranslation != nil {
+		translation = *userTranslation
+	} else {
+		translation, err = translator.Translate(q, sentence)
+		if err != nil {
+			// Panic because this shouldn't happen with generated course files.
+			panic(fmt.Errorf("could not translate sentence (%v): %w", sentence, err))
+		}
+	}
+
+	wordNotes, err := notes.Get(q, word.Word)
 	if err != nil {
-		// Panic because this shouldn't happen with generated course files.
-		panic(fmt.Errorf("could not translate sentence (%v): %w", sentence, err))
+		return item, err
 	}
 	return Item{
+		Notes:       wordNotes,
+		Mode:        ModeCloze,
 		Translation: translation,
 		Sentence: Sentence{
 			ID:        sentence.ID,
@@ -87,3 +137,13 @@ func Get(
 	}
 	return generateItems(con, words)
 }
+
+// Same as Get, but filters words with conditions that can be checked in SQL
+// (see `review_scheduler.Filter`).
+func GetFiltered(con *database.Connection, n int, filter rs.Filter) []Item {
+	words, err := word_scheduler.GetWordsFiltered(con, n, filter)
+	if err != nil {
+		return nil
+	}
+	return generateItems(con, words)
+}
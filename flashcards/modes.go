@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package flashcards
+
+import (
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+// Exercise types.
+const (
+	// Sentence is shown with a blank.
+	ModeCloze = "cloze"
+
+	// Sentence is only heard, the user types the missing word.
+	// Audio comes from the client's text-to-speech.
+	ModeListening = "listening"
+
+	// Only the word is shown, the user grades their own answer.
+	ModeFlashcard = "flashcard"
+
+	// Only the translation is shown, the user types the word in L2.
+	// Production reviews are scheduled separately from the other modes (see
+	// basedir.ProductionReview).
+	ModeProduction = "production"
+)
+
+func IsValidMode(mode string) bool {
+	switch mode {
+	case ModeCloze, ModeListening, ModeFlashcard, ModeProduction:
+		return true
+	}
+	return false
+}
+
+// Returns mode, or ModeCloze if the mode is empty.
+func normalizeMode(mode string) string {
+	if mode == "" {
+		return ModeCloze
+	}
+	return mode
+}
+
+// Number of review results per exercise type.
+type ModeStats struct {
+	Mode      string `json:"mode"`
+	Correct   int    `json:"correct"`
+	Incorrect int    `json:"incorrect"`
+}
+
+// Adds review results to stats of each mode.
+// Reviews with invalid modes are ignored.
+func SaveModeStats[T database.Querier](q T, reviews []review_scheduler.Result) error {
+	query := `
+		INSERT INTO mode_stats (mode, correct, incorrect) VALUES (?, ?, ?)
+		ON CONFLICT (mode) DO UPDATE SET
+			correct = correct + excluded.correct,
+			incorrect = incorrect + excluded.incorrect
+	`
+	for _, review := range reviews {
+		mode := normalizeMode(review.Mode)
+		if !IsValidMode(mode) {
+			continue
+		}
+
+		correct, incorrect := 0, 1
+		if review.Correct {
+			correct, incorrect = 1, 0
+		}
+		if _, err := q.Exec(query, mode, correct, incorrect); err != nil {
+			return fmt.Errorf("failed to save mode stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// Returns stats of modes that have been reviewed.
+func GetModeStats[T database.Querier](q T) ([]ModeStats, error) {
+	query := `SELECT mode, correct, incorrect FROM mode_stats ORDER BY mode`
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mode stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]ModeStats, 0)
+	for rows.Next() {
+		var s ModeStats
+		if err := rows.Scan(&s.Mode, &s.Correct, &s.Incorrect); err != nil {
+			return nil, fmt.Errorf("failed to get mode stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package flashcards
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+func TestModeStats(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	reviews := []review_scheduler.Result{
+		{Word: "foo", Correct: true},
+		{Word: "bar", Correct: false, Mode: ModeListening},
+		{Word: "baz", Correct: true, Mode: ModeListening},
+		{Word: "qux", Correct: true, Mode: "unknown"},
+	}
+	if err := SaveModeStats(db, reviews); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	stats, err := GetModeStats(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	expected := []ModeStats{
+		{Mode: ModeCloze, Correct: 1, Incorrect: 0},
+		{Mode: ModeListening, Correct: 1, Incorrect: 1},
+	}
+	if len(stats) != len(expected) {
+		t.Fatal("unexpected stats:", stats)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Fatal("unexpected stats:", stats)
+		}
+	}
+}
@@ -34,19 +34,14 @@ type Part struct {
 }
 
 // Returns parts of cloze item.
+// The blank can span multiple tokens if the word is a phrase.
 func getParts(tokens []string, word word_scheduler.Word) []Part {
 	// TODO word: string -> Word
 	normalized := text.Casefold(word.Word)
 
 	// Find all matching tokens.
-	var indices []int
-	for i, token := range tokens {
-		if text.Casefold(token) == normalized {
-			indices = append(indices, i)
-		}
-	}
-
-	if len(indices) == 0 {
+	spans := text.FindSpans(tokens, normalized)
+	if len(spans) == 0 {
 		message := fmt.Sprintf(
 			"Python casefold different from golang casefold: %s, %v",
 			normalized,
@@ -57,20 +52,22 @@ func getParts(tokens []string, word word_scheduler.Word) []Part {
 
 	// Pick a random one if there are multiple matches.
 	// TODO turn all matching tokens into blanks instead.
-	index := indices[rand.Intn(len(indices))]
+	span := spans[rand.Intn(len(spans))]
+	start, end := span[0], span[1]
 
 	before := Part{
-		Text: strings.Join(tokens[:index], ""),
+		Text: strings.Join(tokens[:start], ""),
 	}
 	after := Part{
-		Text: strings.Join(tokens[index+1:], ""),
+		Text: strings.Join(tokens[end:], ""),
 	}
 
+	missingText := strings.Join(tokens[start:end], "")
 	missing := Part{
-		Text: tokens[index],
+		Text: missingText,
 		Answers: []Answer{
 			{
-				Text:       tokens[index],
+				Text:       missingText,
 				Normalized: normalized,
 				New:        word.New,
 				Difficulty: word.Difficulty,
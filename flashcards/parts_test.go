@@ -0,0 +1,27 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package flashcards
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/word_scheduler"
+)
+
+func TestGetPartsPhrase(t *testing.T) {
+	// Blanks of phrases should span multiple tokens.
+	t.Parallel()
+
+	tokens := []string{"Por", " ", "favor", ",", " ", "ven", "."}
+	parts := getParts(tokens, word_scheduler.Word{Word: "por favor"})
+	if len(parts) != 3 {
+		t.Fatal("expected three parts:", parts)
+	}
+	if parts[0].Text != "" || parts[1].Text != "Por favor" || parts[2].Text != ", ven." {
+		t.Fatal("unexpected parts:", parts)
+	}
+	if parts[1].Answers[0].Normalized != "por favor" {
+		t.Fatal("unexpected answer:", parts[1].Answers)
+	}
+}
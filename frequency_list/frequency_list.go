@@ -0,0 +1,150 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Custom word frequency lists uploaded by users.
+// Words in the list are introduced before other words, and their frequency
+// classes override the ones in the course.
+package frequency_list
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+var ErrEmpty = errors.New("frequency list is empty")
+
+type Entry struct {
+	Word           string
+	Rank           int // 1 = most frequent
+	FrequencyClass int
+}
+
+// Computes frequency class the same way the course builder does.
+// See `python/scripts/tokenizer.py`.
+func frequencyClass(count, maxCount float64) int {
+	return int(math.Floor(0.5 - math.Log2(count/maxCount)))
+}
+
+// Parses frequency list.
+// Each line contains a word, optionally followed by a comma or tab and the
+// word's count.
+// Lines should be sorted from most to least frequent.
+// Empty lines and lines that start with "#" are skipped.
+// If some lines don't have counts, counts are estimated from the ranks using
+// Zipf's law.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var counts []float64
+	hasCounts := true
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == '\t'
+		})
+		word := text.Casefold(strings.TrimSpace(fields[0]))
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+
+		count := 0.0
+		if len(fields) > 1 {
+			v, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid count on line %q", line)
+			}
+			count = v
+		} else {
+			hasCounts = false
+		}
+
+		entries = append(entries, Entry{Word: word, Rank: len(entries) + 1})
+		counts = append(counts, count)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse frequency list: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrEmpty
+	}
+
+	maxCount := 0.0
+	for i := range entries {
+		if !hasCounts {
+			counts[i] = 1 / float64(entries[i].Rank)
+		}
+		maxCount = math.Max(maxCount, counts[i])
+	}
+	for i := range entries {
+		entries[i].FrequencyClass = frequencyClass(counts[i], maxCount)
+	}
+	return entries, nil
+}
+
+// Replaces user's frequency list.
+// Returns the number of words saved.
+// Words that aren't in the course are skipped.
+// Database connection should have access to course and review data.
+func Save[T database.Querier](q T, entries []Entry) (int, error) {
+	tx, err := q.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to save frequency list: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM custom_frequency`); err != nil {
+		return 0, fmt.Errorf("failed to save frequency list: %w", err)
+	}
+
+	query := `
+		INSERT INTO custom_frequency (item, rank, frequency_class)
+		SELECT word, ?, ? FROM word WHERE word = ?
+	`
+	saved := 0
+	for _, entry := range entries {
+		result, err := tx.Exec(query, entry.Rank, entry.FrequencyClass, entry.Word)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save frequency list: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			saved += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to save frequency list: %w", err)
+	}
+	return saved, nil
+}
+
+// Deletes user's frequency list.
+func Clear[T database.Querier](q T) error {
+	if _, err := q.Exec(`DELETE FROM custom_frequency`); err != nil {
+		return fmt.Errorf("failed to clear frequency list: %w", err)
+	}
+	return nil
+}
+
+// Returns the number of words in the user's frequency list.
+func Count[T database.Querier](q T) (int, error) {
+	var count int
+	if err := q.QueryRow(`SELECT count(*) FROM custom_frequency`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count frequency list: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package frequency_list
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+	"github.com/polycloze/polycloze/word_scheduler"
+)
+
+func TestParseWithCounts(t *testing.T) {
+	t.Parallel()
+
+	input := "# word,count\nDer,1000\nhaus\t250\n\nder,10\n"
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(entries) != 2 || entries[0].Word != "der" || entries[1].Rank != 2 {
+		t.Fatal("unexpected entries:", entries)
+	}
+	if entries[0].FrequencyClass != 0 || entries[1].FrequencyClass != 2 {
+		t.Fatal("unexpected frequency classes:", entries)
+	}
+}
+
+func TestParseWithoutCounts(t *testing.T) {
+	t.Parallel()
+
+	entries, err := Parse(strings.NewReader("a\nb\nc\nd\n"))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if entries[0].FrequencyClass != 0 || entries[3].FrequencyClass != 2 {
+		t.Fatal("expected frequency classes to be estimated from ranks:", entries)
+	}
+
+	if _, err := Parse(strings.NewReader("# nothing\n")); !errors.Is(err, ErrEmpty) {
+		t.Fatal("expected ErrEmpty:", err)
+	}
+	if _, err := Parse(strings.NewReader("a,x\n")); err == nil {
+		t.Fatal("expected invalid count to be rejected")
+	}
+}
+
+func TestSaveOverridesOrdering(t *testing.T) {
+	// Words in the frequency list should be introduced first.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `
+		INSERT INTO word (id, word, frequency_class)
+		VALUES (1, 'der', 0), (2, 'die', 0), (3, 'haus', 3)
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	entries, err := Parse(strings.NewReader("haus\nder\nkatze\n"))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	saved, err := Save(db, entries)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if saved != 2 {
+		t.Fatal("expected words not in the course to be skipped:", saved)
+	}
+
+	words, err := word_scheduler.GetNewWordsWith(db, 3, 0, func(_ string) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 3 || words[0].Word != "haus" || words[1].Word != "der" {
+		t.Fatal("unexpected word order:", words)
+	}
+	if words[0].Difficulty != 0 {
+		t.Fatal("expected custom frequency class:", words[0])
+	}
+
+	if err := Clear(db); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count, err := Count(db); err != nil || count != 0 {
+		t.Fatal("expected frequency list to be empty:", count, err)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Vocabulary goals (e.g. 3000 words by June).
+package goals
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/history"
+)
+
+// Max number of words in a goal.
+const MaxWords = 100000
+
+// Length of the period used to compute the user's current pace.
+const paceWindow = 30 * 24 * time.Hour
+
+type Goal struct {
+	Words    int       `json:"words"`
+	Deadline time.Time `json:"deadline"`
+	Created  time.Time `json:"created"`
+}
+
+func (g Goal) IsValid() bool {
+	return g.Words > 0 && g.Words <= MaxWords && !g.Deadline.IsZero()
+}
+
+type Progress struct {
+	Goal
+	VocabSize int  `json:"vocabSize"`
+	Reached   bool `json:"reached"`
+
+	// Number of words the user has to learn every day to reach the goal on
+	// time.
+	// -1 if the deadline has passed.
+	RequiredPerDay float64 `json:"requiredPerDay"`
+
+	// Estimated number of words learned per day at the current pace.
+	LearnedPerDay float64 `json:"learnedPerDay"`
+
+	// True if the goal will be reached on time at the current pace.
+	OnTrack bool `json:"onTrack"`
+}
+
+// Returns user's goal in the course.
+// Returns nil if the user hasn't set a goal.
+func Get(db *sql.DB) (*Goal, error) {
+	var goal Goal
+	var deadline, created int64
+	query := `SELECT words, deadline, created FROM vocabulary_goal`
+	err := db.QueryRow(query).Scan(&goal.Words, &deadline, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+	goal.Deadline = time.Unix(deadline, 0)
+	goal.Created = time.Unix(created, 0)
+	return &goal, nil
+}
+
+// Replaces the user's goal in the course.
+// Ignores `goal.Created`.
+func Set(db *sql.DB, goal Goal) error {
+	if !goal.IsValid() {
+		return fmt.Errorf("failed to set goal: invalid value: %v", goal)
+	}
+
+	query := `INSERT OR REPLACE INTO vocabulary_goal (words, deadline) VALUES (?, ?)`
+	if _, err := db.Exec(query, goal.Words, goal.Deadline.Unix()); err != nil {
+		return fmt.Errorf("failed to set goal: %w", err)
+	}
+	return nil
+}
+
+// Removes the user's goal in the course.
+func Delete(db *sql.DB) error {
+	if _, err := db.Exec(`DELETE FROM vocabulary_goal`); err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	return nil
+}
+
+// Computes progress towards the user's goal.
+// Returns nil if the user hasn't set a goal.
+func GetProgress(db *sql.DB, now time.Time) (*Progress, error) {
+	goal, err := Get(db)
+	if err != nil || goal == nil {
+		return nil, err
+	}
+
+	vocabSize, err := history.CurrentVocabSize(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal progress: %w", err)
+	}
+	pace, err := history.GetPace(db, now.Add(-paceWindow), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal progress: %w", err)
+	}
+
+	progress := Progress{
+		Goal:          *goal,
+		VocabSize:     vocabSize,
+		Reached:       vocabSize >= goal.Words,
+		LearnedPerDay: pace.LearnedPerDay(),
+	}
+	if progress.Reached {
+		progress.OnTrack = true
+		return &progress, nil
+	}
+
+	days := goal.Deadline.Sub(now).Hours() / 24
+	if days <= 0 {
+		progress.RequiredPerDay = -1
+		return &progress, nil
+	}
+	progress.RequiredPerDay = float64(goal.Words-vocabSize) / days
+	progress.OnTrack = progress.LearnedPerDay >= progress.RequiredPerDay
+	return &progress, nil
+}
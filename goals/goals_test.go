@@ -0,0 +1,59 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package goals
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestGetProgress(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Now()
+	progress, err := GetProgress(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if progress != nil {
+		t.Fatal("expected no progress without a goal:", progress)
+	}
+
+	if err := Set(db, Goal{Words: 0, Deadline: now}); err == nil {
+		t.Fatal("expected invalid goal to be rejected")
+	}
+
+	goal := Goal{Words: 10, Deadline: now.AddDate(0, 0, 9)}
+	if err := Set(db, goal); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := review_scheduler.UpdateReviewAt(db, "foo", true, now.Add(-time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	progress, err = GetProgress(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if progress == nil || progress.VocabSize != 1 || progress.Reached {
+		t.Fatal("unexpected progress:", progress)
+	}
+	if math.Abs(progress.RequiredPerDay-1) > 0.01 || progress.OnTrack {
+		t.Fatal("expected user to need one word per day:", progress)
+	}
+
+	if err := Delete(db); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if goal, err := Get(db); err != nil || goal != nil {
+		t.Fatal("expected goal to be deleted:", goal, err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Moves reviews older than `before` from the review history into the archive
+// DB, and adds them to daily summaries (`history_summary`) so that stats can
+// still be computed.
+// The most recent review of each word is kept, so that the review table can
+// still be reconstructed from the history.
+// Also thins out vocabulary size history older than `before` to one entry per
+// day.
+// Returns the number of archived reviews.
+func Archive(db *sql.DB, archivePath string, before time.Time) (int, error) {
+	ctx := context.Background()
+
+	// ATTACH only applies to one connection.
+	con, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+	defer con.Close()
+
+	query := `ATTACH DATABASE ? AS archive`
+	if _, err := con.ExecContext(ctx, query, archivePath); err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+	defer func() {
+		_, _ = con.ExecContext(ctx, `DETACH DATABASE archive`)
+	}()
+
+	tx, err := con.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+	defer tx.Rollback()
+
+	exec := func(query string) (sql.Result, error) {
+		return tx.ExecContext(ctx, query, sql.Named("before", before.Unix()))
+	}
+
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS archive.history (
+			word TEXT NOT NULL,
+			reviewed INTEGER NOT NULL,
+			interval_before INTEGER,
+			interval_after INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS archive.vocabulary_size_history (
+			t INTEGER NOT NULL,
+			v INTEGER NOT NULL
+		)`,
+		`CREATE TEMP TABLE archived_review AS
+		SELECT rowid AS id FROM main.history
+		WHERE reviewed < @before
+			AND rowid NOT IN (SELECT max(rowid) FROM main.history GROUP BY word)`,
+
+		// Same categories as in `Summarize`.
+		`INSERT INTO main.history_summary
+			(day, unimproved, learned, forgotten, crammed, strengthened, new_words)
+		SELECT
+			reviewed / 86400 AS d,
+			sum(before <= 0 AND interval_after <= 0),
+			sum(before <= 0 AND interval_after > 0),
+			sum(before > 0 AND before > interval_after),
+			sum(before > 0 AND before = interval_after),
+			sum(before > 0 AND before < interval_after),
+			sum(interval_before IS NULL)
+		FROM (
+			SELECT *, coalesce(interval_before, 0) AS before FROM main.history
+			WHERE rowid IN archived_review
+		)
+		GROUP BY d
+		ON CONFLICT (day) DO UPDATE SET
+			unimproved = unimproved + excluded.unimproved,
+			learned = learned + excluded.learned,
+			forgotten = forgotten + excluded.forgotten,
+			crammed = crammed + excluded.crammed,
+			strengthened = strengthened + excluded.strengthened,
+			new_words = new_words + excluded.new_words`,
+		`INSERT INTO archive.history (word, reviewed, interval_before, interval_after)
+		SELECT word, reviewed, interval_before, interval_after FROM main.history
+		WHERE rowid IN archived_review
+		ORDER BY rowid`,
+	}
+	for _, query := range queries {
+		if _, err := exec(query); err != nil {
+			return 0, fmt.Errorf("failed to archive review history: %w", err)
+		}
+	}
+
+	result, err := exec(`DELETE FROM main.history WHERE rowid IN archived_review`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+	archived, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+
+	queries = []string{
+		// Only keep the last vocabulary size of each day.
+		`CREATE TEMP TABLE archived_vocabulary_size AS
+		SELECT id FROM main.vocabulary_size_history
+		WHERE t < @before
+			AND id NOT IN (
+				SELECT max(id) FROM main.vocabulary_size_history GROUP BY t / 86400
+			)`,
+		`INSERT INTO archive.vocabulary_size_history (t, v)
+		SELECT t, v FROM main.vocabulary_size_history
+		WHERE id IN archived_vocabulary_size
+		ORDER BY id`,
+		`DELETE FROM main.vocabulary_size_history WHERE id IN archived_vocabulary_size`,
+
+		// Temp tables are per-connection, so they have to be dropped before the
+		// connection goes back into the pool.
+		`DROP TABLE temp.archived_review`,
+		`DROP TABLE temp.archived_vocabulary_size`,
+	}
+	for _, query := range queries {
+		if _, err := exec(query); err != nil {
+			return 0, fmt.Errorf("failed to archive review history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+	return int(archived), nil
+}
+
+// Returns the time before which some reviews may have been moved into the
+// archive, or the zero time if nothing has been archived.
+// Queries on the `history` table only see every review after this time.
+func ArchivedUntil[T database.Querier](q T) (time.Time, error) {
+	var day sql.NullInt64
+	query := `SELECT max(day) FROM history_summary`
+	if err := q.QueryRow(query).Scan(&day); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get end of archived history: %w", err)
+	}
+	if !day.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix((day.Int64+1)*86400, 0), nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestArchive(t *testing.T) {
+	// Stats should be the same after archiving.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	for _, days := range []int{-10, -9, -8, -2, -1} {
+		at := now.AddDate(0, 0, days)
+		for _, word := range []string{"foo", "bar"} {
+			if err := review_scheduler.UpdateReviewAt(db, word, true, at); err != nil {
+				t.Fatal("expected err to be nil:", err)
+			}
+		}
+	}
+
+	// Archived reviews are summarized by UTC day.
+	from := time.Date(2022, 9, 10, 0, 0, 0, 0, time.UTC)
+	before, err := Summarize(db, from, now, 24*time.Hour)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	archived, err := Archive(db, path, now.AddDate(0, 0, -5))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if archived != 6 {
+		t.Fatal("expected 6 archived reviews:", archived)
+	}
+
+	after, err := Summarize(db, from, now, 24*time.Hour)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			t.Fatal("expected summaries to be unchanged:", before[i], after[i])
+		}
+	}
+
+	count, err := CountReviews(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 10 {
+		t.Fatal("expected 10 reviews:", count)
+	}
+
+	// Raw reviews should be in the archive.
+	adb, err := database.Open(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer adb.Close()
+
+	if err := adb.QueryRow(`SELECT count(*) FROM history`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 6 {
+		t.Fatal("expected 6 reviews in archive:", count)
+	}
+}
+
+func TestArchivedUntil(t *testing.T) {
+	// Should be the start of the UTC day after the last archived day.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	until, err := ArchivedUntil(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !until.IsZero() {
+		t.Fatal("expected zero time if nothing has been archived:", until)
+	}
+
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	for _, days := range []int{-10, -8, -1} {
+		at := now.AddDate(0, 0, days)
+		if err := review_scheduler.UpdateReviewAt(db, "foo", true, at); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	if _, err := Archive(db, path, now.AddDate(0, 0, -5)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	until, err = ArchivedUntil(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	expected := time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC)
+	if !until.Equal(expected) {
+		t.Fatal("expected archived history to end after last archived day:", until, expected)
+	}
+}
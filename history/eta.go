@@ -0,0 +1,87 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Learning pace during a period of time.
+type Pace struct {
+	NewWordsPerDay float64 `json:"newWordsPerDay"`
+
+	// Fraction of correct reviews.
+	Accuracy float64 `json:"accuracy"`
+}
+
+// Estimates the number of words learned per day.
+// Assumes that new words get learned as often as reviews are correct.
+func (p Pace) LearnedPerDay() float64 {
+	return p.NewWordsPerDay * p.Accuracy
+}
+
+// Vocabulary size goal.
+type Milestone struct {
+	Words   int  `json:"words"`
+	Reached bool `json:"reached"`
+
+	// Estimated time when the milestone will be reached.
+	// Nil if the milestone has been reached, or if it can't be reached at the
+	// current pace.
+	ETA *time.Time `json:"eta,omitempty"`
+
+	// Estimated fraction of word occurrences in text covered by the most
+	// frequent words in the course, up to the number of words in the milestone.
+	Coverage float64 `json:"coverage"`
+}
+
+// Computes learning pace from reviews in the given range.
+// Archived reviews are not included.
+func GetPace(db *sql.DB, from, to time.Time) (Pace, error) {
+	var pace Pace
+	days := to.Sub(from).Hours() / 24
+	if days <= 0 {
+		return pace, nil
+	}
+
+	query := `
+		SELECT coalesce(sum(interval_before IS NULL), 0),
+			coalesce(sum(interval_after > 0), 0),
+			count(*)
+		FROM history
+		WHERE reviewed >= ? AND reviewed < ?
+	`
+	var newWords, correct, total int
+	err := db.QueryRow(query, from.Unix(), to.Unix()).Scan(&newWords, &correct, &total)
+	if err != nil {
+		return pace, fmt.Errorf("failed to compute learning pace: %w", err)
+	}
+
+	pace.NewWordsPerDay = float64(newWords) / days
+	if total > 0 {
+		pace.Accuracy = float64(correct) / float64(total)
+	}
+	return pace, nil
+}
+
+// Estimates when the user will reach vocabulary milestones at the given pace.
+// Doesn't fill in `Coverage`.
+func EstimateMilestones(pace Pace, vocabSize int, now time.Time, milestones []int) []Milestone {
+	rate := pace.LearnedPerDay()
+
+	result := make([]Milestone, 0, len(milestones))
+	for _, words := range milestones {
+		milestone := Milestone{Words: words, Reached: vocabSize >= words}
+		if !milestone.Reached && rate > 0 {
+			days := float64(words-vocabSize) / rate
+			eta := now.Add(time.Duration(math.Ceil(days*24)) * time.Hour)
+			milestone.ETA = &eta
+		}
+		result = append(result, milestone)
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestGetPace(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -2)
+	for _, word := range []string{"foo", "bar", "baz", "qux"} {
+		err := review_scheduler.UpdateReviewAt(db, word, word != "qux", now.Add(-time.Hour))
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	pace, err := GetPace(db, from, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if pace.NewWordsPerDay != 2 || pace.Accuracy != 0.75 {
+		t.Fatal("unexpected pace:", pace)
+	}
+}
+
+func TestEstimateMilestones(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000000, 0)
+	pace := Pace{NewWordsPerDay: 20, Accuracy: 0.5}
+	milestones := EstimateMilestones(pace, 1000, now, []int{1000, 2000})
+
+	if !milestones[0].Reached || milestones[0].ETA != nil {
+		t.Fatal("expected first milestone to be reached:", milestones[0])
+	}
+
+	expected := now.AddDate(0, 0, 100)
+	if milestones[1].Reached || milestones[1].ETA == nil || !milestones[1].ETA.Equal(expected) {
+		t.Fatal("expected second milestone to be reached in 100 days:", milestones[1])
+	}
+
+	for _, milestone := range EstimateMilestones(Pace{}, 0, now, []int{1000}) {
+		if milestone.ETA != nil {
+			t.Fatal("expected no ETA without reviews:", milestone)
+		}
+	}
+}
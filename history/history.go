@@ -83,9 +83,57 @@ func Summarize(db *sql.DB, from, to time.Time, step time.Duration) ([]Summary, e
 			summaries[i].Strengthened++
 		}
 	}
+
+	// Add daily summaries of archived reviews.
+	// Archived days are in UTC, so they're only approximately correct if `from`
+	// or `step` isn't aligned with UTC days.
+	query = `
+		SELECT (day * 86400 - @from)/@step, unimproved, learned, forgotten, crammed, strengthened
+		FROM history_summary
+		WHERE day * 86400 >= @from AND day * 86400 < @to
+	`
+	rows, err = db.Query(
+		query,
+		sql.Named("from", from.Unix()),
+		sql.Named("to", to.Unix()),
+		sql.Named("step", step/time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize review history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i int64
+		var s Summary
+		err := rows.Scan(&i, &s.Unimproved, &s.Learned, &s.Forgotten, &s.Crammed, &s.Strengthened)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize review history: %w", err)
+		}
+		summaries[i].Unimproved += s.Unimproved
+		summaries[i].Learned += s.Learned
+		summaries[i].Forgotten += s.Forgotten
+		summaries[i].Crammed += s.Crammed
+		summaries[i].Strengthened += s.Strengthened
+	}
 	return summaries, nil
 }
 
+// Returns total number of reviews, including archived reviews.
+func CountReviews(db *sql.DB) (int, error) {
+	var count int
+	query := `
+		SELECT (SELECT count(*) FROM history) + (
+			SELECT coalesce(sum(unimproved + learned + forgotten + crammed + strengthened), 0)
+			FROM history_summary
+		)
+	`
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+	return count, nil
+}
+
 // Returns list of reviews in given range.
 // The list starts with newer reviews.
 // Specify a negative `limit` to return an unbounded number of `Review`s.
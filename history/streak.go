@@ -0,0 +1,59 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Returns the number of consecutive days with at least one review, counting
+// back from the day of `now`.
+// Days are computed using the time zone of `now`.
+// The streak isn't broken if the user hasn't reviewed yet on the day of `now`.
+func Streak(db *sql.DB, now time.Time) (int, error) {
+	_, offset := now.Zone()
+	today := (now.Unix() + int64(offset)) / 86400
+
+	// Days with archived reviews are in UTC.
+	query := `
+		SELECT (reviewed + @offset) / 86400 AS day
+		FROM history
+		WHERE reviewed < @now
+		UNION
+		SELECT day FROM history_summary
+		WHERE day * 86400 < @now
+		ORDER BY day DESC
+	`
+	rows, err := db.Query(
+		query,
+		sql.Named("offset", offset),
+		sql.Named("now", now.Unix()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute streak: %w", err)
+	}
+	defer rows.Close()
+
+	streak := 0
+	expected := today
+	for rows.Next() {
+		var day int64
+		if err := rows.Scan(&day); err != nil {
+			return 0, fmt.Errorf("failed to compute streak: %w", err)
+		}
+
+		if streak == 0 && day == today-1 {
+			// Hasn't reviewed yet today.
+			expected = day
+		}
+		if day != expected {
+			break
+		}
+		streak++
+		expected--
+	}
+	return streak, nil
+}
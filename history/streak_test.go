@@ -0,0 +1,54 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestStreakNoReviews(t *testing.T) {
+	// Streak should be 0.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	streak, err := Streak(db, time.Now())
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if streak != 0 {
+		t.Fatal("expected streak to be 0:", streak)
+	}
+}
+
+func TestStreakConsecutiveDays(t *testing.T) {
+	// Only consecutive days up to yesterday or today should be counted.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+
+	// Reviews on days -1, -2 and -4.
+	for _, days := range []int{-4, -2, -1} {
+		at := now.AddDate(0, 0, days)
+		if err := review_scheduler.UpdateReviewAt(db, "foo", false, at); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	streak, err := Streak(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if streak != 2 {
+		t.Fatal("expected streak to be 2:", streak)
+	}
+}
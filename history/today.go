@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Review activity on the day of `now`.
+type Activity struct {
+	Reviews  int `json:"reviews"`  // Number of reviews
+	NewWords int `json:"newWords"` // Number of words reviewed for the first time
+}
+
+// Returns the start of the day of t, in the time zone of t.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Summarizes reviews from the start of the day of `now` until `now`.
+// Days are computed using the time zone of `now`.
+func Today(db *sql.DB, now time.Time) (Activity, error) {
+	var activity Activity
+	query := `
+		SELECT count(*), coalesce(sum(interval_before IS NULL), 0)
+		FROM history
+		WHERE reviewed >= @from AND reviewed <= @to
+	`
+	err := db.QueryRow(
+		query,
+		sql.Named("from", startOfDay(now).Unix()),
+		sql.Named("to", now.Unix()),
+	).Scan(&activity.Reviews, &activity.NewWords)
+	if err != nil {
+		return activity, fmt.Errorf("failed to summarize today's activity: %w", err)
+	}
+	return activity, nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestToday(t *testing.T) {
+	// Reviews from previous days shouldn't be counted.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	reviews := []struct {
+		word string
+		at   time.Time
+	}{
+		{"foo", now.AddDate(0, 0, -1)},
+		{"foo", now.Add(-time.Hour)},
+		{"bar", now.Add(-time.Hour)},
+	}
+	for _, review := range reviews {
+		if err := review_scheduler.UpdateReviewAt(db, review.word, true, review.at); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	activity, err := Today(db, now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if activity.Reviews != 2 || activity.NewWords != 1 {
+		t.Fatal("unexpected activity:", activity)
+	}
+}
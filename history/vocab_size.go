@@ -67,3 +67,13 @@ func VocabSize(db *sql.DB, from, to time.Time, step time.Duration) ([]Metric[flo
 	}
 	return series, nil
 }
+
+// Returns the user's current vocab size.
+func CurrentVocabSize(db *sql.DB) (int, error) {
+	var size int
+	query := `SELECT coalesce(max(v), 0) FROM vocabulary_size`
+	if err := db.QueryRow(query).Scan(&size); err != nil {
+		return 0, fmt.Errorf("failed to get current vocabulary size: %w", err)
+	}
+	return size, nil
+}
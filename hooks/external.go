@@ -0,0 +1,95 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Hooks that send events to other programs.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// Max time to wait for external handlers.
+const timeout = 10 * time.Second
+
+// JSON payload sent to external handlers.
+type payload struct {
+	Event string `json:"event"` // e.g. "review"
+	Data  any    `json:"data"`
+}
+
+// Sends events to a handler as JSON.
+type sender func(ctx context.Context, body []byte) error
+
+func (send sender) send(event string, data any) {
+	body, err := json.Marshal(payload{Event: event, Data: data})
+	if err != nil {
+		log.Println(fmt.Errorf("hook failed (%v): %w", event, err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := send(ctx, body); err != nil {
+		log.Println(fmt.Errorf("hook failed (%v): %w", event, err))
+	}
+}
+
+func (send sender) OnReview(e ReviewEvent) {
+	send.send("review", e)
+}
+
+func (send sender) OnWordLearned(e WordLearnedEvent) {
+	send.send("word-learned", e)
+}
+
+func (send sender) OnSessionEnd(e SessionEndEvent) {
+	send.send("session-end", e)
+}
+
+func (send sender) OnUserCreated(e UserCreatedEvent) {
+	send.send("user-created", e)
+}
+
+func (send sender) OnAchievement(e AchievementEvent) {
+	send.send("achievement", e)
+}
+
+// Returns hook that POSTs events as JSON to the URL.
+func Webhook(url string) Hook {
+	return sender(func(ctx context.Context, body []byte) error {
+		r, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected response: %v", resp.Status)
+		}
+		return nil
+	})
+}
+
+// Returns hook that runs the script with the event in stdin as JSON.
+func Script(path string) Hook {
+	return sender(func(ctx context.Context, body []byte) error {
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Stdin = bytes.NewReader(body)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return nil
+	})
+}
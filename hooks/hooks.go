@@ -0,0 +1,129 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Hooks for study events.
+// Lets other Go programs (see package polycloze), webhooks and scripts react to
+// events without modifying the server.
+package hooks
+
+import (
+	"sync"
+	"time"
+)
+
+type ReviewEvent struct {
+	UserID  int       `json:"userID"`
+	L1      string    `json:"l1"`
+	L2      string    `json:"l2"`
+	Word    string    `json:"word"`
+	Correct bool      `json:"correct"`
+	Time    time.Time `json:"time"`
+}
+
+// Emitted when the student sees a word for the first time.
+type WordLearnedEvent struct {
+	UserID int       `json:"userID"`
+	L1     string    `json:"l1"`
+	L2     string    `json:"l2"`
+	Word   string    `json:"word"`
+	Time   time.Time `json:"time"`
+}
+
+// Emitted when the user signs out.
+type SessionEndEvent struct {
+	UserID int       `json:"userID"`
+	Time   time.Time `json:"time"`
+}
+
+type UserCreatedEvent struct {
+	UserID   int       `json:"userID"`
+	Username string    `json:"username"`
+	Time     time.Time `json:"time"`
+}
+
+type AchievementEvent struct {
+	UserID      int       `json:"userID"`
+	L1          string    `json:"l1"`
+	L2          string    `json:"l2"`
+	Achievement string    `json:"achievement"`
+	Time        time.Time `json:"time"`
+}
+
+// Hook methods are called in a separate goroutine, so they can block, but they
+// shouldn't assume anything about the order of events.
+// Embed `Base` to only implement some of the methods.
+type Hook interface {
+	OnReview(e ReviewEvent)
+	OnWordLearned(e WordLearnedEvent)
+	OnSessionEnd(e SessionEndEvent)
+	OnUserCreated(e UserCreatedEvent)
+	OnAchievement(e AchievementEvent)
+}
+
+// Hook that does nothing.
+type Base struct{}
+
+func (Base) OnReview(e ReviewEvent)           {}
+func (Base) OnWordLearned(e WordLearnedEvent) {}
+func (Base) OnSessionEnd(e SessionEndEvent)   {}
+func (Base) OnUserCreated(e UserCreatedEvent) {}
+func (Base) OnAchievement(e AchievementEvent) {}
+
+var (
+	mu    sync.RWMutex
+	hooks []Hook
+	wg    sync.WaitGroup
+)
+
+// Subscribes hook to events.
+func Register(h Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, h)
+}
+
+// Checks if there are registered hooks.
+// Useful for skipping work needed to create events.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(hooks) > 0
+}
+
+// Calls fn on all registered hooks in the background.
+func emit(fn func(h Hook)) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, h := range hooks {
+		wg.Add(1)
+		go func(h Hook) {
+			defer wg.Done()
+			fn(h)
+		}(h)
+	}
+}
+
+// Waits for running hooks to finish (e.g. before shutting down).
+func Wait() {
+	wg.Wait()
+}
+
+func Review(e ReviewEvent) {
+	emit(func(h Hook) { h.OnReview(e) })
+}
+
+func WordLearned(e WordLearnedEvent) {
+	emit(func(h Hook) { h.OnWordLearned(e) })
+}
+
+func SessionEnd(e SessionEndEvent) {
+	emit(func(h Hook) { h.OnSessionEnd(e) })
+}
+
+func UserCreated(e UserCreatedEvent) {
+	emit(func(h Hook) { h.OnUserCreated(e) })
+}
+
+func Achievement(e AchievementEvent) {
+	emit(func(h Hook) { h.OnAchievement(e) })
+}
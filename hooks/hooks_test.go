@@ -0,0 +1,57 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package hooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type counter struct {
+	Base
+	reviews chan ReviewEvent
+}
+
+func (c counter) OnReview(e ReviewEvent) {
+	c.reviews <- e
+}
+
+func TestRegister(t *testing.T) {
+	// Registered hooks should receive events.
+	c := counter{reviews: make(chan ReviewEvent, 1)}
+	Register(c)
+	if !Enabled() {
+		t.Fatal("expected hooks to be enabled")
+	}
+
+	Review(ReviewEvent{Word: "foo", Correct: true})
+	Wait()
+
+	e := <-c.reviews
+	if e.Word != "foo" || !e.Correct {
+		t.Fatal("unexpected event:", e)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	// Webhook should POST event as JSON.
+	received := make(chan payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p payload
+		_ = json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer server.Close()
+
+	Webhook(server.URL).OnUserCreated(UserCreatedEvent{UserID: 1, Username: "foo"})
+
+	p := <-received
+	if p.Event != "user-created" {
+		t.Fatal("unexpected event:", p)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// First file descriptor passed by systemd (see sd_listen_fds(3)).
+const listenFDsStart = 3
+
+// Returns listener inherited from systemd (socket activation), or nil if there
+// isn't any.
+func inheritedListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+
+	// Don't pass the sockets to child processes.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	// Only uses the first socket.
+	f := os.NewFile(listenFDsStart, "LISTEN_FD_3")
+	defer f.Close()
+
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use inherited socket: %w", err)
+	}
+	return l, nil
+}
+
+// Returns listener inherited from systemd if there's one.
+// Otherwise listens on `addr`.
+func listen(addr string) (net.Listener, error) {
+	l, err := inheritedListener()
+	if err != nil || l != nil {
+		return l, err
+	}
+	return net.Listen("tcp", addr)
+}
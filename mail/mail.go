@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Email subsystem.
+// Messages are sent in the background through a queue that retries failed
+// sends. If SMTP isn't configured, messages are logged and dropped.
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // Plain text
+}
+
+type Sender interface {
+	Send(m Message) error
+}
+
+// Checks if the string is a single email address (e.g. "foo@example.com").
+func IsValidAddress(address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
+
+// Sender used when email isn't configured.
+type NoopSender struct{}
+
+func (NoopSender) Send(m Message) error {
+	log.Printf("Email not configured, dropped message to %v: %v\n", m.To, m.Subject)
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Sends emails through an SMTP server.
+// Uses STARTTLS if the server supports it.
+type SMTPSender struct {
+	Config SMTPConfig
+}
+
+// Returns RFC 5322 message.
+func (s SMTPSender) format(m Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %v\r\n", s.Config.From)
+	fmt.Fprintf(&b, "To: %v\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %v\r\n", m.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func (s SMTPSender) Send(m Message) error {
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return fmt.Errorf("failed to send email: invalid header")
+	}
+
+	var auth smtp.Auth
+	if s.Config.Username != "" {
+		auth = smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
+	}
+
+	addr := fmt.Sprintf("%v:%v", s.Config.Host, s.Config.Port)
+	err := smtp.SendMail(addr, auth, s.Config.From, []string{m.To}, s.format(m))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
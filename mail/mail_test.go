@@ -0,0 +1,72 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package mail
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	m, err := Render("test.txt", "foo@example.com", map[string]any{"Username": "foo"})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if m.Subject != "Test email from polycloze" {
+		t.Fatal("unexpected subject:", m.Subject)
+	}
+	if !strings.HasPrefix(m.Body, "Hi foo,") {
+		t.Fatal("unexpected body:", m.Body)
+	}
+}
+
+// Fails the first n sends.
+type flakySender struct {
+	failures int
+	sent     []Message
+}
+
+func (s *flakySender) Send(m Message) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("temporary failure")
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestQueueRetries(t *testing.T) {
+	t.Parallel()
+
+	sender := &flakySender{failures: 2}
+	q := NewQueue(sender)
+	q.backoff = 0
+
+	q.Enqueue(Message{To: "foo@example.com"})
+	q.Close()
+
+	if len(sender.sent) != 1 {
+		t.Fatal("expected message to be sent after retrying:", sender.sent)
+	}
+}
+
+func TestIsValidAddress(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"foo@example.com":       true,
+		"":                      false,
+		"foo":                   false,
+		"Foo <foo@example.com>": false,
+		"foo@example.com\r\nBcc: bar@example.com": false,
+	}
+	for address, expected := range cases {
+		if IsValidAddress(address) != expected {
+			t.Fatal("unexpected result:", address)
+		}
+	}
+}
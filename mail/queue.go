@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package mail
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Sends messages in the background.
+// Retries failed sends with exponential backoff.
+type Queue struct {
+	sender      Sender
+	maxAttempts int
+	backoff     time.Duration // Delay before the first retry
+
+	messages chan Message
+	wg       sync.WaitGroup
+}
+
+func NewQueue(sender Sender) *Queue {
+	q := &Queue{
+		sender:      sender,
+		maxAttempts: 5,
+		backoff:     time.Minute,
+		messages:    make(chan Message, 100),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+	for m := range q.messages {
+		q.send(m)
+	}
+}
+
+func (q *Queue) send(m Message) {
+	delay := q.backoff
+	for attempt := 1; ; attempt++ {
+		err := q.sender.Send(m)
+		if err == nil {
+			return
+		}
+		if attempt >= q.maxAttempts {
+			log.Printf("Gave up sending email to %v: %v\n", m.To, err)
+			return
+		}
+		log.Printf("%v (retrying in %v)\n", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Adds message to the queue.
+func (q *Queue) Enqueue(m Message) {
+	q.messages <- m
+}
+
+// Sends remaining messages and stops the queue.
+func (q *Queue) Close() {
+	close(q.messages)
+	q.wg.Wait()
+}
+
+// Queue used by `Send`.
+var (
+	queue      = NewQueue(NoopSender{})
+	configured = false
+)
+
+// Makes `Send` use the sender.
+// Should be called before the server starts handling requests.
+func Configure(sender Sender) {
+	queue.Close()
+	queue = NewQueue(sender)
+	configured = true
+}
+
+// Checks if a sender has been configured.
+func Configured() bool {
+	return configured
+}
+
+// Sends message in the background.
+func Send(m Message) {
+	queue.Enqueue(m)
+}
+
+// Waits for queued messages to be sent (e.g. before shutting down).
+func Flush() {
+	queue.Close()
+}
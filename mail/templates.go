@@ -0,0 +1,38 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.txt
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.txt"))
+
+// Renders message from template in ./templates.
+// The first line of the template should be the subject ("Subject: ..."),
+// followed by an empty line and the body.
+func Render(name, to string, data any) (Message, error) {
+	var b bytes.Buffer
+	if err := templates.ExecuteTemplate(&b, name, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render email: %w", err)
+	}
+
+	header, body, found := strings.Cut(b.String(), "\n\n")
+	subject, ok := strings.CutPrefix(header, "Subject: ")
+	if !found || !ok {
+		return Message{}, fmt.Errorf("failed to render email: missing subject: %v", name)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject),
+		Body:    body,
+	}, nil
+}
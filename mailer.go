@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/polycloze/polycloze/mail"
+)
+
+// Configures email using the environment variables:
+// POLYCLOZE_SMTP_HOST, POLYCLOZE_SMTP_PORT (default: 587),
+// POLYCLOZE_SMTP_USERNAME, POLYCLOZE_SMTP_PASSWORD and POLYCLOZE_SMTP_FROM.
+// Emails get dropped if POLYCLOZE_SMTP_HOST isn't set.
+func configureMail() {
+	host := os.Getenv("POLYCLOZE_SMTP_HOST")
+	if host == "" {
+		return
+	}
+
+	port, err := strconv.Atoi(os.Getenv("POLYCLOZE_SMTP_PORT"))
+	if err != nil {
+		port = 587
+	}
+
+	from := os.Getenv("POLYCLOZE_SMTP_FROM")
+	if from == "" {
+		from = "polycloze@" + host
+	}
+
+	mail.Configure(mail.SMTPSender{
+		Config: mail.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("POLYCLOZE_SMTP_USERNAME"),
+			Password: os.Getenv("POLYCLOZE_SMTP_PASSWORD"),
+			From:     from,
+		},
+	})
+}
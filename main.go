@@ -4,60 +4,139 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"strconv"
-
-	"github.com/polycloze/polycloze/api"
-	"github.com/polycloze/polycloze/basedir"
-	"github.com/polycloze/polycloze/database"
+	"strings"
 )
 
-type Args struct {
-	cors bool
-	port int
+type Command struct {
+	Usage string
+	Run   func(args []string) error
 }
 
-func defaultPortNumber() int {
-	port := os.Getenv("PORT")
-	if port != "" {
-		v, err := strconv.Atoi(port)
-		if err == nil {
-			return v
-		}
-	}
-	return 3000
+var commands = map[string]Command{
+	"serve": {
+		Usage: "serve [-c] [-p port]",
+		Run:   serve,
+	},
+	"migrate": {
+		Usage: "migrate",
+		Run:   migrate,
+	},
+	"user": {
+		Usage: "user add|passwd|delete|promote|demote <username>",
+		Run:   user,
+	},
+	"merge-users": {
+		Usage: "merge-users <username> <duplicate-username>",
+		Run:   mergeUsers,
+	},
+	"backup": {
+		Usage: "backup <directory>",
+		Run:   backup,
+	},
+	"validate-course": {
+		Usage: "validate-course <course.db>...",
+		Run:   validateCourse,
+	},
+	"add-phrases": {
+		Usage: "add-phrases <course.db> <phrases.txt>",
+		Run:   addPhrases,
+	},
+	"patch-course": {
+		Usage: "patch-course <course.db> <patch.sql>",
+		Run:   patchCourse,
+	},
+	"course-checksum": {
+		Usage: "course-checksum <course.db>...",
+		Run:   courseChecksum,
+	},
+	"sentence-coverage": {
+		Usage: "sentence-coverage [-threshold n] <course.db>",
+		Run:   sentenceCoverage,
+	},
+	"export": {
+		Usage: "export <username> <l1> <l2>",
+		Run:   export,
+	},
+	"export-stats": {
+		Usage: "export-stats <username> <l1> <l2>",
+		Run:   exportStats,
+	},
+	"export-bundle": {
+		Usage: "export-bundle <username> <l1> <l2>",
+		Run:   exportBundle,
+	},
+	"import-bundle": {
+		Usage: "import-bundle <username> <l1> <l2> <bundle.zip>",
+		Run:   importBundle,
+	},
+	"prune": {
+		Usage: "prune [-dry-run] [-inactive-days days]",
+		Run:   prune,
+	},
+	"archive": {
+		Usage: "archive [-days days]",
+		Run:   archive,
+	},
+	"rebuild-reviews": {
+		Usage: "rebuild-reviews",
+		Run:   rebuildReviews,
+	},
+	"restore": {
+		Usage: "restore <username> <l1> <l2> <time>",
+		Run:   restore,
+	},
+	"clean-cache": {
+		Usage: "clean-cache",
+		Run:   cleanCache,
+	},
 }
 
-func parseArgs() Args {
-	var args Args
+var commandOrder = []string{
+	"serve",
+	"migrate",
+	"user",
+	"merge-users",
+	"backup",
+	"validate-course",
+	"add-phrases",
+	"patch-course",
+	"course-checksum",
+	"sentence-coverage",
+	"export",
+	"export-stats",
+	"export-bundle",
+	"import-bundle",
+	"prune",
+	"archive",
+	"rebuild-reviews",
+	"restore",
+	"clean-cache",
+}
 
-	flag.BoolVar(&args.cors, "c", false, "allow CORS")
-	flag.IntVar(&args.port, "p", defaultPortNumber(), "port number")
-	flag.Parse()
-	return args
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	for _, name := range commandOrder {
+		fmt.Fprintln(os.Stderr, "  polycloze", commands[name].Usage)
+	}
 }
 
 func main() {
-	api.Startup()
-
-	args := parseArgs()
-	config := api.Config{AllowCORS: args.cors, Port: args.port}
-
-	db, err := database.OpenAuthDB(basedir.Auth())
-	if err != nil {
-		log.Fatal(err)
+	// Runs `serve` if no subcommand is given, for backward compatibility.
+	name := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	defer db.Close()
 
-	r, err := api.Router(config, db)
-	if err != nil {
+	command, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := command.Run(args); err != nil {
 		log.Fatal(err)
 	}
-	log.Printf("Listening on port %v\n", args.port)
-	log.Printf("Start learning: http://127.0.0.1:%v\n", args.port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", args.port), r))
 }
@@ -0,0 +1,488 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Data retention and pruning.
+package maintenance
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/reminders"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Trial accounts that haven't been used for this long get deleted.
+// They can't be claimed anymore anyway, because sessions don't last this long.
+const trialAccountAge = 24 * time.Hour
+
+// Inactive accounts only get deleted if their users were warned at least this
+// long before.
+const deletionWarningPeriod = 14 * 24 * time.Hour
+
+type Policy struct {
+	// Users with no activity for longer than this get warned that their
+	// accounts will be deleted, and the accounts get deleted after
+	// `deletionWarningPeriod`.
+	// Accounts of users who can't be warned by email don't get deleted.
+	// Zero disables account deletion.
+	InactiveAccountAge time.Duration
+}
+
+type InactiveAccount struct {
+	UserID     int       `json:"userID"`
+	Username   string    `json:"username"`
+	LastActive time.Time `json:"lastActive"`
+}
+
+// Summary of pruned data.
+// If DryRun is set, nothing was actually deleted.
+type Report struct {
+	DryRun           bool              `json:"dryRun"`
+	ExpiredSessions  int               `json:"expiredSessions"`
+	InactiveAccounts []InactiveAccount `json:"inactiveAccounts"`
+	AbandonedTrials  []InactiveAccount `json:"abandonedTrials"`
+
+	// Inactive accounts whose users were warned about the deletion.
+	WarnedAccounts []InactiveAccount `json:"warnedAccounts"`
+
+	// Inactive accounts that don't get deleted, because their users can't be
+	// warned (no email address, or mail isn't configured).
+	UnwarnedAccounts []InactiveAccount `json:"unwarnedAccounts"`
+}
+
+// Returns time of user's most recent review in any course, or of the last
+// time the user was seen signed in, whichever is later.
+// Falls back to the modification time of the user's directory if the user
+// hasn't reviewed anything.
+// Returns the zero value if the user has no files and was never seen.
+func lastActive(db *sql.DB, userID int) (time.Time, error) {
+	var last time.Time
+	if info, err := os.Stat(basedir.User(userID)); err == nil {
+		last = info.ModTime()
+	}
+
+	var seen sql.NullInt64
+	query := `SELECT last_seen FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&seen); err != nil {
+		return last, err
+	}
+	if t := time.Unix(seen.Int64, 0); seen.Valid && t.After(last) {
+		last = t
+	}
+
+	var paths []string
+	for _, dir := range []string{"reviews", "production"} {
+		matches, err := filepath.Glob(filepath.Join(basedir.User(userID), dir, "*.db"))
+		if err != nil {
+			return last, err
+		}
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		rdb, err := database.Open(path + "?mode=ro")
+		if err != nil {
+			return last, err
+		}
+
+		var reviewed sql.NullInt64
+		query := `SELECT max(reviewed) FROM history`
+		err = rdb.QueryRow(query).Scan(&reviewed)
+		rdb.Close()
+		if err != nil {
+			return last, err
+		}
+		if t := time.Unix(reviewed.Int64, 0); reviewed.Valid && t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
+
+// Returns accounts that have been inactive since the cutoff.
+// Only returns anonymous trial accounts if `anonymous` is set, and only
+// permanent accounts otherwise.
+// Skips accounts with unknown activity.
+func inactiveAccounts(db *sql.DB, cutoff time.Time, anonymous bool) ([]InactiveAccount, error) {
+	rows, err := db.Query(`SELECT id, username FROM user WHERE anonymous = ?`, anonymous)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []InactiveAccount
+	for rows.Next() {
+		var a InactiveAccount
+		if err := rows.Scan(&a.UserID, &a.Username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	rows.Close()
+
+	var inactive []InactiveAccount
+	for _, a := range accounts {
+		last, err := lastActive(db, a.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !last.IsZero() && last.Before(cutoff) {
+			a.LastActive = last
+			inactive = append(inactive, a)
+		}
+	}
+	return inactive, nil
+}
+
+// Deletes expired sessions and inactive accounts according to the policy.
+// Doesn't delete anything if `dryRun` is set.
+func Prune(db *sql.DB, policy Policy, now time.Time, dryRun bool) (Report, error) {
+	report := Report{DryRun: dryRun}
+
+	count, err := sessions.PruneExpired(db, dryRun)
+	if err != nil {
+		return report, fmt.Errorf("failed to prune data: %w", err)
+	}
+	report.ExpiredSessions = count
+
+	abandoned, err := inactiveAccounts(db, now.Add(-trialAccountAge), true)
+	if err != nil {
+		return report, fmt.Errorf("failed to prune data: %w", err)
+	}
+	report.AbandonedTrials = abandoned
+	if !dryRun {
+		if err := deleteAccounts(db, abandoned); err != nil {
+			return report, fmt.Errorf("failed to prune data: %w", err)
+		}
+	}
+
+	if policy.InactiveAccountAge <= 0 {
+		return report, nil
+	}
+
+	inactive, err := inactiveAccounts(db, now.Add(-policy.InactiveAccountAge), false)
+	if err != nil {
+		return report, fmt.Errorf("failed to prune data: %w", err)
+	}
+	emails := make(map[int]string)
+	for _, a := range inactive {
+		warned, err := deletionWarned(db, a.UserID)
+		if err != nil {
+			return report, fmt.Errorf("failed to prune data: %w", err)
+		}
+		step := deletionStep(warned, a.LastActive, now)
+		if step == stepWait {
+			continue
+		}
+
+		// Accounts only get deleted after warning emails.
+		email, err := userEmail(a.UserID)
+		if err != nil {
+			return report, fmt.Errorf("failed to prune data: %w", err)
+		}
+		if email == "" || !mail.Configured() {
+			report.UnwarnedAccounts = append(report.UnwarnedAccounts, a)
+			continue
+		}
+		emails[a.UserID] = email
+
+		if step == stepWarn {
+			report.WarnedAccounts = append(report.WarnedAccounts, a)
+		} else {
+			report.InactiveAccounts = append(report.InactiveAccounts, a)
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	for _, a := range report.WarnedAccounts {
+		if err := warnAccount(db, a, emails[a.UserID], now); err != nil {
+			return report, fmt.Errorf("failed to prune data: %w", err)
+		}
+	}
+	if err := deleteAccounts(db, report.InactiveAccounts); err != nil {
+		return report, fmt.Errorf("failed to prune data: %w", err)
+	}
+	return report, nil
+}
+
+type step int
+
+const (
+	stepWait step = iota
+	stepWarn
+	stepDelete
+)
+
+// Decides what to do with an inactive account.
+// `warned` is the zero value if the user has never been warned.
+// Warnings from before the user's last activity don't count, so users get
+// warned again if they become inactive again.
+func deletionStep(warned, lastActive, now time.Time) step {
+	if warned.IsZero() || !warned.After(lastActive) {
+		return stepWarn
+	}
+	if now.Sub(warned) >= deletionWarningPeriod {
+		return stepDelete
+	}
+	return stepWait
+}
+
+// Returns when the user was last warned about the deletion of their account,
+// or the zero value if never.
+func deletionWarned(db *sql.DB, userID int) (time.Time, error) {
+	var warned sql.NullInt64
+	query := `SELECT deletion_warned FROM user WHERE id = ?`
+	if err := db.QueryRow(query, userID).Scan(&warned); err != nil {
+		return time.Time{}, err
+	}
+	if !warned.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(warned.Int64, 0), nil
+}
+
+// Returns the email address in the user's reminder settings, or an empty
+// string if there's none.
+func userEmail(userID int) (string, error) {
+	path := basedir.UserData(userID)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	db, err := database.OpenUserDB(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	s, err := reminders.Get(db)
+	if err != nil {
+		return "", err
+	}
+	return s.Email, nil
+}
+
+// Emails the user that their account will be deleted, and records when the
+// warning was sent.
+func warnAccount(db *sql.DB, a InactiveAccount, email string, now time.Time) error {
+	m, err := mail.Render("deletion_warning.txt", email, map[string]any{
+		"Username":   a.Username,
+		"LastActive": a.LastActive,
+		"Deletion":   now.Add(deletionWarningPeriod),
+	})
+	if err != nil {
+		return err
+	}
+	mail.Send(m)
+
+	query := `UPDATE user SET deletion_warned = ? WHERE id = ?`
+	_, err = db.Exec(query, now.Unix(), a.UserID)
+	return err
+}
+
+// Deletes accounts along with their files.
+func deleteAccounts(db *sql.DB, accounts []InactiveAccount) error {
+	for _, a := range accounts {
+		if err := auth.DeleteUser(db, a.UserID); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(basedir.User(a.UserID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs `Prune` periodically in the background, and logs the results.
+// Call the returned function to stop.
+func Schedule(db *sql.DB, policy Policy, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				report, err := Prune(db, policy, now, false)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				log.Printf(
+					"Pruned %v expired sessions, %v inactive accounts and %v abandoned trials, warned %v inactive users, and kept %v inactive accounts that couldn't be warned\n",
+					report.ExpiredSessions,
+					len(report.InactiveAccounts),
+					len(report.AbandonedTrials),
+					len(report.WarnedAccounts),
+					len(report.UnwarnedAccounts),
+				)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// Returns paths to the review DBs of all users.
+func reviewDBs() ([]string, error) {
+	var paths []string
+	for _, dir := range []string{"reviews", "production"} {
+		matches, err := filepath.Glob(filepath.Join(basedir.StateDir, "users", "*", dir, "*.db"))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// Moves reviews older than `before` in all review DBs into archive DBs.
+// See `history.Archive`.
+// Returns the number of archived reviews.
+func ArchiveHistory(before time.Time) (int, error) {
+	paths, err := reviewDBs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive review history: %w", err)
+	}
+
+	total := 0
+	for _, path := range paths {
+		archive := basedir.ReviewArchive(path)
+		if err := os.MkdirAll(filepath.Dir(archive), 0o755); err != nil {
+			return total, fmt.Errorf("failed to archive review history: %w", err)
+		}
+
+		count, err := archiveHistory(path, archive, before)
+		if err != nil {
+			return total, fmt.Errorf("failed to archive review history (%v): %w", path, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func archiveHistory(path, archive string, before time.Time) (int, error) {
+	defer database.LockReviewDB(path)()
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return history.Archive(db, archive, before)
+}
+
+// Rebuilds the review tables of all review DBs from their event logs.
+// See `review_scheduler.Rebuild`.
+// Returns the number of repaired items.
+func RebuildReviews() (int, error) {
+	paths, err := reviewDBs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild review tables: %w", err)
+	}
+
+	total := 0
+	for _, path := range paths {
+		count, err := rebuildReviews(path)
+		if err != nil {
+			return total, fmt.Errorf("failed to rebuild review table (%v): %w", path, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func rebuildReviews(path string) (int, error) {
+	defer database.LockReviewDB(path)()
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return rs.Rebuild(db)
+}
+
+// Restores the user's review DBs in a course (recognition and production) to
+// their state at time t.
+// See `review_scheduler.Restore`.
+// Review DBs that don't exist are skipped.
+// Returns the number of changed items.
+func RestoreCourse(userID int, l1, l2 string, t time.Time) (int, error) {
+	total := 0
+	for _, path := range []string{
+		basedir.Review(userID, l1, l2),
+		basedir.ProductionReview(userID, l1, l2),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		count, err := restoreReviews(path, t)
+		if err != nil {
+			return total, fmt.Errorf("failed to restore course: %w", err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func restoreReviews(path string, t time.Time) (int, error) {
+	defer database.LockReviewDB(path)()
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return rs.Restore(db, t)
+}
+
+// Deletes everything in the cache directory (reverse courses, search indexes
+// and synthesized speech), which can all be regenerated.
+// Should be run while the server is stopped, because reverse courses only get
+// rebuilt on startup.
+// Returns the number of freed bytes.
+func CleanCache() (int64, error) {
+	entries, err := os.ReadDir(basedir.CacheDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean cache: %w", err)
+	}
+
+	var freed int64
+	for _, entry := range entries {
+		path := filepath.Join(basedir.CacheDir, entry.Name())
+		_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				freed += info.Size()
+			}
+			return nil
+		})
+		if err := os.RemoveAll(path); err != nil {
+			return freed, fmt.Errorf("failed to clean cache: %w", err)
+		}
+	}
+	return freed, nil
+}
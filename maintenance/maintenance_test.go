@@ -0,0 +1,119 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestPruneDryRun(t *testing.T) {
+	// Dry runs should count expired sessions without deleting them.
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	query := `
+		INSERT INTO user_session (session_id, created, updated)
+		VALUES ('old', 0, 0), ('new', unixepoch('now'), unixepoch('now'))
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := Prune(db, Policy{}, time.Now(), dryRun)
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if report.ExpiredSessions != 1 {
+			t.Fatal("expected one expired session:", report)
+		}
+	}
+
+	report, err := Prune(db, Policy{}, time.Now(), true)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if report.ExpiredSessions != 0 {
+		t.Fatal("expected expired session to be deleted:", report)
+	}
+}
+
+func TestDeletionStep(t *testing.T) {
+	// Accounts should only be deleted a while after their users were warned.
+	t.Parallel()
+
+	now := time.Unix(100*24*3600, 0)
+	day := 24 * time.Hour
+	lastActive := now.Add(-60 * day)
+
+	cases := []struct {
+		warned   time.Time
+		expected step
+	}{
+		{time.Time{}, stepWarn},
+		{lastActive.Add(-day), stepWarn}, // Warned before the last activity
+		{now.Add(-day), stepWait},
+		{now.Add(-deletionWarningPeriod), stepDelete},
+	}
+	for _, c := range cases {
+		if s := deletionStep(c.warned, lastActive, now); s != c.expected {
+			t.Fatal("unexpected deletion step:", c.warned, s, c.expected)
+		}
+	}
+}
+
+func TestPruneUnwarnedAccounts(t *testing.T) {
+	// Accounts of users who were seen recently shouldn't count as inactive, and
+	// accounts of users who can't be warned shouldn't be deleted.
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	day := 24 * time.Hour
+	query := `
+		INSERT INTO user (id, username, password, last_seen, deletion_warned)
+		VALUES (?, 'inactive', 'x', ?, ?), (?, 'seen', 'x', ?, NULL)
+	`
+	_, err = db.Exec(
+		query,
+		1000001, now.Add(-100*day).Unix(), now.Add(-30*day).Unix(),
+		1000002, now.Add(-day).Unix(),
+	)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	policy := Policy{InactiveAccountAge: 60 * day}
+	report, err := Prune(db, policy, now, false)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(report.InactiveAccounts) != 0 || len(report.WarnedAccounts) != 0 {
+		t.Fatal("expected no accounts to be warned or deleted:", report)
+	}
+	if len(report.UnwarnedAccounts) != 1 || report.UnwarnedAccounts[0].Username != "inactive" {
+		t.Fatal("expected inactive account to be reported:", report)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM user`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 2 {
+		t.Fatal("expected accounts to be kept:", count)
+	}
+}
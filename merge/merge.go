@@ -0,0 +1,399 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Merging duplicate accounts.
+package merge
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+var ErrSameAccount = errors.New("can't merge account into itself")
+
+// Returned when a review DB's history has been archived (see
+// `history.Archive`).
+// Merging replays the review history, and archived reviews would get lost.
+var ErrArchivedHistory = errors.New("can't merge review database with archived review history")
+
+// Summary of merged account data.
+type Report struct {
+	// Review DBs (e.g. reviews/eng-spa.db) that were in both accounts.
+	Merged []string `json:"merged"`
+
+	// Review DBs that were only in the duplicate account.
+	Copied []string `json:"copied"`
+}
+
+type event struct {
+	item     string
+	itemType rs.ItemType
+	reviewed int64
+	correct  bool
+}
+
+// Reads review history, oldest reviews first.
+// Archived reviews (see `history.Archive`) are not included.
+func readEvents(db *sql.DB) ([]event, error) {
+	query := `
+		SELECT word, coalesce(type, ''), history.reviewed, interval_after > 0
+		FROM history LEFT JOIN review ON word = item
+		ORDER BY history.reviewed ASC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review history: %w", err)
+	}
+	defer rows.Close()
+
+	var events []event
+	for rows.Next() {
+		var e event
+		if err := rows.Scan(&e.item, &e.itemType, &e.reviewed, &e.correct); err != nil {
+			return nil, fmt.Errorf("failed to read review history: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Checks if some of the review history has been moved into an archive.
+func hasArchivedHistory(db *sql.DB) (bool, error) {
+	var archived bool
+	query := `SELECT EXISTS (SELECT 1 FROM history_summary)`
+	if err := db.QueryRow(query).Scan(&archived); err != nil {
+		return false, fmt.Errorf("failed to check for archived review history: %w", err)
+	}
+	return archived, nil
+}
+
+// Removes reviews and the stats derived from them, so that the review history
+// can be replayed from the start.
+func clearReviews(tx *sql.Tx) error {
+	queries := []string{
+		`DELETE FROM review`,
+		`DELETE FROM history`,
+		`DELETE FROM vocabulary_size`,
+		`DELETE FROM vocabulary_size_history`,
+		`DELETE FROM interval WHERE interval != 0`,
+		`UPDATE interval SET correct = 0, incorrect = 0`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds words in src's word list into dst's word list.
+func combineWordLists(tx *sql.Tx, src *sql.DB) error {
+	rows, err := src.Query(`SELECT item, added FROM word_list`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	query := `
+		INSERT INTO word_list (item, added) VALUES (?, ?)
+		ON CONFLICT (item) DO UPDATE SET added = min(added, excluded.added)
+	`
+	for rows.Next() {
+		var item string
+		var added int64
+		if err := rows.Scan(&item, &added); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, item, added); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Entry in the estimated level history.
+type levelChange struct {
+	t int64
+	v int
+}
+
+// Implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// Reads estimated level history in insertion order.
+func readLevelHistory(q queryer) ([]levelChange, error) {
+	rows, err := q.Query(`SELECT t, v FROM estimated_level_history ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []levelChange
+	for rows.Next() {
+		var c levelChange
+		if err := rows.Scan(&c.t, &c.v); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// Merges the estimated level in src into dst.
+// The most recently updated level wins (along with its tuner stats and pinned
+// status), and dst's level is kept on ties.
+// The level histories get combined in chronological order.
+func mergeEstimatedLevel(tx *sql.Tx, src *sql.DB) error {
+	var srcT, dstT int64
+	var v, correct, incorrect int
+	var pinned bool
+	query := `SELECT t, v, correct, incorrect, pinned FROM estimated_level`
+	err := src.QueryRow(query).Scan(&srcT, &v, &correct, &incorrect, &pinned)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`SELECT t FROM estimated_level`).Scan(&dstT)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) || srcT > dstT {
+		query := `
+			INSERT OR REPLACE INTO estimated_level (t, v, correct, incorrect, pinned)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err := tx.Exec(query, srcT, v, correct, incorrect, pinned); err != nil {
+			return err
+		}
+	}
+
+	// The update above may have added an entry that's already in src's
+	// history, so entries that are in both DBs only get kept once.
+	dstHistory, err := readLevelHistory(tx)
+	if err != nil {
+		return err
+	}
+	srcHistory, err := readLevelHistory(src)
+	if err != nil {
+		return err
+	}
+
+	changes := dstHistory
+	seen := make(map[levelChange]bool)
+	for _, c := range dstHistory {
+		seen[c] = true
+	}
+	for _, c := range srcHistory {
+		if !seen[c] {
+			seen[c] = true
+			changes = append(changes, c)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].t < changes[j].t
+	})
+
+	// Readers order the history by ID, so the entries get reinserted in order.
+	if _, err := tx.Exec(`DELETE FROM estimated_level_history`); err != nil {
+		return err
+	}
+	query = `INSERT INTO estimated_level_history (t, v) VALUES (?, ?)`
+	for _, c := range changes {
+		if _, err := tx.Exec(query, c.t, c.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merges reviews and word list in src into dst.
+// Reviews from both DBs get replayed in order, so the schedule in dst is the
+// same as if all the reviews had been done in one account.
+// Reviews that are in both DBs (e.g. when src is a copy of dst from an offline
+// bundle) only get replayed once.
+// The estimated level gets merged by `mergeEstimatedLevel`.
+// Other data in dst (e.g. settings) is kept as is.
+// Returns `ErrArchivedHistory` if either DB has archived reviews.
+func Reviews(dst, src *sql.DB) error {
+	for _, db := range []*sql.DB{dst, src} {
+		archived, err := hasArchivedHistory(db)
+		if err != nil {
+			return fmt.Errorf("failed to merge reviews: %w", err)
+		}
+		if archived {
+			return fmt.Errorf("failed to merge reviews: %w", ErrArchivedHistory)
+		}
+	}
+
+	dstEvents, err := readEvents(dst)
+	if err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+	srcEvents, err := readEvents(src)
+	if err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+
+	events := dstEvents
+	seen := make(map[event]bool)
+	for _, e := range dstEvents {
+		seen[e] = true
+	}
+	for _, e := range srcEvents {
+		if !seen[e] {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].reviewed < events[j].reviewed
+	})
+
+	tx, err := dst.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := clearReviews(tx); err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+	for _, e := range events {
+		result := rs.Result{Word: e.item, Correct: e.correct, Type: e.itemType}
+		if err := rs.UpdateReviewAtTx(tx, result, time.Unix(e.reviewed, 0)); err != nil {
+			return fmt.Errorf("failed to merge reviews: %w", err)
+		}
+	}
+	if err := combineWordLists(tx, src); err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+	if err := mergeEstimatedLevel(tx, src); err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to merge reviews: %w", err)
+	}
+	return nil
+}
+
+// Merges review DB file into another one.
+func Files(dst, src string) error {
+	dstDB, err := database.OpenReviewDB(dst)
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+
+	srcDB, err := database.OpenReviewDB(src)
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+	return Reviews(dstDB, srcDB)
+}
+
+// Returns paths of user's review DBs relative to the user's directory.
+func reviewDBs(userID int) ([]string, error) {
+	var paths []string
+	for _, dir := range []string{"reviews", "production"} {
+		matches, err := filepath.Glob(filepath.Join(basedir.User(userID), dir, "*.db"))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(basedir.User(userID), match)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, rel)
+		}
+	}
+	return paths, nil
+}
+
+// Locks review DBs for writing, in the same order every time so that
+// concurrent merges don't deadlock.
+// Returns function for unlocking all of them.
+func lockReviewDBs(paths []string) (unlock func()) {
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
+
+	var unlocks []func()
+	for _, path := range paths {
+		unlocks = append(unlocks, database.LockReviewDB(path))
+	}
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
+
+// Merges the duplicate account into the user's account.
+// Reviews in courses that both accounts have are merged by timestamp, and
+// courses that only the duplicate has get copied.
+// The duplicate account gets tombstoned (see `auth.Tombstone`) after its
+// reviews have been merged, but its files are kept. If merging fails, the
+// duplicate isn't tombstoned, so the merge can be retried.
+func Accounts(db *sql.DB, userID, duplicateID int) (Report, error) {
+	var report Report
+	if userID == duplicateID {
+		return report, fmt.Errorf("failed to merge accounts: %w", ErrSameAccount)
+	}
+	if auth.IsTombstoned(db, userID) || auth.IsTombstoned(db, duplicateID) {
+		return report, errors.New("failed to merge accounts: account has already been merged")
+	}
+
+	paths, err := reviewDBs(duplicateID)
+	if err != nil {
+		return report, fmt.Errorf("failed to merge accounts: %w", err)
+	}
+
+	// Both accounts' review DBs stay locked until the duplicate has been
+	// tombstoned, so that reviews saved in the meantime don't get lost.
+	var locked []string
+	for _, path := range paths {
+		locked = append(locked,
+			filepath.Join(basedir.User(duplicateID), path),
+			filepath.Join(basedir.User(userID), path),
+		)
+	}
+	defer lockReviewDBs(locked)()
+
+	for _, path := range paths {
+		src := filepath.Join(basedir.User(duplicateID), path)
+		dst := filepath.Join(basedir.User(userID), path)
+
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			if err := database.Backup(src, dst); err != nil {
+				return report, fmt.Errorf("failed to merge accounts (%v): %w", path, err)
+			}
+			report.Copied = append(report.Copied, path)
+			continue
+		}
+		if err := Files(dst, src); err != nil {
+			return report, fmt.Errorf("failed to merge accounts (%v): %w", path, err)
+		}
+		report.Merged = append(report.Merged, path)
+	}
+
+	if err := auth.Tombstone(db, duplicateID, userID); err != nil {
+		return report, fmt.Errorf("failed to merge accounts: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package merge
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+func TestReviews(t *testing.T) {
+	// Reviews from both DBs should be replayed in order.
+	t.Parallel()
+
+	dst, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer dst.Close()
+
+	src, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer src.Close()
+
+	now := time.Unix(1000000, 0)
+	if err := rs.UpdateReviewAt(dst, "foo", true, now); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := rs.UpdateReviewAt(src, "foo", false, now.Add(time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := rs.UpdateReviewAt(src, "bar", true, now.Add(-time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := src.Exec(`INSERT INTO word_list (item) VALUES ('baz')`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := Reviews(dst, src); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	var count int
+	if err := dst.QueryRow(`SELECT count(*) FROM history`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 3 {
+		t.Fatal("expected merged history to contain all reviews:", count)
+	}
+
+	// The most recent review of "foo" was incorrect.
+	items, err := rs.ScheduleReview(dst, now.Add(2*time.Hour), -1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 1 || items[0] != "foo" {
+		t.Fatal("expected only foo to be due:", items)
+	}
+
+	if err := dst.QueryRow(`SELECT count(*) FROM word_list`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 1 {
+		t.Fatal("expected word lists to be combined:", count)
+	}
+}
+
+func TestReviewsSkipsSharedReviews(t *testing.T) {
+	// Reviews that are in both DBs shouldn't be counted twice.
+	t.Parallel()
+
+	dst, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer dst.Close()
+
+	src, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer src.Close()
+
+	now := time.Unix(1000000, 0)
+	for _, db := range []*sql.DB{dst, src} {
+		if err := rs.UpdateReviewAt(db, "foo", true, now); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+	if err := rs.UpdateReviewAt(src, "foo", true, now.Add(time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := Reviews(dst, src); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	var count int
+	if err := dst.QueryRow(`SELECT count(*) FROM history`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 2 {
+		t.Fatal("expected shared review to be merged once:", count)
+	}
+}
+
+func TestReviewsArchivedHistory(t *testing.T) {
+	// Merging shouldn't replace archived reviews with the remaining history.
+	t.Parallel()
+
+	dst, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer dst.Close()
+
+	src, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer src.Close()
+
+	now := time.Unix(1000000, 0)
+	if err := rs.UpdateReviewAt(dst, "foo", true, now); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	query := `INSERT INTO history_summary (day, learned) VALUES (1, 1)`
+	if _, err := dst.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := Reviews(dst, src); !errors.Is(err, ErrArchivedHistory) {
+		t.Fatal("expected ErrArchivedHistory:", err)
+	}
+
+	var count int
+	if err := dst.QueryRow(`SELECT count(*) FROM review`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 1 {
+		t.Fatal("expected reviews to be kept:", count)
+	}
+}
+
+// Sets estimated level and its history.
+func setLevel(t *testing.T, db *sql.DB, at int64, level int, history ...[2]int64) {
+	t.Helper()
+
+	query := `INSERT OR REPLACE INTO estimated_level (t, v, pinned) VALUES (?, ?, 1)`
+	if _, err := db.Exec(query, at, level); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := db.Exec(`DELETE FROM estimated_level_history`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	for _, h := range history {
+		query := `INSERT INTO estimated_level_history (t, v) VALUES (?, ?)`
+		if _, err := db.Exec(query, h[0], h[1]); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+}
+
+func TestReviewsEstimatedLevel(t *testing.T) {
+	// The most recently updated level should win, and the histories should be
+	// combined in order.
+	t.Parallel()
+
+	for _, srcIsNewer := range []bool{true, false} {
+		dst, err := database.OpenReviewDB(":memory:")
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		defer dst.Close()
+
+		src, err := database.OpenReviewDB(":memory:")
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		defer src.Close()
+
+		srcT, dstT := int64(200), int64(100)
+		if !srcIsNewer {
+			srcT, dstT = dstT, srcT
+		}
+		setLevel(t, dst, dstT, 10, [2]int64{dstT, 10})
+		setLevel(t, src, srcT, 20, [2]int64{50, 5}, [2]int64{srcT, 20})
+
+		if err := Reviews(dst, src); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+
+		var level int
+		if err := dst.QueryRow(`SELECT v FROM estimated_level`).Scan(&level); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		expected := 10
+		if srcIsNewer {
+			expected = 20
+		}
+		if level != expected {
+			t.Fatal("expected most recent level to win:", level, expected)
+		}
+
+		rows, err := dst.Query(`SELECT t FROM estimated_level_history ORDER BY id`)
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		var times []int64
+		for rows.Next() {
+			var at int64
+			if err := rows.Scan(&at); err != nil {
+				t.Fatal("expected err to be nil:", err)
+			}
+			times = append(times, at)
+		}
+		rows.Close()
+		if len(times) != 3 || times[0] != 50 || times[1] != 100 || times[2] != 200 {
+			t.Fatal("expected combined level history in order:", times)
+		}
+	}
+}
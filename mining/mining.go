@@ -0,0 +1,90 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Extracts cloze sentences from texts submitted by the user.
+package mining
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Sentences with more words than this are skipped, because they make bad
+// cloze items.
+const maxWords = 30
+
+type Result struct {
+	Sentences int      `json:"sentences"` // Number of new sentences saved
+	Words     []string `json:"words"`     // Words that got new sentences
+}
+
+// Checks if the word is in the course and is either new or due.
+func isWanted[T database.Querier](q T, word string, now time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM word WHERE word = @word)
+			AND NOT EXISTS (SELECT 1 FROM review WHERE item = @word AND due > @now)
+	`
+	var wanted bool
+	err := q.QueryRow(query, sql.Named("word", word), sql.Named("now", now.Unix())).Scan(&wanted)
+	return wanted, err
+}
+
+// Saves sentences in the text that contain new or due words.
+// Database connection should have access to course and review data.
+func Mine[T database.Querier](q T, s string, now time.Time) (Result, error) {
+	result := Result{Words: make([]string, 0)}
+	found := make(map[string]bool)
+	cache := make(map[string]bool)
+
+	for _, sentence := range text.Sentences(s) {
+		tokens := text.Tokenize(sentence)
+
+		var words []string
+		var count int
+		for _, token := range tokens {
+			if !text.IsWord(token) {
+				continue
+			}
+			count++
+
+			word := text.Casefold(token)
+			wanted, ok := cache[word]
+			if !ok {
+				var err error
+				wanted, err = isWanted(q, word, now)
+				if err != nil {
+					return result, fmt.Errorf("failed to mine sentences: %w", err)
+				}
+				cache[word] = wanted
+			}
+			if wanted {
+				words = append(words, word)
+			}
+		}
+		if len(words) == 0 || count > maxWords {
+			continue
+		}
+
+		added, err := sentences.AddUserSentence(q, tokens, words)
+		if err != nil {
+			return result, fmt.Errorf("failed to mine sentences: %w", err)
+		}
+		if !added {
+			continue
+		}
+
+		result.Sentences++
+		for _, word := range words {
+			if !found[word] {
+				found[word] = true
+				result.Words = append(result.Words, word)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package mining
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestMine(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Now()
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'das', 0), (2, 'haus', 0), (3, 'ist', 0)`,
+		`INSERT INTO review (item, interval, reviewed) VALUES ('das', 24, unixepoch('now')), ('ist', 24, 0)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	// "das" isn't due yet, "ist" is due, and "haus" is new.
+	result, err := Mine(db, "Das ist gut. Das Haus ist rot. Das Auto.", now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if result.Sentences != 2 || len(result.Words) != 2 {
+		t.Fatal("unexpected result:", result)
+	}
+
+	sentence, err := sentences.PickUserSentence(db, "Haus")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if sentence.Text != "Das Haus ist rot." || len(sentence.Tokens) != 8 {
+		t.Fatal("unexpected sentence:", sentence)
+	}
+
+	// Sentences are only saved once.
+	result, err = Mine(db, "Das ist gut.", now)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if result.Sentences != 0 {
+		t.Fatal("expected sentence to be skipped:", result)
+	}
+
+	if _, err := sentences.PickUserSentence(db, "das"); err == nil {
+		t.Fatal("expected no sentences for word that isn't due")
+	}
+}
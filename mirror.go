@@ -0,0 +1,74 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/storage"
+)
+
+// Returns mirror of the state directory in S3-compatible storage, or nil if
+// the storage isn't configured.
+// Configured using the environment variables:
+// POLYCLOZE_S3_ENDPOINT, POLYCLOZE_S3_REGION, POLYCLOZE_S3_BUCKET,
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func mirrorFromEnv() *storage.Mirror {
+	bucket := os.Getenv("POLYCLOZE_S3_BUCKET")
+	if bucket == "" {
+		return nil
+	}
+
+	region := os.Getenv("POLYCLOZE_S3_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("POLYCLOZE_S3_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	s := &storage.S3{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}
+	return storage.NewMirror(s, basedir.StateDir)
+}
+
+// Pushes changed databases into the storage after requests that may have
+// written something.
+// WebSocket study sessions start with a GET request, so they get pushed when
+// the session ends.
+// Pushes happen in the background, one at a time.
+func pushAfterWrites(m *storage.Mirror, next http.Handler) http.Handler {
+	pending := make(chan struct{}, 1)
+	go func() {
+		for range pending {
+			if err := m.Push(context.Background()); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		upgrade := strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+		if (r.Method == "GET" || r.Method == "HEAD") && !upgrade {
+			return
+		}
+		select {
+		case pending <- struct{}{}:
+		default:
+			// A push is already pending.
+		}
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Grammar and usage notes on words.
+// Notes come from the course (`note` table in the course DB) and from the
+// user (`user_note` table in the review DB).
+package notes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+type Note struct {
+	Source string `json:"source"` // "course" or "user"
+	Text   string `json:"text"`
+}
+
+// Note written by the user.
+type UserNote struct {
+	Word    string    `json:"word"`
+	Text    string    `json:"text"`
+	Updated time.Time `json:"updated"`
+}
+
+// Checks if the course DB has notes.
+// Courses built before notes were added don't have the table.
+func hasCourseNotes[T database.Querier](q T) bool {
+	query := `SELECT count(*) FROM pragma_table_info('note')`
+	var count int
+	return q.QueryRow(query).Scan(&count) == nil && count > 0
+}
+
+// Returns notes on word.
+// Course notes come before the user's note.
+// Database connection should have access to course and review data.
+func Get[T database.Querier](q T, word string) ([]Note, error) {
+	word = text.Casefold(word)
+	notes := make([]Note, 0)
+
+	if hasCourseNotes(q) {
+		query := `
+			SELECT note.text FROM note JOIN word ON (note.word = word.id)
+			WHERE word.word = ?
+		`
+		rows, err := q.Query(query, word)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get notes (%v): %w", word, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			note := Note{Source: "course"}
+			if err := rows.Scan(&note.Text); err != nil {
+				return nil, fmt.Errorf("failed to get notes (%v): %w", word, err)
+			}
+			notes = append(notes, note)
+		}
+	}
+
+	query := `SELECT text FROM user_note WHERE item = ?`
+	rows, err := q.Query(query, word)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes (%v): %w", word, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		note := Note{Source: "user"}
+		if err := rows.Scan(&note.Text); err != nil {
+			return nil, fmt.Errorf("failed to get notes (%v): %w", word, err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+// Sets user's note on word.
+// Deletes the note if the text is empty.
+func SetUserNote[T database.Querier](q T, word, note string) error {
+	word = text.Casefold(word)
+	if note == "" {
+		if _, err := q.Exec(`DELETE FROM user_note WHERE item = ?`, word); err != nil {
+			return fmt.Errorf("failed to delete note (%v): %w", word, err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO user_note (item, text) VALUES (?, ?)
+		ON CONFLICT (item) DO UPDATE SET
+			text = excluded.text,
+			updated = unixepoch('now')
+	`
+	if _, err := q.Exec(query, word, note); err != nil {
+		return fmt.Errorf("failed to set note (%v): %w", word, err)
+	}
+	return nil
+}
+
+// Returns user's note on word.
+// Returns sql.ErrNoRows if there's none.
+func GetUserNote[T database.Querier](q T, word string) (UserNote, error) {
+	note := UserNote{Word: text.Casefold(word)}
+	query := `SELECT text, updated FROM user_note WHERE item = ?`
+
+	var updated int64
+	if err := q.QueryRow(query, note.Word).Scan(&note.Text, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return note, err
+		}
+		return note, fmt.Errorf("failed to get note (%v): %w", note.Word, err)
+	}
+	note.Updated = time.Unix(updated, 0)
+	return note, nil
+}
+
+// Returns user's notes updated at or after `since`, most recently updated
+// first.
+func GetUserNotes[T database.Querier](q T, since time.Time) ([]UserNote, error) {
+	query := `
+		SELECT item, text, updated FROM user_note
+		WHERE updated >= ?
+		ORDER BY updated DESC, item
+	`
+	rows, err := q.Query(query, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]UserNote, 0)
+	for rows.Next() {
+		var note UserNote
+		var updated int64
+		if err := rows.Scan(&note.Word, &note.Text, &updated); err != nil {
+			return nil, fmt.Errorf("failed to get notes: %w", err)
+		}
+		note.Updated = time.Unix(updated, 0)
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package notes
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'haus', 0)`,
+		`INSERT INTO note (word, text) VALUES (1, 'das Haus, die Häuser')`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	if err := SetUserNote(db, "Haus", "house"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	notes, err := Get(db, "haus")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(notes) != 2 || notes[0].Source != "course" || notes[1].Text != "house" {
+		t.Fatal("unexpected notes:", notes)
+	}
+
+	// Empty note deletes the user's note.
+	if err := SetUserNote(db, "haus", ""); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	notes, err = Get(db, "haus")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(notes) != 1 {
+		t.Fatal("expected user's note to be deleted:", notes)
+	}
+}
+
+func TestUserNotes(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if _, err := GetUserNote(db, "haus"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal("expected sql.ErrNoRows:", err)
+	}
+
+	if err := SetUserNote(db, "Haus", "house"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	note, err := GetUserNote(db, "haus")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if note.Text != "house" {
+		t.Fatal("unexpected note:", note)
+	}
+
+	notes, err := GetUserNotes(db, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(notes) != 1 || notes[0].Word != "haus" {
+		t.Fatal("unexpected notes:", notes)
+	}
+
+	notes, err = GetUserNotes(db, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(notes) != 0 {
+		t.Fatal("expected older notes to be skipped:", notes)
+	}
+}
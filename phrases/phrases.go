@@ -0,0 +1,143 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Adds multi-word items (phrases and collocations) to courses.
+// Phrases are stored in the `word` table like words, so the scheduler treats
+// them the same way.
+package phrases
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polycloze/polycloze/text"
+)
+
+// Max number of example sentences per phrase (same as words).
+const maxExamples = 30
+
+var ErrNotPhrase = errors.New("not a phrase")
+
+// Returns frequency class of the phrase.
+// This is the frequency class of the rarest word in the phrase, or the rarest
+// word in the course if some words aren't in the course.
+func frequencyClass(tx *sql.Tx, phrase string) (int, error) {
+	var max int
+	query := `SELECT coalesce(max(frequency_class), 0) FROM word`
+	if err := tx.QueryRow(query).Scan(&max); err != nil {
+		return 0, err
+	}
+
+	result := 0
+	query = `SELECT frequency_class FROM word WHERE word = ?`
+	for _, word := range strings.Fields(phrase) {
+		var class int
+		err := tx.QueryRow(query, word).Scan(&class)
+		if errors.Is(err, sql.ErrNoRows) {
+			return max, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if class > result {
+			result = class
+		}
+	}
+	return result, nil
+}
+
+// Returns IDs of course sentences that contain the phrase, easiest sentences
+// first.
+func findSentences(tx *sql.Tx, phrase string) ([]int, error) {
+	// Only sentences that contain the first word can contain the phrase.
+	first := strings.Fields(phrase)[0]
+	query := `
+		SELECT id, tokens FROM sentence
+		WHERE id IN (
+			SELECT sentence FROM contains
+			WHERE word = (SELECT id FROM word WHERE word = ?)
+		)
+		ORDER BY frequency_class, id
+	`
+	rows, err := tx.Query(query, first)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() && len(ids) < maxExamples {
+		var id int
+		var encoded string
+		if err := rows.Scan(&id, &encoded); err != nil {
+			return nil, err
+		}
+
+		var tokens []string
+		if err := json.Unmarshal([]byte(encoded), &tokens); err != nil {
+			return nil, err
+		}
+		if len(text.FindSpans(tokens, phrase)) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// Adds phrase to the course DB and links it to sentences that contain it.
+// Returns the number of linked sentences.
+// Phrases that are already in the course get relinked.
+// Phrases that aren't in any sentence aren't added.
+func Add(db *sql.DB, phrase string) (int, error) {
+	phrase = text.Casefold(phrase)
+	if !strings.Contains(phrase, " ") {
+		return 0, fmt.Errorf("failed to add phrase: %w: %v", ErrNotPhrase, phrase)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+	defer tx.Rollback()
+
+	sentences, err := findSentences(tx, phrase)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+	if len(sentences) == 0 {
+		return 0, nil
+	}
+
+	class, err := frequencyClass(tx, phrase)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+
+	var id int
+	query := `
+		INSERT INTO word (word, frequency_class) VALUES (?, ?)
+		ON CONFLICT (word) DO UPDATE SET frequency_class = excluded.frequency_class
+		RETURNING id
+	`
+	if err := tx.QueryRow(query, phrase, class).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM contains WHERE word = ?`, id); err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+	for _, sentence := range sentences {
+		query := `INSERT INTO contains (sentence, word) VALUES (?, ?)`
+		if _, err := tx.Exec(query, sentence, id); err != nil {
+			return 0, fmt.Errorf("failed to add phrase: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to add phrase: %w", err)
+	}
+	return len(sentences), nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package phrases
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'por', 0), (2, 'favor', 2), (3, 'ven', 1)`,
+		`INSERT INTO sentence (id, text, tokens, frequency_class) VALUES
+			(1, 'Por favor, ven.', '["Por", " ", "favor", ",", " ", "ven", "."]', 2),
+			(2, 'Ven por mí.', '["Ven", " ", "por", " ", "mí", "."]', 1)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1), (1, 2), (1, 3), (2, 1), (2, 3)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	count, err := Add(db, "Por  Favor")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 1 {
+		t.Fatal("expected phrase to be linked to one sentence:", count)
+	}
+
+	var class int
+	query := `SELECT frequency_class FROM word WHERE word = 'por favor'`
+	if err := db.QueryRow(query).Scan(&class); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if class != 2 {
+		t.Fatal("expected frequency class of rarest word:", class)
+	}
+
+	if _, err := Add(db, "por"); !errors.Is(err, ErrNotPhrase) {
+		t.Fatal("expected ErrNotPhrase:", err)
+	}
+}
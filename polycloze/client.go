@@ -0,0 +1,108 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package polycloze
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/flashcards"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/word_scheduler"
+)
+
+type Item = flashcards.Item
+type Difficulty = difficulty.Difficulty
+
+// Review result of a word.
+type Review = word_scheduler.ReviewResult
+
+// Scheduler access for one user and course.
+type Client struct {
+	db   *sql.DB
+	con  *database.Connection
+	path string // Path to the review DB
+}
+
+// Opens user's review data for the course.
+// l1 and l2 are ISO 639-3 codes.
+// The caller has to Close the client.
+func NewClient(ctx context.Context, userID int, l1, l2 string) (*Client, error) {
+	course := basedir.Course(l1, l2)
+	if _, err := os.Stat(course); err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	path := basedir.Review(userID, l1, l2)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	con, err := database.NewConnection(db, ctx, database.AttachCourse(course))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return &Client{db: db, con: con, path: path}, nil
+}
+
+func (c *Client) Close() error {
+	if err := c.con.Close(); err != nil {
+		c.db.Close()
+		return err
+	}
+	return c.db.Close()
+}
+
+// Returns at most n flashcards to study next.
+func (c *Client) Flashcards(n int) []Item {
+	return flashcards.Get(c.con, n, func(_ string) bool {
+		return true
+	})
+}
+
+// Saves review results.
+// Returns an error if any of the reviews couldn't be saved.
+func (c *Client) Review(reviews []Review, at time.Time) error {
+	defer database.LockReviewDB(c.path)()
+
+	results, err := word_scheduler.BulkSaveWords(c.con, reviews, at)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.OK {
+			return fmt.Errorf("failed to save review (%v): %v", result.Word, result.Error)
+		}
+	}
+	return nil
+}
+
+// Returns at most n words due for review at the given time.
+func (c *Client) Due(n int, at time.Time) ([]string, error) {
+	return review_scheduler.ScheduleReview(c.con, at, n)
+}
+
+// Returns the current difficulty level.
+func (c *Client) Difficulty() Difficulty {
+	return difficulty.GetLatest(c.con)
+}
+
+// Saves the difficulty level computed by a tuner.
+func (c *Client) SetDifficulty(d Difficulty) error {
+	defer database.LockReviewDB(c.path)()
+	return difficulty.Update(c.con, d)
+}
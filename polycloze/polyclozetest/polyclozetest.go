@@ -0,0 +1,259 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Package polyclozetest provides a polycloze server with seed data for
+// integration tests, so that client authors don't have to build SQLite
+// fixtures by hand.
+//
+// The server stores its files in temporary directories that get deleted when
+// the test ends.
+// Since package basedir is global, tests that use this package shouldn't run
+// in parallel.
+package polyclozetest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/polycloze"
+	"github.com/polycloze/polycloze/sample_course"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Credentials of the seeded user.
+const (
+	Username = "learner"
+	Password = "password"
+)
+
+// Course of the seeded user (see package sample_course).
+const (
+	L1 = sample_course.L1
+	L2 = sample_course.L2
+)
+
+// Test server with the sample course installed and a user (see `Username`)
+// who has reviewed some words.
+type Server struct {
+	*httptest.Server
+
+	// Auth database (users and sessions).
+	DB *sql.DB
+
+	// ID of the seeded user.
+	UserID int
+}
+
+// Starts test server.
+// The server gets closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	for _, set := range []func(string) error{
+		basedir.SetDataDir,
+		basedir.SetStateDir,
+		basedir.SetCacheDir,
+	} {
+		if err := set(t.TempDir()); err != nil {
+			t.Fatal("failed to create test server:", err)
+		}
+	}
+	if err := sample_course.Install(); err != nil {
+		t.Fatal("failed to create test server:", err)
+	}
+
+	server, err := polycloze.NewServer(polycloze.Config{})
+	if err != nil {
+		t.Fatal("failed to create test server:", err)
+	}
+	ts := httptest.NewTLSServer(server)
+	t.Cleanup(func() {
+		ts.Close()
+		server.Close()
+	})
+
+	s := &Server{Server: ts, DB: server.AuthDB()}
+	s.UserID = s.AddUser(t, Username, Password)
+	if err := seedReviews(s.UserID, time.Now()); err != nil {
+		t.Fatal("failed to create test server:", err)
+	}
+	return s
+}
+
+// Creates user and returns the user's ID.
+func (s *Server) AddUser(t testing.TB, username, password string) int {
+	t.Helper()
+	if err := auth.Register(s.DB, username, password); err != nil {
+		t.Fatal("failed to add user:", err)
+	}
+	userID, err := auth.GetUserID(s.DB, username)
+	if err != nil {
+		t.Fatal("failed to add user:", err)
+	}
+	return userID
+}
+
+// Reviews the most frequent words in the sample course over the past week,
+// like a user who has been studying for a few days.
+func seedReviews(userID int, now time.Time) error {
+	db, err := database.Open(basedir.Course(L1, L2) + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var words []string
+	rows, err := db.Query(`SELECT word FROM word ORDER BY frequency_class, id LIMIT 8`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return err
+		}
+		words = append(words, word)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	client, err := polycloze.NewClient(context.Background(), userID, L1, L2)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	for i, word := range words {
+		// Words that were learned earlier get reviewed again, and some of the
+		// newer words are answered incorrectly.
+		learned := now.Add(-time.Duration(7-i%7) * 24 * time.Hour)
+		reviews := []polycloze.Review{{Word: word, Correct: i%3 != 2}}
+		if err := client.Review(reviews, learned); err != nil {
+			return err
+		}
+		if i < 4 {
+			reviews[0].Correct = true
+			if err := client.Review(reviews, learned.Add(48*time.Hour)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HTTP client with a signed in session.
+type Client struct {
+	*http.Client
+	server *Server
+}
+
+// Returns client signed in as the user.
+func (s *Server) SignIn(t testing.TB, username, password string) *Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal("failed to sign in:", err)
+	}
+	client := *s.Server.Client()
+	client.Jar = jar
+
+	// Don't follow the redirect after signing in.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	c := &Client{Client: &client, server: s}
+
+	// Visit the sign in page first to start a session.
+	resp, err := c.Get(s.URL + "/signin")
+	if err != nil {
+		t.Fatal("failed to sign in:", err)
+	}
+	resp.Body.Close()
+
+	form := url.Values{}
+	form.Set("username", username)
+	form.Set("password", password)
+	form.Set("csrf-token", c.CSRFToken())
+	resp, err = c.PostForm(s.URL+"/signin", form)
+	if err != nil {
+		t.Fatal("failed to sign in:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTemporaryRedirect {
+		t.Fatal("failed to sign in:", resp.Status)
+	}
+
+	client.CheckRedirect = nil
+	return c
+}
+
+// Returns CSRF token of the client's session, or an empty string if the
+// client doesn't have a session.
+func (c *Client) CSRFToken() string {
+	u, err := url.Parse(c.server.URL)
+	if err != nil {
+		return ""
+	}
+	for _, cookie := range c.Jar.Cookies(u) {
+		// Session cookie (see package sessions).
+		if cookie.Name == "id" {
+			return sessions.CSRFToken(cookie.Value)
+		}
+	}
+	return ""
+}
+
+// Sends request with JSON body (if not nil) and CSRF token, and decodes the
+// JSON response into v (if not nil).
+// Fails the test if the response status isn't 200 OK.
+func (c *Client) DoJSON(t testing.TB, method, path string, body, v any) {
+	t.Helper()
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal("failed to encode request:", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.server.URL+path, r)
+	if err != nil {
+		t.Fatal("failed to create request:", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", c.CSRFToken())
+
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal("request failed:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal("failed to decode response:", err)
+	}
+}
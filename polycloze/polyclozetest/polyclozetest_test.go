@@ -0,0 +1,33 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package polyclozetest
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(t)
+	c := s.SignIn(t, Username, Password)
+
+	var vocabulary struct {
+		Words []struct {
+			Word string `json:"word"`
+		} `json:"words"`
+	}
+	c.DoJSON(t, "GET", fmt.Sprintf("/api/vocabulary/%s/%s", L1, L2), nil, &vocabulary)
+	if len(vocabulary.Words) == 0 {
+		t.Fatal("expected seeded user to have reviewed words")
+	}
+
+	var settings struct {
+		Hints string `json:"hints"`
+	}
+	body := map[string]any{"hints": "never"}
+	c.DoJSON(t, "PUT", fmt.Sprintf("/api/settings/%s/%s", L1, L2), body, &settings)
+	if settings.Hints != "never" {
+		t.Fatal("expected settings to be updated:", settings)
+	}
+}
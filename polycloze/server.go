@@ -0,0 +1,93 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Package polycloze is the stable API for embedding polycloze in other Go
+// programs.
+//
+// Server serves the web app and the HTTP API. Client drives the scheduler for
+// one user and course directly, without going through HTTP.
+//
+// Course and user files are stored in the directories given by package
+// basedir.
+package polycloze
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/polycloze/polycloze/api"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/hooks"
+)
+
+type Config = api.Config
+
+type Hook = hooks.Hook
+
+// Subscribes hook to study events (reviews, new words, sign-outs and
+// registrations).
+func RegisterHook(h Hook) {
+	hooks.Register(h)
+}
+
+// HTTP server for the web app and API.
+type Server struct {
+	db      *sql.DB
+	handler http.Handler
+	config  Config
+}
+
+// Creates server.
+// Looks for installed courses and prepares the state directory, so this
+// should only be called once per process.
+// The caller has to Close the server.
+func NewServer(config Config) (*Server, error) {
+	if err := api.Startup(config); err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	db, err := database.OpenAuthDB(basedir.Auth())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	r, err := api.Router(config, db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+	return &Server{db: db, handler: r, config: config}, nil
+}
+
+// Looks for installed courses again, e.g. after installing or removing
+// courses.
+// The server keeps serving the old course list if this fails.
+func (s *Server) ReloadCourses() error {
+	return api.ReloadCatalog(s.config)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Returns the auth database (users and sessions).
+func (s *Server) AuthDB() *sql.DB {
+	return s.db
+}
+
+// Ends live study sessions after they're done with the current message.
+// Should be called after `http.Server.Shutdown`, which doesn't wait for
+// WebSocket connections.
+// Waits until the sessions have ended, or until the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return api.CloseStudySessions(ctx)
+}
+
+// Closes the auth database.
+// The server shouldn't be used after calling this.
+func (s *Server) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,12 @@
+begin transaction;
+	pragma user_version = 6;
+
+	-- Grammar and usage notes (e.g. gender, irregular forms).
+	create table if not exists note (
+		word integer not null references word,
+		text text not null
+		);
+
+	create index if not exists index_note_word on note (word);
+
+	commit;
@@ -26,4 +26,8 @@ class Word(str):
         while content.endswith(NO_BREAK_SPACE):
             content = content.removesuffix(NO_BREAK_SPACE)
 
+        # Phrases match regardless of the whitespace between words.
+        if any(c.isspace() for c in content):
+            content = " ".join(content.split())
+
         return super().__new__(cls, content.casefold())
@@ -0,0 +1,183 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Sanity checks on uploaded reviews.
+// Suspicious batches (e.g. from buggy or malicious clients) get quarantined
+// instead of being saved, until an admin approves or discards them.
+package quarantine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+// Tolerated difference between client and server clocks.
+const maxClockSkew = 5 * time.Minute
+
+// Nobody can answer more flashcards than this in one second.
+const maxReviewsPerSecond = 10
+
+var ErrNotFound = errors.New("quarantined batch not found")
+
+const (
+	ReasonFuture   = "timestamp in the future"
+	ReasonInterval = "review predates the word's previous review"
+	ReasonRate     = "too many reviews per second"
+)
+
+// Returns time when the word was last reviewed, and false if the word hasn't
+// been reviewed yet.
+type LastReviewedFunc func(review rs.Result) (time.Time, bool)
+
+// Returns reasons why the batch of reviews looks suspicious.
+// Returns nil if it looks fine.
+// Reviews without client timestamps are skipped.
+func Check(reviews []rs.Result, lastReviewed LastReviewedFunc, now time.Time) []string {
+	var future, interval, rate bool
+	perSecond := make(map[int64]int)
+
+	for _, review := range reviews {
+		if review.Timestamp == 0 {
+			continue
+		}
+		t := time.Unix(review.Timestamp, 0)
+		if t.After(now.Add(maxClockSkew)) {
+			future = true
+		}
+		if last, ok := lastReviewed(review); ok && t.Before(last.Add(-maxClockSkew)) {
+			interval = true
+		}
+		perSecond[review.Timestamp]++
+		if perSecond[review.Timestamp] > maxReviewsPerSecond {
+			rate = true
+		}
+	}
+
+	var reasons []string
+	if future {
+		reasons = append(reasons, ReasonFuture)
+	}
+	if interval {
+		reasons = append(reasons, ReasonInterval)
+	}
+	if rate {
+		reasons = append(reasons, ReasonRate)
+	}
+	return reasons
+}
+
+// Quarantined batch of reviews.
+type Batch struct {
+	ID       int         `json:"id"`
+	UserID   int         `json:"userID"`
+	Username string      `json:"username"`
+	L1       string      `json:"l1"`
+	L2       string      `json:"l2"`
+	Reviews  []rs.Result `json:"reviews"`
+	Reasons  []string    `json:"reasons"`
+	Created  time.Time   `json:"created"`
+}
+
+// Saves batch in the auth DB.
+// Returns the ID of the batch.
+func Add(db *sql.DB, userID int, l1, l2 string, reviews []rs.Result, reasons []string) (int, error) {
+	reviewsJSON, err := json.Marshal(reviews)
+	if err != nil {
+		return 0, fmt.Errorf("failed to quarantine reviews: %w", err)
+	}
+	reasonsJSON, err := json.Marshal(reasons)
+	if err != nil {
+		return 0, fmt.Errorf("failed to quarantine reviews: %w", err)
+	}
+
+	query := `
+		INSERT INTO quarantine (user_id, l1, l2, reviews, reasons)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := db.Exec(query, userID, l1, l2, string(reviewsJSON), string(reasonsJSON))
+	if err != nil {
+		return 0, fmt.Errorf("failed to quarantine reviews: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to quarantine reviews: %w", err)
+	}
+	return int(id), nil
+}
+
+const batchColumns = `
+	SELECT quarantine.id, user_id, username, l1, l2, reviews, reasons, created
+	FROM quarantine JOIN user ON user.id = user_id
+`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBatch(row scanner) (Batch, error) {
+	var b Batch
+	var reviews, reasons string
+	var created int64
+	err := row.Scan(&b.ID, &b.UserID, &b.Username, &b.L1, &b.L2, &reviews, &reasons, &created)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal([]byte(reviews), &b.Reviews); err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal([]byte(reasons), &b.Reasons); err != nil {
+		return b, err
+	}
+	b.Created = time.Unix(created, 0)
+	return b, nil
+}
+
+// Lists quarantined batches, oldest first.
+func List(db *sql.DB) ([]Batch, error) {
+	rows, err := db.Query(batchColumns + ` ORDER BY quarantine.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined reviews: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []Batch{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list quarantined reviews: %w", err)
+		}
+		batches = append(batches, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list quarantined reviews: %w", err)
+	}
+	return batches, nil
+}
+
+func Get(db *sql.DB, id int) (Batch, error) {
+	b, err := scanBatch(db.QueryRow(batchColumns+` WHERE quarantine.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, ErrNotFound
+	}
+	if err != nil {
+		return b, fmt.Errorf("failed to get quarantined reviews: %w", err)
+	}
+	return b, nil
+}
+
+// Deletes batch (e.g. after it gets approved or discarded).
+func Delete(db *sql.DB, id int) error {
+	result, err := db.Exec(`DELETE FROM quarantine WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete quarantined reviews: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
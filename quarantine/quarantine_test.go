@@ -0,0 +1,103 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package quarantine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/database"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+func neverReviewed(rs.Result) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func TestCheckNormalBatch(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	reviews := []rs.Result{
+		{Word: "foo", Correct: true, Timestamp: now.Add(-time.Minute).Unix()},
+		{Word: "bar", Correct: false, Timestamp: now.Add(-30 * time.Second).Unix()},
+		{Word: "baz", Correct: true},
+	}
+	if reasons := Check(reviews, neverReviewed, now); reasons != nil {
+		t.Fatal("expected batch to pass sanity checks:", reasons)
+	}
+}
+
+func TestCheckSuspiciousBatches(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+
+	future := []rs.Result{{Word: "foo", Timestamp: now.Add(time.Hour).Unix()}}
+	if reasons := Check(future, neverReviewed, now); len(reasons) != 1 || reasons[0] != ReasonFuture {
+		t.Fatal("expected future timestamp to be flagged:", reasons)
+	}
+
+	lastReviewed := func(rs.Result) (time.Time, bool) {
+		return now, true
+	}
+	past := []rs.Result{{Word: "foo", Timestamp: now.Add(-time.Hour).Unix()}}
+	if reasons := Check(past, lastReviewed, now); len(reasons) != 1 || reasons[0] != ReasonInterval {
+		t.Fatal("expected impossible interval to be flagged:", reasons)
+	}
+
+	var burst []rs.Result
+	for i := 0; i <= maxReviewsPerSecond; i++ {
+		burst = append(burst, rs.Result{Word: "foo", Timestamp: now.Unix()})
+	}
+	if reasons := Check(burst, neverReviewed, now); len(reasons) != 1 || reasons[0] != ReasonRate {
+		t.Fatal("expected burst of reviews to be flagged:", reasons)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	if err := auth.Register(db, "foo", "bar"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	userID, err := auth.GetUserID(db, "foo")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	reviews := []rs.Result{{Word: "foo", Correct: true, Timestamp: 1}}
+	id, err := Add(db, userID, "eng", "spa", reviews, []string{ReasonFuture})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	batches, err := List(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(batches) != 1 {
+		t.Fatal("expected one quarantined batch:", batches)
+	}
+
+	batch, err := Get(db, id)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if batch.Username != "foo" || batch.L2 != "spa" || len(batch.Reviews) != 1 || batch.Reviews[0] != reviews[0] {
+		t.Fatal("unexpected batch:", batch)
+	}
+
+	if err := Delete(db, id); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := Get(db, id); !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected batch to be deleted:", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Daily review reminder emails.
+package reminders
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones may not be installed on the server.
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/goals"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/review_scheduler"
+)
+
+type Settings struct {
+	Enabled  bool   `json:"enabled"`
+	Email    string `json:"email"`
+	Timezone string `json:"timezone"` // IANA timezone (e.g. "Asia/Manila")
+	Hour     int    `json:"hour"`     // Local hour to send reminders at
+}
+
+func defaultSettings() Settings {
+	return Settings{Timezone: "UTC", Hour: 9}
+}
+
+func (s Settings) IsValid() bool {
+	if s.Hour < 0 || s.Hour > 23 {
+		return false
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return false
+	}
+	if s.Enabled || s.Email != "" {
+		if !mail.IsValidAddress(s.Email) {
+			return false
+		}
+	}
+	return true
+}
+
+// Gets reminder settings from the user DB.
+// Returns default settings if the user hasn't set any.
+func Get(db *sql.DB) (Settings, error) {
+	s := defaultSettings()
+	query := `SELECT enabled, email, timezone, hour FROM reminder`
+	err := db.QueryRow(query).Scan(&s.Enabled, &s.Email, &s.Timezone, &s.Hour)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return s, fmt.Errorf("failed to get reminder settings: %w", err)
+	}
+	return s, nil
+}
+
+// Saves reminder settings in the user DB.
+func Set(db *sql.DB, s Settings) error {
+	if !s.IsValid() {
+		return fmt.Errorf("failed to set reminder settings: invalid value: %v", s)
+	}
+
+	query := `
+		INSERT INTO reminder (enabled, email, timezone, hour) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			enabled = excluded.enabled,
+			email = excluded.email,
+			timezone = excluded.timezone,
+			hour = excluded.hour
+	`
+	if _, err := db.Exec(query, s.Enabled, s.Email, s.Timezone, s.Hour); err != nil {
+		return fmt.Errorf("failed to set reminder settings: %w", err)
+	}
+	return nil
+}
+
+type CourseDue struct {
+	L1  string
+	L2  string
+	Due int
+
+	// Nil if the user hasn't set a vocabulary goal in the course.
+	Goal *goals.Progress
+}
+
+// Returns the start of the local day.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Returns number of due reviews in each of the user's courses.
+// Returns studied = true if the user has reviewed anything since the
+// start of the local day.
+func checkCourses(userID int, now time.Time) (courses []CourseDue, studied bool, err error) {
+	paths, err := filepath.Glob(filepath.Join(basedir.User(userID), "reviews", "*.db"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	today := startOfDay(now)
+	for _, path := range paths {
+		l1, l2, found := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".db"), "-")
+		if !found {
+			continue
+		}
+
+		db, err := database.Open(path + "?mode=ro")
+		if err != nil {
+			return nil, false, err
+		}
+
+		var reviewed sql.NullInt64
+		err = db.QueryRow(`SELECT max(reviewed) FROM history`).Scan(&reviewed)
+		if err == nil && reviewed.Valid && !time.Unix(reviewed.Int64, 0).Before(today) {
+			studied = true
+		}
+
+		var due int
+		if err == nil {
+			due, err = review_scheduler.CountDue(db, now)
+		}
+		var goal *goals.Progress
+		if err == nil && due > 0 {
+			// The DB is opened read-only, so it may not have the goal table
+			// yet.
+			goal, _ = goals.GetProgress(db, now)
+		}
+		db.Close()
+		if err != nil {
+			return nil, false, err
+		}
+		if due > 0 {
+			courses = append(courses, CourseDue{L1: l1, L2: l2, Due: due, Goal: goal})
+		}
+	}
+	return courses, studied, nil
+}
+
+// Checks if it's time to send a reminder.
+// Reminders get sent at most once per local day, after the chosen hour.
+func isTime(s Settings, lastSent, now time.Time) bool {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+	local := now.In(loc)
+	return local.Hour() >= s.Hour && lastSent.Before(startOfDay(local))
+}
+
+// Sends reminder to user if needed.
+// Returns true if a reminder was sent.
+func remind(userID int, username string, now time.Time) (bool, error) {
+	db, err := database.OpenUserDB(basedir.UserData(userID))
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	s, err := Get(db)
+	if err != nil || !s.Enabled || s.Email == "" {
+		return false, err
+	}
+
+	var lastSent int64
+	query := `SELECT last_sent FROM reminder`
+	if err := db.QueryRow(query).Scan(&lastSent); err != nil {
+		return false, err
+	}
+	if !isTime(s, time.Unix(lastSent, 0), now) {
+		return false, nil
+	}
+
+	loc, _ := time.LoadLocation(s.Timezone)
+	courses, studied, err := checkCourses(userID, now.In(loc))
+	if err != nil || studied || len(courses) == 0 {
+		return false, err
+	}
+
+	total := 0
+	for _, c := range courses {
+		total += c.Due
+	}
+	m, err := mail.Render("reminder.txt", s.Email, map[string]any{
+		"Username": username,
+		"Due":      total,
+		"Courses":  courses,
+	})
+	if err != nil {
+		return false, err
+	}
+	mail.Send(m)
+
+	query = `UPDATE reminder SET last_sent = ?`
+	if _, err := db.Exec(query, now.Unix()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Sends reminders to users who are due for one.
+// db: auth DB
+// Returns the number of reminders sent.
+func Run(db *sql.DB, now time.Time) (int, error) {
+	rows, err := db.Query(`SELECT id, username FROM user`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send reminders: %w", err)
+	}
+
+	type user struct {
+		id       int
+		username string
+	}
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.username); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to send reminders: %w", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+
+	sent := 0
+	for _, u := range users {
+		ok, err := remind(u.id, u.username, now)
+		if err != nil {
+			log.Println(fmt.Errorf("failed to send reminder to user %v: %w", u.id, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// Runs `Run` periodically in the background.
+// Call the returned function to stop.
+func Schedule(db *sql.DB, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if _, err := Run(db, now); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package reminders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.OpenUserDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	s, err := Get(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if s != defaultSettings() {
+		t.Fatal("expected default settings:", s)
+	}
+
+	s = Settings{Enabled: true, Email: "foo@example.com", Timezone: "Asia/Manila", Hour: 20}
+	if err := Set(db, s); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if v, err := Get(db); err != nil || v != s {
+		t.Fatal("expected settings to be saved:", v, err)
+	}
+
+	invalid := []Settings{
+		{Enabled: true, Email: "", Timezone: "UTC", Hour: 9},
+		{Email: "foo@example.com", Timezone: "Nowhere/Nothing", Hour: 9},
+		{Timezone: "UTC", Hour: 24},
+	}
+	for _, s := range invalid {
+		if err := Set(db, s); err == nil {
+			t.Fatal("expected invalid settings to be rejected:", s)
+		}
+	}
+}
+
+func TestIsTime(t *testing.T) {
+	t.Parallel()
+
+	s := Settings{Timezone: "Asia/Manila", Hour: 9} // UTC+8
+	day := time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		lastSent time.Time
+		now      time.Time
+		expected bool
+	}{
+		// 08:00 local time.
+		{time.Unix(0, 0), day, false},
+		// 10:00 local time.
+		{time.Unix(0, 0), day.Add(2 * time.Hour), true},
+		// Already sent today (09:30 local time).
+		{day.Add(90 * time.Minute), day.Add(2 * time.Hour), false},
+		// Sent yesterday.
+		{day.Add(-20 * time.Hour), day.Add(2 * time.Hour), true},
+	}
+	for _, c := range cases {
+		if v := isTime(s, c.lastSent, c.now); v != c.expected {
+			t.Fatal("unexpected result:", c.lastSent, c.now, v)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Exports review history as CSV (in the format read by `Replay`).
+// Doesn't include archived reviews (see `ExportWithArchive`).
+func Export[T database.Querier](q T, w io.Writer) error {
+	query := `
+		SELECT word, reviewed, interval_after > 0 FROM history
+		ORDER BY reviewed ASC
+	`
+	rows, err := q.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to export reviews: %w", err)
+	}
+	defer rows.Close()
+	return writeReviews(rows, w)
+}
+
+// Like `Export`, but also includes reviews that have been moved into the
+// archive DB (see `history.Archive`).
+// Falls back to `Export` if the archive doesn't exist.
+func ExportWithArchive(db *sql.DB, archivePath string, w io.Writer) error {
+	if _, err := os.Stat(archivePath); errors.Is(err, os.ErrNotExist) {
+		return Export(db, w)
+	}
+
+	// ATTACH only applies to one connection.
+	ctx := context.Background()
+	con, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to export reviews: %w", err)
+	}
+	defer con.Close()
+
+	query := `ATTACH DATABASE ? AS archive`
+	if _, err := con.ExecContext(ctx, query, archivePath); err != nil {
+		return fmt.Errorf("failed to export reviews: %w", err)
+	}
+	defer func() {
+		_, _ = con.ExecContext(ctx, `DETACH DATABASE archive`)
+	}()
+
+	query = `
+		SELECT word, reviewed, interval_after > 0 FROM archive.history
+		UNION ALL
+		SELECT word, reviewed, interval_after > 0 FROM main.history
+		ORDER BY reviewed ASC
+	`
+	rows, err := con.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to export reviews: %w", err)
+	}
+	defer rows.Close()
+	return writeReviews(rows, w)
+}
+
+// Writes (word, reviewed, correct) rows as CSV.
+func writeReviews(rows *sql.Rows, w io.Writer) error {
+	writer := NewReviewWriter(csv.NewWriter(w))
+	for rows.Next() {
+		var event ReviewEvent
+		var reviewed int64
+		if err := rows.Scan(&event.Word, &reviewed, &event.Correct); err != nil {
+			return fmt.Errorf("failed to export reviews: %w", err)
+		}
+		event.Reviewed = time.Unix(reviewed, 0)
+		if err := writer.WriteReview(event); err != nil {
+			return fmt.Errorf("failed to export reviews: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export reviews: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/history"
+	"github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestExport(t *testing.T) {
+	// Exported reviews should be readable by `ReviewReader`.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Unix(1000000, 0)
+	if err := review_scheduler.UpdateReviewAt(db, "foo", true, now); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := review_scheduler.UpdateReviewAt(db, "bar", false, now.Add(time.Hour)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	b := new(strings.Builder)
+	if err := Export(db, b); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	r := testReader(b.String())
+	for _, expected := range []ReviewEvent{
+		{Word: "foo", Reviewed: now, Correct: true},
+		{Word: "bar", Reviewed: now.Add(time.Hour), Correct: false},
+	} {
+		e, err := r.ReadReview()
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if e != expected {
+			t.Fatal("expected exported review to match:", e, expected)
+		}
+	}
+}
+
+func TestExportWithArchive(t *testing.T) {
+	// Archived reviews should be exported along with the rest.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	now := time.Unix(1000000, 0)
+	expected := []ReviewEvent{
+		{Word: "foo", Reviewed: now, Correct: true},
+		{Word: "foo", Reviewed: now.Add(time.Hour), Correct: false},
+		{Word: "foo", Reviewed: now.Add(10 * 24 * time.Hour), Correct: true},
+	}
+	for _, e := range expected {
+		if err := review_scheduler.UpdateReviewAt(db, e.Word, e.Correct, e.Reviewed); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	archived, err := history.Archive(db, path, now.Add(5*24*time.Hour))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if archived != 2 {
+		t.Fatal("expected 2 archived reviews:", archived)
+	}
+
+	b := new(strings.Builder)
+	if err := ExportWithArchive(db, path, b); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	r := testReader(b.String())
+	for _, e := range expected {
+		actual, err := r.ReadReview()
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if actual != e {
+			t.Fatal("expected exported review to match:", actual, e)
+		}
+	}
+}
@@ -4,30 +4,19 @@
 package replay
 
 import (
-	"bufio"
 	"database/sql"
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/polycloze/polycloze/database"
-	"github.com/polycloze/polycloze/word_scheduler"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+	"github.com/polycloze/polycloze/text"
 )
 
 var ErrHasExistingReviews = errors.New("found existing reviews")
 
-// Checks if `io.Reader` has reached the EOF.
-// Assumes the reader will no longer be used.
-func isEOF(r io.Reader) bool {
-	scanner := bufio.NewScanner(r)
-	if scanner.Scan() {
-		return false
-	}
-	return scanner.Err() == nil
-}
-
 // Checks if there are existing reviews in the DB.
 // Returns an error if there are existing reviews.
 // Also returns an error if the database query fails.
@@ -46,44 +35,38 @@ func hasExistingReviews[T database.Querier](q T) error {
 
 // Imports review data from CSV file.
 // This operation is not allowed if there are existing reviews in the DB.
+// The file is read as a stream, so it doesn't have to fit in memory.
+// Nothing gets imported if the file is invalid (see `ErrInvalidFormat` and
+// `FormatError`).
+// The CSV dialect is detected automatically.
 func Replay[T database.Querier](q T, r io.Reader) error {
+	return ReplayDialect(q, r, Dialect{})
+}
+
+// Same as Replay, but takes the CSV dialect of the file.
+func ReplayDialect[T database.Querier](q T, r io.Reader, dialect Dialect) error {
 	if err := hasExistingReviews(q); err != nil {
 		return fmt.Errorf("failed to import review: %w", err)
 	}
 
-	reader := NewReviewReader(csv.NewReader(r))
-
-	// Ignore first error (it may be a header row), but don't ignore further
-	// errors.
-	if review, err := reader.ReadReview(); err == nil {
-		if err := word_scheduler.UpdateWordAt(
-			q,
-			review.Word,
-			review.Correct,
-			review.Reviewed,
-		); err != nil {
-			return fmt.Errorf("failed to import review: %w", err)
-		}
+	tx, err := q.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import review: %w", err)
 	}
+	defer tx.Rollback()
 
-	var review ReviewEvent
-	var err error
-	for {
-		review, err = reader.ReadReview()
-		if err != nil {
-			break
-		}
-		if err := word_scheduler.UpdateWordAt(
-			q,
-			review.Word,
-			review.Correct,
-			review.Reviewed,
-		); err != nil {
-			return fmt.Errorf("failed to import review: %w", err)
+	_, err = readReviews(r, dialect, func(review ReviewEvent) error {
+		result := rs.Result{
+			Word:    text.Casefold(review.Word),
+			Correct: review.Correct,
 		}
+		return rs.UpdateReviewAtTx(tx, result, review.Reviewed)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import review: %w", err)
 	}
 
-	if !isEOF(r) {
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to import review: %w", err)
 	}
 	return nil
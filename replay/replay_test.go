@@ -0,0 +1,105 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+	"github.com/polycloze/polycloze/wilson"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	s := "word,reviewed,correct\nFoo,1000000,1\nbar,1003600,0\n"
+	if err := Replay(db, strings.NewReader(s)); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	var count int
+	query := `SELECT count(*) FROM review WHERE item IN ('foo', 'bar')`
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 2 {
+		t.Fatal("expected both reviews to be imported:", count)
+	}
+
+	// Can't import into DB with existing reviews.
+	if err := Replay(db, strings.NewReader(s)); !errors.Is(err, ErrHasExistingReviews) {
+		t.Fatal("expected ErrHasExistingReviews:", err)
+	}
+}
+
+func TestReplayInvalidFile(t *testing.T) {
+	// Nothing should be imported if the file is invalid.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	s := "foo,1000000,1\nbar,yesterday,0\n"
+	if err := Replay(db, strings.NewReader(s)); !errors.Is(err, ErrInvalidFormat) {
+		t.Fatal("expected ErrInvalidFormat:", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM review`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 0 {
+		t.Fatal("expected no reviews to be imported:", count)
+	}
+}
+
+func TestReplayStats(t *testing.T) {
+	// Imported stats should match exported stats.
+	t.Parallel()
+
+	src := utils.TestingDatabase()
+	defer src.Close()
+
+	expected := Stats{
+		Level:      4,
+		Correct:    3,
+		Incorrect:  1,
+		Pinned:     true,
+		Thresholds: wilson.DefaultThresholds(),
+		Algorithm:  "bayes",
+	}
+	if err := SetStats(src, expected); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	var b bytes.Buffer
+	if err := ExportStats(src, &b); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	dst := utils.TestingDatabase()
+	defer dst.Close()
+
+	if err := ReplayStats(dst, &b); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	stats, err := GetStats(dst)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if stats != expected {
+		t.Fatal("expected imported stats to match:", stats, expected)
+	}
+
+	invalid := strings.NewReader(`{"level": -1}`)
+	if err := ReplayStats(dst, invalid); !errors.Is(err, ErrInvalidStats) {
+		t.Fatal("expected ErrInvalidStats:", err)
+	}
+}
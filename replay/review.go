@@ -5,7 +5,6 @@ package replay
 
 import (
 	"encoding/csv"
-	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -41,14 +40,18 @@ func (r *ReviewReader) ReadReview() (ReviewEvent, error) {
 		return ReviewEvent{}, fmt.Errorf("failed to read review from CSV: %w", err)
 	}
 	if len(record) != 3 {
-		return ReviewEvent{}, errors.New(
-			"failed to read review from CSV: incorrect number of fields",
+		return ReviewEvent{}, fmt.Errorf(
+			"failed to read review from CSV: incorrect number of fields (expected 3, got %v)",
+			len(record),
 		)
 	}
 
 	i, err := strconv.ParseInt(record[1], 10, 64)
 	if err != nil {
-		return ReviewEvent{}, fmt.Errorf("failed to read review from CSV: %w", err)
+		return ReviewEvent{}, fmt.Errorf(
+			"failed to read review from CSV: invalid timestamp (expected UNIX time, got %q)",
+			record[1],
+		)
 	}
 
 	var correct bool
@@ -58,8 +61,9 @@ func (r *ReviewReader) ReadReview() (ReviewEvent, error) {
 	case "1":
 		correct = true
 	default:
-		return ReviewEvent{}, errors.New(
-			"failed to read review from CSV: invalid correct value",
+		return ReviewEvent{}, fmt.Errorf(
+			"failed to read review from CSV: invalid correct value (expected 0 or 1, got %q)",
+			record[2],
 		)
 	}
 
@@ -70,6 +74,14 @@ func (r *ReviewReader) ReadReview() (ReviewEvent, error) {
 	}, nil
 }
 
+// Returns the line number where the last record starts.
+// Only valid if the record could be parsed as CSV, even if it's not a valid
+// review.
+func (r *ReviewReader) Line() int {
+	line, _ := r.csvReader.FieldPos(0)
+	return line
+}
+
 type ReviewWriter struct {
 	csvWriter *csv.Writer
 }
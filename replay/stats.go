@@ -0,0 +1,125 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/difficulty"
+	"github.com/polycloze/polycloze/wilson"
+)
+
+var ErrInvalidStats = errors.New("invalid tuner stats")
+
+// Difficulty tuner state that can't be recomputed from the review history.
+// Interval stats don't need to be included, because `Replay` recomputes them.
+type Stats struct {
+	Level     int  `json:"level"`
+	Correct   int  `json:"correct"`
+	Incorrect int  `json:"incorrect"`
+	Pinned    bool `json:"pinned"`
+
+	Thresholds wilson.Thresholds `json:"thresholds"`
+	Algorithm  string            `json:"algorithm"`
+}
+
+// Gets tuner stats from the review DB.
+func GetStats[T database.Querier](q T) (Stats, error) {
+	var stats Stats
+
+	// Doesn't use `difficulty.GetLatest`, because that needs the course DB.
+	query := `SELECT v, correct, incorrect, pinned FROM estimated_level`
+	_ = q.QueryRow(query).Scan(
+		&stats.Level,
+		&stats.Correct,
+		&stats.Incorrect,
+		&stats.Pinned,
+	)
+
+	thresholds, err := difficulty.GetThresholds(q)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get tuner stats: %w", err)
+	}
+	stats.Thresholds = thresholds
+
+	algorithm, err := difficulty.GetAlgorithm(q)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get tuner stats: %w", err)
+	}
+	stats.Algorithm = algorithm
+	return stats, nil
+}
+
+// Saves tuner stats in the review DB.
+// Replaces the current level and tuner settings.
+func SetStats[T database.Querier](q T, stats Stats) error {
+	if stats.Level < 0 || stats.Correct < 0 || stats.Incorrect < 0 ||
+		!stats.Thresholds.IsValid() ||
+		!difficulty.IsValidAlgorithm(stats.Algorithm) {
+		return fmt.Errorf("failed to set tuner stats: %w", ErrInvalidStats)
+	}
+
+	tx, err := q.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to set tuner stats: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT OR REPLACE INTO estimated_level (v, correct, incorrect, pinned)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err = tx.Exec(query, stats.Level, stats.Correct, stats.Incorrect, stats.Pinned)
+	if err != nil {
+		return fmt.Errorf("failed to set tuner stats: %w", err)
+	}
+
+	query = `
+		INSERT OR REPLACE INTO tuner_settings (easy_z, easy_bound, hard_z, hard_bound)
+		VALUES (?, ?, ?, ?)
+	`
+	t := stats.Thresholds
+	if _, err := tx.Exec(query, t.EasyZ, t.EasyBound, t.HardZ, t.HardBound); err != nil {
+		return fmt.Errorf("failed to set tuner stats: %w", err)
+	}
+
+	query = `INSERT OR REPLACE INTO tuner_algorithm (name) VALUES (?)`
+	if _, err := tx.Exec(query, stats.Algorithm); err != nil {
+		return fmt.Errorf("failed to set tuner stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to set tuner stats: %w", err)
+	}
+	return nil
+}
+
+// Exports tuner stats as JSON (in the format read by `ReplayStats`).
+func ExportStats[T database.Querier](q T, w io.Writer) error {
+	stats, err := GetStats(q)
+	if err != nil {
+		return fmt.Errorf("failed to export tuner stats: %w", err)
+	}
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		return fmt.Errorf("failed to export tuner stats: %w", err)
+	}
+	return nil
+}
+
+// Imports tuner stats from JSON file.
+// Unlike `Replay`, this may be done after the reviews have been imported.
+func ReplayStats[T database.Querier](q T, r io.Reader) error {
+	var stats Stats
+	if err := json.NewDecoder(r).Decode(&stats); err != nil {
+		return fmt.Errorf("failed to import tuner stats: %w: %v", ErrInvalidStats, err)
+	}
+	if err := SetStats(q, stats); err != nil {
+		return fmt.Errorf("failed to import tuner stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidFormat = errors.New("not a review CSV file")
+
+// Content types that browsers send for CSV files.
+// Only used to reject obviously wrong files; the content is always checked.
+var csvContentTypes = map[string]bool{
+	"":                         true,
+	"text/csv":                 true,
+	"text/plain":               true,
+	"application/csv":          true,
+	"application/vnd.ms-excel": true,
+	"application/octet-stream": true,
+}
+
+// Checks if the content type sent by the client could be a CSV file.
+func IsCSVContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return csvContentTypes[strings.ToLower(strings.TrimSpace(mediaType))]
+}
+
+// Checks if the beginning of the file looks like text.
+func sniff(r *bufio.Reader) error {
+	head, err := r.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return err
+	}
+	if len(head) == 0 {
+		return fmt.Errorf("%w: file is empty", ErrInvalidFormat)
+	}
+
+	contentType := http.DetectContentType(head)
+	if !strings.HasPrefix(contentType, "text/plain") {
+		return fmt.Errorf("%w: detected %v", ErrInvalidFormat, contentType)
+	}
+
+	// The last rune might have been cut off.
+	for i := 0; i < utf8.UTFMax && len(head) > 0; i++ {
+		if utf8.Valid(head) {
+			return nil
+		}
+		head = head[:len(head)-1]
+	}
+	return fmt.Errorf("%w: not UTF-8", ErrInvalidFormat)
+}
+
+// Remembers read errors, so they can be told apart from format errors.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// CSV dialect of review files.
+// Spreadsheet programs use different delimiters depending on the locale, and
+// quote fields differently.
+type Dialect struct {
+	// Field delimiter (e.g. ',', ';' or '\t').
+	// Detected from the beginning of the file if zero.
+	Comma rune
+}
+
+// Delimiters that can be detected.
+var delimiters = []rune{',', ';', '\t'}
+
+// Number of lines used to detect the delimiter.
+const detectLines = 5
+
+// Guesses the delimiter from the beginning of the file.
+// Picks the delimiter that splits the most lines into the number of fields in
+// a review, or a comma if none does.
+func detectComma(r *bufio.Reader) rune {
+	head, _ := r.Peek(4096)
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1] // The last line might have been cut off.
+	}
+
+	best, bestScore := ',', 0
+	for _, comma := range delimiters {
+		cr := csv.NewReader(bytes.NewReader(head))
+		cr.Comma = comma
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+
+		score := 0
+		for i := 0; i < detectLines; i++ {
+			record, err := cr.Read()
+			if err != nil {
+				break
+			}
+			if len(record) == 3 {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = comma, score
+		}
+	}
+	return best
+}
+
+// Reviews can't be more than this far in the future, to allow for clock
+// differences.
+const maxClockSkew = 24 * time.Hour
+
+var errFutureReview = errors.New("review is in the future")
+
+// Max number of invalid lines to report.
+const maxLineErrors = 10
+
+// Invalid line in a review file.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+// Lists invalid lines in a review file (up to `maxLineErrors`).
+// Matches `ErrInvalidFormat` with `errors.Is`.
+type FormatError struct {
+	Lines []LineError
+
+	// Set if there are more invalid lines than the ones listed.
+	More bool
+}
+
+func (e *FormatError) Error() string {
+	lines := make([]string, len(e.Lines))
+	for i, line := range e.Lines {
+		lines[i] = line.Error()
+	}
+	message := fmt.Sprintf("%v: %v", ErrInvalidFormat, strings.Join(lines, "; "))
+	if e.More {
+		message += "; ..."
+	}
+	return message
+}
+
+func (e *FormatError) Is(target error) bool {
+	return target == ErrInvalidFormat
+}
+
+// Reads reviews from a CSV file and calls `f` on each one.
+// The first row is allowed to be a header.
+// Invalid lines are skipped, and get reported in a `FormatError` after the
+// whole file has been read, so `f` should undo its changes if there's an
+// error.
+// Returns the number of reviews read.
+func readReviews(r io.Reader, dialect Dialect, f func(review ReviewEvent) error) (int, error) {
+	er := &errReader{r: r}
+	br := bufio.NewReader(er)
+
+	// Spreadsheet programs add a byte order mark to UTF-8 files.
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = br.Discard(3)
+	}
+	if err := sniff(br); err != nil {
+		return 0, err
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = dialect.Comma
+	if cr.Comma == 0 {
+		cr.Comma = detectComma(br)
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	reader := NewReviewReader(cr)
+	formatErr := &FormatError{}
+	report := func(line int, err error) {
+		if len(formatErr.Lines) < maxLineErrors {
+			formatErr.Lines = append(formatErr.Lines, LineError{Line: line, Err: err})
+		} else {
+			formatErr.More = true
+		}
+	}
+
+	// Reviews in the future would never be due. Uploaded reviews get the same
+	// check before they're saved (see package quarantine).
+	latest := time.Now().Add(maxClockSkew)
+
+	count := 0
+	for record := 1; ; record++ {
+		review, err := reader.ReadReview()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if er.err != nil {
+			return count, er.err
+		}
+		if err != nil {
+			if record == 1 {
+				continue // Header
+			}
+
+			var parseErr *csv.ParseError
+			line := 0
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			} else {
+				line = reader.Line()
+			}
+			report(line, err)
+			continue
+		}
+		if review.Reviewed.After(latest) {
+			report(reader.Line(), errFutureReview)
+			continue
+		}
+		if err := f(review); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if len(formatErr.Lines) > 0 {
+		return count, formatErr
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("%w: no reviews found", ErrInvalidFormat)
+	}
+	return count, nil
+}
+
+// Checks if the file is in the format read by `Replay`.
+// Returns the number of reviews in the file.
+func Validate(r io.Reader) (int, error) {
+	return readReviews(r, Dialect{}, func(ReviewEvent) error { return nil })
+}
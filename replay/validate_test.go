@@ -0,0 +1,99 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package replay
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []string{
+		"word,reviewed,correct\nfoo,0,1\nbar,1,0\n",
+		"foo,0,1\n",
+	}
+	for _, s := range valid {
+		if _, err := Validate(strings.NewReader(s)); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"word,reviewed,correct\n",
+		"foo,0,1\nbar,yesterday,1\n",
+		"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"foo,0,1\nbar,99999999999,1\n",
+	}
+	for _, s := range invalid {
+		if _, err := Validate(strings.NewReader(s)); !errors.Is(err, ErrInvalidFormat) {
+			t.Fatal("expected ErrInvalidFormat:", s, err)
+		}
+	}
+}
+
+func TestValidateReadError(t *testing.T) {
+	// Read errors shouldn't be reported as format errors.
+	t.Parallel()
+
+	errRead := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("foo,0,1\n"), iotest.ErrReader(errRead))
+	_, err := Validate(r)
+	if !errors.Is(err, errRead) || errors.Is(err, ErrInvalidFormat) {
+		t.Fatal("expected read error:", err)
+	}
+}
+
+func TestValidateDialects(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"semicolon":        "word;reviewed;correct\nfoo;0;1\nbar;1;0\n",
+		"tab":              "word\treviewed\tcorrect\nfoo\t0\t1\nbar\t1\t0\n",
+		"quoted":           "\xef\xbb\xbf\"word\",\"reviewed\",\"correct\"\r\n\"foo, bar\",\"0\",\"1\"\r\n",
+		"quoted semicolon": "\"word\";\"reviewed\";\"correct\"\n\"foo; bar\";\"0\";\"1\"\n",
+	}
+	for name, s := range files {
+		count, err := Validate(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("expected err to be nil (%v): %v", name, err)
+		}
+		if count <= 0 {
+			t.Fatalf("expected reviews to be read (%v): %v", name, count)
+		}
+	}
+}
+
+func TestValidateReportsInvalidLines(t *testing.T) {
+	t.Parallel()
+
+	s := "word,reviewed,correct\nfoo,0,1\nbar,yesterday,1\nbaz,2,1\nqux,3,maybe\n"
+	_, err := Validate(strings.NewReader(s))
+
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || !errors.Is(err, ErrInvalidFormat) {
+		t.Fatal("expected FormatError:", err)
+	}
+	if len(formatErr.Lines) != 2 || formatErr.Lines[0].Line != 3 || formatErr.Lines[1].Line != 5 {
+		t.Fatal("expected lines 3 and 5 to be reported:", formatErr.Lines)
+	}
+}
+
+func TestIsCSVContentType(t *testing.T) {
+	t.Parallel()
+
+	for _, contentType := range []string{"text/csv", "text/plain; charset=utf-8", "application/vnd.ms-excel"} {
+		if !IsCSVContentType(contentType) {
+			t.Fatal("expected CSV content type:", contentType)
+		}
+	}
+	if IsCSVContentType("image/png") {
+		t.Fatal("expected image/png to be rejected")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Builds reverse courses (L2 speakers learning L1) from installed courses.
+// Course DBs already contain L1 translations of L2 sentences, so the reverse
+// course uses the translations as sentences and the original sentences as
+// translations.
+// Since the course builder only tokenizes L2 sentences, translations get
+// tokenized with package text, which doesn't work well for languages that
+// don't separate words with spaces.
+package reverse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Max number of example sentences per word.
+// Same as in python/scripts/populate.py.
+const maxExamples = 30
+
+// Builds reverse course of the course in src, and saves it in dst.
+// Builds into a temporary file first, so dst is never left half-written.
+func Build(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to build reverse course: %w", err)
+	}
+
+	tmp := dst + ".tmp"
+	_ = os.Remove(tmp)
+	if err := build(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to build reverse course: %w", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("failed to build reverse course: %w", err)
+	}
+	return nil
+}
+
+func build(src, dst string) error {
+	db, err := database.Open(dst)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`ATTACH DATABASE ? AS src`, src); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := copySchema(tx); err != nil {
+		return err
+	}
+
+	queries := []string{
+		// Swap languages.
+		`INSERT INTO main.language (id, code, name, bcp47)
+		SELECT iif(id = 'l1', 'l2', 'l1'), code, name, bcp47 FROM src.language`,
+
+		// Original sentences become translations.
+		`INSERT INTO main.translation (tatoeba_id, text)
+		SELECT tatoeba_id, text FROM src.sentence WHERE tatoeba_id IS NOT NULL`,
+
+		`INSERT INTO main.translates (source, target)
+		SELECT target, source FROM src.translates`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if err := addSentences(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DETACH DATABASE src`); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// Creates tables and indices in main with the same schema as in src.
+func copySchema(tx *sql.Tx) error {
+	query := `
+		SELECT sql FROM src.sqlite_master
+		WHERE type IN ('table', 'index') AND sql IS NOT NULL
+		ORDER BY type = 'index'
+	`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var schema []string
+	for rows.Next() {
+		var sql string
+		if err := rows.Scan(&sql); err != nil {
+			return err
+		}
+		schema = append(schema, sql)
+	}
+	rows.Close()
+
+	for _, sql := range schema {
+		if _, err := tx.Exec(sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sentence struct {
+	id         int64 // 0 if not inserted
+	tatoebaID  int64
+	text       string
+	tokens     []string
+	words      []string // Casefolded, without duplicates
+	difficulty int
+}
+
+// Computes frequency class from word counts.
+// Same formula as in python/scripts/tokenizer.py.
+func frequencyClass(count, maxCount int) int {
+	return int(math.Floor(0.5 - math.Log2(float64(count)/float64(maxCount))))
+}
+
+// Tokenizes translations in src, then inserts sentences, words and links
+// between them.
+func addSentences(tx *sql.Tx) error {
+	// Only translations of course sentences are included in the course.
+	query := `SELECT tatoeba_id, text FROM src.translation WHERE tatoeba_id IS NOT NULL`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var sentences []sentence
+	counts := make(map[string]int)
+	for rows.Next() {
+		var s sentence
+		if err := rows.Scan(&s.tatoebaID, &s.text); err != nil {
+			return err
+		}
+		s.tokens = text.Tokenize(s.text)
+
+		seen := make(map[string]bool)
+		for _, word := range text.Words(s.text) {
+			word = text.Casefold(word)
+			if !seen[word] {
+				seen[word] = true
+				s.words = append(s.words, word)
+			}
+			counts[word]++
+		}
+		if len(s.words) > 0 {
+			sentences = append(sentences, s)
+		}
+	}
+	rows.Close()
+
+	maxCount := 0
+	for _, count := range counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	// Insert most frequent words first, so that word IDs are deterministic.
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	ids := make(map[string]int64)
+	for _, word := range words {
+		query := `INSERT INTO main.word (word, frequency_class) VALUES (?, ?)`
+		result, err := tx.Exec(query, word, frequencyClass(counts[word], maxCount))
+		if err != nil {
+			return err
+		}
+		if ids[word], err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+
+	// Sentence difficulty is the frequency class of its rarest word.
+	for i, s := range sentences {
+		for _, word := range s.words {
+			if class := frequencyClass(counts[word], maxCount); class > s.difficulty {
+				sentences[i].difficulty = class
+			}
+		}
+
+		tokens, err := json.Marshal(s.tokens)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT OR IGNORE INTO main.sentence (tatoeba_id, text, tokens, frequency_class)
+			VALUES (?, ?, ?, ?)
+		`
+		result, err := tx.Exec(query, s.tatoebaID, s.text, string(tokens), sentences[i].difficulty)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			// Duplicate sentence.
+			continue
+		}
+		if sentences[i].id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+
+	// Link easiest sentences to words first, like the course builder.
+	sort.SliceStable(sentences, func(i, j int) bool {
+		return sentences[i].difficulty < sentences[j].difficulty
+	})
+
+	query = `INSERT INTO main.contains (sentence, word) VALUES (?, ?)`
+	examples := make(map[string]int)
+	for _, s := range sentences {
+		if s.id == 0 {
+			continue
+		}
+		for _, word := range s.words {
+			if examples[word] >= maxExamples {
+				continue
+			}
+			examples[word]++
+			if _, err := tx.Exec(query, s.id, ids[word]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
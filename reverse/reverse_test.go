@@ -0,0 +1,94 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package reverse
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/translator"
+)
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "eng-spa.db")
+	dst := filepath.Join(dir, "reverse", "spa-eng.db")
+
+	db, err := database.Open(src)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	queries := []string{
+		`CREATE TABLE language (id text primary key, code text, name text, bcp47 text)`,
+		`CREATE TABLE word (id integer primary key, word text unique not null, frequency_class integer not null)`,
+		`CREATE TABLE sentence (id integer primary key, tatoeba_id integer unique, text text unique not null, tokens text not null, frequency_class integer not null)`,
+		`CREATE TABLE translation (id integer primary key, tatoeba_id integer unique, text text unique not null)`,
+		`CREATE TABLE translates (source integer not null, target integer not null)`,
+		`CREATE TABLE contains (sentence integer not null, word integer not null)`,
+		`INSERT INTO language VALUES ('l1', 'eng', 'English', 'en'), ('l2', 'spa', 'Spanish', 'es')`,
+		`INSERT INTO word (word, frequency_class) VALUES ('hola', 0), ('mundo', 1)`,
+		`INSERT INTO sentence (tatoeba_id, text, tokens, frequency_class) VALUES
+			(1, 'Hola mundo.', '[]', 1),
+			(2, 'Hola.', '[]', 0)`,
+		`INSERT INTO translation (tatoeba_id, text) VALUES (10, 'Hello world.'), (20, 'Hello.')`,
+		`INSERT INTO translates (source, target) VALUES (1, 10), (2, 20)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	if err := Build(src, dst); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	reversed, err := database.Open(dst)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer reversed.Close()
+
+	var l2 string
+	query := `SELECT code FROM language WHERE id = 'l2'`
+	if err := reversed.QueryRow(query).Scan(&l2); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if l2 != "eng" {
+		t.Fatal("expected languages to be swapped:", l2)
+	}
+
+	// Translations become sentences that can be blanked out.
+	sentence, err := sentences.PickSentence(reversed, "world")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if sentence.Text != "Hello world." || len(sentence.Tokens) != 4 {
+		t.Fatal("unexpected sentence:", sentence)
+	}
+
+	// Original sentences become translations.
+	translation, err := translator.Translate(reversed, sentence)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if translation.Text != "Hola mundo." {
+		t.Fatal("unexpected translation:", translation)
+	}
+
+	var class int
+	query = `SELECT frequency_class FROM word WHERE word = 'hello'`
+	if err := reversed.QueryRow(query).Scan(&class); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if class != 0 {
+		t.Fatal("expected most frequent word to have frequency class 0:", class)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Restores review data from a review DB that was downloaded from polycloze
+// (e.g. an archived copy, or one that was edited with SQLite tools).
+// Uploaded databases are checked before they replace the user's review DB.
+// Like CSV imports (see package replay), restoring is only allowed if the user
+// doesn't have reviews in the course yet.
+package review_restore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/replay"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Returned when the uploaded file isn't a usable review DB.
+var ErrInvalidDatabase = errors.New("invalid review database")
+
+// Uploaded reviews can't be more than this far in the future, to allow for
+// clock differences.
+const maxClockSkew = 24 * time.Hour
+
+func invalid(reason string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidDatabase, fmt.Sprintf(reason, args...))
+}
+
+// Checks the review DB in `src` and installs an upgraded copy of it in
+// `dest`.
+// `src` gets upgraded to the latest schema version, so it should be a copy of
+// the upload.
+// Returns `ErrInvalidDatabase` if `src` fails the checks, and
+// `replay.ErrHasExistingReviews` if `dest` already has reviews.
+func Install(src, dest string, now time.Time) error {
+	if err := install(src, dest, now); err != nil {
+		return fmt.Errorf("failed to restore review database: %w", err)
+	}
+	return nil
+}
+
+// Checks the uploaded review DB in `src` (e.g. from an offline bundle) before
+// it gets used, and upgrades it to the latest schema version.
+// `src` should be a copy of the upload.
+// Returns `ErrInvalidDatabase` if `src` fails the checks.
+func Check(src string, now time.Time) error {
+	db, err := database.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to check review database: %w", err)
+	}
+	defer db.Close()
+
+	if err := check(db, now); err != nil {
+		return fmt.Errorf("failed to check review database: %w", err)
+	}
+	return nil
+}
+
+func check(db *sql.DB, now time.Time) error {
+	if err := checkIntegrity(db); err != nil {
+		return err
+	}
+	if err := checkVersion(db); err != nil {
+		return err
+	}
+	if err := database.UpgradeReviewDB(db); err != nil {
+		return err
+	}
+	if err := checkSchema(db); err != nil {
+		return err
+	}
+	return checkItems(db, now)
+}
+
+func install(src, dest string, now time.Time) error {
+	if err := checkDestination(dest); err != nil {
+		return err
+	}
+
+	db, err := database.Open(src)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := check(db, now); err != nil {
+		return err
+	}
+
+	// Write the new database next to the destination first, so that the
+	// destination gets replaced in one step.
+	tmp := dest + ".restore"
+	_ = os.Remove(tmp)
+	if _, err := db.Exec(`VACUUM INTO ?`, tmp); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dest + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp, dest)
+}
+
+// Checks that there aren't reviews in the destination.
+func checkDestination(dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	db, err := database.OpenReviewDB(dest)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM review)`
+	if err := db.QueryRow(query).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return replay.ErrHasExistingReviews
+	}
+	return nil
+}
+
+// Also fails if the file isn't an SQLite database.
+func checkIntegrity(db *sql.DB) error {
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check(1)`).Scan(&result); err != nil {
+		return invalid("%v", err)
+	}
+	if result != "ok" {
+		return invalid("integrity check failed: %v", result)
+	}
+	return nil
+}
+
+// Checks that the database was created by this or an older version of
+// polycloze.
+func checkVersion(db *sql.DB) error {
+	version, err := database.ReviewDBVersion(db)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	latest, err := database.LatestReviewDBVersion()
+	if err != nil {
+		return err
+	}
+	if version <= 0 {
+		return invalid("not a review database")
+	}
+	if version > latest {
+		return invalid("created by a newer version of polycloze (version %v > %v)", version, latest)
+	}
+	return nil
+}
+
+// Returns normalized SQL of the schema objects in the database, keyed by type
+// and name.
+// Internal objects (e.g. sqlite_sequence and sqlite_stat1) are skipped, because
+// they depend on how the database has been used.
+func readSchema(db *sql.DB) (map[string]string, error) {
+	query := `
+		SELECT type, name, coalesce(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite\_%' ESCAPE '\'
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := make(map[string]string)
+	for rows.Next() {
+		var kind, name, sql string
+		if err := rows.Scan(&kind, &name, &sql); err != nil {
+			return nil, err
+		}
+		schema[kind+" "+name] = strings.Join(strings.Fields(sql), " ")
+	}
+	return schema, rows.Err()
+}
+
+// Checks that the schema is the same as in a review DB created by polycloze.
+// Otherwise, the uploader could add triggers, views or indexes that would run
+// whenever the server or other users (e.g. teachers) use the database.
+// Should be called after upgrading the database.
+func checkSchema(db *sql.DB) error {
+	fresh, err := database.OpenReviewDB(":memory:")
+	if err != nil {
+		return err
+	}
+	defer fresh.Close()
+
+	expected, err := readSchema(fresh)
+	if err != nil {
+		return err
+	}
+	actual, err := readSchema(db)
+	if err != nil {
+		return invalid("%v", err)
+	}
+
+	for key, sql := range actual {
+		if expected[key] != sql {
+			return invalid("unexpected schema object: %v", key)
+		}
+	}
+	for key := range expected {
+		if _, ok := actual[key]; !ok {
+			return invalid("missing schema object: %v", key)
+		}
+	}
+	return nil
+}
+
+// Checks that review items and timestamps are sensible.
+func checkItems(db *sql.DB, now time.Time) error {
+	query := `
+		SELECT item FROM review
+		WHERE item = '' OR interval < 0 OR learned > reviewed OR reviewed > ?
+		LIMIT 1
+	`
+	var item string
+	err := db.QueryRow(query, now.Add(maxClockSkew).Unix()).Scan(&item)
+	if err == nil {
+		return invalid("invalid review of %q", item)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return invalid("%v", err)
+	}
+
+	// Words get casefolded when they're reviewed, so words that aren't
+	// casefolded wouldn't get scheduled correctly.
+	rows, err := db.Query(`SELECT item FROM review WHERE type IN ('word', 'phrase')`)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := rows.Scan(&item); err != nil {
+			return invalid("%v", err)
+		}
+		if text.Casefold(item) != item {
+			return invalid("item isn't casefolded: %q", item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return invalid("%v", err)
+	}
+	return nil
+}
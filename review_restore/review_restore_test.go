@@ -0,0 +1,140 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package review_restore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/replay"
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+// Creates review DB with a review of each item, and returns its path.
+func reviewDB(t *testing.T, items ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "upload.db")
+	db, err := database.OpenReviewDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	for _, item := range items {
+		if err := rs.UpdateReview(db, item, true); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+	return path
+}
+
+func TestInstall(t *testing.T) {
+	t.Parallel()
+
+	dest := filepath.Join(t.TempDir(), "reviews", "eng", "spa.db")
+	if err := Install(reviewDB(t, "hola"), dest, time.Now()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	db, err := database.OpenReviewDB(dest)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	var item string
+	if err := db.QueryRow(`SELECT item FROM review`).Scan(&item); err != nil || item != "hola" {
+		t.Fatal("expected review to be restored:", item, err)
+	}
+
+	// Existing reviews can't be replaced.
+	err = Install(reviewDB(t, "mundo"), dest, time.Now())
+	if !errors.Is(err, replay.ErrHasExistingReviews) {
+		t.Fatal("expected ErrHasExistingReviews:", err)
+	}
+}
+
+func TestInstallInvalidDatabase(t *testing.T) {
+	t.Parallel()
+
+	garbage := filepath.Join(t.TempDir(), "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0o600); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	newer := reviewDB(t, "hola")
+	db, err := database.Open(newer)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	query := `INSERT INTO goose_db_version (version_id, is_applied) VALUES (1000000, 1)`
+	_, err = db.Exec(query)
+	db.Close()
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	uploads := map[string]string{
+		"garbage":        garbage,
+		"newer version":  newer,
+		"not casefolded": reviewDB(t, "Hola"),
+		"empty database": filepath.Join(t.TempDir(), "empty.db"),
+		"future review":  futureReview(t),
+		"extra trigger":  withSQL(t, `CREATE TRIGGER evil AFTER INSERT ON review BEGIN DELETE FROM history; END`),
+		"extra view":     withSQL(t, `CREATE VIEW evil AS SELECT * FROM review`),
+		"dropped index":  withSQL(t, `DROP INDEX index_history_reviewed`),
+		"altered table":  withSQL(t, `ALTER TABLE review ADD COLUMN evil TEXT`),
+	}
+
+	for name, src := range uploads {
+		dest := filepath.Join(t.TempDir(), "spa.db")
+		err := Install(src, dest, time.Now())
+		if !errors.Is(err, ErrInvalidDatabase) {
+			t.Fatalf("expected ErrInvalidDatabase (%v): %v", name, err)
+		}
+		if _, err := os.Stat(dest); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected invalid database to not be installed (%v): %v", name, err)
+		}
+	}
+}
+
+// Returns path to review DB with a review that's too far in the future.
+func futureReview(t *testing.T) string {
+	t.Helper()
+
+	path := reviewDB(t)
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	future := time.Now().Add(7 * 24 * time.Hour)
+	if err := rs.UpdateReviewAt(db, "hola", true, future); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	return path
+}
+
+// Returns path to review DB that has been modified by the SQL statement.
+func withSQL(t *testing.T, query string) string {
+	t.Helper()
+
+	path := reviewDB(t, "hola")
+	db, err := database.Open(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	return path
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package review_scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Change to the review table (see the `review_event` table).
+// Contains the state of the item after the change.
+type Event struct {
+	ID       int64     `json:"id"`
+	Item     string    `json:"item"`
+	Deleted  bool      `json:"deleted,omitempty"`
+	Type     ItemType  `json:"type,omitempty"`
+	Learned  time.Time `json:"learned"`
+	Reviewed time.Time `json:"reviewed"`
+	Interval int64     `json:"interval"` // # of hours
+	Recorded time.Time `json:"recorded"`
+}
+
+// Returns events that come after the event with the given ID, oldest first,
+// but no more than count.
+// Pass 0 to get events from the start.
+func Events[T database.Querier](q T, after int64, count int) ([]Event, error) {
+	query := `
+		SELECT id, item, deleted, coalesce(type, ''), coalesce(learned, 0),
+			coalesce(reviewed, 0), coalesce(interval, 0), recorded
+		FROM review_event WHERE id > ? ORDER BY id LIMIT ?
+	`
+	rows, err := q.Query(query, after, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var learned, reviewed, recorded int64
+		err := rows.Scan(
+			&e.ID,
+			&e.Item,
+			&e.Deleted,
+			&e.Type,
+			&learned,
+			&reviewed,
+			&e.Interval,
+			&recorded,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get review events: %w", err)
+		}
+		e.Learned = time.Unix(learned, 0)
+		e.Reviewed = time.Unix(reviewed, 0)
+		e.Recorded = time.Unix(recorded, 0)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get review events: %w", err)
+	}
+	return events, nil
+}
+
+// Latest state of each item that hasn't been deleted as of @until (UNIX
+// timestamp), according to the event log.
+const projection = `
+	SELECT item, type, learned, reviewed, interval FROM review_event
+	WHERE id IN (
+		SELECT max(id) FROM review_event WHERE recorded <= @until GROUP BY item
+	) AND NOT deleted
+`
+
+// Rebuilds the review table from the event log.
+// Only rows that disagree with the log get changed, so rebuilding a table
+// that's in sync does nothing.
+// Repaired reviews also get added to the review history.
+// Returns the number of repaired items.
+func Rebuild[T database.Querier](q T) (int, error) {
+	repaired, err := project(q, math.MaxInt64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild review table: %w", err)
+	}
+	return repaired, nil
+}
+
+// Restores the review table to its state at the given time, e.g. to undo a
+// botched import.
+// Existing events are kept; the restore gets recorded as new events, so it
+// can be undone by restoring to a later time.
+// Returns the number of changed items.
+func Restore[T database.Querier](q T, t time.Time) (int, error) {
+	changed, err := project(q, t.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore review table: %w", err)
+	}
+	return changed, nil
+}
+
+// Makes the review table match the projection of events recorded until the
+// given UNIX timestamp.
+// Returns the number of changed items.
+func project[T database.Querier](q T, until int64) (int, error) {
+	tx, err := q.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed, err := projectTx(tx, until)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
+
+func projectTx(tx *sql.Tx, until int64) (int, error) {
+	query := `DELETE FROM review WHERE item NOT IN (SELECT item FROM (` + projection + `))`
+	result, err := tx.Exec(query, sql.Named("until", until))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// `WHERE true` is needed to parse upserts with SELECT statements.
+	query = `
+		INSERT INTO review (item, type, learned, reviewed, interval)
+		SELECT item, type, learned, reviewed, interval FROM (` + projection + `)
+		WHERE true
+		ON CONFLICT (item) DO UPDATE SET
+			type = excluded.type,
+			learned = excluded.learned,
+			reviewed = excluded.reviewed,
+			interval = excluded.interval
+		WHERE type IS NOT excluded.type
+			OR learned IS NOT excluded.learned
+			OR reviewed IS NOT excluded.reviewed
+			OR interval IS NOT excluded.interval
+	`
+	result, err = tx.Exec(query, sql.Named("until", until))
+	if err != nil {
+		return 0, err
+	}
+	upserted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted + upserted), nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package review_scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestEventsRecordChanges(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if err := UpdateReview(db, "foo", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := UpdateReview(db, "bar", false); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := Forget(db, "bar"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	events, err := Events(db, 0, 100)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(events) != 3 {
+		t.Fatal("expected three events:", events)
+	}
+	if events[0].Item != "foo" || events[0].Deleted || events[0].Type != Word {
+		t.Fatal("expected first event to be review of foo:", events[0])
+	}
+	if events[2].Item != "bar" || !events[2].Deleted {
+		t.Fatal("expected last event to be deletion of bar:", events[2])
+	}
+
+	events, err = Events(db, events[1].ID, 100)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(events) != 1 {
+		t.Fatal("expected only events after the given ID:", events)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if err := UpdateReview(db, "foo", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := UpdateReview(db, "bar", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	// Rebuilding a table that's in sync shouldn't change anything.
+	repaired, err := Rebuild(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if repaired != 0 {
+		t.Fatal("expected nothing to be repaired:", repaired)
+	}
+
+	// Change the review table without going through the event log (e.g. when
+	// it gets overwritten by an older copy).
+	queries := []string{
+		`DROP TRIGGER trigger_review_event_after_update_on_review`,
+		`DROP TRIGGER trigger_review_event_after_delete_on_review`,
+		`UPDATE review SET interval = 9999 WHERE item = 'foo'`,
+		`DELETE FROM review WHERE item = 'bar'`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	repaired, err = Rebuild(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if repaired != 2 {
+		t.Fatal("expected two items to be repaired:", repaired)
+	}
+
+	var count int
+	query := `SELECT count(*) FROM review WHERE interval != 9999`
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 2 {
+		t.Fatal("expected review table to be restored:", count)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if err := UpdateReview(db, "foo", false); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	// Pretend the first review happened a while ago.
+	if _, err := db.Exec(`UPDATE review_event SET recorded = 1000`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := UpdateReview(db, "foo", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := UpdateReview(db, "bar", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	before, err := Events(db, 0, 100)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	changed, err := Restore(db, time.Unix(1000, 0))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if changed != 2 {
+		t.Fatal("expected two items to be changed:", changed)
+	}
+
+	items, err := ScheduleReviewNow(db, -1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 1 || items[0] != "foo" {
+		t.Fatal("expected only the incorrect review of foo to be left:", items)
+	}
+
+	// Old events should be kept.
+	after, err := Events(db, 0, 100)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(after) <= len(before) {
+		t.Fatal("expected restore to be recorded as new events:", after)
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			t.Fatal("expected old events to be unchanged:", before[i], after[i])
+		}
+	}
+}
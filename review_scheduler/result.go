@@ -3,8 +3,68 @@
 
 package review_scheduler
 
+import "strings"
+
+// Kind of item in the review table.
+type ItemType string
+
+const (
+	Word         ItemType = "word"
+	Phrase       ItemType = "phrase"
+	Sentence     ItemType = "sentence"
+	GrammarPoint ItemType = "grammar-point"
+)
+
+func (t ItemType) IsValid() bool {
+	switch t {
+	case Word, Phrase, Sentence, GrammarPoint:
+		return true
+	}
+	return false
+}
+
+// Guesses the type of a word or phrase item.
+func ItemTypeOf(item string) ItemType {
+	if strings.Contains(item, " ") {
+		return Phrase
+	}
+	return Word
+}
+
 // Review results
 type Result struct {
 	Word    string `json:"word"`
 	Correct bool   `json:"correct"`
+
+	// Item type. Empty means the type is inferred using ItemTypeOf.
+	Type ItemType `json:"type,omitempty"`
+
+	// Exercise type (see flashcards.IsValidMode). Empty means cloze.
+	Mode string `json:"mode,omitempty"`
+
+	// Time of the review on the client (UNIX timestamp), or zero if unknown.
+	// Only used for sanity checks. Reviews are saved at the time they're
+	// received.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// Outcome of saving a review result.
+type SaveResult struct {
+	Word string `json:"word"`
+	OK   bool   `json:"ok"`
+
+	// Reason why the review wasn't saved.
+	Error string `json:"error,omitempty"`
+
+	// Set if the review was held for an admin to approve instead of being
+	// saved. Clients shouldn't resend quarantined reviews.
+	Quarantined bool `json:"quarantined,omitempty"`
+}
+
+// Returns type of reviewed item.
+func (r Result) ItemType() ItemType {
+	if r.Type == "" {
+		return ItemTypeOf(r.Word)
+	}
+	return r.Type
 }
@@ -5,11 +5,11 @@ package review_scheduler
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/polycloze/polycloze/database"
 )
 
@@ -34,34 +34,147 @@ func ScheduleReview[T database.Querier](q T, due time.Time, count int) ([]string
 	return items, nil
 }
 
+// Same as ScheduleReview, but only returns items of the given type.
+func ScheduleReviewOfType[T database.Querier](q T, itemType ItemType, due time.Time, count int) ([]string, error) {
+	query := `
+		SELECT item FROM review WHERE type = ? AND due <= ? ORDER BY due LIMIT ?
+	`
+	rows, err := q.Query(query, string(itemType), due.Unix(), count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule %v reviews: %w", itemType, err)
+	}
+	defer rows.Close()
+
+	var items []string
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, fmt.Errorf("failed to schedule %v reviews: %w", itemType, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Returns the type of a reviewed item.
+// Returns an empty string if the item hasn't been reviewed.
+func GetItemType[T database.Querier](q T, item string) (ItemType, error) {
+	var itemType ItemType
+	query := `SELECT type FROM review WHERE item = ?`
+	err := q.QueryRow(query, item).Scan(&itemType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get item type (%v): %w", item, err)
+	}
+	return itemType, nil
+}
+
+// Counts items that are due for review.
+func CountDue[T database.Querier](q T, due time.Time) (int, error) {
+	var count int
+	query := `SELECT count(*) FROM review WHERE due <= ?`
+	if err := q.QueryRow(query, due.Unix()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count due items: %w", err)
+	}
+	return count, nil
+}
+
 // Same as Schedule, but with some default args.
 func ScheduleReviewNow[T database.Querier](q T, count int) ([]string, error) {
 	return ScheduleReview(q, time.Now(), count)
 }
 
-// Same as ScheduleReviewNowWith, but takes a predicate argument.
-// Only items that satisfy the predicate are included in the result.
-func ScheduleReviewNowWith[T database.Querier](q T, count int, pred func(item string) bool) ([]string, error) {
-	query := `SELECT item FROM review WHERE due <= ? ORDER BY due`
-	rows, err := q.Query(query, time.Now().Unix())
+// Conditions on due items.
+// Conditions other than `Pred` are evaluated in SQL, so that items that don't
+// satisfy them aren't read at all.
+// The zero value includes every item.
+type Filter struct {
+	// Only include items in the course (the `word` table of the attached
+	// course DB).
+	InCourse bool
+
+	// Only include items in the user's word list (see package wordlist).
+	InWordList bool
+
+	// Items to leave out (e.g. items that the client is already showing).
+	Exclude []string
+
+	// Skip new words with few example sentences (see package coverage).
+	// Doesn't affect reviews, so it's ignored by ScheduleReviewFiltered.
+	SkipSparseWords bool
+
+	// Fallback for conditions that can't be expressed in SQL.
+	// Items are only included if this returns true.
+	// Ignored if nil.
+	Pred func(item string) bool
+}
+
+// Checks if the item satisfies `f.Exclude` and `f.Pred`.
+// The other conditions need the database, so they aren't checked.
+func (f Filter) Match(item string) bool {
+	for _, excluded := range f.Exclude {
+		if item == excluded {
+			return false
+		}
+	}
+	return f.Pred == nil || f.Pred(item)
+}
+
+// Same as ScheduleReview, but only returns items that satisfy the filter.
+func ScheduleReviewFiltered[T database.Querier](q T, due time.Time, count int, filter Filter) ([]string, error) {
+	query := `SELECT item FROM review WHERE due <= ?`
+	args := []any{due.Unix()}
+	if filter.InCourse {
+		query += ` AND item IN (SELECT word FROM word)`
+	}
+	if filter.InWordList {
+		query += ` AND item IN (SELECT item FROM word_list)`
+	}
+	if len(filter.Exclude) > 0 {
+		exclude, err := json.Marshal(filter.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("failed to schedule reviews: %w", err)
+		}
+		query += ` AND item NOT IN (SELECT value FROM json_each(?))`
+		args = append(args, string(exclude))
+	}
+	query += ` ORDER BY due`
+
+	// Rows that get filtered out by the predicate shouldn't count towards the
+	// limit.
+	if filter.Pred == nil {
+		query += ` LIMIT ?`
+		args = append(args, count)
+	}
+
+	rows, err := q.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to schedule reviews: %w", err)
 	}
 	defer rows.Close()
 
 	var items []string
-	for rows.Next() && len(items) < count {
+	for rows.Next() && (count < 0 || len(items) < count) {
 		var item string
 		if err := rows.Scan(&item); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to schedule reviews: %w", err)
 		}
-		if pred(item) {
+		if filter.Pred == nil || filter.Pred(item) {
 			items = append(items, item)
 		}
 	}
 	return items, nil
 }
 
+// Same as ScheduleReviewNow, but takes a predicate argument.
+// Only items that satisfy the predicate are included in the result.
+// Prefer ScheduleReviewFiltered, which evaluates common conditions in SQL.
+func ScheduleReviewNowWith[T database.Querier](q T, count int, pred func(item string) bool) ([]string, error) {
+	return ScheduleReviewFiltered(q, time.Now(), count, Filter{Pred: pred})
+}
+
 // Gets most recent review of item.
 func mostRecentReview(tx *sql.Tx, item string) (*Review, error) {
 	query := `SELECT interval, reviewed FROM review WHERE item = ?`
@@ -88,6 +201,11 @@ func mostRecentReview(tx *sql.Tx, item string) (*Review, error) {
 
 // Same as `UpdateReviewAt`, but explicitly takes an `*sql.Tx`.
 func UpdateReviewAtTx(tx *sql.Tx, result Result, now time.Time) error {
+	itemType := result.ItemType()
+	if !itemType.IsValid() {
+		return fmt.Errorf("failed to update review: invalid item type: %v", itemType)
+	}
+
 	review, err := mostRecentReview(tx, result.Word)
 	if err != nil {
 		return fmt.Errorf("failed to update review: %w", err)
@@ -106,8 +224,8 @@ func UpdateReviewAtTx(tx *sql.Tx, result Result, now time.Time) error {
 	}
 
 	query := `
-		INSERT INTO review (item, interval, learned, reviewed)
-		VALUES (@item, @interval, @now, @now)
+		INSERT INTO review (item, type, interval, learned, reviewed)
+		VALUES (@item, @type, @interval, @now, @now)
 		ON CONFLICT (item) DO UPDATE SET
 			interval = excluded.interval,
 			reviewed = excluded.reviewed
@@ -115,6 +233,7 @@ func UpdateReviewAtTx(tx *sql.Tx, result Result, now time.Time) error {
 	_, err = tx.Exec(
 		query,
 		sql.Named("item", result.Word),
+		sql.Named("type", string(itemType)),
 		sql.Named("interval", int64(next.Interval.Hours())),
 		sql.Named("now", now.Unix()),
 	)
@@ -153,20 +272,100 @@ func UpdateReview[T database.Querier](q T, item string, correct bool) error {
 	return UpdateReviewAt(q, item, correct, time.Now().UTC())
 }
 
+// Deletes review history of item, so that it gets introduced as a new item
+// again.
+// Returns false if the item hasn't been reviewed.
+func Forget[T database.Querier](q T, item string) (bool, error) {
+	result, err := q.Exec(`DELETE FROM review WHERE item = ?`, item)
+	if err != nil {
+		return false, fmt.Errorf("failed to forget item (%v): %w", item, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to forget item (%v): %w", item, err)
+	}
+	return n > 0, nil
+}
+
+// Min interval of reviews created by `AssumeKnownTx`.
+const assumedInterval = 30 * day
+
+// Creates reviews for items that the user already knows, as if the user had
+// reviewed them correctly a few times.
+// Items that have already been reviewed are skipped.
+// Interval stats aren't updated, because the items weren't actually reviewed.
+// Returns the items that got reviews.
+func AssumeKnownTx(tx *sql.Tx, items []string, now time.Time) ([]string, error) {
+	interval, err := nextInterval(tx, assumedInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume known items: %w", err)
+	}
+
+	query := `
+		INSERT INTO review (item, type, interval, learned, reviewed)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (item) DO NOTHING
+	`
+	var added []string
+	for _, item := range items {
+		result, err := tx.Exec(
+			query,
+			item,
+			string(ItemTypeOf(item)),
+			int64(interval.Hours()),
+			now.Unix(),
+			now.Unix(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assume known items: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			added = append(added, item)
+		}
+	}
+	return added, nil
+}
+
+// Saves a review inside a savepoint, so that a failed review doesn't leave
+// partial changes in the transaction.
+func saveReview(tx *sql.Tx, review Result, now time.Time) error {
+	if review.Word == "" {
+		return errors.New("empty word")
+	}
+	if _, err := tx.Exec(`SAVEPOINT bulk_review`); err != nil {
+		return err
+	}
+	if err := UpdateReviewAtTx(tx, review, now); err != nil {
+		_, _ = tx.Exec(`ROLLBACK TO bulk_review`)
+		_, _ = tx.Exec(`RELEASE bulk_review`)
+		return err
+	}
+	_, err := tx.Exec(`RELEASE bulk_review`)
+	return err
+}
+
 // Saves reviews in bulk.
-func BulkSaveReviews[T database.Querier](q T, reviews []Result, now time.Time) error {
+// Returns the outcome of each review, in the same order as the input.
+// Reviews that fail don't prevent the others from getting saved.
+func BulkSaveReviews[T database.Querier](q T, reviews []Result, now time.Time) ([]SaveResult, error) {
 	tx, err := q.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to save reviews in bulk: %w", err)
+		return nil, fmt.Errorf("failed to save reviews in bulk: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Best-effort save.
+	results := make([]SaveResult, 0, len(reviews))
 	for _, review := range reviews {
-		_ = UpdateReviewAtTx(tx, review, now)
+		result := SaveResult{Word: review.Word, OK: true}
+		if err := saveReview(tx, review, now); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to save reviews in bulk: %w", err)
+		return nil, fmt.Errorf("failed to save reviews in bulk: %w", err)
 	}
-	return nil
+	return results, nil
 }
@@ -238,3 +238,179 @@ func TestUpdateReviewAt(t *testing.T) {
 		)
 	}
 }
+
+func TestBulkSaveReviewsPerItemResults(t *testing.T) {
+	// Invalid reviews shouldn't prevent the rest from getting saved.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	reviews := []Result{
+		{Word: "foo", Correct: false},
+		{Word: "", Correct: true},
+		{Word: "bar", Correct: false},
+	}
+	results, err := BulkSaveReviews(db, reviews, time.Now())
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results) != 3 || !results[0].OK || results[1].OK || !results[2].OK {
+		t.Fatal("unexpected results:", results)
+	}
+	if results[1].Error == "" {
+		t.Fatal("expected failed review to have a reason:", results[1])
+	}
+
+	count, err := CountDue(db, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 2 {
+		t.Fatal("expected valid reviews to be saved:", count)
+	}
+}
+
+func TestForget(t *testing.T) {
+	// Forgotten items shouldn't have review history.
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	if err := UpdateReview(db, "foo", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	found, err := Forget(db, "foo")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !found {
+		t.Fatal("expected item to be found")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM history WHERE word = 'foo'`).Scan(&count); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if count != 0 {
+		t.Fatal("expected review history to be deleted:", count)
+	}
+
+	var size int
+	if err := db.QueryRow(`SELECT v FROM vocabulary_size`).Scan(&size); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if size != 0 {
+		t.Fatal("expected vocabulary size to decrease:", size)
+	}
+
+	found, err = Forget(db, "foo")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if found {
+		t.Fatal("expected item to be gone")
+	}
+}
+
+func TestItemTypes(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	results := []Result{
+		{Word: "foo", Correct: false},
+		{Word: "foo bar", Correct: false},
+		{Word: "sentence:1", Correct: false, Type: Sentence},
+	}
+	for _, result := range results {
+		if err := UpdateReviewAtTx(tx, result, now); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	invalid := Result{Word: "baz", Type: ItemType("baz")}
+	if err := UpdateReviewAtTx(tx, invalid, now); err == nil {
+		t.Fatal("expected invalid item type to be rejected")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	expected := map[string]ItemType{
+		"foo":        Word,
+		"foo bar":    Phrase,
+		"sentence:1": Sentence,
+		"baz":        "",
+	}
+	for item, itemType := range expected {
+		actual, err := GetItemType(db, item)
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if actual != itemType {
+			t.Errorf("expected type of %q to be %q: %q", item, itemType, actual)
+		}
+	}
+
+	items, err := ScheduleReviewOfType(db, Sentence, now.Add(time.Hour), -1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 1 || items[0] != "sentence:1" {
+		t.Error("expected only sentence items to be scheduled:", items)
+	}
+}
+
+func TestScheduleReviewFiltered(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `INSERT INTO word (word, frequency_class) VALUES ('a', 0), ('b', 0), ('c', 0)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := db.Exec(`INSERT INTO word_list (item) VALUES ('b'), ('c'), ('d')`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	now := time.Now()
+	for _, item := range []string{"a", "b", "c", "d"} {
+		if err := UpdateReviewAt(db, item, false, now); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	due := now.Add(24 * time.Hour)
+	filter := Filter{InCourse: true, InWordList: true, Exclude: []string{"b"}}
+	items, err := ScheduleReviewFiltered(db, due, -1, filter)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 1 || items[0] != "c" {
+		t.Fatal("expected only c to satisfy filter:", items)
+	}
+
+	// Items that get rejected by the predicate shouldn't count towards the
+	// limit.
+	filter = Filter{Pred: func(item string) bool { return item == "d" }}
+	items, err = ScheduleReviewFiltered(db, due, 1, filter)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(items) != 1 || items[0] != "d" {
+		t.Fatal("expected only d to satisfy predicate:", items)
+	}
+}
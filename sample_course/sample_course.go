@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Tiny sample course (English speakers learning Spanish) that's bundled in the
+// binary, so that new instances work without downloading course files.
+// The course gets built from ./spa-eng.sql with package reverse, so that its
+// sentences get tokenized the same way as sentences in reverse courses.
+package sample_course
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/reverse"
+)
+
+// Languages of the sample course.
+const (
+	L1 = "eng"
+	L2 = "spa"
+)
+
+//go:embed spa-eng.sql
+var source string
+
+// Installs the sample course into the data directory.
+// Also writes version.txt if the data directory doesn't have one yet.
+func Install() error {
+	if err := install(); err != nil {
+		return fmt.Errorf("failed to install sample course: %w", err)
+	}
+	return nil
+}
+
+func install() error {
+	dir, err := os.MkdirTemp("", "polycloze-sample-course-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, fmt.Sprintf("%s-%s.db", L2, L1))
+	if err := createSource(src); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(basedir.DataDir, "courses", fmt.Sprintf("%s-%s.db", L1, L2))
+	if err := reverse.Build(src, dst); err != nil {
+		return err
+	}
+	return writeVersion()
+}
+
+// Creates source course DB from the embedded SQL.
+func createSource(path string) error {
+	db, err := database.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(source); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// Writes version.txt, unless it already exists.
+// The version string only changes when the sample course does.
+func writeVersion() error {
+	path := filepath.Join(basedir.DataDir, "version.txt")
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	version := fmt.Sprintf("sample-%x", sha256.Sum256([]byte(source)))
+	return os.WriteFile(path, []byte(version), 0o644)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sample_course
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/sentences"
+	"github.com/polycloze/polycloze/translator"
+)
+
+func TestInstall(t *testing.T) {
+	if err := basedir.SetDataDir(t.TempDir()); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := Install(); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(basedir.DataDir, "version.txt")); err != nil {
+		t.Fatal("expected version.txt to be written:", err)
+	}
+
+	db, err := database.Open(filepath.Join(basedir.DataDir, "courses", "eng-spa.db"))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	sentence, err := sentences.PickSentence(db, "casa")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if sentence.TatoebaID >= 0 {
+		t.Fatal("expected sample sentence to have a negative TatoebaID:", sentence)
+	}
+
+	translation, err := translator.Translate(db, sentence)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if translation.Text == "" {
+		t.Fatal("expected non-empty translation:", translation)
+	}
+}
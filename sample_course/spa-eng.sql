@@ -0,0 +1,115 @@
+-- Copyright (c) 2022 Levi Gruspe
+-- License: MIT, or AGPLv3 or later
+
+-- Source course for the bundled sample course (see package sample_course).
+-- The sample course (eng-spa) is built as the reverse of this spa-eng course,
+-- so that the Spanish sentences get tokenized the same way as in reverse
+-- courses.
+-- Sentences aren't from Tatoeba, so they have negative IDs.
+
+CREATE TABLE contains (
+		sentence integer not null references sentence,
+		word integer not null references word
+		);
+CREATE TABLE language (
+		id text primary key check (id = 'l1' or id = 'l2'),
+		code char(3) not null check (length(code) = 3),
+		name text not null
+		, bcp47 text not null);
+CREATE TABLE note (
+		word integer not null references word,
+		text text not null
+		);
+CREATE TABLE sentence (
+		id integer primary key,
+		tatoeba_id integer unique,	-- null for non-tatoeba sentences
+		text text unique not null,
+		tokens text not null,	-- json array of strings
+		frequency_class integer not null	-- max frequency_class among all words in sentence
+		);
+CREATE TABLE translates (
+		source integer not null,	-- references sentence.tatoeba_id
+		target integer not null		-- references translation.tatoeba_id
+		);
+CREATE TABLE translation (
+		id integer primary key,
+		tatoeba_id integer unique,	-- null for non-tatoeba sentences
+		text text unique not null
+		);
+CREATE TABLE word (
+		id integer primary key,
+		word text unique not null,
+		frequency_class integer not null
+		);
+CREATE INDEX index_contains_word on contains (word);
+CREATE INDEX index_note_word on note (word);
+
+INSERT INTO language (id, code, name, bcp47) VALUES
+	('l1', 'spa', 'Spanish', 'es'),
+	('l2', 'eng', 'English', 'en');
+
+INSERT INTO sentence (tatoeba_id, text, tokens, frequency_class) VALUES
+	(-1001, 'I am.', '[]', 0),
+	(-1002, 'You are.', '[]', 0),
+	(-1003, 'Is it you?', '[]', 0),
+	(-1004, 'You and me.', '[]', 0),
+	(-1005, 'It''s a house.', '[]', 0),
+	(-1006, 'It''s a book.', '[]', 0),
+	(-1007, 'The water is from the house.', '[]', 0),
+	(-1008, 'No, it isn''t a book.', '[]', 0),
+	(-1009, 'I have a dog.', '[]', 0),
+	(-1010, 'The cat drinks water.', '[]', 0),
+	(-1011, 'My house is big.', '[]', 0),
+	(-1012, 'I want a small book.', '[]', 0),
+	(-1013, 'He reads a book.', '[]', 0),
+	(-1014, 'The dog isn''t big.', '[]', 0),
+	(-1015, 'Do you have a cat?', '[]', 0),
+	(-1016, 'Today I want to eat.', '[]', 0),
+	(-1017, 'Tomorrow I''m going to read.', '[]', 0),
+	(-1018, 'I''m very well, thanks.', '[]', 0),
+	(-1019, 'My cat is very small.', '[]', 0),
+	(-1020, 'Today I don''t have water.', '[]', 0);
+
+INSERT INTO translation (tatoeba_id, text) VALUES
+	(-2001, 'Yo soy.'),
+	(-2002, 'Tú eres.'),
+	(-2003, '¿Eres tú?'),
+	(-2004, 'Tú y yo.'),
+	(-2005, 'Es una casa.'),
+	(-2006, 'Es un libro.'),
+	(-2007, 'El agua es de la casa.'),
+	(-2008, 'No, no es un libro.'),
+	(-2009, 'Tengo un perro.'),
+	(-2010, 'El gato bebe agua.'),
+	(-2011, 'Mi casa es grande.'),
+	(-2012, 'Quiero un libro pequeño.'),
+	(-2013, 'Él lee un libro.'),
+	(-2014, 'El perro no es grande.'),
+	(-2015, '¿Tienes un gato?'),
+	(-2016, 'Hoy quiero comer.'),
+	(-2017, 'Mañana voy a leer.'),
+	(-2018, 'Estoy muy bien, gracias.'),
+	(-2019, 'Mi gato es muy pequeño.'),
+	(-2020, 'Hoy no tengo agua.');
+
+INSERT INTO translates (source, target) VALUES
+	(-1001, -2001),
+	(-1002, -2002),
+	(-1003, -2003),
+	(-1004, -2004),
+	(-1005, -2005),
+	(-1006, -2006),
+	(-1007, -2007),
+	(-1008, -2008),
+	(-1009, -2009),
+	(-1010, -2010),
+	(-1011, -2011),
+	(-1012, -2012),
+	(-1013, -2013),
+	(-1014, -2014),
+	(-1015, -2015),
+	(-1016, -2016),
+	(-1017, -2017),
+	(-1018, -2018),
+	(-1019, -2019),
+	(-1020, -2020);
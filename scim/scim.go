@@ -0,0 +1,282 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// SCIM 2.0 user provisioning (RFC 7643 and RFC 7644).
+// Lets identity providers create, update, deactivate and delete accounts.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	UserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Value of the role that grants admin privileges.
+const AdminRole = "admin"
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Role struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+// User resource.
+// Attributes that polycloze doesn't store (e.g. `name`) are ignored.
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+
+	// Defaults to true if not set.
+	Active *bool `json:"active,omitempty"`
+
+	// Write-only.
+	// Accounts created without a password can only sign in after the user
+	// gets a new password from an admin.
+	Password string `json:"password,omitempty"`
+
+	Emails []Email `json:"emails,omitempty"`
+	Roles  []Role  `json:"roles,omitempty"`
+	Meta   *Meta   `json:"meta,omitempty"`
+}
+
+func (u User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+func (u User) IsAdmin() bool {
+	for _, role := range u.Roles {
+		if strings.EqualFold(role.Value, AdminRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns primary email address, or the first one if none is marked as
+// primary.
+// Returns an empty string if there's none.
+func (u User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type PatchRequest struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// SCIM error response.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("scim error (%v): %v", e.Status, e.Detail)
+}
+
+// Returns HTTP status code of the error.
+func (e *Error) StatusCode() int {
+	code, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return code
+}
+
+// scimType: see RFC 7644 section 3.12 (may be empty)
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func errNotFound() *Error {
+	return NewError(http.StatusNotFound, "", "User not found.")
+}
+
+// Only equality filters on `userName` and `externalId` are supported, because
+// that's what identity providers use to look up existing accounts.
+var filterPattern = regexp.MustCompile(`(?i)^\s*(userName|externalId)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+type Filter struct {
+	Attribute string // "userName" or "externalId"
+	Value     string
+}
+
+func ParseFilter(filter string) (Filter, error) {
+	match := filterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return Filter{}, NewError(http.StatusBadRequest, "invalidFilter", "Unsupported filter.")
+	}
+
+	value, err := strconv.Unquote(match[2])
+	if err != nil {
+		return Filter{}, NewError(http.StatusBadRequest, "invalidFilter", "Invalid filter value.")
+	}
+
+	attribute := "userName"
+	if strings.EqualFold(match[1], "externalId") {
+		attribute = "externalId"
+	}
+	return Filter{Attribute: attribute, Value: value}, nil
+}
+
+// Parses boolean value in patch operations.
+// Some identity providers send booleans as strings (e.g. "False").
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+func invalidValue(path string) *Error {
+	return NewError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("Invalid value for %v.", path))
+}
+
+// Applies attribute change to user.
+// Ignores attributes that polycloze doesn't store.
+func applyAttribute(u *User, op, path string, value json.RawMessage) error {
+	remove := op == "remove"
+	switch strings.ToLower(path) {
+	case "active":
+		if remove {
+			return invalidValue(path)
+		}
+		active, err := parseBool(value)
+		if err != nil {
+			return invalidValue(path)
+		}
+		u.Active = &active
+	case "username":
+		if remove {
+			return invalidValue(path)
+		}
+		if err := json.Unmarshal(value, &u.UserName); err != nil || u.UserName == "" {
+			return invalidValue(path)
+		}
+	case "externalid":
+		u.ExternalID = ""
+		if !remove {
+			if err := json.Unmarshal(value, &u.ExternalID); err != nil {
+				return invalidValue(path)
+			}
+		}
+	case "password":
+		if remove {
+			return invalidValue(path)
+		}
+		if err := json.Unmarshal(value, &u.Password); err != nil || u.Password == "" {
+			return invalidValue(path)
+		}
+	case "emails":
+		var emails []Email
+		if !remove {
+			if err := json.Unmarshal(value, &emails); err != nil {
+				return invalidValue(path)
+			}
+		}
+		if op == "add" {
+			emails = append(u.Emails, emails...)
+		}
+		u.Emails = emails
+	case "roles":
+		var roles []Role
+		if !remove {
+			if err := json.Unmarshal(value, &roles); err != nil {
+				return invalidValue(path)
+			}
+		}
+		if op == "add" {
+			roles = append(u.Roles, roles...)
+		}
+		u.Roles = roles
+	}
+	return nil
+}
+
+// Applies patch operations to user.
+func Apply(u *User, ops []Operation) error {
+	for _, operation := range ops {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return NewError(http.StatusBadRequest, "invalidSyntax", "Unsupported patch operation.")
+		}
+
+		if operation.Path != "" {
+			if err := applyAttribute(u, op, operation.Path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Operations without a path contain a map of attributes.
+		if op == "remove" {
+			return NewError(http.StatusBadRequest, "noTarget", "Missing path.")
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return NewError(http.StatusBadRequest, "invalidSyntax", "Invalid patch operation.")
+		}
+		for path, value := range attributes {
+			if err := applyAttribute(u, op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
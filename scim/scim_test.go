@@ -0,0 +1,114 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	filter, err := ParseFilter(`userName eq "foo\"bar"`)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if filter.Attribute != "userName" || filter.Value != `foo"bar` {
+		t.Fatal("unexpected filter:", filter)
+	}
+
+	filter, err = ParseFilter(`externalid EQ "123"`)
+	if err != nil || filter.Attribute != "externalId" || filter.Value != "123" {
+		t.Fatal("expected filter to be case-insensitive:", filter, err)
+	}
+
+	if _, err := ParseFilter(`userName sw "foo"`); err == nil {
+		t.Fatal("expected unsupported filter to be rejected")
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	var ops []Operation
+	body := `[
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "add", "value": {"userName": "bar", "roles": [{"value": "admin"}]}},
+		{"op": "remove", "path": "externalId"}
+	]`
+	if err := json.Unmarshal([]byte(body), &ops); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	u := User{UserName: "foo", ExternalID: "123"}
+	if err := Apply(&u, ops); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if u.IsActive() || u.UserName != "bar" || !u.IsAdmin() || u.ExternalID != "" {
+		t.Fatal("unexpected result of patch:", u)
+	}
+
+	ops = []Operation{{Op: "move", Path: "active"}}
+	if err := Apply(&u, ops); err == nil {
+		t.Fatal("expected unsupported operation to be rejected")
+	}
+}
+
+func TestCreateAndPatch(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.OpenAuthDB(":memory:")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+
+	u, err := Create(db, User{UserName: "foo", ExternalID: "abc"})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if !u.IsActive() || u.IsAdmin() || u.ExternalID != "abc" {
+		t.Fatal("unexpected user:", u)
+	}
+
+	var e *Error
+	_, err = Create(db, User{UserName: "foo"})
+	if !errors.As(err, &e) || e.StatusCode() != http.StatusConflict {
+		t.Fatal("expected duplicate username to be rejected:", err)
+	}
+
+	list, err := List(db, &Filter{Attribute: "externalId", Value: "abc"}, 1, 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if list.TotalResults != 1 || list.Resources[0].ID != u.ID {
+		t.Fatal("expected filter to find user:", list)
+	}
+
+	id, err := strconv.Atoi(u.ID)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	ops := []Operation{
+		{Op: "replace", Path: "active", Value: json.RawMessage(`false`)},
+		{Op: "add", Path: "roles", Value: json.RawMessage(`[{"value": "admin"}]`)},
+	}
+	u, err = Patch(db, id, ops)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if u.IsActive() || !u.IsAdmin() {
+		t.Fatal("expected user to be deactivated admin:", u)
+	}
+
+	if _, err := Get(db, id+1); !errors.As(err, &e) || e.StatusCode() != http.StatusNotFound {
+		t.Fatal("expected missing user to be not found:", err)
+	}
+}
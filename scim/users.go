@@ -0,0 +1,323 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package scim
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/polycloze/polycloze/auth"
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/sessions"
+)
+
+// Base path of SCIM endpoints.
+const BasePath = "/scim/v2"
+
+// Max number of users per page in list responses.
+const MaxCount = 200
+
+// Anonymous trial accounts and merged accounts aren't exposed.
+const userColumns = `
+	SELECT id, username, admin, active, coalesce(external_id, '') FROM user
+	WHERE NOT anonymous AND merged_into IS NULL
+`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Gets email address from the user's reminder settings.
+// Returns an empty string if the user doesn't have a user DB yet.
+func getEmail(userID int) (string, error) {
+	path := basedir.UserData(userID)
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+
+	db, err := database.OpenUserDB(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	settings, err := reminders.Get(db)
+	if err != nil {
+		return "", err
+	}
+	return settings.Email, nil
+}
+
+// Saves email address in the user's reminder settings.
+// Turns off reminders if the email address gets removed.
+func setEmail(userID int, email string) error {
+	path := basedir.UserData(userID)
+	if _, err := os.Stat(path); err != nil && email == "" {
+		// Nothing to remove.
+		return nil
+	}
+
+	if err := os.MkdirAll(basedir.User(userID), 0o700); err != nil {
+		return err
+	}
+	db, err := database.OpenUserDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	settings, err := reminders.Get(db)
+	if err != nil {
+		return err
+	}
+	if settings.Email == email {
+		return nil
+	}
+	settings.Email = email
+	if email == "" {
+		settings.Enabled = false
+	}
+	return reminders.Set(db, settings)
+}
+
+func scanUser(row scanner) (User, error) {
+	var id int
+	var admin, active bool
+	u := User{Schemas: []string{UserSchema}}
+	if err := row.Scan(&id, &u.UserName, &admin, &active, &u.ExternalID); err != nil {
+		return u, err
+	}
+
+	u.ID = strconv.Itoa(id)
+	u.Active = &active
+	if admin {
+		u.Roles = []Role{{Value: AdminRole}}
+	}
+	u.Meta = &Meta{
+		ResourceType: "User",
+		Location:     BasePath + "/Users/" + u.ID,
+	}
+
+	email, err := getEmail(id)
+	if err != nil {
+		return u, err
+	}
+	if email != "" {
+		u.Emails = []Email{{Value: email, Primary: true}}
+	}
+	return u, nil
+}
+
+func Get(db *sql.DB, id int) (User, error) {
+	u, err := scanUser(db.QueryRow(userColumns+` AND id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return u, errNotFound()
+	}
+	if err != nil {
+		return u, fmt.Errorf("failed to get user: %w", err)
+	}
+	return u, nil
+}
+
+// Lists users that match the filter (optional).
+// `startIndex` is 1-based.
+func List(db *sql.DB, filter *Filter, startIndex, count int) (ListResponse, error) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > MaxCount {
+		count = MaxCount
+	}
+
+	where := ""
+	var args []any
+	if filter != nil {
+		if filter.Attribute == "externalId" {
+			where = ` AND external_id = ?`
+		} else {
+			where = ` AND username = ?`
+		}
+		args = append(args, filter.Value)
+	}
+
+	response := ListResponse{
+		Schemas:    []string{ListResponseSchema},
+		StartIndex: startIndex,
+		Resources:  []User{},
+	}
+	query := `SELECT count(*) FROM (` + userColumns + where + `)`
+	if err := db.QueryRow(query, args...).Scan(&response.TotalResults); err != nil {
+		return response, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	query = userColumns + where + ` ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := db.Query(query, append(args, count, startIndex-1)...)
+	if err != nil {
+		return response, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return response, fmt.Errorf("failed to list users: %w", err)
+		}
+		response.Resources = append(response.Resources, u)
+	}
+	if err := rows.Err(); err != nil {
+		return response, fmt.Errorf("failed to list users: %w", err)
+	}
+	response.ItemsPerPage = len(response.Resources)
+	return response, nil
+}
+
+// Returns random password for users created without one.
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validate(u User) error {
+	if u.UserName == "" {
+		return NewError(http.StatusBadRequest, "invalidValue", "Missing userName.")
+	}
+	if email := u.PrimaryEmail(); email != "" && !mail.IsValidAddress(email) {
+		return NewError(http.StatusBadRequest, "invalidValue", "Invalid email address.")
+	}
+	return nil
+}
+
+// Saves user attributes.
+// Signs the user out of all sessions if the username changes or if the user
+// gets deactivated, because sessions store the username.
+func update(db *sql.DB, id int, u User) error {
+	if err := validate(u); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	defer tx.Rollback()
+
+	var username string
+	var active bool
+	query := `SELECT username, active FROM user WHERE id = ?`
+	if err := tx.QueryRow(query, id).Scan(&username, &active); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	query = `
+		UPDATE user SET username = ?, admin = ?, active = ?, external_id = nullif(?, '')
+		WHERE id = ?
+	`
+	if _, err := tx.Exec(query, u.UserName, u.IsAdmin(), u.IsActive(), u.ExternalID, id); err != nil {
+		return NewError(http.StatusConflict, "uniqueness", "userName or externalId is already taken.")
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if username != u.UserName || (active && !u.IsActive()) {
+		if err := sessions.DeleteUserSessions(db, id); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	if u.Password != "" {
+		if err := auth.ChangePassword(db, id, u.Password); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+	if err := setEmail(id, u.PrimaryEmail()); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+func Create(db *sql.DB, u User) (User, error) {
+	if err := validate(u); err != nil {
+		return u, err
+	}
+
+	password := u.Password
+	if password == "" {
+		random, err := randomPassword()
+		if err != nil {
+			return u, fmt.Errorf("failed to create user: %w", err)
+		}
+		password = random
+	}
+	if err := auth.Register(db, u.UserName, password); err != nil {
+		return u, NewError(http.StatusConflict, "uniqueness", "userName is already taken.")
+	}
+	id, err := auth.GetUserID(db, u.UserName)
+	if err != nil {
+		return u, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	u.Password = ""
+	if err := update(db, id, u); err != nil {
+		// Don't leave behind a half-provisioned account.
+		_ = auth.DeleteUser(db, id)
+		_ = os.RemoveAll(basedir.User(id))
+		return u, err
+	}
+	return Get(db, id)
+}
+
+// Replaces the user's attributes (PUT).
+func Replace(db *sql.DB, id int, u User) (User, error) {
+	if _, err := Get(db, id); err != nil {
+		return u, err
+	}
+	if err := update(db, id, u); err != nil {
+		return u, err
+	}
+	return Get(db, id)
+}
+
+// Modifies the user's attributes (PATCH).
+func Patch(db *sql.DB, id int, ops []Operation) (User, error) {
+	u, err := Get(db, id)
+	if err != nil {
+		return u, err
+	}
+	if err := Apply(&u, ops); err != nil {
+		return u, err
+	}
+	if err := update(db, id, u); err != nil {
+		return u, err
+	}
+	return Get(db, id)
+}
+
+// Deletes user along with the user's files.
+func Delete(db *sql.DB, id int) error {
+	if _, err := Get(db, id); err != nil {
+		return err
+	}
+	if err := auth.DeleteUser(db, id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := os.RemoveAll(basedir.User(id)); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package search
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/polycloze/polycloze/text"
+)
+
+// Returns course words that start with prefix, most frequent words first.
+// Uses a range scan on the unique index on word.word instead of LIKE, which
+// SQLite can't optimize with the default case-insensitive LIKE.
+func Autocomplete(db *sql.DB, prefix string, limit int) ([]Word, error) {
+	words := make([]Word, 0)
+	prefix = text.Casefold(prefix)
+	if prefix == "" {
+		return words, nil
+	}
+
+	query := `
+		SELECT word, frequency_class FROM word
+		WHERE word >= ? AND word < ?
+		ORDER BY frequency_class ASC, word ASC
+		LIMIT ?
+	`
+	// U+10FFFF sorts after every other code point in UTF-8.
+	rows, err := db.Query(query, prefix, prefix+"\U0010FFFF", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to autocomplete word: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var word Word
+		if err := rows.Scan(&word.Word, &word.FrequencyClass); err != nil {
+			return nil, fmt.Errorf("failed to autocomplete word: %w", err)
+		}
+		words = append(words, word)
+	}
+	return words, nil
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package search
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestAutocomplete(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `
+		INSERT INTO word (word, frequency_class) VALUES
+			('help', 3), ('hello', 1), ('hel', 2), ('he', 0), ('world', 0)
+	`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	words, err := Autocomplete(db, "HEL", 2)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 2 || words[0].Word != "hello" || words[1].Word != "hel" {
+		t.Fatal("unexpected words:", words)
+	}
+
+	words, err = Autocomplete(db, "", 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 0 {
+		t.Fatal("expected no words:", words)
+	}
+}
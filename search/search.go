@@ -0,0 +1,313 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Search over course words and sentences.
+// Uses FTS5 indexes if SQLite was built with FTS5 (`-tags sqlite_fts5`),
+// otherwise falls back to slower LIKE queries on the course DB.
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+type Word struct {
+	Word           string `json:"word"`
+	FrequencyClass int    `json:"frequencyClass"`
+}
+
+type Sentence struct {
+	ID          int    `json:"id"`
+	TatoebaID   int64  `json:"tatoebaID"` // Non-positive if none
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+}
+
+type Results struct {
+	Words     []Word     `json:"words"`
+	Sentences []Sentence `json:"sentences"`
+}
+
+// Checks if SQLite was built with FTS5.
+func HasFTS5(db *sql.DB) bool {
+	var used bool
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used)
+	return err == nil && used
+}
+
+// Query term.
+type term struct {
+	text   string
+	prefix bool // Only for single words
+}
+
+// Parses search query.
+// Double-quoted strings are phrases, and words that end with "*" are prefixes.
+// Everything else is treated as a word.
+func parseQuery(query string) []term {
+	var terms []term
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			// Inside quotes.
+			if words := text.Words(part); len(words) > 0 {
+				terms = append(terms, term{text: text.Casefold(strings.Join(words, " "))})
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			word := strings.TrimFunc(field, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+			})
+			if word != "" {
+				terms = append(terms, term{text: text.Casefold(word), prefix: prefix})
+			}
+		}
+	}
+	return terms
+}
+
+// Converts search terms into an FTS5 query.
+func ftsQuery(terms []term) string {
+	var parts []string
+	for _, t := range terms {
+		part := `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+		if t.prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Escapes LIKE pattern special characters.
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	return strings.ReplaceAll(s, `_`, `\_`)
+}
+
+// Prevents concurrent index builds.
+var mu sync.Mutex
+
+// Builds search index if it doesn't exist yet or if it's older than the course
+// DB.
+func ensureIndex(indexPath, coursePath string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	course, err := os.Stat(coursePath)
+	if err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	if index, err := os.Stat(indexPath); err == nil && !index.ModTime().Before(course.ModTime()) {
+		return nil
+	}
+	return buildIndex(indexPath, coursePath)
+}
+
+// Builds search index in a temporary file, then moves it to indexPath.
+func buildIndex(indexPath, coursePath string) error {
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0o755); err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	tmp := indexPath + ".tmp"
+	_ = os.Remove(tmp)
+
+	db, err := database.Open(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	defer db.Close()
+
+	queries := []string{
+		`ATTACH DATABASE ? AS course`,
+		`CREATE VIRTUAL TABLE word_index USING fts5(word, frequency_class UNINDEXED)`,
+		`CREATE VIRTUAL TABLE sentence_index USING fts5(
+			text,
+			translation,
+			id UNINDEXED,
+			tatoeba_id UNINDEXED
+		)`,
+		`INSERT INTO word_index (word, frequency_class)
+		SELECT word, frequency_class FROM course.word`,
+		`INSERT INTO sentence_index (text, translation, id, tatoeba_id)
+		SELECT
+			text,
+			coalesce((
+				SELECT translation.text FROM course.translates
+				JOIN course.translation ON (target = translation.tatoeba_id)
+				WHERE source = sentence.tatoeba_id
+				LIMIT 1
+			), ''),
+			id,
+			coalesce(tatoeba_id, -1)
+		FROM course.sentence`,
+		`DETACH DATABASE course`,
+	}
+	for i, query := range queries {
+		var args []any
+		if i == 0 {
+			args = append(args, coursePath)
+		}
+		if _, err := db.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to build search index: %w", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	if err := os.Rename(tmp, indexPath); err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	return nil
+}
+
+// Searches words and sentences in the course.
+// Returns at most `limit` words and `limit` sentences.
+// The index is built on the first search, and rebuilt when the course DB
+// changes.
+func Search(indexPath, coursePath, query string, limit int) (Results, error) {
+	results := Results{
+		Words:     make([]Word, 0),
+		Sentences: make([]Sentence, 0),
+	}
+	terms := parseQuery(query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	course, err := database.Open(coursePath)
+	if err != nil {
+		return results, fmt.Errorf("failed to search course: %w", err)
+	}
+	defer course.Close()
+
+	if !HasFTS5(course) {
+		return searchLike(course, terms, limit)
+	}
+
+	if err := ensureIndex(indexPath, coursePath); err != nil {
+		return results, err
+	}
+	index, err := database.Open(indexPath)
+	if err != nil {
+		return results, fmt.Errorf("failed to search course: %w", err)
+	}
+	defer index.Close()
+	return searchFTS(index, terms, limit)
+}
+
+func searchFTS(db *sql.DB, terms []term, limit int) (Results, error) {
+	results := Results{
+		Words:     make([]Word, 0),
+		Sentences: make([]Sentence, 0),
+	}
+	match := ftsQuery(terms)
+
+	query := `
+		SELECT word, frequency_class FROM word_index
+		WHERE word_index MATCH ?
+		ORDER BY rank LIMIT ?
+	`
+	rows, err := db.Query(query, match, limit)
+	if err != nil {
+		return results, fmt.Errorf("failed to search words: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var word Word
+		if err := rows.Scan(&word.Word, &word.FrequencyClass); err != nil {
+			return results, fmt.Errorf("failed to search words: %w", err)
+		}
+		results.Words = append(results.Words, word)
+	}
+
+	query = `
+		SELECT id, tatoeba_id, text, translation FROM sentence_index
+		WHERE sentence_index MATCH ?
+		ORDER BY rank LIMIT ?
+	`
+	rows, err = db.Query(query, match, limit)
+	if err != nil {
+		return results, fmt.Errorf("failed to search sentences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sentence Sentence
+		err := rows.Scan(&sentence.ID, &sentence.TatoebaID, &sentence.Text, &sentence.Translation)
+		if err != nil {
+			return results, fmt.Errorf("failed to search sentences: %w", err)
+		}
+		results.Sentences = append(results.Sentences, sentence)
+	}
+	return results, nil
+}
+
+// Fallback search for SQLite builds without FTS5.
+// Only searches words by the first term, and sentences that contain all the
+// terms.
+// Doesn't search translations.
+func searchLike(db *sql.DB, terms []term, limit int) (Results, error) {
+	results := Results{
+		Words:     make([]Word, 0),
+		Sentences: make([]Sentence, 0),
+	}
+
+	pattern := escapeLike(terms[0].text)
+	if terms[0].prefix {
+		pattern += "%"
+	}
+	query := `
+		SELECT word, frequency_class FROM word
+		WHERE word LIKE ? ESCAPE '\'
+		ORDER BY id LIMIT ?
+	`
+	rows, err := db.Query(query, pattern, limit)
+	if err != nil {
+		return results, fmt.Errorf("failed to search words: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var word Word
+		if err := rows.Scan(&word.Word, &word.FrequencyClass); err != nil {
+			return results, fmt.Errorf("failed to search words: %w", err)
+		}
+		results.Words = append(results.Words, word)
+	}
+
+	var conditions []string
+	var args []any
+	for _, t := range terms {
+		conditions = append(conditions, `text LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(t.text)+"%")
+	}
+	query = `
+		SELECT id, coalesce(tatoeba_id, -1), text FROM sentence
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY frequency_class, id LIMIT ?
+	`
+	rows, err = db.Query(query, append(args, limit)...)
+	if err != nil {
+		return results, fmt.Errorf("failed to search sentences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sentence Sentence
+		if err := rows.Scan(&sentence.ID, &sentence.TatoebaID, &sentence.Text); err != nil {
+			return results, fmt.Errorf("failed to search sentences: %w", err)
+		}
+		results.Sentences = append(results.Sentences, sentence)
+	}
+	return results, nil
+}
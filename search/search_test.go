@@ -0,0 +1,141 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package search
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+
+	terms := parseQuery(`hel* "Good  morning!" world`)
+	expected := []term{
+		{text: "hel", prefix: true},
+		{text: "good morning"},
+		{text: "world"},
+	}
+	if len(terms) != len(expected) {
+		t.Fatal("unexpected terms:", terms)
+	}
+	for i := range terms {
+		if terms[i] != expected[i] {
+			t.Fatal("unexpected term:", terms[i], expected[i])
+		}
+	}
+
+	if q := ftsQuery(terms); q != `"hel"* "good morning" "world"` {
+		t.Fatal("unexpected FTS query:", q)
+	}
+}
+
+func TestParseQueryEmpty(t *testing.T) {
+	t.Parallel()
+
+	if terms := parseQuery(` "" * `); len(terms) != 0 {
+		t.Fatal("expected no terms:", terms)
+	}
+}
+
+func TestSearchLike(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (word, frequency_class) VALUES
+			('hello', 0), ('help', 1), ('world', 2), ('100%', 3)`,
+		`INSERT INTO sentence (tatoeba_id, text, tokens, frequency_class) VALUES
+			(1, 'Hello world.', '[]', 2),
+			(2, 'Help!', '[]', 1)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	results, err := searchLike(db, parseQuery("hel*"), 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results.Words) != 2 || len(results.Sentences) != 2 {
+		t.Fatal("unexpected results:", results)
+	}
+
+	results, err = searchLike(db, parseQuery(`"hello world"`), 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results.Words) != 0 || len(results.Sentences) != 1 {
+		t.Fatal("unexpected results:", results)
+	}
+	if results.Sentences[0].TatoebaID != 1 {
+		t.Fatal("unexpected sentence:", results.Sentences[0])
+	}
+
+	// LIKE wildcards should be escaped.
+	results, err = searchLike(db, []term{{text: "%", prefix: true}}, 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results.Words) != 0 {
+		t.Fatal("expected wildcards to be escaped:", results.Words)
+	}
+}
+
+func TestSearchFTS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	coursePath := filepath.Join(dir, "course.db")
+	indexPath := filepath.Join(dir, "search", "index.db")
+
+	db, err := database.Open(coursePath)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	defer db.Close()
+	if !HasFTS5(db) {
+		t.Skip("SQLite was built without FTS5")
+	}
+
+	queries := []string{
+		`CREATE TABLE word (id integer primary key, word text, frequency_class integer)`,
+		`CREATE TABLE sentence (id integer primary key, tatoeba_id integer, text text, frequency_class integer)`,
+		`CREATE TABLE translation (id integer primary key, tatoeba_id integer, text text)`,
+		`CREATE TABLE translates (source integer, target integer)`,
+		`INSERT INTO word (word, frequency_class) VALUES ('hello', 0), ('help', 1), ('world', 2)`,
+		`INSERT INTO sentence (tatoeba_id, text, frequency_class) VALUES (1, 'Hello world.', 2)`,
+		`INSERT INTO translation (tatoeba_id, text) VALUES (10, 'Hola mundo.')`,
+		`INSERT INTO translates (source, target) VALUES (1, 10)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	results, err := Search(indexPath, coursePath, "hel*", 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results.Words) != 2 || len(results.Sentences) != 1 {
+		t.Fatal("unexpected results:", results)
+	}
+
+	// Translations should also be searchable.
+	results, err = Search(indexPath, coursePath, `"hola mundo"`, 10)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(results.Sentences) != 1 || results.Sentences[0].Translation != "Hola mundo." {
+		t.Fatal("unexpected results:", results)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sentences
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+var (
+	ErrUnknownWord       = errors.New("word is not in the course")
+	ErrWordNotInSentence = errors.New("word does not appear in the sentence")
+)
+
+// Sentence written by the user for a word.
+type AuthoredSentence struct {
+	ID          int    `json:"id"`
+	Word        string `json:"word"`
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+}
+
+// Saves sentence written by the user as a cloze sentence for the word.
+// The word has to be in the course and in the sentence.
+// Database connection should have access to course and review data.
+func AddAuthoredSentence[T database.Querier](q T, word, s, translation string) (int, error) {
+	word = text.Casefold(word)
+	s = strings.TrimSpace(s)
+	translation = strings.TrimSpace(translation)
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM word WHERE word = ?)`
+	if err := q.QueryRow(query, word).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to add sentence: %w", err)
+	}
+	if !exists {
+		return 0, ErrUnknownWord
+	}
+
+	tokens := text.Tokenize(s)
+	if len(text.FindSpans(tokens, word)) == 0 {
+		return 0, ErrWordNotInSentence
+	}
+
+	encoded, err := json.Marshal(tokens)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add sentence: %w", err)
+	}
+
+	query = `
+		INSERT INTO user_sentence (text, tokens, authored, translation)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (text) DO UPDATE SET
+			authored = 1,
+			translation = excluded.translation
+		RETURNING id
+	`
+	var id int
+	if err := q.QueryRow(query, s, string(encoded), translation).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to add sentence: %w", err)
+	}
+
+	query = `INSERT OR IGNORE INTO user_sentence_word (sentence, item) VALUES (?, ?)`
+	if _, err := q.Exec(query, id, word); err != nil {
+		return 0, fmt.Errorf("failed to add sentence: %w", err)
+	}
+	return id, nil
+}
+
+// Returns sentences written by the user for the word.
+// Returns sentences for all words if word is empty.
+func GetAuthoredSentences[T database.Querier](q T, word string) ([]AuthoredSentence, error) {
+	query := `
+		SELECT id, item, text, translation FROM user_sentence
+		JOIN user_sentence_word ON (sentence = id)
+		WHERE authored AND (@word = '' OR item = @word)
+		ORDER BY item, id
+	`
+	rows, err := q.Query(query, sql.Named("word", text.Casefold(word)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authored sentences: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]AuthoredSentence, 0)
+	for rows.Next() {
+		var s AuthoredSentence
+		if err := rows.Scan(&s.ID, &s.Word, &s.Text, &s.Translation); err != nil {
+			return nil, fmt.Errorf("failed to get authored sentences: %w", err)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Deletes user sentence.
+// Returns false if the sentence doesn't exist.
+func DeleteUserSentence[T database.Querier](q T, id int) (bool, error) {
+	// Foreign keys aren't enforced in the review DB, so words have to be
+	// deleted explicitly.
+	if _, err := q.Exec(`DELETE FROM user_sentence_word WHERE sentence = ?`, id); err != nil {
+		return false, fmt.Errorf("failed to delete user sentence: %w", err)
+	}
+	result, err := q.Exec(`DELETE FROM user_sentence WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user sentence: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user sentence: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Picks random sentence written by the user for the word, and its
+// translation (empty if none).
+// Returns sql.ErrNoRows if there are none.
+func PickAuthoredSentence[T database.Querier](q T, word string) (Sentence, string, error) {
+	query := `
+		SELECT text, tokens, translation FROM user_sentence_word
+		JOIN user_sentence ON (sentence = id)
+		WHERE item = ? AND authored
+		ORDER BY random() LIMIT 1
+	`
+	row := q.QueryRow(query, text.Casefold(word))
+
+	sentence := Sentence{TatoebaID: -1}
+	var tokens, translation string
+	if err := row.Scan(&sentence.Text, &tokens, &translation); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sentence, "", err
+		}
+		return sentence, "", fmt.Errorf("failed to pick authored sentence: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tokens), &sentence.Tokens); err != nil {
+		return sentence, "", fmt.Errorf("failed to pick authored sentence: %w", err)
+	}
+	return sentence, translation, nil
+}
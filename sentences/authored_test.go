@@ -0,0 +1,60 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sentences
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestAddAuthoredSentence(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `INSERT INTO word (id, word, frequency_class) VALUES (1, 'haus', 0)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if _, err := AddAuthoredSentence(db, "katze", "Die Katze schläft.", ""); !errors.Is(err, ErrUnknownWord) {
+		t.Fatal("expected ErrUnknownWord:", err)
+	}
+	if _, err := AddAuthoredSentence(db, "haus", "Das Hausboot.", ""); !errors.Is(err, ErrWordNotInSentence) {
+		t.Fatal("expected ErrWordNotInSentence:", err)
+	}
+
+	id, err := AddAuthoredSentence(db, "Haus", " Das Haus ist alt. ", "The house is old.")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	sentence, translation, err := PickAuthoredSentence(db, "haus")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if sentence.Text != "Das Haus ist alt." || translation != "The house is old." {
+		t.Fatal("unexpected sentence:", sentence, translation)
+	}
+
+	// Authored sentences aren't picked as mined sentences.
+	if _, err := PickUserSentence(db, "haus"); err == nil {
+		t.Fatal("expected no mined sentences")
+	}
+
+	found, err := DeleteUserSentence(db, id)
+	if err != nil || !found {
+		t.Fatal("expected sentence to be deleted:", found, err)
+	}
+	sentences, err := GetAuthoredSentences(db, "")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(sentences) != 0 {
+		t.Fatal("expected no authored sentences:", sentences)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sentences
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Course sentence that contains a word.
+type Example struct {
+	Sentence
+
+	// Empty if the sentence has no translation.
+	Translation string `json:"translation"`
+}
+
+// Returns example sentences that contain the word, easiest sentences first.
+// Each sentence only comes with one translation.
+// Pages start at 0.
+func Examples[T database.Querier](q T, word string, page, limit int) ([]Example, error) {
+	examples := make([]Example, 0)
+
+	id, err := findWordID(q, word)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get examples of word (%v): %w", word, err)
+	}
+
+	query := `
+		SELECT
+			id,
+			tatoeba_id,
+			text,
+			coalesce((
+				SELECT translation.text FROM translates
+				JOIN translation ON (target = translation.tatoeba_id)
+				WHERE source = sentence.tatoeba_id
+				ORDER BY translation.id LIMIT 1
+			), '')
+		FROM contains JOIN sentence ON (sentence = id)
+		WHERE word = ?
+		ORDER BY frequency_class ASC, id ASC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := q.Query(query, id, limit, page*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get examples of word (%v): %w", word, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var example Example
+		var tatoebaID sql.NullInt64
+		err := rows.Scan(&example.ID, &tatoebaID, &example.Text, &example.Translation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get examples of word (%v): %w", word, err)
+		}
+
+		if tatoebaID.Valid {
+			example.TatoebaID = tatoebaID.Int64
+		} else {
+			example.TatoebaID = -1
+		}
+		examples = append(examples, example)
+	}
+	return examples, nil
+}
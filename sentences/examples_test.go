@@ -0,0 +1,63 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sentences
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestExamples(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'foo', 0), (2, 'bar', 0)`,
+		`INSERT INTO sentence (id, tatoeba_id, text, tokens, frequency_class) VALUES
+			(1, 10, 'foo bar', '[]', 2),
+			(2, NULL, 'foo', '[]', 0),
+			(3, 30, 'bar', '[]', 0)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1), (1, 2), (2, 1), (3, 2)`,
+		`INSERT INTO translation (tatoeba_id, text) VALUES (100, 'baz')`,
+		`INSERT INTO translates (source, target) VALUES (10, 100)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	examples, err := Examples(db, "foo", 0, 1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(examples) != 1 || examples[0].Text != "foo" || examples[0].TatoebaID != -1 {
+		t.Fatal("expected easiest sentence first:", examples)
+	}
+
+	examples, err = Examples(db, "foo", 1, 1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(examples) != 1 || examples[0].Text != "foo bar" || examples[0].Translation != "baz" {
+		t.Fatal("unexpected second page:", examples)
+	}
+
+	examples, err = Examples(db, "foo", 2, 1)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(examples) != 0 {
+		t.Fatal("expected empty page:", examples)
+	}
+
+	if _, err := Examples(db, "qux", 0, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatal("expected sql.ErrNoRows:", err)
+	}
+}
@@ -128,3 +128,22 @@ select id, tatoeba_id, tokens from sentence where text = ? collate nocase
 	}
 	return sentence, nil
 }
+
+// Returns sentence with the given ID.
+// The result doesn't include tokens.
+func Get[T database.Querier](q T, id int) (Sentence, error) {
+	query := `SELECT tatoeba_id, text FROM sentence WHERE id = ?`
+	row := q.QueryRow(query, id)
+
+	sentence := Sentence{ID: id}
+	var tatoebaID sql.NullInt64
+	if err := row.Scan(&tatoebaID, &sentence.Text); err != nil {
+		return sentence, fmt.Errorf("failed to get sentence (%v): %w", id, err)
+	}
+	if tatoebaID.Valid {
+		sentence.TatoebaID = tatoebaID.Int64
+	} else {
+		sentence.TatoebaID = -1
+	}
+	return sentence, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sentences
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Saves user sentence that can be used as a cloze for the given words.
+// Returns false if the sentence was already saved.
+func AddUserSentence[T database.Querier](q T, tokens []string, words []string) (bool, error) {
+	s := strings.Join(tokens, "")
+	encoded, err := json.Marshal(tokens)
+	if err != nil {
+		return false, fmt.Errorf("failed to add user sentence: %w", err)
+	}
+
+	query := `INSERT OR IGNORE INTO user_sentence (text, tokens) VALUES (?, ?)`
+	result, err := q.Exec(query, s, string(encoded))
+	if err != nil {
+		return false, fmt.Errorf("failed to add user sentence: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, nil
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, fmt.Errorf("failed to add user sentence: %w", err)
+	}
+
+	query = `INSERT OR IGNORE INTO user_sentence_word (sentence, item) VALUES (?, ?)`
+	for _, word := range words {
+		if _, err := q.Exec(query, id, text.Casefold(word)); err != nil {
+			return false, fmt.Errorf("failed to add user sentence: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// Picks random sentence mined from the user's texts that contains the word.
+// User sentences don't have IDs or Tatoeba IDs.
+// Returns sql.ErrNoRows if there are no user sentences for the word.
+func PickUserSentence[T database.Querier](q T, word string) (Sentence, error) {
+	query := `
+		SELECT text, tokens FROM user_sentence_word
+		JOIN user_sentence ON (sentence = id)
+		WHERE item = ? AND NOT authored
+		ORDER BY random() LIMIT 1
+	`
+	row := q.QueryRow(query, text.Casefold(word))
+
+	sentence := Sentence{TatoebaID: -1}
+	var tokens string
+	if err := row.Scan(&sentence.Text, &tokens); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sentence, err
+		}
+		return sentence, fmt.Errorf("failed to pick user sentence: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tokens), &sentence.Tokens); err != nil {
+		return sentence, fmt.Errorf("failed to pick user sentence: %w", err)
+	}
+	return sentence, nil
+}
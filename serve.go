@@ -0,0 +1,350 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/polycloze/polycloze/basedir"
+	"github.com/polycloze/polycloze/dictionary"
+	"github.com/polycloze/polycloze/digest"
+	"github.com/polycloze/polycloze/hooks"
+	"github.com/polycloze/polycloze/mail"
+	"github.com/polycloze/polycloze/maintenance"
+	"github.com/polycloze/polycloze/polycloze"
+	"github.com/polycloze/polycloze/reminders"
+	"github.com/polycloze/polycloze/sessions"
+	"github.com/polycloze/polycloze/tts"
+)
+
+// Max time to wait for in-flight requests on shutdown.
+const shutdownTimeout = 30 * time.Second
+
+type ServeArgs struct {
+	cors bool
+	port int
+	tls  TLSArgs
+
+	// Comma-separated list of trusted reverse proxies.
+	trustedProxies string
+
+	webhooks []string
+	scripts  []string
+
+	// Users inactive for this many days get warned, and their accounts get
+	// deleted two weeks later (0: never).
+	inactiveDays int
+
+	// Text-to-speech for sentences without recordings.
+	piper       string // Path to piper executable
+	piperModels string // Directory of piper voice models
+	ttsURL      string // URL of HTTP TTS API
+
+	// Size limit of synthesized speech cache in MB (0: unlimited).
+	ttsCacheSize int64
+
+	dictionaryURL string // URL of external dictionary API
+
+	reverseCourses bool
+
+	// Size limit of uploaded files in MB.
+	maxUploadSize int64
+
+	// Course that visitors can try without an account.
+	demoCourse string
+
+	// Allow anonymous trial accounts.
+	allowTrials bool
+
+	// Bearer token for the SCIM API.
+	scimToken string
+
+	// Mount pprof and expvar endpoints for admins.
+	debugEndpoints bool
+
+	// Override basedir.DataDir, basedir.StateDir and basedir.CacheDir
+	// (optional).
+	dataDir  string
+	stateDir string
+	cacheDir string
+
+	// Session cookie attributes.
+	cookieDomain   string
+	cookieSameSite string
+	cookieInsecure bool
+}
+
+func defaultPortNumber() int {
+	port := os.Getenv("PORT")
+	if port != "" {
+		v, err := strconv.Atoi(port)
+		if err == nil {
+			return v
+		}
+	}
+	return 3000
+}
+
+func parseServeArgs(args []string) ServeArgs {
+	var sa ServeArgs
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.BoolVar(&sa.cors, "c", false, "allow CORS")
+	flags.IntVar(&sa.port, "p", defaultPortNumber(), "port number")
+	flags.StringVar(&sa.trustedProxies, "trusted-proxies", os.Getenv("TRUSTED_PROXIES"), "comma-separated list of trusted reverse proxy addresses (IP or CIDR)")
+	flags.StringVar(&sa.tls.certFile, "tls-cert", "", "TLS certificate file")
+	flags.StringVar(&sa.tls.keyFile, "tls-key", "", "TLS key file")
+	flags.StringVar(&sa.tls.domains, "acme-domains", "", "comma-separated list of domains to get certificates for using ACME")
+	flags.StringVar(&sa.tls.email, "acme-email", "", "contact email for ACME")
+	flags.StringVar(&sa.tls.redirect, "redirect", "", "address of HTTP server that redirects to HTTPS (e.g. :80)")
+	flags.StringVar(&sa.piper, "tts-piper", "", "path to piper executable for text-to-speech")
+	flags.StringVar(&sa.piperModels, "tts-piper-models", "", "directory of piper voice models named after ISO 639-3 codes (e.g. deu.onnx)")
+	flags.StringVar(&sa.ttsURL, "tts-url", "", "URL of text-to-speech API (uses POLYCLOZE_TTS_API_KEY)")
+	flags.Int64Var(&sa.ttsCacheSize, "tts-cache-size", 0, "size limit of synthesized speech cache in MB (0: unlimited)")
+	flags.StringVar(&sa.dictionaryURL, "dictionary-url", "", "URL of external dictionary API for word lookups")
+	flags.BoolVar(&sa.reverseCourses, "reverse-courses", false, "build reverse courses (e.g. spa-eng from eng-spa) of installed courses")
+	flags.Int64Var(&sa.maxUploadSize, "max-upload-size", 8, "size limit of uploaded files in MB")
+	flags.StringVar(&sa.demoCourse, "demo-course", "", "course (e.g. eng-spa) that visitors can try at /demo without an account")
+	flags.BoolVar(&sa.allowTrials, "allow-trials", false, "let visitors study under anonymous trial accounts, which they can claim later by registering")
+	flags.StringVar(&sa.scimToken, "scim-token", os.Getenv("SCIM_TOKEN"), "bearer token for SCIM user provisioning (disabled if empty)")
+	flags.BoolVar(&sa.debugEndpoints, "debug-endpoints", false, "serve pprof and expvar under /debug (admins only)")
+	flags.StringVar(&sa.dataDir, "data-dir", "", "directory of installed courses (default: $POLYCLOZE_DATA_DIR or XDG data directory)")
+	flags.StringVar(&sa.stateDir, "state-dir", "", "directory of user data (default: $POLYCLOZE_STATE_DIR or XDG state directory)")
+	flags.StringVar(&sa.cacheDir, "cache-dir", "", "directory of regenerable files (default: $POLYCLOZE_CACHE_DIR or XDG cache directory)")
+	flags.StringVar(&sa.cookieDomain, "cookie-domain", "", "Domain attribute of session cookies")
+	flags.StringVar(&sa.cookieSameSite, "cookie-samesite", "strict", "SameSite attribute of session cookies (strict, lax or none)")
+	flags.BoolVar(&sa.cookieInsecure, "cookie-insecure", false, "don't set the Secure attribute of cookies on requests with unknown scheme (i.e. not over TLS or from a trusted proxy)")
+	flags.IntVar(&sa.inactiveDays, "inactive-days", 0, "warn users inactive for this many days, and delete their accounts two weeks later (0: never)")
+	flags.Func("webhook", "URL to POST study events to (can be repeated)", func(url string) error {
+		sa.webhooks = append(sa.webhooks, url)
+		return nil
+	})
+	flags.Func("hook-script", "script to run on study events (can be repeated)", func(path string) error {
+		sa.scripts = append(sa.scripts, path)
+		return nil
+	})
+	_ = flags.Parse(args)
+	return sa
+}
+
+// Returns text-to-speech synthesizer, or nil if none is configured.
+func (sa ServeArgs) synthesizer() tts.Synthesizer {
+	if sa.piper != "" {
+		return tts.Piper{Binary: sa.piper, ModelDir: sa.piperModels}
+	}
+	if sa.ttsURL != "" {
+		return tts.HTTP{URL: sa.ttsURL, APIKey: os.Getenv("POLYCLOZE_TTS_API_KEY")}
+	}
+	return nil
+}
+
+// Runs the server.
+func serve(args []string) error {
+	sa := parseServeArgs(args)
+	if sa.dataDir != "" {
+		if err := basedir.SetDataDir(sa.dataDir); err != nil {
+			return err
+		}
+	}
+	if sa.stateDir != "" {
+		if err := basedir.SetStateDir(sa.stateDir); err != nil {
+			return err
+		}
+	}
+	if sa.cacheDir != "" {
+		if err := basedir.SetCacheDir(sa.cacheDir); err != nil {
+			return err
+		}
+	}
+
+	config := polycloze.Config{
+		AllowCORS:      sa.cors,
+		Port:           sa.port,
+		TrustedProxies: strings.Split(sa.trustedProxies, ","),
+		TTS:            sa.synthesizer(),
+		TTSCacheSize:   sa.ttsCacheSize * 1024 * 1024,
+		ReverseCourses: sa.reverseCourses,
+		MaxUploadSize:  sa.maxUploadSize * 1024 * 1024,
+		DemoCourse:     sa.demoCourse,
+		AllowTrials:    sa.allowTrials,
+		SCIMToken:      sa.scimToken,
+		DebugEndpoints: sa.debugEndpoints,
+	}
+	if sa.dictionaryURL != "" {
+		config.Dictionary = dictionary.HTTP{URL: sa.dictionaryURL}
+	}
+
+	for _, url := range sa.webhooks {
+		hooks.Register(hooks.Webhook(url))
+	}
+	for _, path := range sa.scripts {
+		hooks.Register(hooks.Script(path))
+	}
+	defer hooks.Wait()
+
+	configureMail()
+	defer mail.Flush()
+
+	sameSite, err := sessions.ParseSameSite(sa.cookieSameSite)
+	if err != nil {
+		return err
+	}
+	if sameSite == http.SameSiteNoneMode && sa.cookieInsecure {
+		// Browsers reject these cookies.
+		return errors.New("-cookie-samesite=none requires secure cookies")
+	}
+	sessions.SetCookieOptions(sessions.CookieOptions{
+		Domain:   sa.cookieDomain,
+		SameSite: sameSite,
+		Secure:   !sa.cookieInsecure,
+	})
+
+	// Share sessions between instances.
+	if addr := os.Getenv("POLYCLOZE_REDIS_ADDR"); addr != "" {
+		sessions.UseStore(sessions.NewRedisStore(addr, os.Getenv("POLYCLOZE_REDIS_PASSWORD")))
+	}
+
+	// Restore user data from object storage.
+	mirror := mirrorFromEnv()
+	if mirror != nil {
+		if err := mirror.Pull(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	server, err := polycloze.NewServer(config)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	// Prune expired sessions and inactive accounts daily.
+	policy := maintenance.Policy{
+		InactiveAccountAge: time.Duration(sa.inactiveDays) * 24 * time.Hour,
+	}
+	stop := maintenance.Schedule(server.AuthDB(), policy, 24*time.Hour)
+	defer stop()
+
+	// Check for due reminders every 15 minutes, and for due weekly digests
+	// every hour.
+	if mail.Configured() {
+		stop := reminders.Schedule(server.AuthDB(), 15*time.Minute)
+		defer stop()
+
+		stopDigests := digest.Schedule(server.AuthDB(), time.Hour)
+		defer stopDigests()
+	}
+
+	var handler http.Handler = server
+	if mirror != nil {
+		handler = pushAfterWrites(mirror, server)
+	}
+	tlsConfig, redirect, err := configureTLS(sa.tls, sa.port)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	servers := []*http.Server{
+		{
+			Addr:      fmt.Sprintf(":%v", sa.port),
+			Handler:   handler,
+			TLSConfig: tlsConfig,
+		},
+	}
+	if tlsConfig != nil && sa.tls.redirect != "" {
+		servers = append(servers, &http.Server{
+			Addr:    sa.tls.redirect,
+			Handler: redirect,
+		})
+	}
+	err = listenAndServe(server.Shutdown, servers...)
+
+	// Push even if the servers didn't shut down cleanly, so that the last
+	// writes don't get lost with the local state directory.
+	if mirror != nil {
+		if pushErr := mirror.Push(context.Background()); err == nil {
+			err = pushErr
+		}
+	}
+	return err
+}
+
+// Runs servers until the process receives SIGINT or SIGTERM.
+// The first server uses the socket passed by systemd if the process was
+// socket-activated.
+// On shutdown, the servers stop accepting new requests and wait for in-flight
+// requests (e.g. flashcard uploads) to finish, so that review data doesn't get
+// cut off mid-write. Then `shutdown` gets called to end connections that the
+// servers don't track (e.g. WebSockets).
+func listenAndServe(shutdown func(context.Context) error, servers ...*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, len(servers))
+	for i, server := range servers {
+		var l net.Listener
+		var err error
+		if i == 0 {
+			l, err = listen(server.Addr)
+		} else {
+			l, err = net.Listen("tcp", server.Addr)
+		}
+		if err != nil {
+			return err
+		}
+
+		scheme := "http"
+		if server.TLSConfig != nil {
+			scheme = "https"
+			l = tls.NewListener(l, server.TLSConfig)
+		}
+		log.Printf("Listening on %v (%v)\n", l.Addr(), scheme)
+		if i == 0 {
+			log.Printf("Start learning: %v://127.0.0.1%v\n", scheme, server.Addr)
+		}
+
+		go func(server *http.Server, l net.Listener) {
+			errs <- server.Serve(l)
+		}(server, l)
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down...")
+	stop()
+
+	// Keep shutting down the rest even if one fails.
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	var err error
+	for _, server := range servers {
+		if e := server.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	if e := shutdown(ctx); e != nil && err == nil {
+		err = e
+	}
+	if err != nil {
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	return nil
+}
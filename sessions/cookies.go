@@ -4,15 +4,80 @@
 package sessions
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Name of cookie that stores session ID.
 const cookieName = "id"
 
+// Attributes of the session cookie.
+// Defaults are for serving directly over HTTPS. Use `SetCookieOptions` to
+// change them, e.g. when the server is behind a reverse proxy on another
+// domain.
+type CookieOptions struct {
+	Domain   string // Empty for the current host only
+	SameSite http.SameSite
+	Secure   bool // Only used if the request scheme is unknown (see `IsSecure`)
+}
+
+func DefaultCookieOptions() CookieOptions {
+	return CookieOptions{
+		SameSite: http.SameSiteStrictMode,
+		Secure:   true,
+	}
+}
+
+var cookieOptions = DefaultCookieOptions()
+
+// Sets attributes of session cookies.
+// Should be called before the server starts handling requests.
+func SetCookieOptions(options CookieOptions) {
+	cookieOptions = options
+}
+
+// Returns attributes of session cookies.
+// Other cookies set by the server should use the same attributes.
+func GetCookieOptions() CookieOptions {
+	return cookieOptions
+}
+
+// Parses SameSite attribute ("strict", "lax" or "none").
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("invalid SameSite value: %v", s)
+	}
+}
+
+// Checks if cookies set in response to the request should have the Secure
+// attribute.
+// Uses the scheme of the request if it's known, i.e. if it came over TLS or
+// through a trusted proxy that sets X-Forwarded-Proto. Browsers don't keep
+// secure cookies set over plain HTTP anyway.
+// Otherwise, falls back to the Secure cookie option.
+func IsSecure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	switch r.URL.Scheme {
+	case "https":
+		return true
+	case "http":
+		return false
+	default:
+		return cookieOptions.Secure
+	}
+}
+
 // Gets session cookie from client.
 // Returns an error if no ID is found.
 // Does not validate the cookie.
@@ -20,38 +85,35 @@ func getCookie(r *http.Request) (*http.Cookie, error) {
 	return r.Cookie(cookieName)
 }
 
-// Checks if the session ID in the cookie is still valid (in DB and not expired).
-func validateCookie(db *sql.DB, c *http.Cookie) error {
+// Checks if the session ID in the cookie is still valid (in store and not
+// expired).
+func validateCookie(st Store, c *http.Cookie) error {
 	if c.Name != cookieName {
 		return errors.New("incorrect cookie name")
 	}
-	var id string
-	// TODO scan expiry date for checking
-	query := `SELECT session_id FROM user_session WHERE session_id = ?`
-	if err := db.QueryRow(query, c.Value).Scan(&id); err != nil {
-		return fmt.Errorf("invalid session ID: %w", err)
-	}
-	return nil
+	return st.Validate(c.Value)
 }
 
-func setCookie(w http.ResponseWriter, id string) {
+func setCookie(w http.ResponseWriter, r *http.Request, id string) {
 	c := http.Cookie{
 		Name:     cookieName,
 		Value:    id,
-		SameSite: http.SameSiteStrictMode,
+		Domain:   cookieOptions.Domain,
+		SameSite: cookieOptions.SameSite,
 		HttpOnly: true,
-		Secure:   true,
+		Secure:   IsSecure(r),
 	}
 	http.SetCookie(w, &c)
 }
 
-func deleteCookie(w http.ResponseWriter) {
+func deleteCookie(w http.ResponseWriter, r *http.Request) {
 	c := http.Cookie{
 		Name:     cookieName,
 		Value:    "",
-		SameSite: http.SameSiteStrictMode,
+		Domain:   cookieOptions.Domain,
+		SameSite: cookieOptions.SameSite,
 		HttpOnly: true,
-		Secure:   true,
+		Secure:   IsSecure(r),
 		MaxAge:   -1,
 	}
 	http.SetCookie(w, &c)
@@ -0,0 +1,35 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sessions
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsSecure(t *testing.T) {
+	// Should use the request scheme if it's known.
+	t.Parallel()
+
+	if r := httptest.NewRequest("GET", "https://example.com/", nil); !IsSecure(r) {
+		t.Fatal("expected TLS request to be secure")
+	}
+
+	// Scheme set by trusted proxies.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.URL.Scheme = "http"
+	if IsSecure(r) {
+		t.Fatal("expected plain HTTP request to be insecure")
+	}
+	r.URL.Scheme = "https"
+	if !IsSecure(r) {
+		t.Fatal("expected forwarded HTTPS request to be secure")
+	}
+
+	// Falls back to the cookie option.
+	r.URL.Scheme = ""
+	if IsSecure(r) != GetCookieOptions().Secure {
+		t.Fatal("expected unknown scheme to use Secure cookie option")
+	}
+}
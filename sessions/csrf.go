@@ -6,6 +6,7 @@ package sessions
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"net/http"
 )
 
 // Creates CSRF token for session.
@@ -24,3 +25,19 @@ func CSRFToken(sessionID string) string {
 func CheckCSRFToken(sessionID, token string) bool {
 	return CSRFToken(sessionID) == token
 }
+
+// Gets CSRF token from the X-CSRF-Token header, or from the csrf-token form
+// field if there's no header.
+func RequestCSRFToken(r *http.Request) string {
+	if token := r.Header.Get("X-CSRF-Token"); token != "" {
+		return token
+	}
+	return r.FormValue("csrf-token")
+}
+
+// Validates CSRF token in the request (see `RequestCSRFToken`).
+// Mutating endpoints should use this, so that they all accept the token the
+// same way.
+func CheckRequestCSRFToken(sessionID string, r *http.Request) bool {
+	return CheckCSRFToken(sessionID, RequestCSRFToken(r))
+}
@@ -4,6 +4,9 @@
 package sessions
 
 import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +39,20 @@ func TestCSRFTokenIdNotEqual(t *testing.T) {
 		t.Fatal("expected token and session ID to be different:", id, token)
 	}
 }
+
+func TestRequestCSRFToken(t *testing.T) {
+	// Header should take precedence over form values.
+	t.Parallel()
+
+	form := url.Values{"csrf-token": {"form"}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token := RequestCSRFToken(r); token != "form" {
+		t.Fatal("expected token from form:", token)
+	}
+
+	r.Header.Set("X-CSRF-Token", "header")
+	if token := RequestCSRFToken(r); token != "header" {
+		t.Fatal("expected token from header:", token)
+	}
+}
@@ -5,8 +5,12 @@ package sessions
 
 import (
 	"database/sql"
+	"time"
 )
 
+// How often the time the user was last seen gets updated.
+const lastSeenInterval = time.Hour
+
 // Gets session data from the database.
 // Returns an empty map even if the ID does not exist.
 func getData(db *sql.DB, id string) map[string]any {
@@ -24,11 +28,35 @@ func getData(db *sql.DB, id string) map[string]any {
 	return data
 }
 
+// Checks if the user in the session data still exists under the same
+// username, and hasn't been deactivated or merged into another account.
+// The username check keeps old sessions from acting as a new user that
+// reuses the ID of a deleted user.
+func canStaySignedIn(db *sql.DB, data map[string]any) bool {
+	var ok bool
+	query := `
+		SELECT active AND merged_into IS NULL FROM user
+		WHERE id = ? AND username = ?
+	`
+	err := db.QueryRow(query, data["userID"], data["username"]).Scan(&ok)
+	return err == nil && ok
+}
+
+// Records that the user was seen at `now`, so that users who sign in without
+// reviewing don't count as inactive (see package maintenance).
+// Skips the write if the user has been seen recently.
+func recordLastSeen(db *sql.DB, userID int, now time.Time) error {
+	query := `
+		UPDATE user SET last_seen = ?
+		WHERE id = ? AND (last_seen IS NULL OR last_seen <= ?)
+	`
+	_, err := db.Exec(query, now.Unix(), userID, now.Add(-lastSeenInterval).Unix())
+	return err
+}
+
 // Saves session data.
 // The session must exist already.
 // `SaveData` would still return `nil`, but wouldn't insert a new entry for the missing session.
 func SaveData(db *sql.DB, s *Session) error {
-	query := `UPDATE user_session SET user_id = ?, username = ?, updated = unixepoch('now') WHERE session_id = ?`
-	_, err := db.Exec(query, s.Data["userID"], s.Data["username"], s.ID)
-	return err
+	return getStore(db).Save(s)
 }
@@ -193,3 +193,42 @@ func TestSaveDataTimestamp(t *testing.T) {
 		t.Fatal("expected before < after:", before, after)
 	}
 }
+
+func TestRecordLastSeen(t *testing.T) {
+	// Last seen time should only be updated about once an hour.
+	t.Parallel()
+
+	db := testDB()
+	defer db.Close()
+
+	query := `INSERT INTO user (id, username, password) VALUES (1, 'foo', 'x')`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	lastSeen := func() int64 {
+		var seen int64
+		query := `SELECT last_seen FROM user WHERE id = 1`
+		if err := db.QueryRow(query).Scan(&seen); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		return seen
+	}
+
+	now := time.Unix(1000000, 0)
+	for _, c := range []struct {
+		at       time.Time
+		expected time.Time
+	}{
+		{now, now},
+		{now.Add(time.Minute), now},
+		{now.Add(lastSeenInterval), now.Add(lastSeenInterval)},
+	} {
+		if err := recordLastSeen(db, 1, c.at); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if seen := lastSeen(); seen != c.expected.Unix() {
+			t.Fatal("unexpected last seen time:", seen, c.expected.Unix())
+		}
+	}
+}
@@ -8,7 +8,8 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"fmt"
-	"strings"
+
+	"github.com/polycloze/polycloze/database"
 )
 
 // Generates a cryptographically secure random 128-bit string in base64.
@@ -34,7 +35,7 @@ func generateUniqueID(db *sql.DB) (string, error) {
 			return id, nil
 		}
 
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		if database.IsUniqueConstraintError(err) {
 			continue
 		}
 		return "", fmt.Errorf("failed to generate a unique ID: %w", err)
@@ -60,3 +61,31 @@ func deleteID(db *sql.DB, id string) error {
 	_, err := db.Exec(query, id)
 	return err
 }
+
+// Deletes old and idle sessions (see `deleteID`), including their messages.
+// Only counts them if `dryRun` is set.
+// Returns the number of expired sessions.
+// Does nothing if sessions aren't stored in the auth DB, because other stores
+// expire sessions on their own.
+func PruneExpired(db *sql.DB, dryRun bool) (int, error) {
+	if store != nil {
+		return 0, nil
+	}
+
+	var count int
+	query := `
+		SELECT count(*) FROM user_session
+		WHERE created < (unixepoch('now') - 14400)
+			OR updated < (unixepoch('now') - 1800)
+	`
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+	if dryRun || count == 0 {
+		return count, nil
+	}
+	if err := deleteID(db, ""); err != nil {
+		return 0, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+	return count, nil
+}
@@ -5,7 +5,6 @@ package sessions
 
 import (
 	"database/sql"
-	"strings"
 	"testing"
 
 	"github.com/polycloze/polycloze/database"
@@ -34,7 +33,7 @@ func TestReserveID(t *testing.T) {
 		t.Fatal("expected uniqueness constraint error")
 	}
 
-	if !strings.Contains(err.Error(), "UNIQUE constraint failed") {
+	if !database.IsUniqueConstraintError(err) {
 		t.Fatal("expected uniqueness constraint error")
 	}
 }
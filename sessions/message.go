@@ -95,23 +95,15 @@ func deleteMessages(tx *sql.Tx, sessionID string, contexts ...string) error {
 // If there are no context args, returns messages with a null context
 // (those that were inserted with `Session.Message` without context args).
 func (s *Session) Messages(contexts ...string) ([]Message, error) {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get messages from the database: %w", err)
+	if len(contexts) == 0 && !containsNullContext(contexts) {
+		// Insert null context if empty.
+		contexts = append(contexts, "")
 	}
 
-	messages, err := getMessages(tx, s.ID, contexts...)
+	messages, err := s.store.PopMessages(s.ID, contexts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get messages from the database: %w", err)
 	}
-
-	if err := deleteMessages(tx, s.ID, contexts...); err != nil {
-		return nil, fmt.Errorf("failed to get messages from the database: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to get messages from the database: %w", err)
-	}
 	return messages, nil
 }
 
@@ -141,24 +133,17 @@ func (s *Session) Message(kind string, message string, contexts ...string) error
 	}
 
 	// Add one copy of the message for each context arg.
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to save message for user: %w", err)
-	}
-
-	query := `
-		INSERT INTO message (session_id, message, kind, context)
-		VALUES (?, ?, ?, ?)
-	`
 	for _, context := range contexts {
-		if _, err := tx.Exec(query, s.ID, message, kind, context); err != nil {
+		m := Message{
+			Created: time.Now(),
+			Message: message,
+			Kind:    kind,
+			Context: context,
+		}
+		if err := s.store.AddMessage(s.ID, m); err != nil {
 			return fmt.Errorf("failed to save message for user: %w", err)
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to save message for user: %w", err)
-	}
 	return nil
 }
 
@@ -0,0 +1,395 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sessions
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Sessions expire after 4 hours, or after 30 minutes of inactivity.
+	// Same as sessions in the auth DB.
+	sessionMaxAge  = 4 * time.Hour
+	sessionMaxIdle = 30 * time.Minute
+
+	// Max duration of connecting to Redis, or of a command round trip.
+	redisTimeout = 5 * time.Second
+)
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// Minimal Redis client (RESP2) for storing sessions.
+// Uses a single connection, and reconnects if it breaks or times out.
+type redisClient struct {
+	addr     string
+	password string
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *redisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	if c.password != "" {
+		if _, err := c.roundTrip("AUTH", c.password); err != nil {
+			c.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *redisClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.reader = nil
+}
+
+// Encodes command as RESP array of bulk strings.
+func encodeCommand(args ...string) []byte {
+	b := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		b = append(b, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+	return b
+}
+
+// Reads RESP reply.
+// Returns string, int64, nil (null reply), []any or redisError.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, errors.New("redis: invalid reply")
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readReply(r)
+			var rerr redisError
+			if err != nil && !errors.As(err, &rerr) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, errors.New("redis: invalid reply")
+	}
+}
+
+// Sends command and reads reply. Caller should hold the lock.
+// Fails if Redis doesn't reply in time, so that a stuck connection doesn't
+// block every request.
+func (c *redisClient) roundTrip(args ...string) (any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(encodeCommand(args...)); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// Checks if the error is from a connection that had been closed by Redis.
+func isClosedConn(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// Runs command.
+// Reconnects if the connection is broken. The command only gets retried if
+// the connection was closed before Redis could reply (e.g. after being idle for
+// too long), and not after timeouts, because Redis may have already run it.
+func (c *redisClient) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reused := c.conn != nil
+	if !reused {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		// Connection may be broken.
+		c.close()
+		if !reused || !isClosedConn(err) {
+			return nil, err
+		}
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+		reply, err = c.roundTrip(args...)
+		if err != nil && !errors.As(err, &rerr) {
+			c.close()
+		}
+	}
+	return reply, err
+}
+
+// Session store in Redis.
+// Session data is stored in a hash, and messages are stored in lists (one for
+// each context).
+type redisStore struct {
+	client *redisClient
+}
+
+// Returns store that keeps sessions in Redis at `addr` (host:port).
+func NewRedisStore(addr, password string) Store {
+	return redisStore{
+		client: &redisClient{addr: addr, password: password, timeout: redisTimeout},
+	}
+}
+
+func sessionKey(id string) string {
+	return "polycloze:session:" + id
+}
+
+func messagesKey(id, context string) string {
+	return "polycloze:session:" + id + ":messages:" + context
+}
+
+// Set of IDs of the user's sessions, for signing the user out everywhere.
+// May contain IDs of sessions that have expired.
+func userSessionsKey(userID int) string {
+	return "polycloze:user:" + strconv.Itoa(userID) + ":sessions"
+}
+
+func idleTimeout() string {
+	return strconv.Itoa(int(sessionMaxIdle.Seconds()))
+}
+
+func (s redisStore) NewID() (string, error) {
+	for {
+		id, err := generateID()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a unique ID: %w", err)
+		}
+
+		created := strconv.FormatInt(time.Now().Unix(), 10)
+		reply, err := s.client.Do("HSETNX", sessionKey(id), "created", created)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate a unique ID: %w", err)
+		}
+		if reply != int64(1) {
+			continue // ID in use
+		}
+		if _, err := s.client.Do("EXPIRE", sessionKey(id), idleTimeout()); err != nil {
+			return "", fmt.Errorf("failed to generate a unique ID: %w", err)
+		}
+		return id, nil
+	}
+}
+
+func (s redisStore) Validate(id string) error {
+	reply, err := s.client.Do("HGET", sessionKey(id), "created")
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return errors.New("invalid session ID: not found")
+	}
+
+	created, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || time.Since(time.Unix(created, 0)) > sessionMaxAge {
+		_ = s.Delete(id)
+		return errors.New("invalid session ID: expired")
+	}
+
+	// Extend idle timeout.
+	_, err = s.client.Do("EXPIRE", sessionKey(id), idleTimeout())
+	return err
+}
+
+func (s redisStore) Data(id string) map[string]any {
+	data := make(map[string]any)
+	reply, err := s.client.Do("HMGET", sessionKey(id), "userID", "username")
+	if err != nil {
+		return data
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return data
+	}
+	userID, ok1 := values[0].(string)
+	username, ok2 := values[1].(string)
+	if !ok1 || !ok2 {
+		return data
+	}
+	if id, err := strconv.Atoi(userID); err == nil {
+		data["userID"] = id
+		data["username"] = username
+	}
+	return data
+}
+
+func (s redisStore) Save(session *Session) error {
+	key := sessionKey(session.ID)
+	reply, err := s.client.Do("EXISTS", key)
+	if err != nil {
+		return err
+	}
+	if reply != int64(1) {
+		// Don't create missing sessions.
+		return nil
+	}
+
+	userID, ok1 := session.Data["userID"].(int)
+	username, ok2 := session.Data["username"].(string)
+	if ok1 && ok2 {
+		_, err = s.client.Do("HSET", key, "userID", strconv.Itoa(userID), "username", username)
+		if err == nil {
+			err = s.addUserSession(userID, session.ID)
+		}
+	} else {
+		_, err = s.client.Do("HDEL", key, "userID", "username")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do("EXPIRE", key, idleTimeout())
+	return err
+}
+
+func (s redisStore) Delete(id string) error {
+	if id == "" {
+		// Expired sessions get deleted by Redis.
+		return nil
+	}
+	// Messages expire on their own.
+	_, err := s.client.Do("DEL", sessionKey(id))
+	return err
+}
+
+// Adds session to the user's set of sessions.
+// The set expires when the last session added to it expires.
+func (s redisStore) addUserSession(userID int, id string) error {
+	key := userSessionsKey(userID)
+	if _, err := s.client.Do("SADD", key, id); err != nil {
+		return err
+	}
+	_, err := s.client.Do("EXPIRE", key, strconv.Itoa(int(sessionMaxAge.Seconds())))
+	return err
+}
+
+func (s redisStore) DeleteUserSessions(userID int) error {
+	key := userSessionsKey(userID)
+	reply, err := s.client.Do("SMEMBERS", key)
+	if err != nil {
+		return err
+	}
+	values, _ := reply.([]any)
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			if err := s.Delete(id); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = s.client.Do("DEL", key)
+	return err
+}
+
+func (s redisStore) AddMessage(id string, m Message) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	key := messagesKey(id, m.Context)
+	if _, err := s.client.Do("RPUSH", key, string(value)); err != nil {
+		return err
+	}
+	_, err = s.client.Do("EXPIRE", key, idleTimeout())
+	return err
+}
+
+func (s redisStore) PopMessages(id string, contexts []string) ([]Message, error) {
+	var messages []Message
+	for _, context := range contexts {
+		key := messagesKey(id, context)
+		reply, err := s.client.Do("LRANGE", key, "0", "-1")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.client.Do("DEL", key); err != nil {
+			return nil, err
+		}
+
+		values, _ := reply.([]any)
+		for _, value := range values {
+			str, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var m Message
+			if err := json.Unmarshal([]byte(str), &m); err != nil {
+				return nil, err
+			}
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
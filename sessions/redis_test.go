@@ -0,0 +1,140 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sessions
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeCommand(t *testing.T) {
+	t.Parallel()
+
+	expected := "*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n"
+	if b := string(encodeCommand("GET", "foo")); b != expected {
+		t.Fatalf("unexpected encoding: %q", b)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		input    string
+		expected any
+	}{
+		{"+OK\r\n", "OK"},
+		{":1\r\n", int64(1)},
+		{"$3\r\nfoo\r\n", "foo"},
+		{"$-1\r\n", nil},
+		{"*2\r\n$3\r\nfoo\r\n$-1\r\n", []any{"foo", nil}},
+	}
+
+	for _, c := range cases {
+		reply, err := readReply(bufio.NewReader(strings.NewReader(c.input)))
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if !reflect.DeepEqual(reply, c.expected) {
+			t.Fatal("unexpected reply:", c.input, reply)
+		}
+	}
+}
+
+func TestReadReplyError(t *testing.T) {
+	t.Parallel()
+
+	_, err := readReply(bufio.NewReader(strings.NewReader("-ERR unknown\r\n")))
+	if err == nil || err.Error() != "redis: ERR unknown" {
+		t.Fatal("expected redis error:", err)
+	}
+}
+
+// Starts fake Redis server that handles each connection with `handle`.
+// Returns the server address.
+func fakeRedis(t *testing.T, handle func(conn net.Conn, r *bufio.Reader)) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn, bufio.NewReader(conn))
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestRedisClientTimeout(t *testing.T) {
+	// Should time out if Redis doesn't reply, and reconnect on the next
+	// command.
+	t.Parallel()
+
+	connections := make(chan struct{}, 2)
+	addr := fakeRedis(t, func(conn net.Conn, r *bufio.Reader) {
+		connections <- struct{}{}
+		if len(connections) == 1 {
+			// Never reply on the first connection.
+			_, _ = r.ReadString('\x00')
+			return
+		}
+		for {
+			if _, err := readReply(r); err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("+PONG\r\n"))
+		}
+	})
+
+	c := &redisClient{addr: addr, timeout: 100 * time.Millisecond}
+	if _, err := c.Do("PING"); err == nil {
+		t.Fatal("expected command to time out")
+	}
+
+	reply, err := c.Do("PING")
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if reply != "PONG" {
+		t.Fatal("unexpected reply:", reply)
+	}
+}
+
+func TestRedisClientRetry(t *testing.T) {
+	// Should retry on a new connection if the old one got closed.
+	t.Parallel()
+
+	addr := fakeRedis(t, func(conn net.Conn, r *bufio.Reader) {
+		// Close connection after one command.
+		if _, err := readReply(r); err == nil {
+			_, _ = conn.Write([]byte("+PONG\r\n"))
+		}
+	})
+
+	c := &redisClient{addr: addr, timeout: time.Second}
+	for i := 0; i < 3; i++ {
+		reply, err := c.Do("PING")
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if reply != "PONG" {
+			t.Fatal("unexpected reply:", reply)
+		}
+	}
+}
@@ -5,16 +5,18 @@ package sessions
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Represents a user session.
 // Shouldn't be used as a constructor.
 type Session struct {
-	ID   string
-	Data map[string]any
-	db   *sql.DB // Reference to auth/session DB.
+	ID    string
+	Data  map[string]any
+	store Store
 }
 
 // Checks if session data contains a user ID.
@@ -60,21 +62,28 @@ func StartSession(db *sql.DB, w http.ResponseWriter, r *http.Request) (*Session,
 		return nil, fmt.Errorf("failed to start session: %w", err)
 	}
 
-	id, err := generateUniqueID(db)
+	st := getStore(db)
+	id, err := st.NewID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to start session: %w", err)
 	}
 
-	setCookie(w, id)
+	setCookie(w, r, id)
 
 	s := Session{
-		ID:   id,
-		Data: make(map[string]any),
-		db:   db,
+		ID:    id,
+		Data:  make(map[string]any),
+		store: st,
 	}
 	return &s, nil
 }
 
+// Implemented by response writers that want to know who made the request
+// (e.g. for access logs).
+type userRecorder interface {
+	RecordUser(username string)
+}
+
 // Resumes an existing (valid) session.
 // If there's none, returns an error.
 func ResumeSession(db *sql.DB, w http.ResponseWriter, r *http.Request) (*Session, error) {
@@ -83,15 +92,29 @@ func ResumeSession(db *sql.DB, w http.ResponseWriter, r *http.Request) (*Session
 		return nil, fmt.Errorf("failed to resume session: %w", err)
 	}
 
-	if err := validateCookie(db, c); err != nil {
+	st := getStore(db)
+	if err := validateCookie(st, c); err != nil {
 		_ = EndSession(db, w, r)
 		return nil, fmt.Errorf("failed to resume session: %w", err)
 	}
 
 	s := Session{
-		ID:   c.Value,
-		Data: getData(db, c.Value),
-		db:   db,
+		ID:    c.Value,
+		Data:  st.Data(c.Value),
+		store: st,
+	}
+	if s.IsSignedIn() && !canStaySignedIn(db, s.Data) {
+		// The user has been deleted, deactivated or merged into another
+		// account since signing in.
+		_ = EndSession(db, w, r)
+		return nil, errors.New("failed to resume session: user can't sign in")
+	}
+	if s.IsSignedIn() {
+		// Not being able to record activity shouldn't sign the user out.
+		_ = recordLastSeen(db, s.Data["userID"].(int), time.Now())
+	}
+	if rec, ok := w.(userRecorder); ok && s.IsSignedIn() {
+		rec.RecordUser(s.Data["username"].(string))
 	}
 	return &s, nil
 }
@@ -105,6 +128,41 @@ func StartOrResumeSession(db *sql.DB, w http.ResponseWriter, r *http.Request) (*
 	return StartSession(db, w, r)
 }
 
+// Replaces the session ID, and therefore the CSRF token, but keeps the session
+// data.
+// Should be called on privilege changes (e.g. after signing in), so that IDs
+// and tokens from before the change can't be reused.
+// Also saves the session data.
+func RotateSession(db *sql.DB, w http.ResponseWriter, r *http.Request, s *Session) error {
+	st := getStore(db)
+	id, err := st.NewID()
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	old := s.ID
+	s.ID = id
+	s.store = st
+	if err := st.Save(s); err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if err := st.Delete(old); err != nil {
+		return fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	setCookie(w, r, id)
+	return nil
+}
+
+// Signs the user out of all sessions.
+// Should be called when the user gets deactivated, deleted or renamed.
+func DeleteUserSessions(db *sql.DB, userID int) error {
+	if err := getStore(db).DeleteUserSessions(userID); err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	return nil
+}
+
 // Ends a session.
 // Does nothing if there's no client session cookie.
 func EndSession(db *sql.DB, w http.ResponseWriter, r *http.Request) error {
@@ -115,11 +173,11 @@ func EndSession(db *sql.DB, w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Deleting empty ID doesn't delete any specific session, but deletes stale sessions.
-	if err := deleteID(db, id); err != nil {
+	if err := getStore(db).Delete(id); err != nil {
 		return fmt.Errorf("failed to end session: %w", err)
 	}
 
 	// Deletes the cookie whether valid or not.
-	deleteCookie(w)
+	deleteCookie(w, r)
 	return nil
 }
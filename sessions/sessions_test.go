@@ -0,0 +1,136 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sessions
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRotateSession(t *testing.T) {
+	// Session should get a new ID and CSRF token, but keep its data.
+	t.Parallel()
+
+	db := testDB()
+	defer db.Close()
+	disableForeignKeys(db)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	s, err := StartSession(db, httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	old := s.ID
+	s.Data["userID"] = 123
+	s.Data["username"] = "foobar"
+
+	w := httptest.NewRecorder()
+	if err := RotateSession(db, w, r, s); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if s.ID == old || CSRFToken(s.ID) == CSRFToken(old) {
+		t.Fatal("expected new session ID and CSRF token:", s.ID, old)
+	}
+
+	st := getStore(db)
+	if err := st.Validate(old); err == nil {
+		t.Fatal("expected old session to be invalid")
+	}
+	if data := st.Data(s.ID); data["username"] != "foobar" {
+		t.Fatal("expected session data to be kept:", data)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != s.ID {
+		t.Fatal("expected cookie with new session ID:", cookies)
+	}
+}
+
+// Starts session signed in as the user, and returns request with the session
+// cookie.
+func signIn(t *testing.T, db *sql.DB, userID int, username string) *http.Request {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	s, err := StartSession(db, w, httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	s.Data["userID"] = userID
+	s.Data["username"] = username
+	if err := SaveData(db, s); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	// The first cookie deletes the previous session.
+	cookies := w.Result().Cookies()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[len(cookies)-1])
+	return r
+}
+
+func TestResumeSessionOfInactiveUser(t *testing.T) {
+	t.Parallel()
+
+	db := testDB()
+	defer db.Close()
+
+	query := `INSERT INTO user (id, username, password) VALUES (1, 'foo', 'x')`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	r := signIn(t, db, 1, "foo")
+	if _, err := ResumeSession(db, httptest.NewRecorder(), r); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if _, err := db.Exec(`UPDATE user SET active = 0 WHERE id = 1`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := ResumeSession(db, httptest.NewRecorder(), r); err == nil {
+		t.Fatal("expected session of inactive user to be rejected")
+	}
+}
+
+func TestResumeSessionOfReusedUserID(t *testing.T) {
+	// Sessions of deleted users shouldn't act as new users with the same ID.
+	t.Parallel()
+
+	db := testDB()
+	defer db.Close()
+
+	query := `INSERT INTO user (id, username, password) VALUES (1, 'bar', 'x')`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	disableForeignKeys(db)
+	r := signIn(t, db, 1, "foo")
+	if _, err := ResumeSession(db, httptest.NewRecorder(), r); err == nil {
+		t.Fatal("expected session of deleted user to be rejected")
+	}
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	t.Parallel()
+
+	db := testDB()
+	defer db.Close()
+
+	query := `INSERT INTO user (id, username, password) VALUES (1, 'foo', 'x')`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	r := signIn(t, db, 1, "foo")
+	if err := DeleteUserSessions(db, 1); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := ResumeSession(db, httptest.NewRecorder(), r); err == nil {
+		t.Fatal("expected session to be deleted")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package sessions
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Storage for session records and messages.
+// By default, sessions are stored in the auth DB. Use `UseStore` to share
+// sessions between server instances (e.g. using Redis).
+type Store interface {
+	// Generates and reserves an unused session ID.
+	NewID() (string, error)
+
+	// Returns an error if the session doesn't exist or has expired.
+	Validate(id string) error
+
+	// Returns an empty map if the session doesn't exist.
+	Data(id string) map[string]any
+
+	// Saves session data. Doesn't create missing sessions.
+	Save(s *Session) error
+
+	// Deletes session. Also deletes expired sessions.
+	Delete(id string) error
+
+	// Deletes all sessions of the user (i.e. signs the user out everywhere).
+	DeleteUserSessions(userID int) error
+
+	// Saves message for the session.
+	AddMessage(id string, m Message) error
+
+	// Returns and deletes the session's messages in the given contexts.
+	PopMessages(id string, contexts []string) ([]Message, error)
+}
+
+// Store set by `UseStore`.
+var store Store
+
+// Makes sessions use the store instead of the auth DB.
+// Should be called before the server starts handling requests.
+func UseStore(s Store) {
+	store = s
+}
+
+// Returns store set by `UseStore`, or the auth DB store.
+func getStore(db *sql.DB) Store {
+	if store != nil {
+		return store
+	}
+	return sqlStore{db: db}
+}
+
+// Session store in the auth DB.
+type sqlStore struct {
+	db *sql.DB
+}
+
+func (s sqlStore) NewID() (string, error) {
+	return generateUniqueID(s.db)
+}
+
+func (s sqlStore) Validate(id string) error {
+	// TODO scan expiry date for checking
+	query := `SELECT session_id FROM user_session WHERE session_id = ?`
+	if err := s.db.QueryRow(query, id).Scan(&id); err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+	return nil
+}
+
+func (s sqlStore) Data(id string) map[string]any {
+	return getData(s.db, id)
+}
+
+func (s sqlStore) Save(session *Session) error {
+	query := `UPDATE user_session SET user_id = ?, username = ?, updated = unixepoch('now') WHERE session_id = ?`
+	_, err := s.db.Exec(query, session.Data["userID"], session.Data["username"], session.ID)
+	return err
+}
+
+func (s sqlStore) Delete(id string) error {
+	return deleteID(s.db, id)
+}
+
+func (s sqlStore) DeleteUserSessions(userID int) error {
+	query := `DELETE FROM user_session WHERE user_id = ?`
+	_, err := s.db.Exec(query, userID)
+	return err
+}
+
+func (s sqlStore) AddMessage(id string, m Message) error {
+	query := `
+		INSERT INTO message (session_id, message, kind, context)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, id, m.Message, m.Kind, m.Context)
+	return err
+}
+
+func (s sqlStore) PopMessages(id string, contexts []string) ([]Message, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	messages, err := getMessages(tx, id, contexts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := deleteMessages(tx, id, contexts...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+// Keeps SQLite databases in a local directory in sync with a storage.
+// Databases that get deleted from the local directory (e.g. when a user gets
+// deleted) also get deleted from the storage on the next push, as long as they
+// were pushed or pulled by the same mirror.
+type Mirror struct {
+	Storage Storage
+	Dir     string
+
+	mu     sync.Mutex
+	pushed map[string]time.Time // Modification times of pushed files
+}
+
+func NewMirror(s Storage, dir string) *Mirror {
+	return &Mirror{
+		Storage: s,
+		Dir:     dir,
+		pushed:  make(map[string]time.Time),
+	}
+}
+
+// Downloads all databases from the storage into the local directory.
+// Overwrites existing local files.
+func (m *Mirror) Pull(ctx context.Context) error {
+	keys, err := m.Storage.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to pull databases: %w", err)
+	}
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".db") {
+			continue
+		}
+		if err := m.pull(ctx, key); err != nil {
+			return fmt.Errorf("failed to pull databases: %w", err)
+		}
+	}
+	return nil
+}
+
+// Returns local path of the object.
+// Returns an error if the key would be outside the local directory.
+func (m *Mirror) localPath(key string) (string, error) {
+	path := filepath.Join(m.Dir, filepath.FromSlash(key))
+	rel, err := filepath.Rel(m.Dir, path)
+	if err != nil || filepath.IsAbs(key) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return path, nil
+}
+
+func (m *Mirror) pull(ctx context.Context, key string) error {
+	dest, err := m.localPath(key)
+	if err != nil {
+		return err
+	}
+
+	r, err := m.Storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return err
+	}
+
+	// Write to temporary file first, so that the local database is never
+	// partially overwritten.
+	f, err := os.CreateTemp(filepath.Dir(dest), ".pull-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Journal files of the old database don't belong to the pulled one.
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dest + suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(f.Name(), dest); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.pushed[dest] = info.ModTime()
+	m.mu.Unlock()
+	return nil
+}
+
+// Returns latest modification time of database, including its WAL file.
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := info.ModTime()
+	if wal, err := os.Stat(path + "-wal"); err == nil && wal.ModTime().After(t) {
+		t = wal.ModTime()
+	}
+	return t, nil
+}
+
+// Uploads databases in the local directory that changed since the last push,
+// and deletes databases that no longer exist locally from the storage.
+func (m *Mirror) Push(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := make(map[string]bool)
+	err := filepath.WalkDir(m.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".db" {
+			return nil
+		}
+		found[path] = true
+
+		t, err := modTime(path)
+		if err != nil {
+			return err
+		}
+		if pushed, ok := m.pushed[path]; ok && !t.After(pushed) {
+			return nil
+		}
+
+		if err := m.push(ctx, path); err != nil {
+			return err
+		}
+		m.pushed[path] = t
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push databases: %w", err)
+	}
+
+	for path := range m.pushed {
+		if found[path] {
+			continue
+		}
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		rel, err := filepath.Rel(m.Dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to push databases: %w", err)
+		}
+		if err := m.Storage.Delete(ctx, filepath.ToSlash(rel)); err != nil {
+			return fmt.Errorf("failed to push databases: %w", err)
+		}
+		delete(m.pushed, path)
+	}
+	return nil
+}
+
+// Uploads consistent copy of database.
+func (m *Mirror) push(ctx context.Context, path string) error {
+	rel, err := filepath.Rel(m.Dir, path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "polycloze-push-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	snapshot := filepath.Join(tmp, "snapshot.db")
+	if err := database.Backup(path, snapshot); err != nil {
+		return err
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return m.Storage.Put(ctx, filepath.ToSlash(rel), f, info.Size())
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/polycloze/polycloze/database"
+)
+
+func TestMirrorPushPull(t *testing.T) {
+	// Databases pushed from one directory should be pulled into another.
+	t.Parallel()
+
+	ctx := context.Background()
+	remote := Dir(t.TempDir())
+	src := NewMirror(remote, t.TempDir())
+	dest := NewMirror(remote, t.TempDir())
+
+	path := filepath.Join(src.Dir, "users", "1", "user.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db, err := database.OpenUserDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.Close()
+
+	if err := src.Push(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	keys, err := remote.List(ctx, "users/")
+	if err != nil || len(keys) != 1 || keys[0] != "users/1/user.db" {
+		t.Fatal("expected pushed database in storage:", keys, err)
+	}
+
+	if err := dest.Pull(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db, err = database.OpenUserDB(filepath.Join(dest.Dir, "users", "1", "user.db"))
+	if err != nil {
+		t.Fatal("expected pulled database to be usable:", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM preferences`).Scan(&count); err != nil {
+		t.Fatal("expected pulled database to be migrated:", err)
+	}
+}
+
+func TestMirrorPushDeletesRemovedDatabases(t *testing.T) {
+	// Deleted databases shouldn't come back on the next pull.
+	t.Parallel()
+
+	ctx := context.Background()
+	remote := Dir(t.TempDir())
+	m := NewMirror(remote, t.TempDir())
+
+	path := filepath.Join(m.Dir, "users", "1", "user.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db, err := database.OpenUserDB(path)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	db.Close()
+
+	if err := m.Push(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := os.RemoveAll(filepath.Join(m.Dir, "users", "1")); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := m.Push(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	keys, err := remote.List(ctx, "")
+	if err != nil || len(keys) != 0 {
+		t.Fatal("expected database to be deleted from storage:", keys, err)
+	}
+}
+
+func TestMirrorLocalPath(t *testing.T) {
+	t.Parallel()
+
+	m := NewMirror(Dir(t.TempDir()), t.TempDir())
+	if _, err := m.localPath("../escaped.db"); err == nil {
+		t.Fatal("expected key outside the directory to be rejected")
+	}
+	if _, err := m.localPath("users/1/user.db"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+}
+
+func TestMirrorPullRemovesStaleJournal(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	remote := Dir(t.TempDir())
+	m := NewMirror(remote, t.TempDir())
+
+	if err := remote.Put(ctx, "auth.db", strings.NewReader(""), 0); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	wal := filepath.Join(m.Dir, "auth.db-wal")
+	if err := os.WriteFile(wal, []byte("stale"), 0o600); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := m.Pull(ctx); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := os.Stat(wal); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected stale WAL file to be removed:", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Blob storage for user data.
+// The server still opens SQLite files from the local state directory; the
+// storage is used to restore the files on startup and to save changes, so that
+// the local directory can be thrown away (e.g. in containers).
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type Storage interface {
+	// Returns contents of object.
+	// Returns ErrNotFound if the object doesn't exist.
+	// The caller has to Close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Creates or replaces object.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Returns keys of objects that start with the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Deletes object.
+	// Doesn't return an error if the object doesn't exist.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package text
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rule for normalizing answers before comparison.
+type Replacement struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+var (
+	answerRulesMu sync.RWMutex
+
+	// Answer normalization rules by language (ISO 639-3), for languages typed
+	// with input methods or with optional characters.
+	answerRules = map[string][]Replacement{
+		// Arabic: hamza forms, alef maqsura and short vowel marks.
+		"ara": {
+			{From: "أ", To: "ا"},
+			{From: "إ", To: "ا"},
+			{From: "آ", To: "ا"},
+			{From: "ؤ", To: "و"},
+			{From: "ئ", To: "ي"},
+			{From: "ى", To: "ي"},
+			{From: "ً", To: ""}, // Fathatan
+			{From: "ٌ", To: ""}, // Dammatan
+			{From: "ٍ", To: ""}, // Kasratan
+			{From: "َ", To: ""}, // Fatha
+			{From: "ُ", To: ""}, // Damma
+			{From: "ِ", To: ""}, // Kasra
+			{From: "ّ", To: ""}, // Shadda
+			{From: "ْ", To: ""}, // Sukun
+		},
+
+		// German: ß can be typed as ss.
+		"deu": {
+			{From: "ß", To: "ss"},
+			{From: "ẞ", To: "SS"},
+		},
+
+		// Japanese: IMEs disagree on long vowel marks and wave dashes.
+		"jpn": {
+			{From: "ー", To: ""},
+			{From: "〜", To: "~"},
+		},
+	}
+)
+
+// Returns answer normalization rules for the language (ISO 639-3).
+func AnswerRules(lang string) []Replacement {
+	answerRulesMu.RLock()
+	defer answerRulesMu.RUnlock()
+	return append([]Replacement(nil), answerRules[lang]...)
+}
+
+// Replaces answer normalization rules for the language (ISO 639-3).
+// Pass nil to remove the rules.
+func SetAnswerRules(lang string, rules []Replacement) {
+	answerRulesMu.Lock()
+	defer answerRulesMu.Unlock()
+	if rules == nil {
+		delete(answerRules, lang)
+		return
+	}
+	answerRules[lang] = append([]Replacement(nil), rules...)
+}
+
+// Normalizes answer in the language before comparison.
+// Applies NFKC normalization (e.g. to fold full-width characters typed with
+// IMEs), and then the language's rules (see `AnswerRules`).
+// NOTE Clients apply the same steps before grading answers, so any changes
+// here should be reflected in `normalize` in api/js/src/blank.ts.
+func NormalizeAnswer(lang, s string) string {
+	s = norm.NFKC.String(s)
+	for _, rule := range AnswerRules(lang) {
+		s = strings.ReplaceAll(s, rule.From, rule.To)
+	}
+	return s
+}
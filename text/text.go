@@ -5,6 +5,7 @@ package text
 
 import (
 	"strings"
+	"unicode"
 
 	"golang.org/x/text/cases"
 )
@@ -37,5 +38,9 @@ func Casefold(s string) string {
 		s = strings.TrimSuffix(s, noBreakSpace)
 	}
 
+	// Phrases match regardless of the whitespace between words.
+	if strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		s = strings.Join(strings.Fields(s), " ")
+	}
 	return caser.String(s)
 }
@@ -4,6 +4,7 @@
 package text
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -81,3 +82,96 @@ func TestRemoveNoBreakSpace(t *testing.T) {
 		t.Fatal("expected no-break space in the middle to be left alone")
 	}
 }
+
+func TestWords(t *testing.T) {
+	t.Parallel()
+
+	words := Words("Don't stop—it's an e-mail, not 2 letters!")
+	expected := []string{"Don't", "stop", "it's", "an", "e-mail", "not", "2", "letters"}
+	if len(words) != len(expected) {
+		t.Fatal("unexpected words:", words)
+	}
+	for i := range expected {
+		if words[i] != expected[i] {
+			t.Fatal("unexpected words:", words)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	s := "Hello, it's me."
+	tokens := Tokenize(s)
+	expected := []string{"Hello", ", ", "it's", " ", "me", "."}
+	if strings.Join(tokens, "") != s || len(tokens) != len(expected) {
+		t.Fatal("unexpected tokens:", tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Fatal("unexpected tokens:", tokens)
+		}
+	}
+}
+
+func TestSentences(t *testing.T) {
+	t.Parallel()
+
+	sentences := Sentences("Hi! Is it 3.5 km away?\nYes... it is")
+	expected := []string{"Hi!", "Is it 3.5 km away?", "Yes...", "it is"}
+	if len(sentences) != len(expected) {
+		t.Fatal("unexpected sentences:", sentences)
+	}
+	for i := range expected {
+		if sentences[i] != expected[i] {
+			t.Fatal("unexpected sentences:", sentences)
+		}
+	}
+}
+
+func TestNormalizeAnswer(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		lang     string
+		a        string
+		b        string
+		expected bool
+	}{
+		{"deu", "Straße", "Strasse", true},
+		{"ara", "أحمد", "احمد", true},
+		{"jpn", "コーヒー", "コヒ", true},
+		{"eng", "ｆｏｏ", "foo", true}, // Full-width
+		{"eng", "Straße", "Strasse", false},
+	}
+	for _, c := range cases {
+		equal := NormalizeAnswer(c.lang, c.a) == NormalizeAnswer(c.lang, c.b)
+		if equal != c.expected {
+			t.Fatal("unexpected answer normalization:", c.lang, c.a, c.b)
+		}
+	}
+}
+
+func TestCasefoldPhrase(t *testing.T) {
+	// Whitespace between words in phrases shouldn't matter.
+	t.Parallel()
+
+	if s := Casefold("Por   Favor"); s != "por favor" {
+		t.Fatal("expected `s` to be 'por favor':", s)
+	}
+}
+
+func TestFindSpans(t *testing.T) {
+	t.Parallel()
+
+	tokens := []string{"A", " ", "lot", " ", "of", " ", "a", "  ", "lot", "."}
+	spans := FindSpans(tokens, "a lot")
+	if len(spans) != 2 || spans[0] != [2]int{0, 3} || spans[1] != [2]int{6, 9} {
+		t.Fatal("unexpected spans:", spans)
+	}
+
+	spans = FindSpans(tokens, "lot")
+	if len(spans) != 2 || spans[0] != [2]int{2, 3} {
+		t.Fatal("unexpected spans:", spans)
+	}
+}
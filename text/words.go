@@ -0,0 +1,131 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Checks if the rune can be part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+// Checks if the rune can join two parts of a word (e.g. "don't", "e-mail").
+func isJoiner(r rune) bool {
+	return r == '\'' || r == '’' || r == '-'
+}
+
+// Checks if the rune ends a sentence.
+func isTerminator(r rune) bool {
+	return strings.ContainsRune(".!?…。！？", r)
+}
+
+// Checks if the token is a word.
+func IsWord(token string) bool {
+	for _, r := range token {
+		return isWordRune(r)
+	}
+	return false
+}
+
+// Splits text into tokens.
+// Concatenating the tokens gives back the original text.
+// This is only an approximation of the tokenizer used to build courses, so
+// results can differ for some languages (e.g. languages without spaces).
+func Tokenize(s string) []string {
+	var tokens []string
+	var token strings.Builder
+	inWord := false
+
+	runes := []rune(s)
+	for i, r := range runes {
+		wordRune := isWordRune(r) ||
+			(inWord && isJoiner(r) && i+1 < len(runes) && isWordRune(runes[i+1]))
+		if wordRune != inWord && token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+		inWord = wordRune
+		token.WriteRune(r)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+// Splits text into words.
+// Punctuation and whitespace are dropped.
+func Words(s string) []string {
+	var words []string
+	for _, token := range Tokenize(s) {
+		if IsWord(token) {
+			words = append(words, token)
+		}
+	}
+	return words
+}
+
+// Splits text into sentences.
+// Sentences end at line breaks, or at terminal punctuation followed by a space.
+func Sentences(s string) []string {
+	var sentences []string
+	var sentence strings.Builder
+
+	flush := func() {
+		if v := strings.TrimSpace(sentence.String()); v != "" {
+			sentences = append(sentences, v)
+		}
+		sentence.Reset()
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		sentence.WriteRune(r)
+		if isTerminator(r) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			flush()
+		}
+	}
+	flush()
+	return sentences
+}
+
+// Checks if the token only contains whitespace.
+func isSpace(token string) bool {
+	return strings.TrimFunc(token, unicode.IsSpace) == ""
+}
+
+// Finds spans of tokens that match the word or phrase.
+// `normalized` should already be casefolded (see `Casefold`).
+// Returns start and end (exclusive) indices of the spans.
+// Phrases (e.g. "por favor") can span multiple tokens, and the whitespace
+// between words doesn't have to match.
+func FindSpans(tokens []string, normalized string) [][2]int {
+	var spans [][2]int
+	for i := range tokens {
+		if isSpace(tokens[i]) {
+			continue
+		}
+
+		var b strings.Builder
+		for j := i; j < len(tokens); j++ {
+			b.WriteString(tokens[j])
+			s := Casefold(b.String())
+			if s == normalized && !isSpace(tokens[j]) {
+				spans = append(spans, [2]int{i, j + 1})
+				break
+			}
+			if !strings.HasPrefix(normalized, s) {
+				break
+			}
+		}
+	}
+	return spans
+}
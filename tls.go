@@ -0,0 +1,93 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/polycloze/polycloze/basedir"
+)
+
+type TLSArgs struct {
+	certFile string
+	keyFile  string
+
+	// Comma-separated list of domains for ACME (e.g. Let's Encrypt).
+	domains string
+	email   string
+
+	// Address of HTTP server that redirects to HTTPS (e.g. ":80").
+	redirect string
+}
+
+func (ta TLSArgs) enabled() bool {
+	return ta.certFile != "" || ta.keyFile != "" || ta.domains != ""
+}
+
+// Returns TLS config for the server, and the handler for plain HTTP requests.
+// `port` is the port of the HTTPS server.
+// Returns nil config if TLS is disabled.
+func configureTLS(ta TLSArgs, port int) (*tls.Config, http.Handler, error) {
+	if !ta.enabled() {
+		return nil, nil, nil
+	}
+
+	if ta.domains != "" {
+		if ta.certFile != "" || ta.keyFile != "" {
+			return nil, nil, errors.New("can't use ACME with certificate files")
+		}
+
+		var domains []string
+		for _, domain := range strings.Split(ta.domains, ",") {
+			domains = append(domains, strings.TrimSpace(domain))
+		}
+
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(filepath.Join(basedir.StateDir, "autocert")),
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Email:      ta.email,
+		}
+		// Also answers ACME HTTP-01 challenges.
+		return m.TLSConfig(), m.HTTPHandler(redirectHTTPS(port)), nil
+	}
+
+	if ta.certFile == "" || ta.keyFile == "" {
+		return nil, nil, errors.New("missing TLS certificate or key file")
+	}
+	cert, err := tls.LoadX509KeyPair(ta.certFile, ta.keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return config, redirectHTTPS(port), nil
+}
+
+// Redirects HTTP requests to the HTTPS server on `port`.
+func redirectHTTPS(port int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		port   int
+		host   string
+		target string
+	}{
+		// Redirects to the port of the HTTPS server.
+		{3000, "example.com", "https://example.com:3000/foo?bar=1"},
+		{3000, "example.com:80", "https://example.com:3000/foo?bar=1"},
+		{3000, "[::1]:80", "https://[::1]:3000/foo?bar=1"},
+
+		// Leaves out the default port.
+		{443, "example.com:80", "https://example.com/foo?bar=1"},
+		{443, "[::1]:80", "https://[::1]/foo?bar=1"},
+	} {
+		r := httptest.NewRequest("GET", "/foo?bar=1", nil)
+		r.Host = c.host
+		w := httptest.NewRecorder()
+		redirectHTTPS(c.port)(w, r)
+
+		if location := w.Header().Get("Location"); location != c.target {
+			t.Fatal("unexpected redirect:", c.port, c.host, location)
+		}
+	}
+}
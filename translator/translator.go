@@ -19,7 +19,10 @@ type Translation struct {
 func Translate[T database.Querier](q T, sentence sentences.Sentence) (Translation, error) {
 	var translation Translation
 
-	if sentence.TatoebaID <= 0 {
+	// Sentences without a TatoebaID get -1 (see `sentences.PickSentence`).
+	// Other negative IDs are used by bundled sentences that aren't from
+	// Tatoeba, but still have translations (see package sample_course).
+	if sentence.TatoebaID == 0 || sentence.TatoebaID == -1 {
 		return translation, errors.New("sentence has no TatoebaID")
 	}
 
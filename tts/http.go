@@ -0,0 +1,60 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Synthesizes speech using an HTTP API.
+// Sends a POST request with the JSON body `{"lang": ..., "text": ...}` and
+// expects the audio file in the response body.
+// Use this to bridge cloud TTS services.
+type HTTP struct {
+	URL    string
+	APIKey string // Sent as bearer token if non-empty
+
+	Client *http.Client // Uses http.DefaultClient if nil
+}
+
+func (h HTTP) Synthesize(ctx context.Context, lang, text string) (Audio, error) {
+	body, err := json.Marshal(map[string]string{"lang": lang, "text": text})
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to encode TTS request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to create TTS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Audio{}, fmt.Errorf("TTS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Audio{}, fmt.Errorf("TTS request failed: %v", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Audio{}, fmt.Errorf("failed to read TTS response: %w", err)
+	}
+	return Audio{Data: data, ContentType: resp.Header.Get("Content-Type")}, nil
+}
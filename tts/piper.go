@@ -0,0 +1,37 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package tts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Synthesizes speech locally using Piper (https://github.com/rhasspy/piper).
+type Piper struct {
+	Binary string // Path to piper executable
+
+	// Directory of voice models.
+	// Each model should be named after the ISO 639-3 code of the language
+	// (e.g. "deu.onnx").
+	ModelDir string
+}
+
+func (p Piper) Synthesize(ctx context.Context, lang, text string) (Audio, error) {
+	model := path.Join(p.ModelDir, lang+".onnx")
+	cmd := exec.CommandContext(ctx, p.Binary, "--model", model, "--output_file", "-")
+	cmd.Stdin = strings.NewReader(text)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Audio{}, fmt.Errorf("piper failed: %w: %v", err, stderr.String())
+	}
+	return Audio{Data: stdout.Bytes(), ContentType: "audio/wav"}, nil
+}
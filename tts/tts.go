@@ -0,0 +1,226 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Text-to-speech for sentences without recordings.
+package tts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Audio struct {
+	Data        []byte
+	ContentType string // e.g. "audio/wav"
+}
+
+// Converts text into speech.
+// lang: ISO 639-3 code
+type Synthesizer interface {
+	Synthesize(ctx context.Context, lang, text string) (Audio, error)
+}
+
+// Synthesizer that runs at most a fixed number of syntheses at a time.
+type limited struct {
+	synthesizer Synthesizer
+	slots       chan struct{}
+}
+
+// Returns synthesizer that runs at most n syntheses at a time (e.g. so that
+// requests can't start an unbounded number of Piper processes).
+// Other calls wait for a free slot, or until their context is done.
+func Limit(synthesizer Synthesizer, n int) Synthesizer {
+	return limited{
+		synthesizer: synthesizer,
+		slots:       make(chan struct{}, n),
+	}
+}
+
+func (l limited) Synthesize(ctx context.Context, lang, text string) (Audio, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Audio{}, fmt.Errorf("failed to wait for synthesizer: %w", ctx.Err())
+	}
+	defer func() { <-l.slots }()
+	return l.synthesizer.Synthesize(ctx, lang, text)
+}
+
+// Stores synthesized speech on disk, so that each text only gets synthesized
+// once.
+type Cache struct {
+	Synthesizer Synthesizer
+
+	// Returns directory for cached files in the language.
+	Dir func(lang string) string
+
+	// Max total size (in bytes) of cached files in Root.
+	// Least recently used files get deleted when the cache gets bigger than
+	// this.
+	// Zero means unlimited.
+	MaxSize int64
+
+	// Directory that contains the language directories (see Dir).
+	// Only needed if MaxSize is set.
+	Root string
+}
+
+// Audio formats that may not be in the system's MIME table.
+var extensions = map[string]string{
+	"audio/mpeg": ".mp3",
+	"audio/ogg":  ".ogg",
+	"audio/wav":  ".wav",
+	"audio/webm": ".webm",
+}
+
+// Returns file extension for the content type.
+func extension(contentType string) string {
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+	if ext, ok := extensions[mediatype]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediatype); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".audio"
+}
+
+// Returns content type of audio file based on its extension.
+func ContentType(file string) string {
+	ext := filepath.Ext(file)
+	for contentType, e := range extensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// Returns cache key of text.
+func key(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Returns path to cached file, or an empty string if it's not in the cache.
+func (c Cache) lookup(lang, text string) string {
+	matches, err := filepath.Glob(path.Join(c.Dir(lang), key(text)+".*"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// Returns path to file containing the synthesized speech.
+// Synthesizes the text if it's not in the cache.
+func (c Cache) Get(ctx context.Context, lang, text string) (string, error) {
+	if file := c.lookup(lang, text); file != "" {
+		if c.MaxSize > 0 {
+			// Mark as recently used.
+			now := time.Now()
+			_ = os.Chtimes(file, now, now)
+		}
+		return file, nil
+	}
+
+	audio, err := c.Synthesizer.Synthesize(ctx, lang, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+
+	ext := extension(audio.ContentType)
+	dir := c.Dir(lang)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to cache speech: %w", err)
+	}
+
+	// Write to temporary file first so that concurrent requests never see
+	// partially written files.
+	tmp, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to cache speech: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(audio.Data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to cache speech: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to cache speech: %w", err)
+	}
+
+	file := path.Join(dir, key(text)+ext)
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", fmt.Errorf("failed to cache speech: %w", err)
+	}
+
+	if c.MaxSize > 0 {
+		if _, err := Evict(c.Root, c.MaxSize, file); err != nil {
+			return "", fmt.Errorf("failed to cache speech: %w", err)
+		}
+	}
+	return file, nil
+}
+
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Deletes least recently used (by modification time) files in dir until the
+// total size is at most maxSize.
+// Never deletes `keep` (e.g. the file that was just added).
+// Returns the number of deleted bytes.
+func Evict(dir string, maxSize int64, keep string) (int64, error) {
+	var files []cachedFile
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		// Skip files that are still being written.
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), "tmp-") {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cachedFile{path: p, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to evict cached speech: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var deleted int64
+	for _, f := range files {
+		if total <= maxSize {
+			break
+		}
+		if filepath.Clean(f.path) == filepath.Clean(keep) {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to evict cached speech: %w", err)
+		}
+		total -= f.size
+		deleted += f.size
+	}
+	return deleted, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package tts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = w.Write([]byte("RIFF"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache := Cache{
+		Synthesizer: HTTP{URL: server.URL},
+		Dir:         func(lang string) string { return dir },
+	}
+
+	for i := 0; i < 2; i++ {
+		file, err := cache.Get(context.Background(), "deu", "Hallo!")
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		if string(data) != "RIFF" {
+			t.Fatal("unexpected cached data:", string(data))
+		}
+	}
+
+	if calls != 1 {
+		t.Fatal("expected speech to be synthesized once:", calls)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"audio/wav":                ".wav",
+		"audio/mpeg":               ".mp3",
+		"audio/ogg; codecs=opus":   ".ogg",
+		"application/x-nonsense42": ".audio",
+	}
+	for contentType, ext := range cases {
+		if v := extension(contentType); v != ext {
+			t.Fatal("unexpected extension:", contentType, v)
+		}
+		if ext != ".audio" && ContentType("foo"+ext) == "" {
+			t.Fatal("expected content type to be non-empty:", ext)
+		}
+	}
+}
+
+func TestEvict(t *testing.T) {
+	// Least recently used files should be deleted first.
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old", "new", "newest"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte("1234"), 0o644); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	deleted, err := Evict(dir, 8, filepath.Join(dir, "newest"))
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if deleted != 4 {
+		t.Fatal("expected one file to be deleted:", deleted)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); err == nil {
+		t.Fatal("expected oldest file to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); err != nil {
+		t.Fatal("expected newer file to be kept:", err)
+	}
+}
+
+// Synthesizer that blocks until released.
+type blockingSynthesizer struct {
+	release chan struct{}
+}
+
+func (b blockingSynthesizer) Synthesize(ctx context.Context, lang, text string) (Audio, error) {
+	<-b.release
+	return Audio{Data: []byte("RIFF"), ContentType: "audio/wav"}, nil
+}
+
+func TestLimit(t *testing.T) {
+	// Calls should wait while all slots are in use.
+	t.Parallel()
+
+	b := blockingSynthesizer{release: make(chan struct{})}
+	s := Limit(b, 1)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Synthesize(context.Background(), "deu", "Hallo!")
+		done <- err
+	}()
+
+	// Wait for the first call to take the slot.
+	for len(s.(limited).slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Synthesize(ctx, "deu", "Tschüss!"); err == nil {
+		t.Fatal("expected call to time out while waiting for a slot")
+	}
+
+	close(b.release)
+	if err := <-done; err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := s.Synthesize(context.Background(), "deu", "Tschüss!"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+}
@@ -9,6 +9,10 @@ CREATE TABLE language (
 		code char(3) not null check (length(code) = 3),
 		name text not null
 		, bcp47 text not null);
+CREATE TABLE note (
+		word integer not null references word,
+		text text not null
+		);
 CREATE TABLE sentence (
 		id integer primary key,
 		tatoeba_id integer unique,	-- null for non-tatoeba sentences
@@ -31,4 +35,5 @@ CREATE TABLE word (
 		frequency_class integer not null
 		);
 CREATE INDEX index_contains_word on contains (word);
+CREATE INDEX index_note_word on note (word);
 COMMIT;
@@ -9,7 +9,6 @@ import (
 	"embed"
 	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/polycloze/polycloze/database"
 )
 
@@ -45,24 +45,56 @@ func Wilson(success, fail int, z float64) float64 {
 	return (ns+z2/2)/(n+z2) + (z/(n+z2))*math.Sqrt((ns*nf)/n+z2/4)
 }
 
-func IsTooEasy(correct, incorrect int) bool {
+// Parameters of the Wilson score tests used for tuning.
+type Thresholds struct {
+	// z-score of the one-sided lower bound (should be negative).
+	EasyZ float64 `json:"easyZ"`
+
+	// Too easy if the lower bound is above this value.
+	EasyBound float64 `json:"easyBound"`
+
+	// z-score of the one-sided upper bound (should be positive).
+	HardZ float64 `json:"hardZ"`
+
+	// Too hard if the upper bound is below this value.
+	HardBound float64 `json:"hardBound"`
+}
+
+func DefaultThresholds() Thresholds {
 	// Threshold can't be too high or the tuner will be too conservative.
 	// Only uses 0.85 confidence, higher values require too many samples.
+	// It's too hard to level up with a 0.9 test when incorrect > 0.
+	// 0.85 threshold is chosen so tuner won't trigger with < 5 samples.
+	return Thresholds{
+		EasyZ:     -1.035, // 85% confidence
+		EasyBound: 0.85,
+		HardZ:     3.1, // 99.9% confidence
+		HardBound: 0.8,
+	}
+}
 
-	z := -1.035 // z-score for one-sided confidence interval (85% confidence)
-	lower := Wilson(correct, incorrect, z)
+// Checks if the parameters make sense.
+func (t Thresholds) IsValid() bool {
+	return t.EasyZ < 0 && t.HardZ > 0 &&
+		t.EasyBound > 0 && t.EasyBound < 1 &&
+		t.HardBound > 0 && t.HardBound < 1
+}
 
-	// 85% likelihood that the true proportion is bounded below by `lower`.
-	// It's too hard to level up with a 0.9 test when incorrect > 0.
-	return lower > 0.85
+func (t Thresholds) IsTooEasy(correct, incorrect int) bool {
+	return Wilson(correct, incorrect, t.EasyZ) > t.EasyBound
+}
 
-	// 0.85 threshold is chosen so tuner won't trigger with < 5 samples.
+func (t Thresholds) IsTooHard(correct, incorrect int) bool {
+	return Wilson(correct, incorrect, t.HardZ) < t.HardBound
 }
 
-func IsTooHard(correct, incorrect int) bool {
-	z := 3.1 // z-score for one-sided confidence interval
-	upper := Wilson(correct, incorrect, z)
+// 85% likelihood that the true proportion is bounded below by the lower bound.
+func IsTooEasy(correct, incorrect int) bool {
+	return DefaultThresholds().IsTooEasy(correct, incorrect)
+}
 
-	// 99.9% confident that the true proportion is bounded above by `upper`.
-	return upper < 0.8
+// 99.9% confident that the true proportion is bounded above by the upper
+// bound.
+func IsTooHard(correct, incorrect int) bool {
+	return DefaultThresholds().IsTooHard(correct, incorrect)
 }
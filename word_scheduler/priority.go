@@ -0,0 +1,69 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package word_scheduler
+
+import (
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Bounds of word priorities.
+const (
+	MinPriority = -100
+	MaxPriority = 100
+)
+
+// User's priority override for a new word.
+type Priority struct {
+	Word     string `json:"word"`
+	Priority int    `json:"priority"`
+}
+
+// Sets priority of word in the new word queue.
+// Words with higher priorities are introduced first.
+// Priority 0 removes the override.
+func SetPriority[T database.Querier](q T, word string, priority int) error {
+	word = text.Casefold(word)
+	if priority < MinPriority || priority > MaxPriority {
+		return fmt.Errorf("failed to set priority (%v): invalid value: %v", word, priority)
+	}
+
+	if priority == 0 {
+		if _, err := q.Exec(`DELETE FROM word_priority WHERE item = ?`, word); err != nil {
+			return fmt.Errorf("failed to set priority (%v): %w", word, err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO word_priority (item, priority) VALUES (?, ?)
+		ON CONFLICT (item) DO UPDATE SET priority = excluded.priority
+	`
+	if _, err := q.Exec(query, word, priority); err != nil {
+		return fmt.Errorf("failed to set priority (%v): %w", word, err)
+	}
+	return nil
+}
+
+// Returns user's priority overrides, highest first.
+func GetPriorities[T database.Querier](q T) ([]Priority, error) {
+	query := `SELECT item, priority FROM word_priority ORDER BY priority DESC, item`
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get priorities: %w", err)
+	}
+	defer rows.Close()
+
+	priorities := make([]Priority, 0)
+	for rows.Next() {
+		var p Priority
+		if err := rows.Scan(&p.Word, &p.Priority); err != nil {
+			return nil, fmt.Errorf("failed to get priorities: %w", err)
+		}
+		priorities = append(priorities, p)
+	}
+	return priorities, nil
+}
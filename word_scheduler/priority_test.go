@@ -0,0 +1,51 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package word_scheduler
+
+import (
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	// Boosted words should come first, and deprioritized words last.
+	t.Parallel()
+
+	s := wordScheduler()
+	defer s.Close()
+
+	query := `INSERT INTO word (id, word, frequency_class) VALUES (1, 'a', 0), (2, 'b', 0), (3, 'c', 0)`
+	if _, err := s.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	if err := SetPriority(s, "C", 10); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := SetPriority(s, "a", -5); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := SetPriority(s, "b", 1000); err == nil {
+		t.Fatal("expected invalid priority to be rejected")
+	}
+
+	words, err := GetNewWordsWith(s, 3, 0, func(_ string) bool { return true })
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 3 || words[0].Word != "c" || words[1].Word != "b" || words[2].Word != "a" {
+		t.Fatal("unexpected word order:", words)
+	}
+
+	// Priority 0 removes the override.
+	if err := SetPriority(s, "a", 0); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	priorities, err := GetPriorities(s)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(priorities) != 1 || priorities[0].Word != "c" {
+		t.Fatal("unexpected priorities:", priorities)
+	}
+}
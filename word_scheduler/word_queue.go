@@ -6,7 +6,9 @@ package word_scheduler
 
 import (
 	"database/sql"
+	"fmt"
 
+	"github.com/polycloze/polycloze/coverage"
 	"github.com/polycloze/polycloze/database"
 )
 
@@ -31,14 +33,29 @@ func getNRows(rows *sql.Rows, n int, pred func(word string) bool) ([]Word, error
 	return words, nil
 }
 
-func getWordsAboveDifficultyWith[T database.Querier](q T, n, preferredDifficulty int, pred func(word string) bool) ([]Word, error) {
+// Course words with frequency classes from the user's frequency list (see
+// package frequency_list) and with the user's priority overrides.
+// `rank` is null for words that aren't in the list.
+const customWords = `
+	SELECT
+		word.id AS id,
+		word.word AS word,
+		coalesce(custom_frequency.frequency_class, word.frequency_class) AS frequency_class,
+		custom_frequency.rank AS rank,
+		coalesce(word_priority.priority, 0) AS priority
+	FROM word
+	LEFT JOIN custom_frequency ON (word.word = custom_frequency.item)
+	LEFT JOIN word_priority ON (word.word = word_priority.item)
+`
+
+func getWordsAboveDifficultyWith[T database.Querier](q T, n, preferredDifficulty int, cond string, pred func(word string) bool) ([]Word, error) {
 	query := `
 		SELECT word, frequency_class
-		FROM word
-		WHERE frequency_class >= ? AND word NOT IN (
-			SELECT item FROM review
-		)
-		ORDER BY id ASC
+		FROM (` + customWords + `)
+		WHERE frequency_class >= ? AND NOT EXISTS (
+			SELECT 1 FROM review WHERE item = word
+		) ` + cond + `
+		ORDER BY priority DESC, rank IS NULL, rank ASC, id ASC
 `
 	rows, err := q.Query(query, preferredDifficulty)
 	if err != nil {
@@ -48,14 +65,14 @@ func getWordsAboveDifficultyWith[T database.Querier](q T, n, preferredDifficulty
 	return getNRows(rows, n, pred)
 }
 
-func getWordsBelowDifficultyWith[T database.Querier](q T, n, preferredDifficulty int, pred func(word string) bool) ([]Word, error) {
+func getWordsBelowDifficultyWith[T database.Querier](q T, n, preferredDifficulty int, cond string, pred func(word string) bool) ([]Word, error) {
 	query := `
 		SELECT word, frequency_class
-		FROM word
-		WHERE frequency_class < ? AND word NOT IN (
-			SELECT item FROM review
-		)
-		ORDER BY id DESC
+		FROM (` + customWords + `)
+		WHERE frequency_class < ? AND NOT EXISTS (
+			SELECT 1 FROM review WHERE item = word
+		) ` + cond + `
+		ORDER BY priority DESC, rank IS NULL, rank DESC, id DESC
 `
 	rows, err := q.Query(query, preferredDifficulty)
 	if err != nil {
@@ -65,24 +82,84 @@ func getWordsBelowDifficultyWith[T database.Querier](q T, n, preferredDifficulty
 	return getNRows(rows, n, pred)
 }
 
+// Gets new words in the user's word list, oldest first.
+func getListedWordsWith[T database.Querier](q T, n int, cond string, pred func(word string) bool) ([]Word, error) {
+	query := `
+		SELECT word, frequency_class
+		FROM (` + customWords + `) JOIN word_list ON (word = word_list.item)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM review WHERE item = word
+		) ` + cond + `
+		ORDER BY added ASC, id ASC
+`
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return getNRows(rows, n, pred)
+}
+
 // Gets up to n new words from db.
 // Pass a negative n if you don't want a word limit.
 // Uses preferredDifficulty as minimum word frequency class.
 // If there are not enough words in query result, will also include words below
 // the preferredDifficulty.
 // Only words that satisfy the predicate are included in the result.
+// Words in the user's word list come first.
 func GetNewWordsWith[T database.Querier](q T, n, preferredDifficulty int, pred func(word string) bool) ([]Word, error) {
-	words, err := getWordsAboveDifficultyWith(q, n, preferredDifficulty, pred)
+	return getNewWords(q, n, preferredDifficulty, "", pred)
+}
+
+// Condition on new words that have enough example sentences (see package
+// coverage).
+// Stops counting sentences at the threshold, so that it stays cheap for
+// frequent words.
+var hasEnoughSentences = fmt.Sprintf(
+	`AND (SELECT count(*) FROM (SELECT 1 FROM contains WHERE contains.word = id LIMIT %[1]d)) >= %[1]d`,
+	coverage.MinSentences,
+)
+
+// Same as GetNewWordsWith, but takes an additional SQL condition on words
+// (e.g. `hasEnoughSentences`).
+func getNewWords[T database.Querier](q T, n, preferredDifficulty int, cond string, pred func(word string) bool) ([]Word, error) {
+	words, err := getListedWordsWith(q, n, cond, pred)
+	if err != nil {
+		return nil, err
+	}
+	if n >= 0 && len(words) >= n {
+		return words, nil
+	}
+
+	// Skip words that were already taken from the word list.
+	listed := make(map[string]bool)
+	for _, word := range words {
+		listed[word.Word] = true
+	}
+	unlisted := func(word string) bool {
+		return !listed[word] && pred(word)
+	}
+
+	more, err := getWordsAboveDifficultyWith(q, remaining(n, len(words)), preferredDifficulty, cond, unlisted)
 	if err != nil {
 		return nil, err
 	}
-	if preferredDifficulty <= 0 || len(words) >= n {
+	words = append(words, more...)
+	if preferredDifficulty <= 0 || (n >= 0 && len(words) >= n) {
 		return words, nil
 	}
 
-	more, err := getWordsBelowDifficultyWith(q, n-len(words), preferredDifficulty, pred)
+	more, err = getWordsBelowDifficultyWith(q, remaining(n, len(words)), preferredDifficulty, cond, unlisted)
 	if err != nil {
 		return nil, err
 	}
 	return append(words, more...), nil
 }
+
+// Returns number of words left to fetch (negative if there's no limit).
+func remaining(n, fetched int) int {
+	if n < 0 {
+		return n
+	}
+	return n - fetched
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package word_scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	rs "github.com/polycloze/polycloze/review_scheduler"
+)
+
+func TestGetNewWordsSkipsReviewedWords(t *testing.T) {
+	t.Parallel()
+
+	db := wordScheduler()
+	defer db.Close()
+
+	query := `INSERT INTO word (word, frequency_class) VALUES ('a', 0), ('b', 1), ('c', 2)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := db.Exec(`INSERT INTO word_list (item) VALUES ('a'), ('c')`); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if err := rs.UpdateReview(db, "a", true); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	words, err := GetNewWordsWith(db, 3, 0, func(_ string) bool { return true })
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 2 || words[0].Word != "c" || words[1].Word != "b" {
+		t.Fatal("expected unreviewed words, with listed words first:", words)
+	}
+}
+
+func TestGetWordsFilteredSkipsSparseWords(t *testing.T) {
+	t.Parallel()
+
+	db := wordScheduler()
+	defer db.Close()
+
+	queries := []string{
+		`INSERT INTO word (id, word, frequency_class) VALUES (1, 'a', 0), (2, 'b', 0)`,
+		`INSERT INTO contains (sentence, word) VALUES (1, 1), (2, 1), (3, 1), (1, 2)`,
+		`INSERT INTO word_list (item) VALUES ('b')`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal("expected err to be nil:", err)
+		}
+	}
+
+	words, err := GetWordsFiltered(db, 10, rs.Filter{})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 2 {
+		t.Fatal("expected sparse words to be included by default:", words)
+	}
+
+	words, err = GetWordsFiltered(db, 10, rs.Filter{SkipSparseWords: true})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 1 || words[0].Word != "a" {
+		t.Fatal("expected sparse word to be skipped:", words)
+	}
+}
+
+// Simulates a user who has reviewed half of a large course.
+func BenchmarkGetNewWords(b *testing.B) {
+	db := wordScheduler()
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatal("expected err to be nil:", err)
+	}
+	now := time.Now()
+	for i := 0; i < 20000; i++ {
+		word := fmt.Sprintf("word%v", i)
+		query := `INSERT INTO word (word, frequency_class) VALUES (?, ?)`
+		if _, err := tx.Exec(query, word, i/1000); err != nil {
+			b.Fatal("expected err to be nil:", err)
+		}
+		if i%2 == 0 {
+			query := `INSERT INTO review (item, learned, reviewed, interval) VALUES (?, ?, ?, 24)`
+			if _, err := tx.Exec(query, word, now.Unix(), now.Unix()); err != nil {
+				b.Fatal("expected err to be nil:", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal("expected err to be nil:", err)
+	}
+
+	pred := func(_ string) bool { return true }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := GetNewWordsWith(db, 10, 5, pred); err != nil {
+			b.Fatal("expected err to be nil:", err)
+		}
+	}
+}
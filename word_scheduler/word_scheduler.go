@@ -38,10 +38,19 @@ func GetWordsAt[T database.Querier](q T, n int, due time.Time) ([]Word, error) {
 
 // Returns up to words to make flashcards for.
 // Only includes words that satisfy the predicate.
+// Prefer GetWordsFiltered.
 func GetWordsWith[T database.Querier](q T, n int, pred func(word string) bool) ([]Word, error) {
+	return GetWordsFiltered(q, n, rs.Filter{Pred: pred})
+}
+
+// Returns up to n words to make flashcards for.
+// Only includes words that satisfy the filter.
+// New words always come from the course, so `filter.InCourse` and
+// `filter.InWordList` only apply to reviews.
+func GetWordsFiltered[T database.Querier](q T, n int, filter rs.Filter) ([]Word, error) {
 	var result []Word
 
-	reviews, err := rs.ScheduleReviewNowWith(q, n, pred)
+	reviews, err := rs.ScheduleReviewFiltered(q, time.Now(), n, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +62,11 @@ func GetWordsWith[T database.Querier](q T, n int, pred func(word string) bool) (
 	}
 
 	level := difficulty.GetLatest(q).Level
-	words, err := GetNewWordsWith(q, n-len(reviews), level, pred)
+	var cond string
+	if filter.SkipSparseWords {
+		cond = hasEnoughSentences
+	}
+	words, err := getNewWords(q, n-len(reviews), level, cond, filter.Match)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +74,7 @@ func GetWordsWith[T database.Querier](q T, n int, pred func(word string) bool) (
 }
 
 func frequencyClass[T database.Querier](q T, word string) int {
-	query := `select frequency_class from word where word = ?`
+	query := `select frequency_class from (` + customWords + `) where word = ?`
 	row := q.QueryRow(query, text.Casefold(word))
 
 	var result int
@@ -81,7 +94,7 @@ func UpdateWordAt[T database.Querier](q T, word string, correct bool, at time.Ti
 type ReviewResult = rs.Result
 
 // Saves word review results in bulk.
-func BulkSaveWords[T database.Querier](q T, reviews []ReviewResult, at time.Time) error {
+func BulkSaveWords[T database.Querier](q T, reviews []ReviewResult, at time.Time) ([]rs.SaveResult, error) {
 	// Client already casefolds words, but let's casefold again to be sure.
 	for i, review := range reviews {
 		reviews[i].Word = text.Casefold(review.Word)
@@ -0,0 +1,63 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+// Words the user wants to learn next.
+// The word scheduler introduces new words in the list before other new words.
+package wordlist
+
+import (
+	"fmt"
+
+	"github.com/polycloze/polycloze/database"
+	"github.com/polycloze/polycloze/text"
+)
+
+// Adds words to the list.
+// Words that aren't in the course are skipped.
+// Returns the number of words added.
+// Database connection should have access to course and review data.
+func Add[T database.Querier](q T, words []string) (int, error) {
+	query := `
+		INSERT OR IGNORE INTO word_list (item)
+		SELECT word FROM word WHERE word = ?
+	`
+	added := 0
+	for _, word := range words {
+		result, err := q.Exec(query, text.Casefold(word))
+		if err != nil {
+			return added, fmt.Errorf("failed to add to word list: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+	return added, nil
+}
+
+// Removes word from the list.
+func Remove[T database.Querier](q T, word string) error {
+	query := `DELETE FROM word_list WHERE item = ?`
+	if _, err := q.Exec(query, text.Casefold(word)); err != nil {
+		return fmt.Errorf("failed to remove from word list: %w", err)
+	}
+	return nil
+}
+
+// Returns words in the list, oldest first.
+func List[T database.Querier](q T) ([]string, error) {
+	rows, err := q.Query(`SELECT item FROM word_list ORDER BY added, item`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get word list: %w", err)
+	}
+	defer rows.Close()
+
+	words := make([]string, 0)
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("failed to get word list: %w", err)
+		}
+		words = append(words, word)
+	}
+	return words, nil
+}
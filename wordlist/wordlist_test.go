@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Levi Gruspe
+// License: GNU AGPLv3 or later
+
+package wordlist
+
+import (
+	"testing"
+
+	"github.com/polycloze/polycloze/utils"
+)
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	db := utils.TestingDatabase()
+	defer db.Close()
+
+	query := `INSERT INTO word (id, word, frequency_class) VALUES (1, 'haus', 0), (2, 'baum', 0)`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	// Words not in the course are skipped.
+	added, err := Add(db, []string{"Haus", "haus", "katze"})
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if added != 1 {
+		t.Fatal("expected only one word to be added:", added)
+	}
+
+	if err := Remove(db, "HAUS"); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if _, err := Add(db, []string{"baum"}); err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+
+	words, err := List(db)
+	if err != nil {
+		t.Fatal("expected err to be nil:", err)
+	}
+	if len(words) != 1 || words[0] != "baum" {
+		t.Fatal("unexpected word list:", words)
+	}
+}